@@ -0,0 +1,121 @@
+package httpx
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+)
+
+// APIKeySlot identifies which of the keys from a KeyProvider was accepted
+type APIKeySlot int
+
+const (
+	APIKeyPrimary APIKeySlot = iota
+	APIKeyFallback
+)
+
+// String returns "primary" or "fallback"
+func (s APIKeySlot) String() string {
+	if s == APIKeyFallback {
+		return "fallback"
+	}
+	return "primary"
+}
+
+// KeyProvider supplies the API keys used by SetAPIKey.
+//
+// During a rotation the new key is usually the primary and the old key the fallback, or the other way around,
+// until every server accepts the new key. Accepted reports which key the server took so the rollout can be
+// monitored and the fallback removed once it is no longer used.
+type KeyProvider interface {
+	// APIKeys returns the primary key and an optional fallback, empty if there is none
+	APIKeys(ctx context.Context) (primary, fallback string, err error)
+	// Accepted is called after a response that was not 401 Unauthorized
+	Accepted(ctx context.Context, slot APIKeySlot)
+}
+
+// SecretKeys returns a KeyProvider reading the primary and fallback keys from p by name.
+// An empty fallbackName disables the fallback, a fallback that is not found is ignored.
+// accepted may be nil
+func SecretKeys(p SecretProvider, primaryName, fallbackName string, accepted func(ctx context.Context, slot APIKeySlot)) KeyProvider {
+	return &secretKeys{p: p, primary: primaryName, fallback: fallbackName, accepted: accepted}
+}
+
+type secretKeys struct {
+	p                 SecretProvider
+	primary, fallback string
+	accepted          func(context.Context, APIKeySlot)
+}
+
+func (s *secretKeys) APIKeys(ctx context.Context) (string, string, error) {
+	primary, err := getSecret(ctx, s.p, s.primary)
+	if err != nil {
+		return "", "", err
+	}
+	if s.fallback == "" {
+		return primary, "", nil
+	}
+	fallback, err := s.p.Get(ctx, s.fallback)
+	if err != nil {
+		return primary, "", nil
+	}
+	return primary, fallback, nil
+}
+
+func (s *secretKeys) Accepted(ctx context.Context, slot APIKeySlot) {
+	if s.accepted != nil {
+		s.accepted(ctx, slot)
+	}
+}
+
+// SetAPIKey sets the header to the primary key of the provider. If the server responds 401 Unauthorized and the
+// provider has a fallback key the request is sent once more with the fallback.
+//
+// The request is not sent again if its body cannot be replayed, see SetRequestBody
+func SetAPIKey(c Client, header string, provider KeyProvider) ClientFunc {
+	c = nilClientCheck(c)
+	header = textproto.CanonicalMIMEHeaderKey(header)
+	return func(req *http.Request) (*http.Response, error) {
+		ctx := req.Context()
+		primary, fallback, err := provider.APIKeys(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("could not get api key: %w", err)
+		}
+		if req.Header == nil {
+			req.Header = make(http.Header)
+		}
+		req.Header.Set(header, primary)
+		resp, err := c.Do(req)
+		if err != nil {
+			return resp, err
+		}
+		if resp.StatusCode != http.StatusUnauthorized {
+			provider.Accepted(ctx, APIKeyPrimary)
+			return resp, nil
+		}
+		if fallback == "" || fallback == primary ||
+			(req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+			return resp, nil
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return resp, nil
+			}
+			req.Body = body
+		}
+		if resp.Body != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+			resp.Body.Close()
+		}
+		req.Header.Set(header, fallback)
+		resp, err = c.Do(req)
+		if err == nil && resp.StatusCode != http.StatusUnauthorized {
+			provider.Accepted(ctx, APIKeyFallback)
+		}
+		return resp, err
+	}
+}
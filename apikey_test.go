@@ -0,0 +1,52 @@
+package httpx_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tflyons/httpx"
+)
+
+func TestSetAPIKey_Fallback(t *testing.T) {
+	valid := "old-key"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != valid {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer srv.Close()
+	t.Setenv("KEY_NEW", "new-key")
+	t.Setenv("KEY_OLD", "old-key")
+
+	var accepted []httpx.APIKeySlot
+	keys := httpx.SecretKeys(httpx.EnvSecrets("KEY_"), "NEW", "OLD", func(_ context.Context, slot httpx.APIKeySlot) {
+		accepted = append(accepted, slot)
+	})
+	var c httpx.Client = srv.Client()
+	c = httpx.SetAPIKey(c, "x-api-key", keys)
+	c = httpx.RequireResponseStatus(c, http.StatusOK)
+	c = httpx.SetRequestBody(c, nil, []byte("payload"))
+	c = httpx.SetRequest(c, http.MethodPost, srv.URL)
+
+	// the server has not been rolled out yet so only the old key works
+	if _, err := c.Do(nil); err != nil {
+		t.Fatal(err)
+	}
+	valid = "new-key"
+	if _, err := c.Do(nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(accepted) != 2 || accepted[0] != httpx.APIKeyFallback || accepted[1] != httpx.APIKeyPrimary {
+		t.Fatal(accepted)
+	}
+
+	valid = "other-key"
+	if _, err := c.Do(nil); err == nil {
+		t.Fatal("expected error")
+	}
+	if len(accepted) != 2 {
+		t.Fatal(accepted)
+	}
+}
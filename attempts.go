@@ -0,0 +1,89 @@
+package httpx
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// AttemptResult is the outcome of one attempt of a request sent several times
+type AttemptResult struct {
+	// Number counts the attempts from 1
+	Number int
+	URL    string
+	// StatusCode is the status of the response, 0 if the attempt failed with Err
+	StatusCode int
+	Err        error
+	Start      time.Time
+	Duration   time.Duration
+}
+
+func newAttemptResult(number int, req *http.Request, resp *http.Response, err error, start, end time.Time) AttemptResult {
+	a := AttemptResult{Number: number, URL: req.URL.String(), Err: err, Start: start, Duration: end.Sub(start)}
+	if resp != nil {
+		a.StatusCode = resp.StatusCode
+	}
+	return a
+}
+
+// AttemptsError is returned by the decorators that send a request several times, such as SetRetry and SetFailover,
+// when it failed every time. It keeps the result of every attempt and matches the error of any of them,
+// and its own Err, with errors.Is and errors.As
+type AttemptsError struct {
+	Attempts []AttemptResult
+	// Err is why no further attempt was made if it was not the failure of the last attempt, such as ErrRetryDeadline
+	Err error
+}
+
+// Error summarizes every attempt on one line
+func (e *AttemptsError) Error() string {
+	parts := make([]string, len(e.Attempts))
+	for i, a := range e.Attempts {
+		result := fmt.Sprintf("status %d", a.StatusCode)
+		if a.Err != nil {
+			result = a.Err.Error()
+		}
+		parts[i] = fmt.Sprintf("attempt %d: %s after %s", a.Number, result, a.Duration.Round(time.Millisecond))
+	}
+	msg := fmt.Sprintf("%d attempts failed: %s", len(e.Attempts), strings.Join(parts, "; "))
+	if e.Err != nil {
+		msg = e.Err.Error() + ": " + msg
+	}
+	return msg
+}
+
+// Is matches Err and the error of every attempt
+func (e *AttemptsError) Is(target error) bool {
+	for _, err := range e.errs() {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first error in Err or the attempts, the last attempt first, that matches target
+func (e *AttemptsError) As(target any) bool {
+	for _, err := range e.errs() {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// errs returns Err and the errors of the attempts from the last to the first
+func (e *AttemptsError) errs() []error {
+	var errs []error
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+	for i := len(e.Attempts) - 1; i >= 0; i-- {
+		if e.Attempts[i].Err != nil {
+			errs = append(errs, e.Attempts[i].Err)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,62 @@
+package httpx_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx"
+)
+
+type attemptErr struct{ n int }
+
+func (e attemptErr) Error() string { return fmt.Sprintf("failure %d", e.n) }
+
+func TestAttemptsError(t *testing.T) {
+	errFirst := fmt.Errorf("first")
+	var calls int
+	var c httpx.Client = httpx.ClientFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		switch calls {
+		case 1:
+			return nil, errFirst
+		case 2:
+			return &http.Response{StatusCode: http.StatusBadGateway, Body: http.NoBody}, nil
+		}
+		return nil, attemptErr{n: calls}
+	})
+	c = httpx.SetRetry(c, httpx.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond})
+	_, err := httpx.SetRequest(c, http.MethodGet, "http://example.com").Do(nil)
+
+	var attempts *httpx.AttemptsError
+	if !errors.As(err, &attempts) || len(attempts.Attempts) != 3 || attempts.Attempts[1].StatusCode != http.StatusBadGateway {
+		t.Fatal(err)
+	}
+	// every attempt can be matched
+	var last attemptErr
+	if !errors.Is(err, errFirst) || !errors.As(err, &last) || last.n != 3 {
+		t.Fatal(err)
+	}
+	if msg := err.Error(); !strings.HasPrefix(msg, "3 attempts failed: attempt 1: first after") || !strings.Contains(msg, "attempt 2: status 502") {
+		t.Fatal(msg)
+	}
+}
+
+func TestAttemptsError_Failover(t *testing.T) {
+	e, err := httpx.NewEndpoints("http://a.example.com", "http://b.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	errDown := fmt.Errorf("down")
+	var c httpx.Client = httpx.ClientFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, errDown
+	})
+	_, err = httpx.SetRequest(httpx.SetFailover(c, e), http.MethodGet, "http://a.example.com/things").Do(nil)
+	var attempts *httpx.AttemptsError
+	if !errors.Is(err, errDown) || !errors.As(err, &attempts) || attempts.Attempts[1].URL != "http://b.example.com/things" {
+		t.Fatal(err)
+	}
+}
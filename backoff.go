@@ -0,0 +1,103 @@
+package httpx
+
+import (
+	"math"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// Backoff decides how long to wait before trying again after a failed attempt, see RetryPolicy.Backoff,
+// InitializerPolicy.Backoff and WithBackoff.
+// Implementations must be safe for concurrent use
+type Backoff interface {
+	// NextDelay returns the wait after the failed attempt, numbered from 1, with its response or error,
+	// and false to stop trying
+	NextDelay(attempt int, resp *http.Response, err error) (time.Duration, bool)
+}
+
+// BackoffFunc is an adapter to allow the use of ordinary functions as a Backoff
+type BackoffFunc func(attempt int, resp *http.Response, err error) (time.Duration, bool)
+
+// NextDelay calls f
+func (f BackoffFunc) NextDelay(attempt int, resp *http.Response, err error) (time.Duration, bool) {
+	return f(attempt, resp, err)
+}
+
+// ExponentialBackoff waits Initial after the first attempt, multiplying the wait by Multiplier for each further
+// attempt up to Max. Defaults to 100 milliseconds, 10 seconds and 2
+type ExponentialBackoff struct {
+	Initial    time.Duration
+	Max        time.Duration
+	Multiplier float64
+}
+
+// NextDelay implements Backoff
+func (b ExponentialBackoff) NextDelay(attempt int, _ *http.Response, _ error) (time.Duration, bool) {
+	initial, max := backoffDefaults(b.Initial, b.Max)
+	multiplier := b.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+	return capDelay(float64(initial)*math.Pow(multiplier, float64(attempt-1)), max), true
+}
+
+// ConstantBackoff waits the same time after every attempt
+type ConstantBackoff time.Duration
+
+// NextDelay implements Backoff
+func (b ConstantBackoff) NextDelay(int, *http.Response, error) (time.Duration, bool) {
+	return time.Duration(b), true
+}
+
+// FibonacciBackoff waits Initial after the first two attempts and then the sum of the previous two waits,
+// up to Max. Defaults to 100 milliseconds and 10 seconds. It grows more gently than ExponentialBackoff
+type FibonacciBackoff struct {
+	Initial time.Duration
+	Max     time.Duration
+}
+
+// NextDelay implements Backoff
+func (b FibonacciBackoff) NextDelay(attempt int, _ *http.Response, _ error) (time.Duration, bool) {
+	initial, max := backoffDefaults(b.Initial, b.Max)
+	prev, cur := 0.0, 1.0
+	for i := 1; i < attempt && float64(initial)*cur < float64(max); i++ {
+		prev, cur = cur, prev+cur
+	}
+	return capDelay(float64(initial)*cur, max), true
+}
+
+// DecorrelatedJitterBackoff waits a random time between Base and three times the previous upper bound, up to Max,
+// so that clients failing together do not retry together. Defaults to 100 milliseconds and 10 seconds
+type DecorrelatedJitterBackoff struct {
+	Base time.Duration
+	Max  time.Duration
+}
+
+// NextDelay implements Backoff
+func (b DecorrelatedJitterBackoff) NextDelay(attempt int, _ *http.Response, _ error) (time.Duration, bool) {
+	base, max := backoffDefaults(b.Base, b.Max)
+	upper := capDelay(float64(base)*math.Pow(3, float64(attempt)), max)
+	if upper <= base {
+		return upper, true
+	}
+	return base + time.Duration(rand.Int63n(int64(upper-base))), true
+}
+
+func backoffDefaults(initial, max time.Duration) (time.Duration, time.Duration) {
+	if initial <= 0 {
+		initial = 100 * time.Millisecond
+	}
+	if max <= 0 {
+		max = 10 * time.Second
+	}
+	return initial, max
+}
+
+// capDelay converts d to a duration no longer than max, d may be too large for a duration
+func capDelay(d float64, max time.Duration) time.Duration {
+	if d >= float64(max) {
+		return max
+	}
+	return time.Duration(d)
+}
@@ -0,0 +1,59 @@
+package httpx_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx"
+)
+
+func TestBackoff(t *testing.T) {
+	for name, tc := range map[string]struct {
+		backoff httpx.Backoff
+		want    []time.Duration
+	}{
+		"exponential": {httpx.ExponentialBackoff{Initial: time.Second, Max: 5 * time.Second}, []time.Duration{1, 2, 4, 5, 5}},
+		"multiplier":  {httpx.ExponentialBackoff{Initial: time.Second, Multiplier: 3}, []time.Duration{1, 3, 9, 10, 10}},
+		"constant":    {httpx.ConstantBackoff(2 * time.Second), []time.Duration{2, 2, 2, 2, 2}},
+		"fibonacci":   {httpx.FibonacciBackoff{Initial: time.Second, Max: 6 * time.Second}, []time.Duration{1, 1, 2, 3, 5, 6}},
+	} {
+		for i, want := range tc.want {
+			got, ok := tc.backoff.NextDelay(i+1, nil, nil)
+			if !ok || got != want*time.Second {
+				t.Error(name, i+1, got, ok)
+			}
+		}
+	}
+
+	jitter := httpx.DecorrelatedJitterBackoff{Base: time.Second, Max: 20 * time.Second}
+	for attempt, upper := range []time.Duration{3, 9, 20, 20} {
+		got, _ := jitter.NextDelay(attempt+1, nil, nil)
+		if got < time.Second || got > upper*time.Second {
+			t.Error("jitter", attempt+1, got)
+		}
+	}
+}
+
+func TestSetRetryBackoffStrategy(t *testing.T) {
+	var calls int
+	var c httpx.Client = httpx.ClientFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: http.NoBody}, nil
+	})
+	var waits []int
+	c = httpx.SetRetry(c, httpx.RetryPolicy{
+		MaxAttempts: 10,
+		Backoff: httpx.BackoffFunc(func(attempt int, resp *http.Response, err error) (time.Duration, bool) {
+			waits = append(waits, attempt)
+			// a custom strategy can stop retrying
+			return time.Millisecond, attempt < 3
+		}),
+	})
+	if _, err := httpx.SetRequest(c, http.MethodGet, "http://example.com").Do(nil); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 || len(waits) != 3 {
+		t.Fatal(calls, waits)
+	}
+}
@@ -0,0 +1,93 @@
+package httpx
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"net/http"
+)
+
+// LoadBalancerOptions configures SetLoadBalancer
+type LoadBalancerOptions struct {
+	// AffinityCookie is the name of a request cookie whose value is used as the affinity key when the context has
+	// none, see WithAffinityKey
+	AffinityCookie string
+	// TargetHeader, if set, is a response header set to the base url of the endpoint that served the request so
+	// that responses can be attributed to a target, for example to compare a canary with the stable endpoint
+	TargetHeader string
+}
+
+type affinityKey struct{}
+
+// WithAffinityKey returns a copy of ctx whose requests SetLoadBalancer sends to the same endpoint as every other
+// request with the same key, for example a user or session id
+func WithAffinityKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, affinityKey{}, key)
+}
+
+// SetLoadBalancer spreads requests over the healthy endpoints of e in proportion to their weights, see
+// Endpoints.SetWeight. The scheme and host of the request are
+// replaced by those of the selected endpoint and results update its health, see Endpoints.
+//
+// Requests with an affinity key, from WithAffinityKey or the AffinityCookie, are sticky: a key always maps to the
+// same endpoint using weighted rendezvous hashing, and when that endpoint is ejected only its keys move to other
+// endpoints, returning once it is healthy again
+func SetLoadBalancer(c Client, e *Endpoints, opts LoadBalancerOptions) ClientFunc {
+	c = nilClientCheck(c)
+	return func(req *http.Request) (*http.Response, error) {
+		ctx := req.Context()
+		clock := ClockFromContext(ctx)
+		candidates, weights := e.candidates(clock.Now())
+
+		key, _ := ctx.Value(affinityKey{}).(string)
+		if key == "" && opts.AffinityCookie != "" {
+			if cookie, err := req.Cookie(opts.AffinityCookie); err == nil {
+				key = cookie.Value
+			}
+		}
+		var ep *endpoint
+		if key != "" {
+			ep = rendezvous(key, candidates, weights)
+		} else {
+			ep = e.next(candidates)
+		}
+
+		resp, err := c.Do(withEndpoint(req, ep))
+		e.report(ep, endpointFailed(ctx, resp, err), clock.Now())
+		if resp != nil && opts.TargetHeader != "" {
+			if resp.Header == nil {
+				resp.Header = make(http.Header)
+			}
+			resp.Header.Set(opts.TargetHeader, ep.base.String())
+		}
+		return resp, err
+	}
+}
+
+// rendezvous returns the candidate with the highest weighted score for the key. The score of an endpoint is
+// -weight/ln(h) for a hash h of the key and its base url mapped into (0, 1), which selects endpoints in proportion
+// to their weights. weights holds the weight of each candidate
+func rendezvous(key string, candidates []*endpoint, weights []int) *endpoint {
+	var best *endpoint
+	bestScore := math.Inf(-1)
+	for i, ep := range candidates {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(key))
+		_, _ = h.Write([]byte{0})
+		_, _ = h.Write([]byte(ep.base.String()))
+		// fnv mixes the last bytes poorly, so spread the bits before using them
+		x := h.Sum64()
+		x ^= x >> 33
+		x *= 0xff51afd7ed558ccd
+		x ^= x >> 33
+		unit := (float64(x>>11) + 0.5) / (1 << 53)
+		w := float64(weights[i])
+		if w == 0 {
+			w = 1
+		}
+		if score := -w / math.Log(unit); best == nil || score > bestScore {
+			best, bestScore = ep, score
+		}
+	}
+	return best
+}
@@ -0,0 +1,171 @@
+package httpx_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/httpxtest"
+)
+
+func TestSetLoadBalancer(t *testing.T) {
+	down := map[string]bool{}
+	var urls []string
+	for i := 0; i < 3; i++ {
+		name := fmt.Sprint("server-", i)
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if down[name] {
+				w.WriteHeader(http.StatusBadGateway)
+			}
+			_, _ = w.Write([]byte(name))
+		}))
+		defer srv.Close()
+		urls = append(urls, srv.URL)
+	}
+	endpoints, err := httpx.NewEndpoints(urls...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpoints.EjectAfter = 1
+
+	clock := httpxtest.NewClock(time.Now())
+	var body string
+	var c httpx.Client = httpx.SetLoadBalancer(http.DefaultClient, endpoints, httpx.LoadBalancerOptions{AffinityCookie: "session"})
+	c = httpx.SetResponseBodyString(c, &body)
+	c = httpx.SetClock(c, clock)
+	get := func(ctx context.Context, cookies ...*http.Cookie) string {
+		t.Helper()
+		if _, err := httpx.SetRequestWithContext(ctx, httpx.AddCookies(c, cookies...), http.MethodGet, urls[0]).Do(nil); err != nil {
+			t.Fatal(err)
+		}
+		return body
+	}
+
+	// round robin without a key
+	seen := map[string]int{}
+	for i := 0; i < 6; i++ {
+		seen[get(context.Background())]++
+	}
+	if len(seen) != 3 || seen["server-0"] != 2 {
+		t.Fatal(seen)
+	}
+
+	// keys are sticky, from the context or the cookie
+	owners := map[string]string{}
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprint("user-", i)
+		owners[key] = get(httpx.WithAffinityKey(context.Background(), key))
+		for j := 0; j < 3; j++ {
+			if got := get(context.Background(), &http.Cookie{Name: "session", Value: key}); got != owners[key] {
+				t.Fatal(key, got, owners[key])
+			}
+		}
+	}
+
+	// ejecting an endpoint only moves its own keys
+	down["server-1"] = true
+	for key, owner := range owners {
+		got := get(httpx.WithAffinityKey(context.Background(), key))
+		if owner == "server-1" {
+			// the failed request ejects the endpoint, the next one is remapped
+			got = get(httpx.WithAffinityKey(context.Background(), key))
+			if got == "server-1" {
+				t.Fatal(key, "was not remapped")
+			}
+		} else if got != owner {
+			t.Fatal(key, "moved from", owner, "to", got)
+		}
+	}
+	down["server-1"] = false
+	clock.Advance(time.Minute)
+	for key, owner := range owners {
+		if got := get(httpx.WithAffinityKey(context.Background(), key)); got != owner {
+			t.Fatal(key, "did not return to", owner, got)
+		}
+	}
+}
+
+func TestSetLoadBalancer_Weights(t *testing.T) {
+	stable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer stable.Close()
+	canary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer canary.Close()
+	endpoints, err := httpx.NewEndpoints(stable.URL, canary.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := endpoints.SetWeight(stable.URL, 95); err != nil {
+		t.Fatal(err)
+	}
+	if err := endpoints.SetWeight(canary.URL, 5); err != nil {
+		t.Fatal(err)
+	}
+
+	c := httpx.SetLoadBalancer(http.DefaultClient, endpoints, httpx.LoadBalancerOptions{TargetHeader: "X-Served-By"})
+	count := func(ctx func(i int) context.Context) map[string]int {
+		served := map[string]int{}
+		for i := 0; i < 1000; i++ {
+			resp, err := httpx.SetRequestWithContext(ctx(i), c, http.MethodGet, stable.URL).Do(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			served[resp.Header.Get("X-Served-By")]++
+		}
+		return served
+	}
+	if served := count(func(int) context.Context { return context.Background() }); served[stable.URL] != 950 || served[canary.URL] != 50 {
+		t.Fatal(served)
+	}
+	sticky := count(func(i int) context.Context { return httpx.WithAffinityKey(context.Background(), fmt.Sprint(i)) })
+	if sticky[canary.URL] < 20 || sticky[canary.URL] > 90 {
+		t.Fatal("expected about 5% of keys on the canary", sticky)
+	}
+
+	// draining the canary at runtime
+	if err := endpoints.SetWeight(canary.URL, 0); err != nil {
+		t.Fatal(err)
+	}
+	if served := count(func(int) context.Context { return context.Background() }); served[canary.URL] != 0 {
+		t.Fatal(served)
+	}
+	if err := endpoints.SetWeight("http://unknown", 1); err == nil {
+		t.Fatal("expected unknown endpoint error")
+	}
+}
+
+// TestSetLoadBalancer_SetWeightConcurrently is meant to be run with -race
+func TestSetLoadBalancer_SetWeightConcurrently(t *testing.T) {
+	endpoints, err := httpx.NewEndpoints("http://a.example.com", "http://b.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok := httpx.ClientFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+	c := httpx.SetLoadBalancer(ok, endpoints, httpx.LoadBalancerOptions{})
+	stop, done := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+				_ = endpoints.SetWeight("http://b.example.com", 1+i%3)
+			}
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		ctx := httpx.WithAffinityKey(context.Background(), fmt.Sprint(i))
+		if _, err := httpx.SetRequestWithContext(ctx, c, http.MethodGet, "http://a.example.com/").Do(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(stop)
+	<-done
+}
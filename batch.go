@@ -0,0 +1,324 @@
+package httpx
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// BatchFormat is the wire format of a batch call
+type BatchFormat int
+
+const (
+	// BatchMultipart sends each request as an application/http part of a multipart/mixed body, as used by
+	// Google APIs. The parts of the multipart/mixed response are matched to the requests by Content-ID, or by
+	// position if the server does not echo it
+	BatchMultipart BatchFormat = iota
+	// BatchJSON sends the requests as a JSON envelope {"requests": [{"id", "method", "url", "headers", "body"}]}
+	// and reads {"responses": [{"id", "status", "headers", "body"}]}, as used by OData and Microsoft Graph. The url
+	// of each request is relative to the parent of the batch endpoint, so the batch URL
+	// https://graph.microsoft.com/v1.0/$batch sends a request for /v1.0/me as /me
+	BatchJSON
+)
+
+// Batch composes requests into a single batch call to URL. Only the method, path, query, headers and body of each
+// request are sent, their scheme and host are those of the batch endpoint
+type Batch struct {
+	Format BatchFormat
+	// URL is the batch endpoint, such as https://www.googleapis.com/batch/drive/v3
+	URL string
+
+	requests []*http.Request
+	ptrs     []any
+}
+
+// BatchResult is the outcome of one request of a batch
+type BatchResult struct {
+	// Response holds the response to the request with its body buffered, nil if none was returned
+	Response *http.Response
+	// Err is a *StatusError for a status outside of 2xx or the error decoding the body into the pointer given to Add
+	Err error
+}
+
+// Add adds req to the batch and returns its index in the results. The JSON body of a successful response is
+// decoded into ptr unless it is nil
+func (b *Batch) Add(req *http.Request, ptr any) int {
+	b.requests = append(b.requests, req)
+	b.ptrs = append(b.ptrs, ptr)
+	return len(b.requests) - 1
+}
+
+// Len returns the number of requests added
+func (b *Batch) Len() int {
+	return len(b.requests)
+}
+
+// Do sends the batch with c and returns one result per request, in the order they were added. An error is only
+// returned if the batch call itself failed, the outcome of each request is in its result
+func (b *Batch) Do(ctx context.Context, c Client) ([]BatchResult, error) {
+	c = nilClientCheck(c)
+	if len(b.requests) == 0 {
+		return nil, nil
+	}
+	var body []byte
+	var contentType string
+	var err error
+	switch b.Format {
+	case BatchMultipart:
+		body, contentType, err = b.encodeMultipart()
+	case BatchJSON:
+		body, err = b.encodeJSON()
+		contentType = "application/json"
+	default:
+		err = fmt.Errorf("unknown batch format %d", b.Format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var resp *http.Response
+	var respBody []byte
+	bc := RequireResponseStatus(c, http.StatusOK)
+	bc = SetResponseBodyBytes(bc, &respBody)
+	bc = SetRequestBody(bc, nil, body)
+	bc = SetHeader(bc, "Content-Type", contentType)
+	bc = SetRequestWithContext(ctx, bc, http.MethodPost, b.URL)
+	if resp, err = bc.Do(nil); err != nil {
+		if resp != nil && resp.Body != nil {
+			resp.Body.Close()
+		}
+		return nil, fmt.Errorf("batch call failed: %w", err)
+	}
+
+	var responses []*http.Response
+	if b.Format == BatchMultipart {
+		responses, err = b.decodeMultipart(resp.Header.Get("Content-Type"), respBody)
+	} else {
+		responses, err = b.decodeJSON(respBody)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read batch response: %w", err)
+	}
+	results := make([]BatchResult, len(b.requests))
+	for i, r := range responses {
+		results[i] = b.result(i, r)
+	}
+	return results, nil
+}
+
+// result checks the status of the response to request i and decodes its body
+func (b *Batch) result(i int, resp *http.Response) BatchResult {
+	if resp == nil {
+		return BatchResult{Err: fmt.Errorf("batch response has no result for request %d", i)}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return BatchResult{Response: resp, Err: &StatusError{StatusCode: resp.StatusCode, Header: resp.Header}}
+	}
+	if b.ptrs[i] == nil {
+		return BatchResult{Response: resp}
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err := jsonUnmarshal(body, b.ptrs[i]); err != nil {
+		return BatchResult{Response: resp, Err: fmt.Errorf("could not unmarshal response to request %d: %w", i, err)}
+	}
+	return BatchResult{Response: resp}
+}
+
+// requestBody returns the body of req without consuming a body that can be sent again
+func requestBody(req *http.Request) ([]byte, error) {
+	body := req.Body
+	if req.GetBody != nil {
+		var err error
+		if body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	if body == nil || body == http.NoBody {
+		return nil, nil
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+func (b *Batch) encodeMultipart() ([]byte, string, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for i, req := range b.requests {
+		body, err := requestBody(req)
+		if err != nil {
+			return nil, "", fmt.Errorf("could not read body of request %d: %w", i, err)
+		}
+		part, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type": {"application/http"},
+			"Content-Id":   {"<item-" + strconv.Itoa(i) + ">"},
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		fmt.Fprintf(part, "%s %s HTTP/1.1\r\n", req.Method, req.URL.RequestURI())
+		header := req.Header.Clone()
+		if header == nil {
+			header = make(http.Header)
+		}
+		if len(body) > 0 {
+			header.Set("Content-Length", strconv.Itoa(len(body)))
+		}
+		if err = header.Write(part); err != nil {
+			return nil, "", err
+		}
+		fmt.Fprint(part, "\r\n")
+		_, _ = part.Write(body)
+	}
+	if err := mw.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "multipart/mixed; boundary=" + mw.Boundary(), nil
+}
+
+func (b *Batch) decodeMultipart(contentType string, body []byte) ([]*http.Response, error) {
+	mt, params, err := mime.ParseMediaType(contentType)
+	if err != nil || !strings.HasPrefix(mt, "multipart/") || params["boundary"] == "" {
+		return nil, fmt.Errorf("unexpected content type %q", contentType)
+	}
+	responses := make([]*http.Response, len(b.requests))
+	mr := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	for n := 0; ; n++ {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return responses, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		resp, err := http.ReadResponse(bufio.NewReader(part), nil)
+		if err != nil {
+			return nil, fmt.Errorf("part %d: %w", n, err)
+		}
+		partBody, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("part %d: %w", n, err)
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(partBody))
+
+		i := n
+		id := strings.Trim(part.Header.Get("Content-Id"), "<>")
+		if _, item, ok := strings.Cut(id, "item-"); ok {
+			if i, err = strconv.Atoi(item); err != nil {
+				return nil, fmt.Errorf("part %d: invalid content id %q", n, id)
+			}
+		}
+		if i < 0 || i >= len(responses) {
+			return nil, fmt.Errorf("part %d: no request %d in the batch", n, i)
+		}
+		resp.Request = b.requests[i]
+		responses[i] = resp
+	}
+}
+
+// batchJSONRequest is a request of a BatchJSON envelope
+type batchJSONRequest struct {
+	ID      string            `json:"id"`
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// batchJSONResponse is a response of a BatchJSON envelope
+type batchJSONResponse struct {
+	ID      string            `json:"id"`
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers"`
+	Body    json.RawMessage   `json:"body"`
+}
+
+func (b *Batch) encodeJSON() ([]byte, error) {
+	var envelope struct {
+		Requests []batchJSONRequest `json:"requests"`
+	}
+	// request URLs are relative to the service root the batch endpoint is under, such as /v1.0
+	var root string
+	if u, err := url.Parse(b.URL); err == nil && strings.HasPrefix(u.Path, "/") {
+		root = strings.TrimSuffix(path.Dir(u.Path), "/")
+	}
+	for i, req := range b.requests {
+		r := batchJSONRequest{ID: strconv.Itoa(i), Method: req.Method, URL: relativeRequestURI(root, req.URL)}
+		for k := range req.Header {
+			if r.Headers == nil {
+				r.Headers = make(map[string]string)
+			}
+			r.Headers[k] = req.Header.Get(k)
+		}
+		body, err := requestBody(req)
+		if err != nil {
+			return nil, fmt.Errorf("could not read body of request %d: %w", i, err)
+		}
+		if len(body) > 0 {
+			if !json.Valid(body) {
+				// non JSON bodies are sent as a string, base64 encoded as Microsoft Graph expects
+				body, _ = json.Marshal(body)
+			}
+			r.Body = body
+		}
+		envelope.Requests = append(envelope.Requests, r)
+	}
+	return json.Marshal(envelope)
+}
+
+// relativeRequestURI returns the request URI of u without the leading path root
+func relativeRequestURI(root string, u *url.URL) string {
+	uri := u.RequestURI()
+	switch {
+	case root == "":
+		return uri
+	case uri == root:
+		return "/"
+	case strings.HasPrefix(uri, root+"/"):
+		return uri[len(root):]
+	case strings.HasPrefix(uri, root+"?"):
+		return "/" + uri[len(root):]
+	}
+	return uri
+}
+
+func (b *Batch) decodeJSON(body []byte) ([]*http.Response, error) {
+	var envelope struct {
+		Responses []batchJSONResponse `json:"responses"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, err
+	}
+	responses := make([]*http.Response, len(b.requests))
+	for _, r := range envelope.Responses {
+		i, err := strconv.Atoi(r.ID)
+		if err != nil || i < 0 || i >= len(responses) {
+			return nil, fmt.Errorf("no request with id %q in the batch", r.ID)
+		}
+		resp := &http.Response{StatusCode: r.Status, Status: fmt.Sprintf("%d %s", r.Status, http.StatusText(r.Status)), Header: make(http.Header), Request: b.requests[i]}
+		for k, v := range r.Headers {
+			resp.Header.Set(k, v)
+		}
+		var raw []byte
+		if !strings.Contains(resp.Header.Get("Content-Type"), "json") && json.Unmarshal(r.Body, &raw) == nil {
+			// the body of a non JSON response is a base64 encoded string
+			r.Body = raw
+		}
+		resp.ContentLength = int64(len(r.Body))
+		resp.Body = io.NopCloser(bytes.NewReader(r.Body))
+		responses[i] = resp
+	}
+	return responses, nil
+}
@@ -0,0 +1,170 @@
+package httpx_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/tflyons/httpx"
+)
+
+// thingAPI serves GET /things/{name} and POST /things
+var thingAPI = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/things":
+		var thing Thing
+		if err := json.NewDecoder(r.Body).Decode(&thing); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(thing)
+	case r.Method == http.MethodGet && r.URL.Path == "/things/a":
+		_ = json.NewEncoder(w).Encode(Thing{Foo: "a", Bar: 1})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{}`))
+	}
+})
+
+// newBatchRequests adds requests for thingAPI served under root, such as /v1.0
+func newBatchRequests(b *httpx.Batch, root string) (a, created *Thing) {
+	a, created = &Thing{}, &Thing{}
+	get, _ := http.NewRequest(http.MethodGet, root+"/things/a", nil)
+	post, _ := http.NewRequest(http.MethodPost, root+"/things", strings.NewReader(`{"Foo": "new", "Bar": 2}`))
+	post.Header.Set("Content-Type", "application/json")
+	missing, _ := http.NewRequest(http.MethodGet, root+"/things/missing", nil)
+	b.Add(get, a)
+	b.Add(post, created)
+	b.Add(missing, nil)
+	return a, created
+}
+
+func checkBatchResults(t *testing.T, results []httpx.BatchResult, a, created *Thing) {
+	t.Helper()
+	var statusErr *httpx.StatusError
+	if len(results) != 3 || results[0].Err != nil || results[1].Err != nil || !errors.As(results[2].Err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Fatal(results)
+	}
+	if *a != (Thing{Foo: "a", Bar: 1}) || *created != (Thing{Foo: "new", Bar: 2}) || results[1].Response.StatusCode != http.StatusCreated {
+		t.Fatal(a, created)
+	}
+}
+
+func TestBatch_Multipart(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		mr := multipart.NewReader(r.Body, params["boundary"])
+		var out bytes.Buffer
+		mw := multipart.NewWriter(&out)
+		var parts []func() error
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			inner, err := http.ReadRequest(bufio.NewReader(part))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			rec := httptest.NewRecorder()
+			thingAPI.ServeHTTP(rec, inner)
+			id := "<response-" + strings.Trim(part.Header.Get("Content-Id"), "<>") + ">"
+			resp := rec.Result()
+			parts = append(parts, func() error {
+				pw, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/http"}, "Content-Id": {id}})
+				if err != nil {
+					return err
+				}
+				return resp.Write(pw)
+			})
+		}
+		// answer in reverse order to check the responses are matched by content id
+		for i := len(parts) - 1; i >= 0; i-- {
+			if err := parts[i](); err != nil {
+				t.Error(err)
+			}
+		}
+		mw.Close()
+		w.Header().Set("Content-Type", "multipart/mixed; boundary="+mw.Boundary())
+		_, _ = w.Write(out.Bytes())
+	}))
+	defer srv.Close()
+
+	b := &httpx.Batch{Format: httpx.BatchMultipart, URL: srv.URL + "/batch"}
+	a, created := newBatchRequests(b, "")
+	results, err := b.Do(context.Background(), srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkBatchResults(t, results, a, created)
+}
+
+func TestBatch_JSON(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in struct {
+			Requests []struct {
+				ID      string            `json:"id"`
+				Method  string            `json:"method"`
+				URL     string            `json:"url"`
+				Headers map[string]string `json:"headers"`
+				Body    json.RawMessage   `json:"body"`
+			} `json:"requests"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var out struct {
+			Responses []map[string]any `json:"responses"`
+		}
+		for _, req := range in.Requests {
+			inner := httptest.NewRequest(req.Method, req.URL, bytes.NewReader(req.Body))
+			rec := httptest.NewRecorder()
+			thingAPI.ServeHTTP(rec, inner)
+			out.Responses = append(out.Responses, map[string]any{
+				"id":      req.ID,
+				"status":  rec.Code,
+				"headers": map[string]string{"Content-Type": rec.Header().Get("Content-Type")},
+				"body":    json.RawMessage(rec.Body.Bytes()),
+			})
+		}
+		_ = json.NewEncoder(w).Encode(out)
+	}))
+	defer srv.Close()
+
+	b := &httpx.Batch{Format: httpx.BatchJSON, URL: srv.URL + "/$batch"}
+	a, created := newBatchRequests(b, "")
+	results, err := b.Do(context.Background(), srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkBatchResults(t, results, a, created)
+	if b.Len() != 3 {
+		t.Fatal(b.Len())
+	}
+
+	// request URLs are sent relative to the service root of a versioned batch endpoint
+	b = &httpx.Batch{Format: httpx.BatchJSON, URL: srv.URL + "/v1.0/$batch"}
+	a, created = newBatchRequests(b, srv.URL+"/v1.0")
+	if results, err = b.Do(context.Background(), srv.Client()); err != nil {
+		t.Fatal(err)
+	}
+	checkBatchResults(t, results, a, created)
+}
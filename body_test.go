@@ -0,0 +1,62 @@
+package httpx_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tflyons/httpx"
+)
+
+func TestSetResponseBodyString(t *testing.T) {
+	srv := httptest.NewServer(echoHandler)
+	defer srv.Close()
+
+	var s string
+	var c httpx.Client = srv.Client()
+	c = httpx.SetResponseBodyString(c, &s)
+	c = httpx.SetRequestBody(c, nil, strings.NewReader("hello"))
+	c = httpx.SetRequest(c, http.MethodPost, srv.URL)
+	if _, err := c.Do(nil); err != nil {
+		t.Fatal(err)
+	}
+	if s != "hello" {
+		t.Fatal(s)
+	}
+}
+
+func TestSetResponseBodyBytes(t *testing.T) {
+	srv := httptest.NewServer(echoHandler)
+	defer srv.Close()
+
+	var b []byte
+	var c httpx.Client = srv.Client()
+	c = httpx.SetResponseBodyBytes(c, &b)
+	c = httpx.SetRequestBody(c, nil, []byte{1, 2, 3})
+	c = httpx.SetRequest(c, http.MethodPost, srv.URL)
+	if _, err := c.Do(nil); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, []byte{1, 2, 3}) {
+		t.Fatal(b)
+	}
+}
+
+func TestSetResponseBodyWriter(t *testing.T) {
+	srv := httptest.NewServer(echoHandler)
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	var c httpx.Client = srv.Client()
+	c = httpx.SetResponseBodyWriter(c, &buf)
+	c = httpx.SetRequestBody(c, nil, strings.NewReader("streamed"))
+	c = httpx.SetRequest(c, http.MethodPost, srv.URL)
+	if _, err := c.Do(nil); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "streamed" {
+		t.Fatal(buf.String())
+	}
+}
@@ -0,0 +1,79 @@
+package httpx_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/tflyons/httpx"
+)
+
+func TestBuilder(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Path", r.URL.Path)
+		w.Header().Set("X-Token", r.Header.Get("Authorization"))
+	}))
+	defer srv.Close()
+
+	b, err := httpx.NewBuilder(srv.Client(), httpx.Config{
+		BaseURL: srv.URL + "/v1",
+		Headers: http.Header{"Authorization": {"Bearer old"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := httpx.SetRequest(b, http.MethodGet, "/users")
+
+	resp, err := c.Do(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Header.Get("X-Path") != "/v1/users" || resp.Header.Get("X-Token") != "Bearer old" {
+		t.Fatal(resp.Header)
+	}
+
+	cfg := b.Config()
+	cfg.Headers = http.Header{"Authorization": {"Bearer new"}}
+	if err = b.Update(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if resp, err = c.Do(nil); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Header.Get("X-Token") != "Bearer new" {
+		t.Fatal(resp.Header)
+	}
+
+	// an invalid config keeps the current chain
+	if err = b.Update(httpx.Config{BaseURL: "relative/path"}); err == nil {
+		t.Fatal("expected error")
+	}
+	if b.Config().Headers.Get("Authorization") != "Bearer new" {
+		t.Fatal(b.Config())
+	}
+}
+
+func TestSetBaseURL(t *testing.T) {
+	var got string
+	var c httpx.Client = httpx.ClientFunc(func(req *http.Request) (*http.Response, error) {
+		got = req.URL.String()
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+	base, _ := url.Parse("https://api.example.com/v1")
+	c = httpx.SetBaseURL(c, base)
+
+	tests := map[string]string{
+		"/users?page=2":              "https://api.example.com/v1/users?page=2",
+		"users/1":                    "https://api.example.com/v1/users/1",
+		"https://other.example.com/": "https://other.example.com/",
+	}
+	for in, want := range tests {
+		if _, err := httpx.SetRequest(c, http.MethodGet, in).Do(nil); err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Fatal(in, got)
+		}
+	}
+}
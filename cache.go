@@ -0,0 +1,524 @@
+package httpx
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheStore holds serialized responses for a Cache. Implementations must be safe for concurrent use and may be
+// shared between processes, for example with Redis or memcached, see the cachestoretest package for a conformance
+// suite. A value stored with a ttl is gone once the ttl has passed by the clock of the context, a ttl of zero keeps
+// the value until it is deleted or evicted. Stores may evict values at any time.
+type CacheStore interface {
+	// Get returns the value stored under key and whether there was one
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value under key for ttl, replacing any previous value
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// SetIfAbsent stores value under key for ttl only if there is no value under key, and reports whether it did.
+	// The check and the write are atomic, as with SET NX in Redis or add in memcached
+	SetIfAbsent(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+	// Delete removes key, it is not an error if there is no such key
+	Delete(ctx context.Context, key string) error
+	// DeletePrefix removes every key starting with prefix.
+	// Stores without key enumeration, such as memcached, can keep a generation number per prefix instead
+	DeletePrefix(ctx context.Context, prefix string) error
+}
+
+// NewMemoryCacheStore returns a CacheStore local to the process without a size limit, see NewLRUCacheStore
+func NewMemoryCacheStore() CacheStore {
+	return NewLRUCacheStore(0)
+}
+
+// NewLRUCacheStore returns a CacheStore local to the process holding at most maxEntries values, evicting the least
+// recently used value to make room for a new one. There is no limit if maxEntries is zero
+func NewLRUCacheStore(maxEntries int) CacheStore {
+	return &memoryCacheStore{maxEntries: maxEntries, values: make(map[string]*list.Element), lru: list.New()}
+}
+
+type memoryCacheStore struct {
+	maxEntries int
+
+	mu     sync.Mutex
+	values map[string]*list.Element
+	// lru holds the values most recently used first
+	lru *list.List
+}
+
+type memoryCacheValue struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// get returns the live value of key, the lock must be held
+func (s *memoryCacheStore) get(key string, now time.Time) ([]byte, bool) {
+	e, ok := s.values[key]
+	if !ok {
+		return nil, false
+	}
+	v := e.Value.(*memoryCacheValue)
+	if !v.expires.IsZero() && !now.Before(v.expires) {
+		s.remove(e)
+		return nil, false
+	}
+	s.lru.MoveToFront(e)
+	return v.value, true
+}
+
+// set stores value under key, the lock must be held
+func (s *memoryCacheStore) set(key string, value []byte, ttl time.Duration, now time.Time) {
+	v := &memoryCacheValue{key: key, value: append([]byte(nil), value...)}
+	if ttl > 0 {
+		v.expires = now.Add(ttl)
+	}
+	if e, ok := s.values[key]; ok {
+		e.Value = v
+		s.lru.MoveToFront(e)
+		return
+	}
+	s.values[key] = s.lru.PushFront(v)
+	for s.maxEntries > 0 && s.lru.Len() > s.maxEntries {
+		s.remove(s.lru.Back())
+	}
+}
+
+// remove deletes the value of e, the lock must be held
+func (s *memoryCacheStore) remove(e *list.Element) {
+	s.lru.Remove(e)
+	delete(s.values, e.Value.(*memoryCacheValue).key)
+}
+
+func (s *memoryCacheStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	now := ClockFromContext(ctx).Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.get(key, now)
+	return v, ok, nil
+}
+
+func (s *memoryCacheStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	now := ClockFromContext(ctx).Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.set(key, value, ttl, now)
+	return nil
+}
+
+func (s *memoryCacheStore) SetIfAbsent(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	now := ClockFromContext(ctx).Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.get(key, now); ok {
+		return false, nil
+	}
+	s.set(key, value, ttl, now)
+	return true, nil
+}
+
+func (s *memoryCacheStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.values[key]; ok {
+		s.remove(e)
+	}
+	return nil
+}
+
+func (s *memoryCacheStore) DeletePrefix(_ context.Context, prefix string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, e := range s.values {
+		if strings.HasPrefix(k, prefix) {
+			s.remove(e)
+		}
+	}
+	return nil
+}
+
+// Cache stores successful GET responses for SetCache. A response is fresh for its Cache-Control max-age,
+// or until its Expires header, or for TTL if it has neither. Stale responses with an ETag or Last-Modified header
+// are revalidated with a conditional request. Responses marked no-store are never stored.
+// The zero value is not usable, see NewCache
+type Cache struct {
+	// KeyFunc returns the key a GET request is stored under, DefaultCacheKey if nil. A stored response only answers
+	// requests with the same values of the headers in its Vary header, include such headers to keep one response
+	// per value rather than replacing each other
+	KeyFunc func(*http.Request) string
+	// TTL is how long a response without freshness headers is fresh, such responses are not stored if zero
+	TTL time.Duration
+	// StaleWhileRevalidate is how long after a response becomes stale it is still served while it is refreshed in
+	// the background. The stale-while-revalidate directive of the response is used if it is longer
+	StaleWhileRevalidate time.Duration
+	// Private stores responses to requests with an Authorization or Cookie header that are not marked public or
+	// s-maxage. Set it only when the cache is used on behalf of a single user or KeyFunc tells users apart, as
+	// otherwise one user is served the response of another
+	Private bool
+
+	store CacheStore
+
+	mu         sync.Mutex
+	refreshing map[string]bool
+}
+
+// NewCache returns a cache keeping responses in store, a memory store if nil
+func NewCache(store CacheStore) *Cache {
+	if store == nil {
+		store = NewMemoryCacheStore()
+	}
+	return &Cache{store: store, refreshing: make(map[string]bool)}
+}
+
+// DefaultCacheKey is the URL of the request
+func DefaultCacheKey(req *http.Request) string {
+	return req.URL.String()
+}
+
+// Invalidate removes the response stored under key
+func (ca *Cache) Invalidate(ctx context.Context, key string) error {
+	if err := ca.store.Delete(ctx, key); err != nil {
+		return fmt.Errorf("could not invalidate cache key %q: %w", key, err)
+	}
+	return nil
+}
+
+// InvalidatePrefix removes every response stored under a key starting with prefix,
+// with the default keys for example every query of a path or everything under a base URL
+func (ca *Cache) InvalidatePrefix(ctx context.Context, prefix string) error {
+	if err := ca.store.DeletePrefix(ctx, prefix); err != nil {
+		return fmt.Errorf("could not invalidate cache prefix %q: %w", prefix, err)
+	}
+	return nil
+}
+
+func (ca *Cache) key(req *http.Request) string {
+	if ca.KeyFunc != nil {
+		return ca.KeyFunc(req)
+	}
+	return DefaultCacheKey(req)
+}
+
+// cacheEntry is the stored form of a response
+type cacheEntry struct {
+	Stored  time.Time `json:"stored"`
+	Expires time.Time `json:"expires"`
+	// StaleUntil ends the stale-while-revalidate window
+	StaleUntil time.Time   `json:"stale_until"`
+	Status     int         `json:"status"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+	// Vary holds the request headers named by the Vary header of the response, the entry only answers requests
+	// with the same values
+	Vary http.Header `json:"vary,omitempty"`
+}
+
+// varyHeader returns the request headers named by the Vary header of resp and false if the response varies by
+// something other than request headers
+func varyHeader(req *http.Request, resp http.Header) (http.Header, bool) {
+	var vary http.Header
+	for _, v := range resp.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			switch name {
+			case "":
+				continue
+			case "*":
+				return nil, false
+			}
+			if vary == nil {
+				vary = make(http.Header)
+			}
+			vary[name] = append([]string{}, req.Header.Values(name)...)
+		}
+	}
+	return vary, true
+}
+
+// matches reports whether req has the header values the entry varies by
+func (e *cacheEntry) matches(req *http.Request) bool {
+	for name, want := range e.Vary {
+		got := req.Header.Values(name)
+		if len(got) != len(want) {
+			return false
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (e *cacheEntry) response(req *http.Request, now time.Time) *http.Response {
+	header := e.Header.Clone()
+	header.Set("Age", strconv.Itoa(int(now.Sub(e.Stored)/time.Second)))
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.Status, http.StatusText(e.Status)),
+		StatusCode:    e.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// canRevalidate reports whether a conditional request can be made for the entry
+func (e *cacheEntry) canRevalidate() bool {
+	return e.Header.Get("ETag") != "" || e.Header.Get("Last-Modified") != ""
+}
+
+// freshness sets how long the entry may be served from the cache, fresh and then stale while it is refreshed,
+// counting from now. It returns false if the entry may not be stored at all
+func (ca *Cache) freshness(entry *cacheEntry, now time.Time) bool {
+	directives := cacheControl(entry.Header.Get("Cache-Control"))
+	if _, ok := directives["no-store"]; ok {
+		return false
+	}
+	fresh := ca.TTL
+	if _, ok := directives["no-cache"]; ok {
+		fresh = 0
+	} else if v, ok := directives["max-age"]; ok {
+		secs, err := strconv.Atoi(v)
+		if err != nil {
+			return false
+		}
+		fresh = time.Duration(secs) * time.Second
+	} else if v := entry.Header.Get("Expires"); v != "" {
+		// an invalid Expires means already expired
+		fresh = 0
+		if expires, err := http.ParseTime(v); err == nil {
+			date := now
+			if d, err := http.ParseTime(entry.Header.Get("Date")); err == nil {
+				date = d
+			}
+			fresh = expires.Sub(date)
+		}
+	}
+	stale := ca.StaleWhileRevalidate
+	if v, ok := directives["stale-while-revalidate"]; ok {
+		if secs, err := strconv.Atoi(v); err == nil && time.Duration(secs)*time.Second > stale {
+			stale = time.Duration(secs) * time.Second
+		}
+	}
+	entry.Stored, entry.Expires, entry.StaleUntil = now, now.Add(fresh), now.Add(fresh)
+	if fresh > 0 {
+		entry.StaleUntil = entry.Expires.Add(stale)
+	}
+	return fresh > 0 || entry.canRevalidate()
+}
+
+// cacheControl parses the directives of a Cache-Control header, lower cased and without quotes
+func cacheControl(v string) map[string]string {
+	directives := make(map[string]string)
+	for _, part := range strings.Split(v, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		if name != "" {
+			directives[strings.ToLower(name)] = strings.Trim(value, `"`)
+		}
+	}
+	return directives
+}
+
+// SetCache serves GET requests from the cache while their stored response is fresh, see Cache.
+//
+// Within the stale-while-revalidate window a stale response is returned straight away and refreshed through c in
+// the background, one refresh per key at a time. The refresh keeps the values of the request context but not its
+// cancellation. A successful POST, PUT, PATCH or DELETE invalidates the stored GET of the same URL and, as with the
+// default keys the query follows a '?', of every query of the same path. Requests made with the SkipCache override,
+// or with a Cache-Control: no-cache header, are sent upstream and their responses are stored. Requests with a Range
+// or If-Range header bypass the cache. Responses vary by the request headers named in their Vary header, those with
+// Vary: * are not stored, and responses to requests with credentials are only stored as described by Cache.Private.
+// Errors from the store are returned, wrap the store to ignore them instead
+func SetCache(c Client, ca *Cache) ClientFunc {
+	c = nilClientCheck(c)
+	return func(req *http.Request) (*http.Response, error) {
+		ctx := req.Context()
+		switch req.Method {
+		case http.MethodGet:
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+			resp, err := c.Do(req)
+			if err == nil && resp.StatusCode >= 200 && resp.StatusCode < 400 {
+				if err = ca.invalidateResource(ctx, req); err != nil {
+					return resp, err
+				}
+			}
+			return resp, err
+		default:
+			return c.Do(req)
+		}
+
+		if req.Header.Get("Range") != "" || req.Header.Get("If-Range") != "" {
+			// a partial response must not be served from, or stored as, the full representation
+			return c.Do(req)
+		}
+		key := ca.key(req)
+		var entry *cacheEntry
+		if !OverridesFromContext(ctx).SkipCache && !strings.Contains(strings.ToLower(req.Header.Get("Cache-Control")), "no-cache") {
+			b, ok, err := ca.store.Get(ctx, key)
+			if err != nil {
+				return nil, fmt.Errorf("could not read cache: %w", err)
+			}
+			if ok {
+				entry = &cacheEntry{}
+				if err = json.Unmarshal(b, entry); err != nil || !entry.matches(req) {
+					// a corrupt entry, or one for other values of the Vary headers, is replaced by the response
+					entry = nil
+				}
+			}
+		}
+		now := ClockFromContext(ctx).Now()
+		if entry != nil && now.Before(entry.Expires) {
+			return entry.response(req, now), nil
+		}
+		if entry != nil && now.Before(entry.StaleUntil) {
+			resp := entry.response(req, now)
+			ca.refresh(c, req, key, entry)
+			return resp, nil
+		}
+		return ca.fetch(c, req, key, entry)
+	}
+}
+
+// refresh fetches the entry again in the background unless a refresh of key is already running
+func (ca *Cache) refresh(c Client, req *http.Request, key string, entry *cacheEntry) {
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+	if ca.refreshing[key] {
+		return
+	}
+	ca.refreshing[key] = true
+	req = req.Clone(detachedContext{req.Context()})
+	go func() {
+		defer func() {
+			ca.mu.Lock()
+			delete(ca.refreshing, key)
+			ca.mu.Unlock()
+		}()
+		// a failed refresh leaves the stale entry, the request after the window sees the error
+		resp, _ := ca.fetch(c, req, key, entry)
+		if resp != nil && resp.Body != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+			resp.Body.Close()
+		}
+	}()
+}
+
+// fetch sends req, conditionally if entry can be revalidated, and stores the response
+func (ca *Cache) fetch(c Client, req *http.Request, key string, entry *cacheEntry) (*http.Response, error) {
+	ctx := req.Context()
+	clock := ClockFromContext(ctx)
+	// the header is copied so that the caller's request is left unchanged
+	if entry != nil && entry.canRevalidate() {
+		req = req.Clone(ctx)
+		if etag := entry.Header.Get("ETag"); etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if modified := entry.Header.Get("Last-Modified"); modified != "" {
+			req.Header.Set("If-Modified-Since", modified)
+		}
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return resp, err
+	}
+	now := clock.Now()
+	if resp.StatusCode == http.StatusNotModified && entry != nil && entry.canRevalidate() {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+		resp.Body.Close()
+		for k, v := range resp.Header {
+			entry.Header[k] = v
+		}
+		ca.freshness(entry, now)
+		return entry.response(req, now), ca.put(ctx, key, entry)
+	}
+	if resp.StatusCode != http.StatusOK || !ca.storable(req, resp) {
+		return resp, nil
+	}
+	vary, ok := varyHeader(req, resp.Header)
+	if !ok {
+		return resp, nil
+	}
+	entry = &cacheEntry{Status: resp.StatusCode, Header: resp.Header.Clone(), Vary: vary}
+	if !ca.freshness(entry, now) {
+		return resp, nil
+	}
+	b, err := io.ReadAll(resp.Body)
+	closeErr := resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(b))
+	if err != nil {
+		return resp, err
+	}
+	if closeErr != nil {
+		return resp, errBodyCloser{next: closeErr}
+	}
+	entry.Body = b
+	return resp, ca.put(ctx, key, entry)
+}
+
+// storable reports whether the response may be shared with other users of the cache: a response to a request with
+// credentials only if it is marked public or s-maxage, unless the cache is Private
+func (ca *Cache) storable(req *http.Request, resp *http.Response) bool {
+	if ca.Private || (req.Header.Get("Authorization") == "" && req.Header.Get("Cookie") == "") {
+		return true
+	}
+	directives := cacheControl(resp.Header.Get("Cache-Control"))
+	_, public := directives["public"]
+	_, shared := directives["s-maxage"]
+	return public || shared
+}
+
+func (ca *Cache) put(ctx context.Context, key string, entry *cacheEntry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("could not encode cache entry: %w", err)
+	}
+	// entries that can be revalidated stay useful once stale
+	var ttl time.Duration
+	if !entry.canRevalidate() {
+		ttl = entry.StaleUntil.Sub(entry.Stored)
+	}
+	if err = ca.store.Set(ctx, key, b, ttl); err != nil {
+		return fmt.Errorf("could not write cache: %w", err)
+	}
+	return nil
+}
+
+// invalidateResource removes the stored GET responses of the URL modified by req
+func (ca *Cache) invalidateResource(ctx context.Context, req *http.Request) error {
+	get := req.Clone(ctx)
+	get.Method = http.MethodGet
+	get.Body = nil
+	if err := ca.Invalidate(ctx, ca.key(get)); err != nil {
+		return err
+	}
+	u := *get.URL
+	u.RawQuery, u.ForceQuery = "", false
+	get.URL = &u
+	pathKey := ca.key(get)
+	if err := ca.Invalidate(ctx, pathKey); err != nil {
+		return err
+	}
+	return ca.InvalidatePrefix(ctx, pathKey+"?")
+}
+
+// detachedContext keeps the values of a context without its cancellation or deadline
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+func (d detachedContext) Value(key any) any         { return d.parent.Value(key) }
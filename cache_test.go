@@ -0,0 +1,284 @@
+package httpx_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/httpxtest"
+)
+
+func TestSetCache(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fmt.Fprintf(w, "response %d", n)
+	}))
+	defer srv.Close()
+
+	clock := httpxtest.NewClock(time.Now())
+	cache := httpx.NewCache(nil)
+	var c httpx.Client = srv.Client()
+	c = httpx.SetCache(c, cache)
+	c = httpx.SetClock(c, clock)
+	get := func(ctx context.Context) string {
+		t.Helper()
+		var body string
+		if _, err := httpx.SetRequestWithContext(ctx, httpx.SetResponseBodyString(c, &body), http.MethodGet, srv.URL+"/things/1").Do(nil); err != nil {
+			t.Fatal(err)
+		}
+		return body
+	}
+	ctx := context.Background()
+
+	if get(ctx) != "response 1" || get(ctx) != "response 1" || calls != 1 {
+		t.Fatal("expected a cached response", calls)
+	}
+	// a stale entry is revalidated
+	clock.Advance(time.Minute)
+	if get(ctx) != "response 1" || calls != 2 {
+		t.Fatal("expected a revalidated response", calls)
+	}
+	// the override bypasses the stored response
+	if get(httpx.WithOverride(ctx, httpx.SkipCache())) != "response 3" {
+		t.Fatal("expected a fresh response", calls)
+	}
+	if err := cache.Invalidate(ctx, srv.URL+"/things/1"); err != nil {
+		t.Fatal(err)
+	}
+	if get(ctx) != "response 4" {
+		t.Fatal("expected the entry to be invalidated", calls)
+	}
+}
+
+func TestSetCacheInvalidation(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		if r.Method == http.MethodGet {
+			fmt.Fprintf(w, "%s %d", r.URL.RequestURI(), n)
+		}
+	}))
+	defer srv.Close()
+
+	cache := httpx.NewCache(nil)
+	cache.TTL = time.Minute
+	var c httpx.Client = srv.Client()
+	c = httpx.SetCache(c, cache)
+	do := func(method, path string) string {
+		t.Helper()
+		var body string
+		if _, err := httpx.SetRequest(httpx.SetResponseBodyString(c, &body), method, srv.URL+path).Do(nil); err != nil {
+			t.Fatal(err)
+		}
+		return body
+	}
+
+	user, list, other := do(http.MethodGet, "/users/1"), do(http.MethodGet, "/users/1?fields=name"), do(http.MethodGet, "/users/2")
+	// a successful update invalidates every query of the path
+	do(http.MethodPut, "/users/1")
+	if do(http.MethodGet, "/users/1") == user || do(http.MethodGet, "/users/1?fields=name") == list {
+		t.Fatal("expected the updated resource to be fetched again")
+	}
+	if do(http.MethodGet, "/users/2") != other {
+		t.Fatal("expected other resources to stay cached")
+	}
+
+	// everything under a prefix
+	if err := cache.InvalidatePrefix(context.Background(), srv.URL+"/users/"); err != nil {
+		t.Fatal(err)
+	}
+	if do(http.MethodGet, "/users/2") == other {
+		t.Fatal("expected the prefix to be invalidated")
+	}
+}
+
+func TestSetCacheKeyFunc(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		_, _ = w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer srv.Close()
+
+	cache := httpx.NewCache(nil)
+	cache.KeyFunc = func(req *http.Request) string {
+		return req.Header.Get("Authorization") + " " + httpx.DefaultCacheKey(req)
+	}
+	// the key tells users apart so their responses can be stored
+	cache.Private = true
+	var c httpx.Client = srv.Client()
+	c = httpx.SetCache(c, cache)
+	for _, user := range []string{"alice", "bob", "alice"} {
+		var body string
+		uc := httpx.SetResponseBodyString(httpx.SetHeader(c, "Authorization", user), &body)
+		if _, err := httpx.SetRequest(uc, http.MethodGet, srv.URL).Do(nil); err != nil {
+			t.Fatal(err)
+		}
+		if body != user {
+			t.Fatal(body, user)
+		}
+	}
+	if calls != 2 {
+		t.Fatal(calls)
+	}
+}
+
+func TestSetCacheSharedUsers(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		cc := "max-age=60"
+		if r.URL.Path == "/public" {
+			cc += ", public"
+		}
+		w.Header().Set("Cache-Control", cc)
+		w.Header().Set("Vary", "Accept-Language")
+		_, _ = w.Write([]byte(r.Header.Get("Authorization") + r.Header.Get("Accept-Language")))
+	}))
+	defer srv.Close()
+
+	c := httpx.SetCache(srv.Client(), httpx.NewCache(nil))
+	get := func(path, auth, lang string) string {
+		var body string
+		var uc httpx.Client = httpx.SetResponseBodyString(c, &body)
+		if auth != "" {
+			uc = httpx.SetHeader(uc, "Authorization", auth)
+		}
+		uc = httpx.SetHeader(uc, "Accept-Language", lang)
+		if _, err := httpx.SetRequest(uc, http.MethodGet, srv.URL+path).Do(nil); err != nil {
+			t.Fatal(err)
+		}
+		return body
+	}
+	if get("/private", "alice", "en") != "aliceen" || get("/private", "bob", "en") != "boben" || atomic.LoadInt32(&calls) != 2 {
+		t.Fatal("a response to a request with credentials should not be served to another user", calls)
+	}
+	if get("/public", "alice", "en") != "aliceen" || get("/public", "bob", "en") != "aliceen" || atomic.LoadInt32(&calls) != 3 {
+		t.Fatal("a public response should be stored", calls)
+	}
+	if get("/", "", "en") != "en" || get("/", "", "fr") != "fr" || get("/", "", "fr") != "fr" || atomic.LoadInt32(&calls) != 5 {
+		t.Fatal("a response should only answer requests with the same values of its Vary headers", calls)
+	}
+}
+
+func TestSetCacheStaleWhileRevalidate(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		if n > 1 {
+			<-release
+		}
+		w.Header().Set("Cache-Control", "max-age=60, stale-while-revalidate=60")
+		fmt.Fprintf(w, "response %d", n)
+	}))
+	defer srv.Close()
+
+	clock := httpxtest.NewClock(time.Now())
+	var c httpx.Client = srv.Client()
+	c = httpx.SetCache(c, httpx.NewCache(nil))
+	c = httpx.SetClock(c, clock)
+	get := func() string {
+		t.Helper()
+		var body string
+		if _, err := httpx.SetRequest(httpx.SetResponseBodyString(c, &body), http.MethodGet, srv.URL).Do(nil); err != nil {
+			t.Fatal(err)
+		}
+		return body
+	}
+
+	get()
+	clock.Advance(90 * time.Second)
+	// stale responses are served while a single refresh is blocked upstream
+	for i := 0; i < 3; i++ {
+		if body := get(); body != "response 1" {
+			t.Fatal(body)
+		}
+	}
+	close(release)
+	for deadline := time.Now().Add(5 * time.Second); get() != "response 2"; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the refreshed response")
+		}
+	}
+	if calls != 2 {
+		t.Fatal("expected a single refresh", calls)
+	}
+
+	// past the window the response is fetched before returning
+	clock.Advance(3 * time.Minute)
+	if body := get(); body != "response 3" {
+		t.Fatal(body)
+	}
+}
+
+func TestSetCacheRange(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader("0123456789"))
+	}))
+	defer srv.Close()
+
+	var c httpx.Client = httpx.SetCache(srv.Client(), httpx.NewCache(nil))
+	get := func(rng string) string {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+		if rng != "" {
+			req.Header.Set("Range", rng)
+		}
+		resp, err := c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return string(b)
+	}
+	if body := get(""); body != "0123456789" {
+		t.Fatal(body)
+	}
+	if body := get("bytes=2-4"); body != "234" {
+		t.Fatal("a range request should not be answered with the cached full response", body)
+	}
+	if body := get(""); body != "0123456789" || calls != 2 {
+		t.Fatal("a range response should not replace the cached full response", body, calls)
+	}
+}
+
+func TestLRUCacheStore(t *testing.T) {
+	ctx := context.Background()
+	s := httpx.NewLRUCacheStore(2)
+	for _, key := range []string{"a", "b"} {
+		if err := s.Set(ctx, key, []byte(key), 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a is now used more recently than b, so b makes room for c
+	if _, ok, _ := s.Get(ctx, "a"); !ok {
+		t.Fatal("expected a")
+	}
+	if err := s.Set(ctx, "c", []byte("c"), 0); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, ok, _ := s.Get(ctx, key); ok != want {
+			t.Fatal(key, ok)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+// Package cachestoretest checks that an httpx.CacheStore behaves as the cache decorator expects, so that adapters
+// for stores such as Redis or memcached can be tested against the same contract as the stores of this module:
+//
+//	func TestRedisStore(t *testing.T) {
+//		cachestoretest.Run(t, NewRedisStore(client))
+//	}
+package cachestoretest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx"
+)
+
+// TTL is the time to live used to check expiry, stores with a coarser expiry must round it up rather than down
+var TTL = 50 * time.Millisecond
+
+// Run checks store against the CacheStore contract in subtests. Keys are prefixed with a value unique to the run
+// so a store shared with other data can be used, and the keys written are deleted afterwards
+func Run(t *testing.T, store httpx.CacheStore) {
+	ctx := context.Background()
+	prefix := fmt.Sprintf("cachestoretest/%d/", time.Now().UnixNano())
+	t.Cleanup(func() {
+		_ = store.DeletePrefix(ctx, prefix)
+	})
+	key := func(t *testing.T, name string) string {
+		return prefix + t.Name() + "/" + name
+	}
+
+	t.Run("GetMissing", func(t *testing.T) {
+		if v, ok, err := store.Get(ctx, key(t, "missing")); err != nil || ok || v != nil {
+			t.Fatal(v, ok, err)
+		}
+	})
+	t.Run("SetGet", func(t *testing.T) {
+		// every byte value must round trip
+		value := make([]byte, 256)
+		for i := range value {
+			value[i] = byte(i)
+		}
+		set(t, store, key(t, "a"), value, 0)
+		expect(t, store, key(t, "a"), value)
+		set(t, store, key(t, "a"), []byte("replaced"), 0)
+		expect(t, store, key(t, "a"), []byte("replaced"))
+		set(t, store, key(t, "empty"), []byte{}, 0)
+		expect(t, store, key(t, "empty"), []byte{})
+	})
+	t.Run("Delete", func(t *testing.T) {
+		set(t, store, key(t, "a"), []byte("a"), 0)
+		if err := store.Delete(ctx, key(t, "a")); err != nil {
+			t.Fatal(err)
+		}
+		expect(t, store, key(t, "a"), nil)
+		if err := store.Delete(ctx, key(t, "a")); err != nil {
+			t.Fatal("deleting a missing key must not fail:", err)
+		}
+	})
+	t.Run("DeletePrefix", func(t *testing.T) {
+		set(t, store, key(t, "users/1"), []byte("1"), 0)
+		set(t, store, key(t, "users/1?fields=name"), []byte("2"), 0)
+		set(t, store, key(t, "users2"), []byte("3"), 0)
+		if err := store.DeletePrefix(ctx, key(t, "users/")); err != nil {
+			t.Fatal(err)
+		}
+		expect(t, store, key(t, "users/1"), nil)
+		expect(t, store, key(t, "users/1?fields=name"), nil)
+		expect(t, store, key(t, "users2"), []byte("3"))
+	})
+	t.Run("TTL", func(t *testing.T) {
+		set(t, store, key(t, "short"), []byte("short"), TTL)
+		set(t, store, key(t, "forever"), []byte("forever"), 0)
+		expect(t, store, key(t, "short"), []byte("short"))
+		time.Sleep(TTL + TTL/2)
+		expect(t, store, key(t, "short"), nil)
+		expect(t, store, key(t, "forever"), []byte("forever"))
+	})
+	t.Run("SetIfAbsent", func(t *testing.T) {
+		if ok, err := store.SetIfAbsent(ctx, key(t, "a"), []byte("first"), TTL); err != nil || !ok {
+			t.Fatal("expected an absent key to be set", ok, err)
+		}
+		if ok, err := store.SetIfAbsent(ctx, key(t, "a"), []byte("second"), 0); err != nil || ok {
+			t.Fatal("expected a present key to be kept", ok, err)
+		}
+		expect(t, store, key(t, "a"), []byte("first"))
+		// an expired value is absent
+		time.Sleep(TTL + TTL/2)
+		if ok, err := store.SetIfAbsent(ctx, key(t, "a"), []byte("third"), 0); err != nil || !ok {
+			t.Fatal("expected an expired key to be set", ok, err)
+		}
+		expect(t, store, key(t, "a"), []byte("third"))
+	})
+	t.Run("SetIfAbsentConcurrent", func(t *testing.T) {
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		var won int
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				ok, err := store.SetIfAbsent(ctx, key(t, "lock"), []byte(fmt.Sprint(i)), 0)
+				if err != nil {
+					t.Error(err)
+				}
+				if ok {
+					mu.Lock()
+					won++
+					mu.Unlock()
+				}
+			}(i)
+		}
+		wg.Wait()
+		if won != 1 {
+			t.Fatal("expected exactly one SetIfAbsent to succeed, got", won)
+		}
+	})
+	t.Run("ValuesAreCopied", func(t *testing.T) {
+		value := []byte("original")
+		set(t, store, key(t, "a"), value, 0)
+		copy(value, "modified")
+		expect(t, store, key(t, "a"), []byte("original"))
+	})
+}
+
+func set(t *testing.T, store httpx.CacheStore, key string, value []byte, ttl time.Duration) {
+	t.Helper()
+	if err := store.Set(context.Background(), key, value, ttl); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// expect checks the value under key, nil meaning there must be none
+func expect(t *testing.T, store httpx.CacheStore, key string, want []byte) {
+	t.Helper()
+	got, ok, err := store.Get(context.Background(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want == nil {
+		if ok {
+			t.Fatalf("expected no value for %q, got %q", key, got)
+		}
+		return
+	}
+	if !ok || !bytes.Equal(got, want) {
+		t.Fatalf("expected %q for %q, got %q (found %v)", want, key, got, ok)
+	}
+}
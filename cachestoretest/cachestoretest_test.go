@@ -0,0 +1,24 @@
+package cachestoretest_test
+
+import (
+	"testing"
+
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/cachestoretest"
+)
+
+func TestMemoryCacheStore(t *testing.T) {
+	cachestoretest.Run(t, httpx.NewMemoryCacheStore())
+}
+
+func TestLRUCacheStore(t *testing.T) {
+	cachestoretest.Run(t, httpx.NewLRUCacheStore(1000))
+}
+
+func TestDiskCacheStore(t *testing.T) {
+	store, err := httpx.NewDiskCacheStore(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cachestoretest.Run(t, store)
+}
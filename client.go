@@ -3,7 +3,6 @@ package httpx
 import (
 	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
@@ -75,7 +74,7 @@ func RequireResponseBody(c Client) ClientFunc {
 	}
 }
 
-// RequireResponseStatus returns a non-nil error if the response status does not match one of the statuses given
+// RequireResponseStatus returns a *StatusError if the response status does not match one of the statuses given
 func RequireResponseStatus(c Client, status ...int) ClientFunc {
 	c = nilClientCheck(c)
 	if len(status) == 0 {
@@ -91,7 +90,7 @@ func RequireResponseStatus(c Client, status ...int) ClientFunc {
 			return resp, err
 		}
 		if !valid[resp.StatusCode] {
-			return resp, fmt.Errorf("received invalid satus code: %d", resp.StatusCode)
+			return resp, &StatusError{StatusCode: resp.StatusCode, Header: resp.Header}
 		}
 		return resp, nil
 	}
@@ -130,13 +129,15 @@ type Marshaller func(v any) ([]byte, error)
 type Unmarshaller func(b []byte, v any) error
 
 // SetRequestBody sets the value v to the request body using the given Marshaller
+//
+// Marshalled values and byte slices can be sent again by retrying decorators, readers cannot
 func SetRequestBody(c Client, m Marshaller, v any) ClientFunc {
 	c = nilClientCheck(c)
 	return func(req *http.Request) (*http.Response, error) {
 		if m == nil {
 			switch t := v.(type) {
 			case []byte:
-				req.Body = io.NopCloser(bytes.NewReader(t))
+				setBodyBytes(req, t)
 			case io.ReadCloser:
 				req.Body = t
 			case io.Reader:
@@ -149,19 +150,30 @@ func SetRequestBody(c Client, m Marshaller, v any) ClientFunc {
 			if err != nil {
 				return nil, fmt.Errorf("could not marshal request body: %w", err)
 			}
-			req.Body = io.NopCloser(bytes.NewReader(b))
+			setBodyBytes(req, b)
 		}
 		return c.Do(req)
 	}
 }
 
+// setBodyBytes sets b as the request body in a way that allows the body to be sent again, for example on retry
+func setBodyBytes(req *http.Request, b []byte) {
+	req.ContentLength = int64(len(b))
+	req.Body = io.NopCloser(bytes.NewReader(b))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(b)), nil
+	}
+}
+
 // SetRequestBodyJSON is a helper function around SetHeader and SetRequestBody for json specific encoding
 func SetRequestBodyJSON(c Client, v any) ClientFunc {
 	c = SetHeader(c, "Content-Type", "application/json")
-	return SetRequestBody(c, json.Marshal, v)
+	return SetRequestBody(c, jsonMarshal, v)
 }
 
 // SetResponseBodyHandler adds a function to unmarshal the response body into a given pointer ptr
+//
+// If u is nil the Unmarshaller registered for the response Content-Type is used, see SetResponseBodyAuto
 func SetResponseBodyHandler(c Client, u Unmarshaller, ptr any) ClientFunc {
 	c = RequireResponseBody(c)
 	return func(req *http.Request) (*http.Response, error) {
@@ -175,7 +187,12 @@ func SetResponseBodyHandler(c Client, u Unmarshaller, ptr any) ClientFunc {
 			return resp, err
 		}
 		resp.Body = io.NopCloser(bytes.NewBuffer(b))
-		if err = u(b, ptr); err != nil {
+		if u == nil {
+			err = unmarshalAuto(resp.Header.Get("Content-Type"), b, ptr)
+		} else {
+			err = u(b, ptr)
+		}
+		if err != nil {
 			return resp, err
 		}
 		if closeErr != nil {
@@ -188,14 +205,59 @@ func SetResponseBodyHandler(c Client, u Unmarshaller, ptr any) ClientFunc {
 // SetResponseJSONReader performs the request and attempts to unmarshal the response body as json
 func SetResponseBodyHandlerJSON(c Client, ptr any) ClientFunc {
 	c = SetHeader(c, "Accept", "application/json")
-	return SetResponseBodyHandler(c, json.Unmarshal, ptr)
+	return SetResponseBodyHandler(c, jsonUnmarshal, ptr)
+}
+
+// SetResponseBodyBytes performs the request and copies the raw response body into b
+func SetResponseBodyBytes(c Client, b *[]byte) ClientFunc {
+	return SetResponseBodyHandler(c, func(body []byte, _ any) error {
+		*b = append([]byte(nil), body...)
+		return nil
+	}, b)
+}
+
+// SetResponseBodyString performs the request and stores the response body as a string in s
+func SetResponseBodyString(c Client, s *string) ClientFunc {
+	return SetResponseBodyHandler(c, func(body []byte, _ any) error {
+		*s = string(body)
+		return nil
+	}, s)
+}
+
+// SetResponseBodyWriter performs the request and streams the response body into w.
+//
+// The body is not buffered so the returned response has an empty body.
+func SetResponseBodyWriter(c Client, w io.Writer) ClientFunc {
+	c = RequireResponseBody(c)
+	return func(req *http.Request) (*http.Response, error) {
+		resp, err := c.Do(req)
+		if err != nil {
+			return resp, err
+		}
+		_, err = io.Copy(w, resp.Body)
+		closeErr := resp.Body.Close()
+		resp.Body = http.NoBody
+		if err != nil {
+			return resp, fmt.Errorf("could not write response body: %w", err)
+		}
+		if closeErr != nil {
+			return resp, errBodyCloser{next: closeErr}
+		}
+		return resp, nil
+	}
 }
 
 // SetTimeout sets a time limit on the entire lifetime of the request including connection and header reads
+//
+// The limit can be changed for a single request with WithOverride and OverrideTimeout
 func SetTimeout(c Client, d time.Duration) ClientFunc {
 	c = nilClientCheck(c)
 	return func(req *http.Request) (*http.Response, error) {
-		ctx, cancel := context.WithTimeout(req.Context(), d)
+		timeout := d
+		if o := OverridesFromContext(req.Context()); o.Timeout > 0 {
+			timeout = o.Timeout
+		}
+		ctx, cancel := withClockTimeout(req.Context(), ClockFromContext(req.Context()), timeout)
 		defer cancel()
 		req = req.Clone(ctx)
 		return c.Do(req)
@@ -229,59 +291,12 @@ func SetCookies(c Client, cookie ...*http.Cookie) ClientFunc {
 // For example, if max is set to 100 and duration is set to 1*time.Minute then the client can perform
 // at most 100 requests per minute.
 // All of these requests may occur at any time within that minute.
+// A window starts with the first request after the previous window has ended and is measured by the clock
+// of the request, see SetClock. Requests made with the SkipRateLimit override are not limited.
 //
-// Duration must be greater than 0 or else the underlying function will panic.
-// Max must be greater than 0 or else the client may deadlock
+// Duration must be greater than 0 or else the function will panic.
+// Max must be greater than 0 or else the client may deadlock.
+// See SetLimiter to share a limit between processes
 func SetRateLimit(c Client, max int, duration time.Duration) ClientFunc {
-	c = nilClientCheck(c)
-	ticker := time.NewTicker(duration)
-	ch := make(chan struct{}, max)
-	go func() {
-		// every time interval, drain the channel
-		for range ticker.C {
-			for i := 0; i < max; i++ {
-				select {
-				case <-ch:
-				default:
-					break
-				}
-			}
-		}
-	}()
-	return func(req *http.Request) (*http.Response, error) {
-		select {
-		case <-req.Context().Done():
-			// if it has timed out return an error
-			return nil, fmt.Errorf("request timed out during rate limit: %w", req.Context().Err())
-
-		case ch <- struct{}{}:
-			// we're still within the rate limit
-		}
-		return c.Do(req)
-	}
-}
-
-// Initializer is a function signature that accepts a Client and returns either a client function or an error
-type Initializer func(Client) (ClientFunc, error)
-
-// SetInitializer is a helper function for constructing clients that may need to initialize with some
-// external dependency. It will retry the init function until it suceeds
-func SetInitializer(c Client, init Initializer) ClientFunc {
-	c = nilClientCheck(c)
-	oneAtATime := make(chan struct{}, 1)
-	oneAtATime <- struct{}{}
-	var f ClientFunc
-	return func(req *http.Request) (*http.Response, error) {
-		_, ok := <-oneAtATime
-		if ok {
-			var err error
-			f, err = init(c)
-			if err != nil {
-				oneAtATime <- struct{}{}
-				return nil, err
-			}
-			close(oneAtATime)
-		}
-		return f.Do(req)
-	}
+	return SetLimiter(c, NewWindowLimiter(NewMemoryWindowStore(), "", max, duration))
 }
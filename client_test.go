@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/httpxtest"
 )
 
 var echoHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -73,26 +74,37 @@ func TestClient_SetCookies(t *testing.T) {
 func TestRateLimit(t *testing.T) {
 	srv := httptest.NewServer(echoHandler)
 	defer srv.Close()
+	clock := httpxtest.NewClock(time.Now())
 	var c httpx.Client = srv.Client()
 
 	rateLimit := 20
 	d := time.Millisecond * 50
-	n := 10
 	c = httpx.SetRateLimit(c, rateLimit, d)
+	c = httpx.SetClock(c, clock)
 	c = httpx.SetRequest(c, http.MethodGet, srv.URL)
 
-	// make enough requests to get rate limited n times
-	start := time.Now()
-	for i := 0; i < rateLimit*n; i++ {
+	// the whole limit can be used at once
+	for i := 0; i < rateLimit; i++ {
 		if _, err := c.Do(nil); err != nil {
 			t.Fatal(err)
 		}
 	}
-	end := time.Now()
 
-	// the time difference should be greater than or equal to n durations
-	if end.Sub(start) < d*time.Duration(n) {
+	// the next request waits until the duration has passed
+	done := make(chan error)
+	go func() {
+		_, err := c.Do(nil)
+		done <- err
+	}()
+	clock.BlockUntil(1)
+	select {
+	case <-done:
 		t.Fatal("expected time delay due to rate limit")
+	default:
+	}
+	clock.Advance(d)
+	if err := <-done; err != nil {
+		t.Fatal(err)
 	}
 }
 
@@ -0,0 +1,128 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Clock is the source of time for the time-based decorators such as SetRateLimit, SetTimeout,
+// SetSlowRequestWarning and SetStats. httpxtest provides a fake implementation for deterministic tests
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker delivers ticks at intervals, see time.Ticker
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// systemClock is the Clock backed by the time package
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (systemClock) NewTicker(d time.Duration) Ticker       { return systemTicker{time.NewTicker(d)} }
+
+type systemTicker struct {
+	t *time.Ticker
+}
+
+func (t systemTicker) C() <-chan time.Time { return t.t.C }
+func (t systemTicker) Stop()               { t.t.Stop() }
+
+// clockHolder allows an interface value to be stored in an atomic.Pointer
+type clockHolder struct {
+	clock Clock
+}
+
+var defaultClock atomic.Pointer[clockHolder]
+
+func init() {
+	SetDefaultClock(nil)
+}
+
+// SetDefaultClock replaces the clock used by every chain that does not override it with SetClock.
+// A nil clock restores the system clock
+func SetDefaultClock(clock Clock) {
+	if clock == nil {
+		clock = systemClock{}
+	}
+	defaultClock.Store(&clockHolder{clock: clock})
+}
+
+type clockKey struct{}
+
+// SetClock makes the time-based decorators inside c use clock instead of the default clock.
+// It must wrap those decorators, for example as the last decoration before SetRequest
+func SetClock(c Client, clock Clock) ClientFunc {
+	c = nilClientCheck(c)
+	return func(req *http.Request) (*http.Response, error) {
+		return c.Do(req.WithContext(context.WithValue(req.Context(), clockKey{}, clock)))
+	}
+}
+
+// ClockFromContext returns the clock set with SetClock or the default clock
+func ClockFromContext(ctx context.Context) Clock {
+	if clock, ok := ctx.Value(clockKey{}).(Clock); ok {
+		return clock
+	}
+	return defaultClock.Load().clock
+}
+
+// withClockTimeout is context.WithTimeout measured by clock
+func withClockTimeout(ctx context.Context, clock Clock, d time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := clock.(systemClock); ok {
+		return context.WithTimeout(ctx, d)
+	}
+	deadline := clock.Now().Add(d)
+	if cur, ok := ctx.Deadline(); ok && cur.Before(deadline) {
+		// the parent expires first, as with context.WithTimeout
+		return context.WithCancel(ctx)
+	}
+	parent, cancel := context.WithCancel(ctx)
+	tc := &clockContext{Context: parent, deadline: deadline}
+	go func() {
+		select {
+		case <-clock.After(d):
+			tc.expire()
+			cancel()
+		case <-parent.Done():
+		}
+	}()
+	return tc, cancel
+}
+
+// clockContext reports context.DeadlineExceeded when a Clock based timeout expires
+type clockContext struct {
+	context.Context
+	deadline time.Time
+
+	mu      sync.Mutex
+	expired bool
+}
+
+func (c *clockContext) expire() {
+	c.mu.Lock()
+	c.expired = true
+	c.mu.Unlock()
+}
+
+func (c *clockContext) Deadline() (time.Time, bool) {
+	return c.deadline, true
+}
+
+func (c *clockContext) Err() error {
+	err := c.Context.Err()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil && c.expired {
+		return context.DeadlineExceeded
+	}
+	return err
+}
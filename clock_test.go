@@ -0,0 +1,69 @@
+package httpx_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/httpxtest"
+)
+
+func TestSetTimeoutClock(t *testing.T) {
+	clock := httpxtest.NewClock(time.Now())
+	var deadline time.Time
+	var c httpx.Client = httpx.ClientFunc(func(req *http.Request) (*http.Response, error) {
+		deadline, _ = req.Context().Deadline()
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	})
+	c = httpx.SetTimeout(c, time.Minute)
+	c = httpx.SetClock(c, clock)
+	c = httpx.SetRequest(c, http.MethodGet, "http://example.com")
+
+	done := make(chan error)
+	go func() {
+		_, err := c.Do(nil)
+		done <- err
+	}()
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	if err := <-done; !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal(err)
+	}
+	if !deadline.Equal(clock.Now()) {
+		t.Fatal(deadline, clock.Now())
+	}
+}
+
+func TestSetSlowRequestWarningClock(t *testing.T) {
+	clock := httpxtest.NewClock(time.Now())
+	release := make(chan struct{})
+	warned := make(chan time.Duration, 1)
+	var c httpx.Client = httpx.ClientFunc(func(req *http.Request) (*http.Response, error) {
+		<-release
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+	c = httpx.SetSlowRequestWarning(c, time.Second, func(req *http.Request, elapsed time.Duration) {
+		warned <- elapsed
+	})
+	c = httpx.SetClock(c, clock)
+	c = httpx.SetRequest(c, http.MethodGet, "http://example.com")
+
+	done := make(chan error)
+	go func() {
+		_, err := c.Do(nil)
+		done <- err
+	}()
+	clock.BlockUntil(1)
+	clock.Advance(2 * time.Second)
+	if elapsed := <-warned; elapsed != 2*time.Second {
+		t.Fatal(elapsed)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,113 @@
+// Command httpxcontract converts recorded traffic into contract fixtures and verifies them against a provider:
+//
+//	httpxcontract generate -har traffic.har -consumer web -provider users > users.pact.json
+//	httpxcontract verify -contract users.pact.json -target https://staging.example.com
+//
+// verify prints every mismatch and exits with status 1 if there are any, see contractx.Verify.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/contractx"
+	"github.com/tflyons/httpx/harx"
+)
+
+// errMismatches is returned by verify when the provider does not honor the contract
+var errMismatches = fmt.Errorf("contract verification failed")
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := run(ctx, os.Stdout, os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "httpxcontract:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, w io.Writer, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("expected a generate or verify command")
+	}
+	switch args[0] {
+	case "generate":
+		fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+		harFile := fs.String("har", "", "path to the HAR file, reads from stdin if empty")
+		consumer := fs.String("consumer", "consumer", "name of the consumer")
+		provider := fs.String("provider", "provider", "name of the provider")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		return generate(w, *harFile, *consumer, *provider)
+	case "verify":
+		fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+		contractFile := fs.String("contract", "", "path to the contract, reads from stdin if empty")
+		target := fs.String("target", "", "base url of the provider")
+		timeout := fs.Duration("timeout", 30*time.Second, "time limit of each request")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		return verify(ctx, w, *contractFile, *target, *timeout)
+	}
+	return fmt.Errorf("unknown command %q", args[0])
+}
+
+func open(path string) (io.ReadCloser, error) {
+	if path == "" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(path)
+}
+
+func generate(w io.Writer, harFile, consumer, provider string) error {
+	in, err := open(harFile)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	h, err := harx.Read(in)
+	if err != nil {
+		return err
+	}
+	contract, err := contractx.FromHAR(h, consumer, provider)
+	if err != nil {
+		return err
+	}
+	return contractx.Write(w, contract)
+}
+
+func verify(ctx context.Context, w io.Writer, contractFile, target string, timeout time.Duration) error {
+	base, err := url.Parse(target)
+	if err != nil || !base.IsAbs() {
+		return fmt.Errorf("invalid target %q", target)
+	}
+	in, err := open(contractFile)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	contract, err := contractx.Read(in)
+	if err != nil {
+		return err
+	}
+	mismatches, err := contractx.Verify(ctx, httpx.SetTimeout(httpx.DefaultClient, timeout), base, contract)
+	for _, m := range mismatches {
+		fmt.Fprintf(w, "%s: %s: expected %s, got %s\n", m.Interaction, m.Field, m.Expected, m.Actual)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "%d interactions, %d mismatches\n", len(contract.Interactions), len(mismatches))
+	if len(mismatches) > 0 {
+		return errMismatches
+	}
+	return nil
+}
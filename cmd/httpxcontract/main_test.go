@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tflyons/httpx/harx"
+)
+
+func TestRun(t *testing.T) {
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	h := &harx.HAR{Log: harx.Log{Version: "1.2", Entries: []harx.Entry{{
+		Request: harx.Request{Method: http.MethodGet, URL: "https://prod.example.com/health"},
+		Response: harx.Response{
+			Status:  http.StatusOK,
+			Headers: []harx.NameValue{{Name: "Content-Type", Value: "application/json"}},
+			Content: harx.Content{MimeType: "application/json", Text: `{"ok":false}`},
+		},
+	}}}}
+	var buf bytes.Buffer
+	if err := harx.Write(&buf, h); err != nil {
+		t.Fatal(err)
+	}
+	harFile, contractFile := filepath.Join(dir, "traffic.har"), filepath.Join(dir, "contract.json")
+	if err := os.WriteFile(harFile, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := run(context.Background(), &out, []string{"generate", "-har", harFile, "-consumer", "web"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(contractFile, out.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	out.Reset()
+	if err := run(context.Background(), &out, []string{"verify", "-contract", contractFile, "-target", srv.URL}); err != nil {
+		t.Fatal(err, out.String())
+	}
+	status = http.StatusInternalServerError
+	out.Reset()
+	err := run(context.Background(), &out, []string{"verify", "-contract", contractFile, "-target", srv.URL})
+	if !errors.Is(err, errMismatches) || !strings.Contains(out.String(), "status: expected 200, got 500") {
+		t.Fatal(err, out.String())
+	}
+}
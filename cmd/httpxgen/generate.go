@@ -0,0 +1,453 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/tflyons/httpx/openapix"
+)
+
+// Generate returns the formatted Go source of a typed client for the document
+func Generate(doc *openapix.Document, pkg string) ([]byte, error) {
+	g := &generator{
+		doc:   doc,
+		types: make(map[string]string),
+	}
+	for _, name := range sortedKeys(doc.Components.Schemas) {
+		if err := g.declare(goName(name), doc.Components.Schemas[name]); err != nil {
+			return nil, fmt.Errorf("schema %s: %w", name, err)
+		}
+	}
+	var methods bytes.Buffer
+	for _, path := range sortedKeys(doc.Paths) {
+		item := doc.Paths[path]
+		for _, op := range item.Operations() {
+			if err := g.operation(&methods, path, item, op); err != nil {
+				return nil, fmt.Errorf("%s %s: %w", op.Method, path, err)
+			}
+		}
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by httpxgen. DO NOT EDIT.\n\n")
+	if doc.Info.Title != "" {
+		fmt.Fprintf(&out, "// Package %s is a client for %s %s\n", pkg, doc.Info.Title, doc.Info.Version)
+	}
+	fmt.Fprintf(&out, "package %s\n\n", pkg)
+	out.WriteString("import (\n\t\"context\"\n\t\"net/http\"\n")
+	if g.usesTime {
+		out.WriteString("\t\"time\"\n")
+	}
+	out.WriteString("\n\t\"github.com/tflyons/httpx\"\n)\n\n")
+	if len(doc.Servers) > 0 {
+		fmt.Fprintf(&out, "// DefaultBaseURL is the first server listed in the API description\nconst DefaultBaseURL = %q\n\n", doc.Servers[0].URL)
+	}
+	out.WriteString(clientSource)
+	out.Write(methods.Bytes())
+	for _, name := range g.order {
+		out.WriteString(g.types[name])
+	}
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated invalid source: %w\n%s", err, out.String())
+	}
+	return src, nil
+}
+
+const clientSource = `// Client calls the API using any httpx.Client so callers can bring their own
+// authentication, retry and rate limiting decorators
+type Client struct {
+	client  httpx.Client
+	baseURL string
+}
+
+// NewClient returns a Client that sends requests to baseURL through c
+func NewClient(c httpx.Client, baseURL string) *Client {
+	if c == nil {
+		c = httpx.DefaultClient
+	}
+	return &Client{
+		client:  c,
+		baseURL: baseURL,
+	}
+}
+
+`
+
+type generator struct {
+	doc      *openapix.Document
+	types    map[string]string
+	order    []string
+	usesTime bool
+}
+
+// declare adds a named type for the schema
+func (g *generator) declare(name string, s *openapix.Schema) error {
+	if _, ok := g.types[name]; ok {
+		return nil
+	}
+	// reserve the name before resolving fields so recursive schemas terminate
+	g.types[name] = ""
+	g.order = append(g.order, name)
+
+	var b strings.Builder
+	writeComment(&b, name, s.Description)
+	if s.Ref != "" || s.Type != "object" || len(s.Properties) == 0 {
+		t, err := g.goType(s, name+"Value")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, "type %s %s\n\n", name, t)
+		g.types[name] = b.String()
+		return nil
+	}
+	required := make(map[string]bool, len(s.Required))
+	for _, r := range s.Required {
+		required[r] = true
+	}
+	fmt.Fprintf(&b, "type %s struct {\n", name)
+	for _, prop := range sortedKeys(s.Properties) {
+		field := goName(prop)
+		t, err := g.goType(s.Properties[prop], name+field)
+		if err != nil {
+			return fmt.Errorf("property %s: %w", prop, err)
+		}
+		if d := s.Properties[prop].Description; d != "" {
+			fmt.Fprintf(&b, "\t// %s\n", oneLine(d))
+		}
+		tag := prop
+		if !required[prop] {
+			// optional properties are pointers so that a zero value can be sent and an absent one omitted
+			tag += ",omitempty"
+			if !g.nillable(s.Properties[prop]) {
+				t = "*" + t
+			}
+		}
+		fmt.Fprintf(&b, "\t%s %s `json:%q`\n", field, t, tag)
+	}
+	b.WriteString("}\n\n")
+	g.types[name] = b.String()
+	return nil
+}
+
+// nillable reports whether the Go type of the schema is a slice, map or interface, which omitempty already leaves
+// out when nil
+func (g *generator) nillable(s *openapix.Schema) bool {
+	for depth := 0; s != nil && s.Ref != "" && depth < 8; depth++ {
+		s = g.doc.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+	if s == nil {
+		return true
+	}
+	switch s.Type {
+	case "array":
+		return true
+	case "object":
+		return len(s.Properties) == 0
+	case "string":
+		return s.Format == "byte" || s.Format == "binary"
+	case "integer", "number", "boolean":
+		return false
+	}
+	return true
+}
+
+// goType returns the Go type for the schema, declaring inline objects with the hint as their name
+func (g *generator) goType(s *openapix.Schema, hint string) (string, error) {
+	if s == nil {
+		return "any", nil
+	}
+	if s.Ref != "" {
+		const prefix = "#/components/schemas/"
+		if !strings.HasPrefix(s.Ref, prefix) {
+			return "", fmt.Errorf("unsupported reference %q", s.Ref)
+		}
+		name := strings.TrimPrefix(s.Ref, prefix)
+		if _, ok := g.doc.Components.Schemas[name]; !ok {
+			return "", fmt.Errorf("unresolved reference %q", s.Ref)
+		}
+		return goName(name), nil
+	}
+	switch s.Type {
+	case "string":
+		switch s.Format {
+		case "date-time":
+			g.usesTime = true
+			return "time.Time", nil
+		case "byte", "binary":
+			return "[]byte", nil
+		}
+		return "string", nil
+	case "integer":
+		if s.Format == "int32" {
+			return "int32", nil
+		}
+		return "int64", nil
+	case "number":
+		if s.Format == "float" {
+			return "float32", nil
+		}
+		return "float64", nil
+	case "boolean":
+		return "bool", nil
+	case "array":
+		t, err := g.goType(s.Items, hint+"Item")
+		if err != nil {
+			return "", err
+		}
+		return "[]" + t, nil
+	case "object":
+		if len(s.Properties) > 0 {
+			if err := g.declare(hint, s); err != nil {
+				return "", err
+			}
+			return hint, nil
+		}
+		var additional openapix.Schema
+		if len(s.AdditionalProperties) > 0 && json.Unmarshal(s.AdditionalProperties, &additional) == nil {
+			t, err := g.goType(&additional, hint+"Value")
+			if err != nil {
+				return "", err
+			}
+			return "map[string]" + t, nil
+		}
+		return "map[string]any", nil
+	}
+	return "any", nil
+}
+
+// operation writes the client method for a single operation
+func (g *generator) operation(w *bytes.Buffer, path string, item openapix.PathItem, op openapix.MethodOperation) error {
+	name := goName(op.OperationID)
+	if name == "" {
+		name = goName(strings.ToLower(op.Method) + " " + path)
+	}
+
+	var params []*openapix.Parameter
+	for _, p := range append(append([]*openapix.Parameter{}, item.Parameters...), op.Parameters...) {
+		resolved, err := g.doc.ResolveParameter(p)
+		if err != nil {
+			return err
+		}
+		if resolved.In == "cookie" {
+			continue
+		}
+		params = append(params, resolved)
+	}
+	paramsType := ""
+	if len(params) > 0 {
+		paramsType = name + "Params"
+		if err := g.declareParams(paramsType, name, params); err != nil {
+			return err
+		}
+	}
+
+	bodyType := ""
+	if op.RequestBody != nil {
+		if s := openapix.JSONContent(op.RequestBody.Content); s != nil {
+			t, err := g.goType(s, name+"Request")
+			if err != nil {
+				return fmt.Errorf("request body: %w", err)
+			}
+			bodyType = t
+		}
+	}
+
+	var statuses []int
+	respType := ""
+	for _, code := range sortedKeys(op.Responses) {
+		status, err := strconv.Atoi(code)
+		if err != nil || status < 200 || status > 299 {
+			continue
+		}
+		statuses = append(statuses, status)
+		if respType != "" {
+			continue
+		}
+		if s := openapix.JSONContent(op.Responses[code].Content); s != nil {
+			t, err := g.goType(s, name+"Response")
+			if err != nil {
+				return fmt.Errorf("response %s: %w", code, err)
+			}
+			respType = t
+		}
+	}
+	if len(statuses) == 0 {
+		statuses = []int{200}
+	}
+
+	// signature
+	fmt.Fprintf(w, "// %s calls %s %s\n", name, op.Method, path)
+	summary := op.Summary
+	if summary == "" {
+		summary = op.Description
+	}
+	if summary != "" {
+		fmt.Fprintf(w, "//\n// %s\n", oneLine(summary))
+	}
+	args := []string{"ctx context.Context"}
+	if paramsType != "" {
+		args = append(args, "params "+paramsType)
+	}
+	if bodyType != "" {
+		args = append(args, "body "+bodyType)
+	}
+	results := "error"
+	if respType != "" {
+		results = "(" + respType + ", error)"
+	}
+	fmt.Fprintf(w, "func (c *Client) %s(%s) %s {\n", name, strings.Join(args, ", "), results)
+
+	// body
+	if respType != "" {
+		fmt.Fprintf(w, "\tvar out %s\n", respType)
+	}
+	codes := make([]string, len(statuses))
+	for i, s := range statuses {
+		codes[i] = strconv.Itoa(s)
+	}
+	fmt.Fprintf(w, "\thc := httpx.RequireResponseStatus(c.client, %s)\n", strings.Join(codes, ", "))
+	if respType != "" {
+		fmt.Fprintf(w, "\thc = httpx.SetResponseBodyHandlerJSON(hc, &out)\n")
+	}
+	if paramsType != "" {
+		fmt.Fprintf(w, "\thc = httpx.SetRequestFromStruct(hc, params)\n")
+	}
+	if bodyType != "" {
+		fmt.Fprintf(w, "\thc = httpx.SetRequestBodyJSON(hc, body)\n")
+	}
+	fmt.Fprintf(w, "\thc = httpx.SetRequestWithContext(ctx, hc, %s, c.baseURL+%q)\n", methodConst(op.Method), path)
+	// a response comes with the error of an unexpected status, its body is closed so the connection is reused
+	fmt.Fprintf(w, "\tresp, err := hc.Do(nil)\n")
+	fmt.Fprintf(w, "\tif resp != nil && resp.Body != nil {\n\t\t_ = resp.Body.Close()\n\t}\n")
+	if respType != "" {
+		fmt.Fprintf(w, "\treturn out, err\n}\n\n")
+		return nil
+	}
+	fmt.Fprintf(w, "\treturn err\n}\n\n")
+	return nil
+}
+
+// declareParams adds a struct type whose tags are understood by httpx.SetRequestFromStruct
+func (g *generator) declareParams(typeName, opName string, params []*openapix.Parameter) error {
+	g.types[typeName] = ""
+	g.order = append(g.order, typeName)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "// %s holds the path, query and header parameters for %s\n", typeName, opName)
+	fmt.Fprintf(&b, "type %s struct {\n", typeName)
+	seen := make(map[string]bool)
+	for _, p := range params {
+		field := goName(p.Name)
+		if seen[field] {
+			field = goName(p.In + " " + p.Name)
+		}
+		seen[field] = true
+		t, err := g.goType(p.Schema, typeName+field)
+		if err != nil {
+			return fmt.Errorf("parameter %s: %w", p.Name, err)
+		}
+		if p.Description != "" {
+			fmt.Fprintf(&b, "\t// %s\n", oneLine(p.Description))
+		}
+		tag := p.Name
+		if !p.Required && p.In != "path" {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(&b, "\t%s %s `%s:%q`\n", field, t, p.In, tag)
+	}
+	b.WriteString("}\n\n")
+	g.types[typeName] = b.String()
+	return nil
+}
+
+func methodConst(method string) string {
+	switch method {
+	case "GET":
+		return "http.MethodGet"
+	case "HEAD":
+		return "http.MethodHead"
+	case "POST":
+		return "http.MethodPost"
+	case "PUT":
+		return "http.MethodPut"
+	case "PATCH":
+		return "http.MethodPatch"
+	case "DELETE":
+		return "http.MethodDelete"
+	case "OPTIONS":
+		return "http.MethodOptions"
+	}
+	return strconv.Quote(method)
+}
+
+// initialisms are upper cased when they make up a whole word of a Go name
+var initialisms = map[string]bool{
+	"API": true, "HTTP": true, "HTTPS": true, "ID": true, "JSON": true,
+	"URI": true, "URL": true, "UUID": true, "XML": true,
+}
+
+// goName converts an identifier from the document into an exported Go name
+func goName(s string) string {
+	var words []string
+	for _, field := range strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		words = append(words, splitCamel(field)...)
+	}
+	var b strings.Builder
+	for _, w := range words {
+		if initialisms[strings.ToUpper(w)] {
+			b.WriteString(strings.ToUpper(w))
+			continue
+		}
+		r := []rune(w)
+		r[0] = unicode.ToUpper(r[0])
+		b.WriteString(string(r))
+	}
+	name := b.String()
+	if name != "" && unicode.IsDigit([]rune(name)[0]) {
+		name = "N" + name
+	}
+	return name
+}
+
+// splitCamel splits a camel case word where a lower case letter is followed by an upper case letter
+func splitCamel(s string) []string {
+	var words []string
+	r := []rune(s)
+	start := 0
+	for i := 1; i < len(r); i++ {
+		if unicode.IsLower(r[i-1]) && unicode.IsUpper(r[i]) {
+			words = append(words, string(r[start:i]))
+			start = i
+		}
+	}
+	return append(words, string(r[start:]))
+}
+
+func writeComment(b *strings.Builder, name, description string) {
+	fmt.Fprintf(b, "// %s is generated from the API description\n", name)
+	if description != "" {
+		fmt.Fprintf(b, "//\n// %s\n", oneLine(description))
+	}
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/tflyons/httpx/cmd/httpxgen/internal/petstore"
+	"github.com/tflyons/httpx/openapix"
+)
+
+func TestGenerate_Golden(t *testing.T) {
+	b, err := os.ReadFile("testdata/petstore.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := openapix.Parse(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Generate(doc, "petstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile("internal/petstore/client.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatal("generated client is out of date, run go generate")
+	}
+}
+
+func TestGenerate_Client(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/pets/7":
+			_ = json.NewEncoder(w).Encode(petstore.Pet{ID: 7, Name: "rex"})
+		case r.Method == http.MethodDelete && r.URL.Path == "/pets/7":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	c := petstore.NewClient(srv.Client(), srv.URL)
+	pet, err := c.ShowPetByID(context.Background(), petstore.ShowPetByIDParams{PetID: "7"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pet.Name != "rex" {
+		t.Fatal(pet)
+	}
+	if err = c.DeletePetsPetID(context.Background(), petstore.DeletePetsPetIDParams{PetID: "7"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.ListPets(context.Background(), petstore.ListPetsParams{}); err == nil {
+		t.Fatal("expected an error for an unexpected status")
+	}
+}
+
+func TestGoName(t *testing.T) {
+	for in, want := range map[string]string{
+		"showPetById":  "ShowPetByID",
+		"X-Request-Id": "XRequestID",
+		"get /pets":    "GetPets",
+		"2fa_code":     "N2faCode",
+	} {
+		if got := goName(in); got != want {
+			t.Errorf("goName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestGenerate_OptionalFields(t *testing.T) {
+	b, err := json.Marshal(petstore.Pet{ID: 1, Name: "rex"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"id":1,"name":"rex"}` {
+		t.Fatal("absent optional properties should be omitted", string(b))
+	}
+	empty := ""
+	b, err = json.Marshal(petstore.CreatePetRequest{Name: "rex", Tag: &empty})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"name":"rex","tag":""}` {
+		t.Fatal("a zero optional property should be sent when set", string(b))
+	}
+}
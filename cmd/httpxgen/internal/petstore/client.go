@@ -0,0 +1,141 @@
+// Code generated by httpxgen. DO NOT EDIT.
+
+// Package petstore is a client for Petstore 1.0.0
+package petstore
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/tflyons/httpx"
+)
+
+// DefaultBaseURL is the first server listed in the API description
+const DefaultBaseURL = "https://petstore.example.com/v1"
+
+// Client calls the API using any httpx.Client so callers can bring their own
+// authentication, retry and rate limiting decorators
+type Client struct {
+	client  httpx.Client
+	baseURL string
+}
+
+// NewClient returns a Client that sends requests to baseURL through c
+func NewClient(c httpx.Client, baseURL string) *Client {
+	if c == nil {
+		c = httpx.DefaultClient
+	}
+	return &Client{
+		client:  c,
+		baseURL: baseURL,
+	}
+}
+
+// ListPets calls GET /pets
+//
+// Lists all pets
+func (c *Client) ListPets(ctx context.Context, params ListPetsParams) ([]Pet, error) {
+	var out []Pet
+	hc := httpx.RequireResponseStatus(c.client, 200)
+	hc = httpx.SetResponseBodyHandlerJSON(hc, &out)
+	hc = httpx.SetRequestFromStruct(hc, params)
+	hc = httpx.SetRequestWithContext(ctx, hc, http.MethodGet, c.baseURL+"/pets")
+	resp, err := hc.Do(nil)
+	if resp != nil && resp.Body != nil {
+		_ = resp.Body.Close()
+	}
+	return out, err
+}
+
+// CreatePet calls POST /pets
+//
+// Creates a pet
+func (c *Client) CreatePet(ctx context.Context, body CreatePetRequest) (Pet, error) {
+	var out Pet
+	hc := httpx.RequireResponseStatus(c.client, 201)
+	hc = httpx.SetResponseBodyHandlerJSON(hc, &out)
+	hc = httpx.SetRequestBodyJSON(hc, body)
+	hc = httpx.SetRequestWithContext(ctx, hc, http.MethodPost, c.baseURL+"/pets")
+	resp, err := hc.Do(nil)
+	if resp != nil && resp.Body != nil {
+		_ = resp.Body.Close()
+	}
+	return out, err
+}
+
+// ShowPetByID calls GET /pets/{petId}
+//
+// Info for a specific pet
+func (c *Client) ShowPetByID(ctx context.Context, params ShowPetByIDParams) (Pet, error) {
+	var out Pet
+	hc := httpx.RequireResponseStatus(c.client, 200)
+	hc = httpx.SetResponseBodyHandlerJSON(hc, &out)
+	hc = httpx.SetRequestFromStruct(hc, params)
+	hc = httpx.SetRequestWithContext(ctx, hc, http.MethodGet, c.baseURL+"/pets/{petId}")
+	resp, err := hc.Do(nil)
+	if resp != nil && resp.Body != nil {
+		_ = resp.Body.Close()
+	}
+	return out, err
+}
+
+// DeletePetsPetID calls DELETE /pets/{petId}
+func (c *Client) DeletePetsPetID(ctx context.Context, params DeletePetsPetIDParams) error {
+	hc := httpx.RequireResponseStatus(c.client, 204)
+	hc = httpx.SetRequestFromStruct(hc, params)
+	hc = httpx.SetRequestWithContext(ctx, hc, http.MethodDelete, c.baseURL+"/pets/{petId}")
+	resp, err := hc.Do(nil)
+	if resp != nil && resp.Body != nil {
+		_ = resp.Body.Close()
+	}
+	return err
+}
+
+// Error is generated from the API description
+type Error struct {
+	Code    int32  `json:"code"`
+	Message string `json:"message"`
+}
+
+// Pet is generated from the API description
+//
+// A pet in the store
+type Pet struct {
+	Born   *time.Time        `json:"born,omitempty"`
+	ID     int64             `json:"id"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Name   string            `json:"name"`
+	Owner  *PetOwner         `json:"owner,omitempty"`
+	Tag    *string           `json:"tag,omitempty"`
+}
+
+// PetOwner is generated from the API description
+type PetOwner struct {
+	Email *string `json:"email,omitempty"`
+}
+
+// ListPetsParams holds the path, query and header parameters for ListPets
+type ListPetsParams struct {
+	// maximum number of results
+	Limit      int32  `query:"limit,omitempty"`
+	XRequestID string `header:"X-Request-Id,omitempty"`
+}
+
+// CreatePetRequest is generated from the API description
+type CreatePetRequest struct {
+	Name string  `json:"name"`
+	Tag  *string `json:"tag,omitempty"`
+}
+
+// ShowPetByIDParams holds the path, query and header parameters for ShowPetByID
+type ShowPetByIDParams struct {
+	// the id of the pet
+	PetID string `path:"petId"`
+}
+
+// DeletePetsPetIDParams holds the path, query and header parameters for DeletePetsPetID
+type DeletePetsPetIDParams struct {
+	// the id of the pet
+	PetID string `path:"petId"`
+}
@@ -0,0 +1,58 @@
+// Command httpxgen generates a typed Go client from an OpenAPI 3 document.
+//
+// The generated methods are built from httpx decorators and accept any httpx.Client,
+// so authentication, retries and rate limiting are configured by the caller:
+//
+//	httpxgen -spec openapi.json -package petstore -o petstore/client.go
+//
+// Only JSON encoded documents are supported.
+package main
+
+//go:generate go run . -spec testdata/petstore.json -package petstore -o internal/petstore/client.go
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/tflyons/httpx/openapix"
+)
+
+func main() {
+	spec := flag.String("spec", "", "path to the OpenAPI document, reads from stdin if empty")
+	pkg := flag.String("package", "client", "package name of the generated code")
+	out := flag.String("o", "", "output file, writes to stdout if empty")
+	flag.Parse()
+
+	if err := run(*spec, *pkg, *out); err != nil {
+		fmt.Fprintln(os.Stderr, "httpxgen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(spec, pkg, out string) error {
+	var b []byte
+	var err error
+	if spec == "" {
+		b, err = io.ReadAll(os.Stdin)
+	} else {
+		b, err = os.ReadFile(spec)
+	}
+	if err != nil {
+		return err
+	}
+	doc, err := openapix.Parse(b)
+	if err != nil {
+		return err
+	}
+	src, err := Generate(doc, pkg)
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return os.WriteFile(out, src, 0o644)
+}
@@ -0,0 +1,119 @@
+// Command httpxreplay replays requests recorded in a HAR file, as written by harx.Recorder or exported from a
+// browser, at a controlled rate for load testing and incident reproduction:
+//
+//	httpxreplay -har incident.har -target https://staging.example.com -rate 20/1s -concurrency 4
+//
+// Requests stored by httpx.SetDeadLetter in a httpx.FileDeadLetterSink are replayed with -dlq instead of -har:
+//
+//	httpxreplay -dlq failed.jsonl -rate 5/1s
+//
+// Each response is printed as it arrives followed by a summary of latency and errors.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/harx"
+)
+
+func main() {
+	harFile := flag.String("har", "", "path to the HAR file, reads from stdin if empty")
+	dlq := flag.Bool("dlq", false, "read dead letters written by httpx.FileDeadLetterSink instead of a HAR file")
+	target := flag.String("target", "", "replace the scheme and host of every request, for example https://staging.example.com")
+	rate := flag.String("rate", "", "maximum request rate as requests/period, for example 20/1s, unlimited if empty")
+	concurrency := flag.Int("concurrency", 1, "number of requests in flight at once")
+	timeout := flag.Duration("timeout", 30*time.Second, "time limit of each request")
+	match := flag.String("match", "", "only replay requests whose URL contains this string")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := run(ctx, os.Stdout, *harFile, *dlq, *target, *rate, *concurrency, *timeout, *match); err != nil {
+		fmt.Fprintln(os.Stderr, "httpxreplay:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, w io.Writer, harFile string, dlq bool, target, rate string, concurrency int, timeout time.Duration, match string) error {
+	in := os.Stdin
+	if harFile != "" {
+		f, err := os.Open(harFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	var entries []harx.Entry
+	if dlq {
+		dls, err := httpx.ReadDeadLetters(in)
+		if err != nil {
+			return err
+		}
+		entries = harx.DeadLetterEntries(dls)
+	} else {
+		h, err := harx.Read(in)
+		if err != nil {
+			return err
+		}
+		entries = h.Log.Entries
+	}
+
+	var err error
+	opts := harx.ReplayOptions{Concurrency: concurrency}
+	if target != "" {
+		if opts.Target, err = url.Parse(target); err != nil || !opts.Target.IsAbs() {
+			return fmt.Errorf("invalid target %q", target)
+		}
+	}
+	if rate != "" {
+		max, period, ok := strings.Cut(rate, "/")
+		if ok {
+			if opts.Rate, err = strconv.Atoi(max); err == nil {
+				opts.Period, err = time.ParseDuration(period)
+			}
+		}
+		if !ok || err != nil || opts.Rate <= 0 || opts.Period <= 0 {
+			return fmt.Errorf("invalid rate %q, expected requests/period such as 20/1s", rate)
+		}
+	}
+	if match != "" {
+		opts.Filter = func(e *harx.Entry) bool {
+			return strings.Contains(e.Request.URL, match)
+		}
+	}
+	opts.OnResult = func(r harx.Result) {
+		if r.Err != nil {
+			fmt.Fprintf(w, "%s %s error %v\n", r.Entry.Request.Method, r.Entry.Request.URL, r.Err)
+			return
+		}
+		u := r.Entry.Request.URL
+		if r.Response.Request != nil {
+			u = r.Response.Request.URL.String()
+		}
+		fmt.Fprintf(w, "%s %s %d %s\n", r.Entry.Request.Method, u, r.Response.StatusCode, r.Duration.Round(time.Millisecond))
+	}
+
+	var stats httpx.Stats
+	var c httpx.Client = http.DefaultClient
+	c = httpx.SetStats(c, &stats)
+	c = httpx.SetTimeout(c, timeout)
+	_, err = harx.Replay(ctx, c, entries, opts)
+
+	s := stats.Snapshot()
+	fmt.Fprintf(w, "\n%d requests, %d errors, mean %s, p50 %s, p90 %s, p99 %s\n",
+		s.Requests, s.Errors, s.MeanLatency.Round(time.Millisecond), s.P50.Round(time.Millisecond),
+		s.P90.Round(time.Millisecond), s.P99.Round(time.Millisecond))
+	return err
+}
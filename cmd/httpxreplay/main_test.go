@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/harx"
+)
+
+func TestRun(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	h := &harx.HAR{Log: harx.Log{Version: "1.2", Entries: []harx.Entry{
+		{Request: harx.Request{Method: http.MethodGet, URL: "https://prod.example.com/ok"}},
+		{Request: harx.Request{Method: http.MethodGet, URL: "https://prod.example.com/missing"}},
+		{Request: harx.Request{Method: http.MethodGet, URL: "https://prod.example.com/other"}},
+	}}}
+	path := filepath.Join(t.TempDir(), "traffic.har")
+	var buf bytes.Buffer
+	if err := harx.Write(&buf, h); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := run(context.Background(), &out, path, false, srv.URL, "100/1s", 2, time.Second, "/o"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), srv.URL+"/ok 200") || strings.Contains(out.String(), "/missing") ||
+		!strings.Contains(out.String(), "2 requests, 0 errors") {
+		t.Fatal(out.String())
+	}
+	if err := run(context.Background(), &out, path, false, srv.URL, "fast", 1, time.Second, ""); err == nil {
+		t.Fatal("expected invalid rate error")
+	}
+}
+
+func TestRun_DeadLetters(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		got = r.Method + " " + r.URL.Path + " " + string(b)
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "failed.jsonl")
+	sink, err := httpx.NewFileDeadLetterSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dl := &httpx.DeadLetter{Method: http.MethodPost, URL: "https://prod.example.com/orders", Body: []byte(`{"id": 1}`), StatusCode: 503}
+	if err = sink.Put(context.Background(), dl); err != nil {
+		t.Fatal(err)
+	}
+	sink.Close()
+
+	var out bytes.Buffer
+	if err := run(context.Background(), &out, path, true, srv.URL, "", 1, time.Second, ""); err != nil {
+		t.Fatal(err)
+	}
+	if got != `POST /orders {"id": 1}` || !strings.Contains(out.String(), "1 requests, 0 errors") {
+		t.Fatal(got, out.String())
+	}
+}
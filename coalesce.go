@@ -0,0 +1,162 @@
+package httpx
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Coalescer combines GET requests for the same resource family made within a short window into one bulk request,
+// for APIs with bulk endpoints such as GET /users?ids=1,2,3 next to GET /users/1. See SetCoalescing.
+//
+// Key, Combine and Split must be set. A Coalescer is safe for concurrent use
+type Coalescer struct {
+	// Window is how long the first request of a family waits for others, 10 milliseconds if zero
+	Window time.Duration
+	// MaxBatch sends the bulk request as soon as it combines this many requests, unlimited if zero
+	MaxBatch int
+	// Key returns the family of a GET request and whether it can be combined with others of the family
+	Key func(req *http.Request) (string, bool)
+	// Combine returns the bulk request for two or more requests of one family
+	Combine func(reqs []*http.Request) (*http.Request, error)
+	// Split returns one response per request, in the order of reqs, from the response to the bulk request.
+	// The bulk response body is closed once Split returns
+	Split func(reqs []*http.Request, resp *http.Response) ([]*http.Response, error)
+
+	mu      sync.Mutex
+	pending map[string]*coalesceBatch
+}
+
+type coalesceBatch struct {
+	reqs    []*http.Request
+	results []chan coalesceResult
+}
+
+type coalesceResult struct {
+	resp *http.Response
+	err  error
+}
+
+// SetCoalescing holds back GET requests that co.Key accepts for co.Window and sends the requests of each family
+// gathered in that time as one bulk request built by co.Combine, giving each caller its part of the response from
+// co.Split. A request that is alone in its window is sent as is.
+//
+// The bulk request keeps the context values of the first request but not its cancellation, so that a caller
+// giving up does not fail the others; a caller whose context is done returns straight away. Put SetTimeout inside
+// SetCoalescing to limit the bulk request. Every caller receives the error of a failed bulk request
+func SetCoalescing(c Client, co *Coalescer) ClientFunc {
+	c = nilClientCheck(c)
+	return func(req *http.Request) (*http.Response, error) {
+		if req.Method != http.MethodGet {
+			return c.Do(req)
+		}
+		key, ok := co.Key(req)
+		if !ok {
+			return c.Do(req)
+		}
+		ch := make(chan coalesceResult, 1)
+		co.mu.Lock()
+		if co.pending == nil {
+			co.pending = make(map[string]*coalesceBatch)
+		}
+		b := co.pending[key]
+		if b == nil {
+			b = &coalesceBatch{}
+			co.pending[key] = b
+			window := co.Window
+			if window <= 0 {
+				window = 10 * time.Millisecond
+			}
+			timer := ClockFromContext(req.Context()).After(window)
+			go func() {
+				<-timer
+				co.flush(c, key, b)
+			}()
+		}
+		b.reqs = append(b.reqs, req)
+		b.results = append(b.results, ch)
+		full := co.MaxBatch > 0 && len(b.reqs) >= co.MaxBatch
+		if full {
+			delete(co.pending, key)
+		}
+		co.mu.Unlock()
+		if full {
+			go co.send(c, b)
+		}
+
+		select {
+		case r := <-ch:
+			return r.resp, r.err
+		case <-req.Context().Done():
+			go func() {
+				if r := <-ch; r.resp != nil && r.resp.Body != nil {
+					r.resp.Body.Close()
+				}
+			}()
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// flush sends the batch of key if it has not already been sent for being full
+func (co *Coalescer) flush(c Client, key string, b *coalesceBatch) {
+	co.mu.Lock()
+	if co.pending[key] != b {
+		co.mu.Unlock()
+		return
+	}
+	delete(co.pending, key)
+	co.mu.Unlock()
+	co.send(c, b)
+}
+
+// send performs the requests of b and delivers a result to every caller
+func (co *Coalescer) send(c Client, b *coalesceBatch) {
+	if len(b.reqs) == 1 {
+		resp, err := c.Do(b.reqs[0])
+		b.results[0] <- coalesceResult{resp: resp, err: err}
+		return
+	}
+	resps, err := co.bulk(c, b.reqs)
+	for i, ch := range b.results {
+		if err != nil {
+			ch <- coalesceResult{err: err}
+			continue
+		}
+		if resps[i].Request == nil {
+			resps[i].Request = b.reqs[i]
+		}
+		ch <- coalesceResult{resp: resps[i]}
+	}
+}
+
+// bulk sends the combined request for reqs and splits its response
+func (co *Coalescer) bulk(c Client, reqs []*http.Request) ([]*http.Response, error) {
+	bulkReq, err := co.Combine(reqs)
+	if err != nil {
+		return nil, fmt.Errorf("could not combine %d requests: %w", len(reqs), err)
+	}
+	resp, err := c.Do(bulkReq.WithContext(detachedContext{reqs[0].Context()}))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+		resp.Body.Close()
+	}()
+	resps, err := co.Split(reqs, resp)
+	if err != nil {
+		return nil, fmt.Errorf("could not split response to %d combined requests: %w", len(reqs), err)
+	}
+	if len(resps) != len(reqs) {
+		return nil, fmt.Errorf("could not split response to %d combined requests: got %d responses", len(reqs), len(resps))
+	}
+	for i, r := range resps {
+		if r == nil {
+			return nil, fmt.Errorf("could not split response to %d combined requests: no response for request %d", len(reqs), i)
+		}
+	}
+	return resps, nil
+}
@@ -0,0 +1,118 @@
+package httpx_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/httpxtest"
+)
+
+func newThingCoalescer(base string) *httpx.Coalescer {
+	return &httpx.Coalescer{
+		Key: func(req *http.Request) (string, bool) {
+			return "things", strings.HasPrefix(req.URL.Path, "/things/")
+		},
+		Combine: func(reqs []*http.Request) (*http.Request, error) {
+			var ids []string
+			for _, r := range reqs {
+				ids = append(ids, strings.TrimPrefix(r.URL.Path, "/things/"))
+			}
+			return http.NewRequest(http.MethodGet, base+"/things?ids="+strings.Join(ids, ","), nil)
+		},
+		Split: func(reqs []*http.Request, resp *http.Response) ([]*http.Response, error) {
+			var things map[string]Thing
+			if err := json.NewDecoder(resp.Body).Decode(&things); err != nil {
+				return nil, err
+			}
+			out := make([]*http.Response, len(reqs))
+			for i, r := range reqs {
+				b, _ := json.Marshal(things[strings.TrimPrefix(r.URL.Path, "/things/")])
+				out[i] = &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(bytes.NewReader(b))}
+			}
+			return out, nil
+		},
+	}
+}
+
+func TestSetCoalescing(t *testing.T) {
+	var bulk, single int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/things" {
+			atomic.AddInt32(&bulk, 1)
+			things := make(map[string]Thing)
+			for _, id := range strings.Split(r.URL.Query().Get("ids"), ",") {
+				things[id] = Thing{Foo: id, Bar: len(id)}
+			}
+			_ = json.NewEncoder(w).Encode(things)
+			return
+		}
+		atomic.AddInt32(&single, 1)
+		_ = json.NewEncoder(w).Encode(Thing{Foo: strings.TrimPrefix(r.URL.Path, "/things/"), Bar: -1})
+	}))
+	defer srv.Close()
+
+	co := newThingCoalescer(srv.URL)
+	co.MaxBatch = 3
+	co.Window = time.Hour
+	client := httpx.SetCoalescing(srv.Client(), co)
+
+	ids := []string{"a", "bb", "ccc"}
+	got := make([]Thing, len(ids))
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func(i int, id string) {
+			defer wg.Done()
+			c := httpx.SetResponseBodyHandlerJSON(client, &got[i])
+			if _, err := httpx.SetRequest(c, http.MethodGet, srv.URL+"/things/"+id).Do(nil); err != nil {
+				t.Error(err)
+			}
+		}(i, id)
+	}
+	wg.Wait()
+	for i, id := range ids {
+		if got[i] != (Thing{Foo: id, Bar: len(id)}) {
+			t.Fatal(got)
+		}
+	}
+	if bulk != 1 || single != 0 {
+		t.Fatal(bulk, single)
+	}
+}
+
+func TestSetCoalescing_Window(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		_ = json.NewEncoder(w).Encode(Thing{Foo: r.URL.Path})
+	}))
+	defer srv.Close()
+	clock := httpxtest.NewClock(time.Now())
+
+	var got Thing
+	c := httpx.SetCoalescing(srv.Client(), newThingCoalescer(srv.URL))
+	c = httpx.SetResponseBodyHandlerJSON(httpx.SetClock(c, clock), &got)
+	c = httpx.SetRequest(c, http.MethodGet, srv.URL+"/things/a")
+	done := make(chan error)
+	go func() {
+		_, err := c.Do(nil)
+		done <- err
+	}()
+	clock.BlockUntil(1)
+	if atomic.LoadInt32(&calls) != 0 {
+		t.Fatal("expected the request to wait for the window")
+	}
+	clock.Advance(10 * time.Millisecond)
+	if err := <-done; err != nil || got.Foo != "/things/a" || calls != 1 {
+		t.Fatal(err, got, calls)
+	}
+}
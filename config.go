@@ -0,0 +1,181 @@
+package httpx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"time"
+)
+
+// FromConfig builds a Client on DefaultClient from a JSON document listing decorators in order, the first being the
+// outermost:
+//
+//	{
+//	  "base_url": "https://api.example.com/v1",
+//	  "decorators": [
+//	    {"headers": {"X-Api-Version": "2"}},
+//	    {"logging": {"prefix": "api "}},
+//	    {"timeout": "30s"},
+//	    {"retry": {"max_attempts": 3, "initial_backoff": "100ms", "max_backoff": "5s",
+//	      "statuses": [502, 503, 504], "methods": ["GET", "PUT"]}},
+//	    {"rate_limit": {"max": 100, "per": "1m"}},
+//	    {"cache": {"ttl": "5m", "stale_while_revalidate": "1m", "max_entries": 1000}}
+//	  ]
+//	}
+//
+// Durations are strings in time.ParseDuration syntax. Logging writes to standard error through
+// NewDefaultScrubber, set headers or body to true to log them as well. The cache keeps responses in memory, without
+// a limit if max_entries is zero.
+// Unknown decorators and fields are errors so that typos are caught when the config is loaded.
+// See yamlx.FromConfig for the same document written in YAML
+func FromConfig(r io.Reader) (Client, error) {
+	var doc struct {
+		BaseURL    string                       `json:"base_url"`
+		Decorators []map[string]json.RawMessage `json:"decorators"`
+	}
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("could not parse client config: %w", err)
+	}
+
+	c := DefaultClient
+	var err error
+	// decorators are applied innermost first
+	for i := len(doc.Decorators) - 1; i >= 0; i-- {
+		if len(doc.Decorators[i]) != 1 {
+			return nil, fmt.Errorf("client config: decorator %d must have exactly one name", i)
+		}
+		for name, raw := range doc.Decorators[i] {
+			build, ok := configDecorators[name]
+			if !ok {
+				return nil, fmt.Errorf("client config: unknown decorator %q", name)
+			}
+			if c, err = build(c, raw); err != nil {
+				return nil, fmt.Errorf("client config: %s: %w", name, err)
+			}
+		}
+	}
+	if doc.BaseURL != "" {
+		base, parseErr := url.Parse(doc.BaseURL)
+		if parseErr != nil || !base.IsAbs() {
+			return nil, fmt.Errorf("client config: base_url %q is not an absolute url", doc.BaseURL)
+		}
+		c = SetBaseURL(c, base)
+	}
+	return c, nil
+}
+
+// configDecorators builds each named decorator of FromConfig from its JSON options
+var configDecorators = map[string]func(c Client, raw json.RawMessage) (Client, error){
+	"headers": func(c Client, raw json.RawMessage) (Client, error) {
+		var headers map[string]string
+		if err := decodeStrict(raw, &headers); err != nil {
+			return nil, err
+		}
+		for k, v := range headers {
+			c = SetHeader(c, k, v)
+		}
+		return c, nil
+	},
+	"timeout": func(c Client, raw json.RawMessage) (Client, error) {
+		var d configDuration
+		if err := decodeStrict(raw, &d); err != nil {
+			return nil, err
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("timeout must be positive")
+		}
+		return SetTimeout(c, time.Duration(d)), nil
+	},
+	"retry": func(c Client, raw json.RawMessage) (Client, error) {
+		var opts struct {
+			MaxAttempts    int            `json:"max_attempts"`
+			InitialBackoff configDuration `json:"initial_backoff"`
+			MaxBackoff     configDuration `json:"max_backoff"`
+			Statuses       []int          `json:"statuses"`
+			Methods        []string       `json:"methods"`
+		}
+		if err := decodeStrict(raw, &opts); err != nil {
+			return nil, err
+		}
+		return SetRetry(c, RetryPolicy{
+			MaxAttempts:    opts.MaxAttempts,
+			InitialBackoff: time.Duration(opts.InitialBackoff),
+			MaxBackoff:     time.Duration(opts.MaxBackoff),
+			Statuses:       opts.Statuses,
+			Methods:        opts.Methods,
+		}), nil
+	},
+	"rate_limit": func(c Client, raw json.RawMessage) (Client, error) {
+		var opts struct {
+			Max int            `json:"max"`
+			Per configDuration `json:"per"`
+		}
+		if err := decodeStrict(raw, &opts); err != nil {
+			return nil, err
+		}
+		if opts.Max <= 0 || opts.Per <= 0 {
+			return nil, fmt.Errorf("max and per must be positive")
+		}
+		return SetRateLimit(c, opts.Max, time.Duration(opts.Per)), nil
+	},
+	"cache": func(c Client, raw json.RawMessage) (Client, error) {
+		var opts struct {
+			TTL                  configDuration `json:"ttl"`
+			StaleWhileRevalidate configDuration `json:"stale_while_revalidate"`
+			MaxEntries           int            `json:"max_entries"`
+		}
+		if err := decodeStrict(raw, &opts); err != nil {
+			return nil, err
+		}
+		if opts.TTL < 0 || opts.StaleWhileRevalidate < 0 || opts.MaxEntries < 0 {
+			return nil, fmt.Errorf("ttl, stale_while_revalidate and max_entries must not be negative")
+		}
+		ca := NewCache(NewLRUCacheStore(opts.MaxEntries))
+		ca.TTL = time.Duration(opts.TTL)
+		ca.StaleWhileRevalidate = time.Duration(opts.StaleWhileRevalidate)
+		return SetCache(c, ca), nil
+	},
+	"logging": func(c Client, raw json.RawMessage) (Client, error) {
+		var opts struct {
+			Prefix  string `json:"prefix"`
+			Headers bool   `json:"headers"`
+			Body    bool   `json:"body"`
+		}
+		if err := decodeStrict(raw, &opts); err != nil {
+			return nil, err
+		}
+		return SetLogger(c, log.New(os.Stderr, opts.Prefix, log.LstdFlags), LoggerOptions{Headers: opts.Headers, Body: opts.Body}), nil
+	},
+}
+
+// decodeStrict decodes raw into v rejecting unknown fields
+func decodeStrict(raw json.RawMessage, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// configDuration decodes a time.ParseDuration string
+type configDuration time.Duration
+
+func (d *configDuration) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string: %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = configDuration(parsed)
+	return nil
+}
@@ -0,0 +1,67 @@
+package httpx_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/tflyons/httpx"
+)
+
+func TestFromConfig(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.URL.Path != "/v1/things" || r.Header.Get("X-Api-Version") != "2" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := `{
+  "base_url": "` + srv.URL + `/v1/",
+  "decorators": [
+    {"headers": {"X-Api-Version": "2"}},
+    {"timeout": "5s"},
+    {"retry": {"max_attempts": 2, "initial_backoff": "1ms"}},
+    {"rate_limit": {"max": 10, "per": "1s"}},
+    {"cache": null}
+  ]
+}`
+	c, err := httpx.FromConfig(strings.NewReader(cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, "things", nil)
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || calls != 2 {
+		t.Fatal(resp.StatusCode, calls)
+	}
+}
+
+func TestFromConfig_Invalid(t *testing.T) {
+	for _, cfg := range []string{
+		``,
+		`{"decorators": [{"caching": {}}]}`,
+		`{"decorators": [{"timeout": "0s"}]}`,
+		`{"decorators": [{"timeout": 5}]}`,
+		`{"decorators": [{"cache": {"ttl": "-1m"}}]}`,
+		`{"decorators": [{"retry": {"max_atempts": 3}}]}`,
+		`{"decorators": [{"timeout": "1s", "retry": {}}]}`,
+		`{"decorator": []}`,
+		`{"base_url": "/relative"}`,
+	} {
+		if _, err := httpx.FromConfig(strings.NewReader(cfg)); err == nil {
+			t.Error("expected error for", cfg)
+		}
+	}
+}
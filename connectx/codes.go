@@ -0,0 +1,57 @@
+package connectx
+
+import "net/http"
+
+// Code is the status of an RPC, named as in the Connect protocol
+type Code string
+
+// The codes shared by Connect and gRPC, in the order of their gRPC numbers starting at 1
+const (
+	CodeCanceled           Code = "canceled"
+	CodeUnknown            Code = "unknown"
+	CodeInvalidArgument    Code = "invalid_argument"
+	CodeDeadlineExceeded   Code = "deadline_exceeded"
+	CodeNotFound           Code = "not_found"
+	CodeAlreadyExists      Code = "already_exists"
+	CodePermissionDenied   Code = "permission_denied"
+	CodeResourceExhausted  Code = "resource_exhausted"
+	CodeFailedPrecondition Code = "failed_precondition"
+	CodeAborted            Code = "aborted"
+	CodeOutOfRange         Code = "out_of_range"
+	CodeUnimplemented      Code = "unimplemented"
+	CodeInternal           Code = "internal"
+	CodeUnavailable        Code = "unavailable"
+	CodeDataLoss           Code = "data_loss"
+	CodeUnauthenticated    Code = "unauthenticated"
+)
+
+var grpcCodes = []Code{
+	CodeCanceled, CodeUnknown, CodeInvalidArgument, CodeDeadlineExceeded, CodeNotFound, CodeAlreadyExists,
+	CodePermissionDenied, CodeResourceExhausted, CodeFailedPrecondition, CodeAborted, CodeOutOfRange,
+	CodeUnimplemented, CodeInternal, CodeUnavailable, CodeDataLoss, CodeUnauthenticated,
+}
+
+// codeFromGRPC returns the code for a grpc-status number
+func codeFromGRPC(n int) Code {
+	if n < 1 || n > len(grpcCodes) {
+		return CodeUnknown
+	}
+	return grpcCodes[n-1]
+}
+
+// codeFromHTTP returns the code for a response that does not carry one, such as an error from a proxy
+func codeFromHTTP(status int) Code {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeInternal
+	case http.StatusUnauthorized:
+		return CodeUnauthenticated
+	case http.StatusForbidden:
+		return CodePermissionDenied
+	case http.StatusNotFound:
+		return CodeUnimplemented
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return CodeUnavailable
+	}
+	return CodeUnknown
+}
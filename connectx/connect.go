@@ -0,0 +1,253 @@
+// Package connectx calls Connect and gRPC-Web services through httpx clients, so that RPCs pass through the usual
+// decoration chain of retries, rate limits and logging without importing a full RPC stack.
+//
+// Only unary calls are supported. Messages are encoded with a Codec, JSON by default; a protobuf Codec can be
+// given for services that do not accept JSON.
+package connectx
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/tflyons/httpx"
+)
+
+// Protocol is the wire protocol used by a Client
+type Protocol int
+
+const (
+	// ProtocolConnect sends unary calls as plain HTTP requests as described by the Connect protocol
+	ProtocolConnect Protocol = iota
+	// ProtocolGRPCWeb sends calls as length prefixed frames with the status in a trailer frame
+	ProtocolGRPCWeb
+)
+
+// Codec encodes messages. Name is the suffix of the content type, such as "json" or "proto"
+type Codec interface {
+	Name() string
+	Marshal(v any) ([]byte, error)
+	Unmarshal(b []byte, v any) error
+}
+
+// JSON encodes messages with encoding/json
+var JSON Codec = jsonCodec{}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string                    { return "json" }
+func (jsonCodec) Marshal(v any) ([]byte, error)   { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(b []byte, v any) error { return json.Unmarshal(b, v) }
+
+// Client calls the procedures of a service at BaseURL
+type Client struct {
+	// Client performs the requests, httpx.DefaultClient if nil
+	Client httpx.Client
+	// BaseURL is the URL the procedure paths are appended to, such as "https://api.example.com"
+	BaseURL  string
+	Protocol Protocol
+	// Codec encodes the messages, JSON if nil
+	Codec Codec
+	// MaxMessageBytes is the largest response message accepted, 4 MiB if zero
+	MaxMessageBytes int64
+}
+
+// CallUnary calls procedure, such as "/acme.orders.v1.OrderService/GetOrder", with req and decodes the reply into
+// resp. An RPC that fails returns an *Error with the status sent by the server. The deadline of ctx is sent to the
+// server as the timeout of the call
+func (c *Client) CallUnary(ctx context.Context, procedure string, req, resp any) error {
+	codec := c.Codec
+	if codec == nil {
+		codec = JSON
+	}
+	msg, err := codec.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("could not marshal request: %w", err)
+	}
+	u := strings.TrimSuffix(c.BaseURL, "/") + "/" + strings.TrimPrefix(procedure, "/")
+	var body []byte
+	switch c.Protocol {
+	case ProtocolConnect:
+		body = msg
+	case ProtocolGRPCWeb:
+		body = make([]byte, 5, 5+len(msg))
+		binary.BigEndian.PutUint32(body[1:], uint32(len(msg)))
+		body = append(body, msg...)
+	default:
+		return fmt.Errorf("unknown protocol %d", c.Protocol)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	var timeout int64 = -1
+	if deadline, ok := ctx.Deadline(); ok {
+		if timeout = deadline.Sub(httpx.ClockFromContext(ctx).Now()).Milliseconds(); timeout < 1 {
+			timeout = 1
+		}
+	}
+	if c.Protocol == ProtocolConnect {
+		httpReq.Header.Set("Content-Type", "application/"+codec.Name())
+		httpReq.Header.Set("Connect-Protocol-Version", "1")
+		if timeout > 0 {
+			httpReq.Header.Set("Connect-Timeout-Ms", strconv.FormatInt(timeout, 10))
+		}
+	} else {
+		httpReq.Header.Set("Content-Type", "application/grpc-web+"+codec.Name())
+		httpReq.Header.Set("X-Grpc-Web", "1")
+		if timeout > 0 {
+			httpReq.Header.Set("Grpc-Timeout", strconv.FormatInt(timeout, 10)+"m")
+		}
+	}
+
+	hc := c.Client
+	if hc == nil {
+		hc = httpx.DefaultClient
+	}
+	httpResp, err := hc.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+	max := c.MaxMessageBytes
+	if max <= 0 {
+		max = 4 << 20
+	}
+	if c.Protocol == ProtocolConnect {
+		return readConnect(httpResp, codec, max, resp)
+	}
+	return readGRPCWeb(httpResp, codec, max, resp)
+}
+
+// readConnect decodes a unary Connect response, any status other than 200 carries a JSON error
+func readConnect(httpResp *http.Response, codec Codec, max int64, resp any) error {
+	b, err := io.ReadAll(io.LimitReader(httpResp.Body, max+1))
+	if err != nil {
+		return fmt.Errorf("could not read response: %w", err)
+	}
+	if int64(len(b)) > max {
+		return &Error{Code: CodeResourceExhausted, Message: fmt.Sprintf("message larger than %d bytes", max), Header: httpResp.Header}
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		rpcErr := &Error{Header: httpResp.Header}
+		if json.Unmarshal(b, rpcErr) != nil || rpcErr.Code == "" {
+			rpcErr.Code = codeFromHTTP(httpResp.StatusCode)
+			rpcErr.Message = strings.TrimSpace(http.StatusText(httpResp.StatusCode))
+		}
+		return rpcErr
+	}
+	if err = codec.Unmarshal(b, resp); err != nil {
+		return fmt.Errorf("could not unmarshal response: %w", err)
+	}
+	return nil
+}
+
+// readGRPCWeb decodes the data frame of a gRPC-Web response and the status from its trailers, which are sent in
+// the HTTP headers instead of a trailer frame when the call failed before any message
+func readGRPCWeb(httpResp *http.Response, codec Codec, max int64, resp any) error {
+	if httpResp.StatusCode != http.StatusOK {
+		return &Error{Code: codeFromHTTP(httpResp.StatusCode), Message: http.StatusText(httpResp.StatusCode), Header: httpResp.Header}
+	}
+	trailer := textproto.MIMEHeader(httpResp.Header)
+	var msg []byte
+	received := false
+	for {
+		var prefix [5]byte
+		if _, err := io.ReadFull(httpResp.Body, prefix[:]); err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("could not read frame: %w", err)
+		}
+		size := int64(binary.BigEndian.Uint32(prefix[1:]))
+		if size > max {
+			return &Error{Code: CodeResourceExhausted, Message: fmt.Sprintf("message larger than %d bytes", max), Header: httpResp.Header}
+		}
+		frame := make([]byte, size)
+		if _, err := io.ReadFull(httpResp.Body, frame); err != nil {
+			return fmt.Errorf("could not read frame: %w", err)
+		}
+		switch {
+		case prefix[0]&0x80 != 0:
+			t, err := parseTrailers(frame)
+			if err != nil {
+				return fmt.Errorf("could not read trailers: %w", err)
+			}
+			trailer = t
+		case prefix[0]&0x01 != 0:
+			return &Error{Code: CodeInternal, Message: "compressed messages are not supported", Header: httpResp.Header}
+		case received:
+			return &Error{Code: CodeUnimplemented, Message: "unary call received more than one message", Header: httpResp.Header}
+		default:
+			msg, received = frame, true
+		}
+	}
+
+	status := trailer.Get("Grpc-Status")
+	if status == "" {
+		return &Error{Code: CodeInternal, Message: "missing grpc-status", Header: httpResp.Header}
+	}
+	if status != "0" {
+		n, _ := strconv.Atoi(status)
+		message, _ := url.PathUnescape(trailer.Get("Grpc-Message"))
+		return &Error{Code: codeFromGRPC(n), Message: message, Header: http.Header(trailer)}
+	}
+	if !received {
+		return &Error{Code: CodeUnimplemented, Message: "unary call received no message", Header: httpResp.Header}
+	}
+	if err := codec.Unmarshal(msg, resp); err != nil {
+		return fmt.Errorf("could not unmarshal response: %w", err)
+	}
+	return nil
+}
+
+// parseTrailers reads a trailer frame, which holds header lines without the blank line ending a header block
+func parseTrailers(frame []byte) (textproto.MIMEHeader, error) {
+	r := io.MultiReader(bytes.NewReader(frame), strings.NewReader("\r\n\r\n"))
+	return textproto.NewReader(bufio.NewReader(r)).ReadMIMEHeader()
+}
+
+// ErrorDetail is a detail attached to an Error, Value is the base64 encoded protobuf message of Type
+type ErrorDetail struct {
+	Type  string          `json:"type"`
+	Value string          `json:"value"`
+	Debug json.RawMessage `json:"debug,omitempty"`
+}
+
+// Error is a failed RPC
+type Error struct {
+	Code    Code          `json:"code"`
+	Message string        `json:"message"`
+	Details []ErrorDetail `json:"details,omitempty"`
+	// Header holds the response headers, or the trailers of a gRPC-Web call
+	Header http.Header `json:"-"`
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return "rpc error: " + string(e.Code)
+	}
+	return fmt.Sprintf("rpc error: %s: %s", e.Code, e.Message)
+}
+
+// CodeOf returns the code of the *Error in err, CodeUnknown for any other error or an empty code if err is nil
+func CodeOf(err error) Code {
+	if err == nil {
+		return ""
+	}
+	var rpcErr *Error
+	if errors.As(err, &rpcErr) {
+		return rpcErr.Code
+	}
+	return CodeUnknown
+}
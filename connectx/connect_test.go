@@ -0,0 +1,99 @@
+package connectx_test
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx/connectx"
+)
+
+type getOrder struct {
+	ID string `json:"id"`
+}
+
+type order struct {
+	ID    string `json:"id"`
+	Total int    `json:"total"`
+}
+
+func TestCallUnary_Connect(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/acme.v1.OrderService/GetOrder" || r.Header.Get("Content-Type") != "application/json" ||
+			r.Header.Get("Connect-Protocol-Version") != "1" || r.Header.Get("Connect-Timeout-Ms") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var req getOrder
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req.ID != "o-1" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"code": "not_found", "message": "no order ` + req.ID + `"}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(order{ID: req.ID, Total: 42})
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c := &connectx.Client{Client: srv.Client(), BaseURL: srv.URL}
+	var out order
+	if err := c.CallUnary(ctx, "/acme.v1.OrderService/GetOrder", getOrder{ID: "o-1"}, &out); err != nil || out.Total != 42 {
+		t.Fatal(err, out)
+	}
+	err := c.CallUnary(ctx, "/acme.v1.OrderService/GetOrder", getOrder{ID: "o-2"}, &out)
+	if connectx.CodeOf(err) != connectx.CodeNotFound || err.Error() != "rpc error: not_found: no order o-2" {
+		t.Fatal(err)
+	}
+	err = c.CallUnary(context.Background(), "/acme.v1.OrderService/GetOrder", getOrder{ID: "o-1"}, &out)
+	if connectx.CodeOf(err) != connectx.CodeInternal {
+		t.Fatal(err)
+	}
+}
+
+func frame(flag byte, b []byte) []byte {
+	out := make([]byte, 5, 5+len(b))
+	out[0] = flag
+	binary.BigEndian.PutUint32(out[1:], uint32(len(b)))
+	return append(out, b...)
+}
+
+func TestCallUnary_GRPCWeb(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("Content-Type") != "application/grpc-web+json" || len(body) < 5 || int(binary.BigEndian.Uint32(body[1:5])) != len(body)-5 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var req getOrder
+		_ = json.Unmarshal(body[5:], &req)
+		w.Header().Set("Content-Type", "application/grpc-web+json")
+		if req.ID != "o-1" {
+			// a trailers-only response
+			w.Header().Set("Grpc-Status", "7")
+			w.Header().Set("Grpc-Message", "not%20yours")
+			return
+		}
+		msg, _ := json.Marshal(order{ID: req.ID, Total: 42})
+		_, _ = w.Write(frame(0, msg))
+		_, _ = w.Write(frame(0x80, []byte("grpc-status: 0\r\ngrpc-message: \r\n")))
+	}))
+	defer srv.Close()
+
+	c := &connectx.Client{Client: srv.Client(), BaseURL: srv.URL, Protocol: connectx.ProtocolGRPCWeb}
+	var out order
+	if err := c.CallUnary(context.Background(), "acme.v1.OrderService/GetOrder", getOrder{ID: "o-1"}, &out); err != nil || out.Total != 42 {
+		t.Fatal(err, out)
+	}
+	err := c.CallUnary(context.Background(), "acme.v1.OrderService/GetOrder", getOrder{ID: "o-2"}, &out)
+	if connectx.CodeOf(err) != connectx.CodePermissionDenied || err.Error() != "rpc error: permission_denied: not yours" {
+		t.Fatal(err)
+	}
+}
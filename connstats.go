@@ -0,0 +1,172 @@
+package httpx
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+)
+
+// ConnStats tracks the connection pool of a transport per host. Connections are counted by a transport built by
+// NewTransport with TransportConfig.ConnStats set and their use by requests made through SetConnStats.
+// The zero value is ready to use
+type ConnStats struct {
+	mu    sync.Mutex
+	conns map[net.Conn]*connState
+	hosts map[string]*HostConnStats
+}
+
+// HostConnStats are the connection counters of a single host:port
+type HostConnStats struct {
+	// Open is the number of connections, Idle those waiting in the pool and Active those in use.
+	// HTTP/2 connections are counted as active while open
+	Open, Idle, Active int
+	// NewConns and ReusedConns count the connections requests got, newly dialed or from the pool
+	NewConns, ReusedConns int64
+}
+
+// ReuseRatio is the fraction of requests that reused a pooled connection. A low ratio with many new connections
+// means TLS handshakes are repeated, often because response bodies are not read to the end and closed or
+// MaxIdleConnsPerHost is too small
+func (h HostConnStats) ReuseRatio() float64 {
+	if total := h.NewConns + h.ReusedConns; total > 0 {
+		return float64(h.ReusedConns) / float64(total)
+	}
+	return 0
+}
+
+type connState struct {
+	host   string
+	idle   bool
+	closed bool
+}
+
+// Snapshot returns the counters of every host seen so far
+func (s *ConnStats) Snapshot() map[string]HostConnStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]HostConnStats, len(s.hosts))
+	for host, h := range s.hosts {
+		snap := *h
+		snap.Active = snap.Open - snap.Idle
+		out[host] = snap
+	}
+	return out
+}
+
+// hostLocked returns the counters of host
+func (s *ConnStats) hostLocked(host string) *HostConnStats {
+	if s.hosts == nil {
+		s.hosts = make(map[string]*HostConnStats)
+		s.conns = make(map[net.Conn]*connState)
+	}
+	h, ok := s.hosts[host]
+	if !ok {
+		h = &HostConnStats{}
+		s.hosts[host] = h
+	}
+	return h
+}
+
+// dialer wraps dial so that the connections it makes are counted
+func (s *ConnStats) dialer(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return conn, err
+		}
+		tc := &trackedConn{Conn: conn, stats: s}
+		s.mu.Lock()
+		s.hostLocked(addr).Open++
+		s.conns[tc] = &connState{host: addr}
+		s.mu.Unlock()
+		return tc, nil
+	}
+}
+
+// lookupLocked returns the state of a connection handed out by the transport
+func (s *ConnStats) lookupLocked(conn net.Conn) *connState {
+	if tc, ok := conn.(*tls.Conn); ok {
+		conn = tc.NetConn()
+	}
+	return s.conns[conn]
+}
+
+// trackedConn updates the counters when it is closed
+type trackedConn struct {
+	net.Conn
+	stats *ConnStats
+}
+
+func (c *trackedConn) Close() error {
+	s := c.stats
+	s.mu.Lock()
+	if st := s.conns[c]; st != nil && !st.closed {
+		st.closed = true
+		h := s.hostLocked(st.host)
+		h.Open--
+		if st.idle {
+			h.Idle--
+		}
+		delete(s.conns, c)
+	}
+	s.mu.Unlock()
+	return c.Conn.Close()
+}
+
+// SetConnStats records in s whether each request reused a pooled connection and when connections return to the
+// pool. Open and idle connection counts are only available for transports built by NewTransport with s set as
+// TransportConfig.ConnStats; for other transports connections are attributed to the host and port of the URL
+func SetConnStats(c Client, s *ConnStats) ClientFunc {
+	c = nilClientCheck(c)
+	return func(req *http.Request) (*http.Response, error) {
+		var conn net.Conn
+		trace := &httptrace.ClientTrace{
+			GotConn: func(info httptrace.GotConnInfo) {
+				s.mu.Lock()
+				defer s.mu.Unlock()
+				conn = info.Conn
+				host := canonicalHostPort(req)
+				if st := s.lookupLocked(conn); st != nil {
+					host = st.host
+					if st.idle {
+						st.idle = false
+						s.hostLocked(host).Idle--
+					}
+				}
+				if h := s.hostLocked(host); info.Reused {
+					h.ReusedConns++
+				} else {
+					h.NewConns++
+				}
+			},
+			PutIdleConn: func(err error) {
+				s.mu.Lock()
+				defer s.mu.Unlock()
+				if err != nil || conn == nil {
+					return
+				}
+				if st := s.lookupLocked(conn); st != nil && !st.idle && !st.closed {
+					st.idle = true
+					s.hostLocked(st.host).Idle++
+				}
+			},
+		}
+		ctx := httptrace.WithClientTrace(req.Context(), trace)
+		return c.Do(req.WithContext(ctx))
+	}
+}
+
+// canonicalHostPort returns the host and port of the request url, adding the default port of the scheme
+func canonicalHostPort(req *http.Request) string {
+	host, port := req.URL.Hostname(), req.URL.Port()
+	if port == "" {
+		port = "80"
+		if req.URL.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return net.JoinHostPort(host, port)
+}
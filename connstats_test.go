@@ -0,0 +1,66 @@
+package httpx_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx"
+)
+
+func TestSetConnStats(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	stats := &httpx.ConnStats{}
+	tr, err := httpx.NewTransport(httpx.TransportConfig{ConnStats: stats})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body string
+	var c httpx.Client = &http.Client{Transport: tr}
+	c = httpx.SetConnStats(c, stats)
+	c = httpx.SetResponseBodyString(c, &body)
+	c = httpx.SetRequest(c, http.MethodGet, srv.URL)
+	for i := 0; i < 5; i++ {
+		if _, err := c.Do(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h := stats.Snapshot()[host]
+	if h.NewConns != 1 || h.ReusedConns != 4 || h.ReuseRatio() != 0.8 {
+		t.Fatalf("%+v", h)
+	}
+	// the transport returns connections to the pool in the background once a body has been read
+	eventually := func(ok func(h httpx.HostConnStats) bool) {
+		t.Helper()
+		for i := 0; !ok(stats.Snapshot()[host]); i++ {
+			if i == 100 {
+				t.Fatalf("%+v", stats.Snapshot()[host])
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	eventually(func(h httpx.HostConnStats) bool { return h.Open == 1 && h.Idle == 1 && h.Active == 0 })
+
+	// a response in progress holds its connection
+	resp, err := httpx.SetRequest(httpx.SetConnStats(&http.Client{Transport: tr}, stats), http.MethodGet, srv.URL).Do(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h := stats.Snapshot()[host]; h.Active != 1 || h.Idle != 0 {
+		t.Fatalf("%+v", h)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	eventually(func(h httpx.HostConnStats) bool { return h.Idle == 1 })
+
+	tr.CloseIdleConnections()
+	eventually(func(h httpx.HostConnStats) bool { return h.Open == 0 && h.Idle == 0 })
+}
@@ -0,0 +1,169 @@
+// Package contractx builds consumer driven contract fixtures from recorded traffic and verifies them against a
+// provider.
+//
+// Contracts use the JSON layout of Pact specification 2, so fixtures can be shared with Pact tooling, but bodies are
+// matched by shape rather than by value: the provider passes if every recorded field is present with the same JSON
+// type.
+package contractx
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"strings"
+
+	"github.com/tflyons/httpx/harx"
+)
+
+// Contract is a set of interactions between a consumer and a provider
+type Contract struct {
+	Consumer     Pacticipant   `json:"consumer"`
+	Provider     Pacticipant   `json:"provider"`
+	Interactions []Interaction `json:"interactions"`
+	Metadata     Metadata      `json:"metadata"`
+}
+
+// Pacticipant names a party of the contract
+type Pacticipant struct {
+	Name string `json:"name"`
+}
+
+// Metadata records the specification version of the contract
+type Metadata struct {
+	PactSpecification struct {
+		Version string `json:"version"`
+	} `json:"pactSpecification"`
+}
+
+// Interaction is an expected request and response
+type Interaction struct {
+	Description string   `json:"description"`
+	Request     Request  `json:"request"`
+	Response    Response `json:"response"`
+}
+
+// Request is the request sent by the consumer
+type Request struct {
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Query   string            `json:"query,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Body is the decoded JSON value, or a string for other media types
+	Body any `json:"body,omitempty"`
+}
+
+// Response is the response the consumer relies on
+type Response struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    any               `json:"body,omitempty"`
+}
+
+// Read decodes a contract
+func Read(r io.Reader) (*Contract, error) {
+	var c Contract
+	if err := json.NewDecoder(r).Decode(&c); err != nil {
+		return nil, fmt.Errorf("could not decode contract: %w", err)
+	}
+	return &c, nil
+}
+
+// Write encodes c as an indented JSON document
+func Write(w io.Writer, c *Contract) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(c)
+}
+
+// FromHAR builds a contract from the entries of a HAR recording, see harx.Recorder.
+//
+// Requests keep only their Content-Type and Accept headers and responses only their Content-Type, since other
+// headers are rarely part of what a consumer depends on. Entries with the same method, path, query and status as an
+// earlier entry are left out
+func FromHAR(h *harx.HAR, consumer, provider string) (*Contract, error) {
+	c := &Contract{Consumer: Pacticipant{consumer}, Provider: Pacticipant{provider}, Interactions: []Interaction{}}
+	c.Metadata.PactSpecification.Version = "2.0.0"
+	seen := make(map[string]bool)
+	for i := range h.Log.Entries {
+		e := &h.Log.Entries[i]
+		u, err := url.Parse(e.Request.URL)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i, err)
+		}
+		key := fmt.Sprintf("%s %s?%s %d", e.Request.Method, u.EscapedPath(), u.RawQuery, e.Response.Status)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		in := Interaction{
+			Description: fmt.Sprintf("%s %s returns %d", e.Request.Method, u.Path, e.Response.Status),
+			Request:     Request{Method: e.Request.Method, Path: u.EscapedPath(), Query: u.RawQuery},
+			Response:    Response{Status: e.Response.Status},
+		}
+		for _, hv := range e.Request.Headers {
+			if name := canonical(hv.Name); name == "Content-Type" || name == "Accept" {
+				in.Request.Headers = setHeader(in.Request.Headers, name, hv.Value)
+			}
+		}
+		if pd := e.Request.PostData; pd != nil && pd.Text != "" {
+			if in.Request.Body, err = decodeBody(pd.MimeType, []byte(pd.Text)); err != nil {
+				return nil, fmt.Errorf("entry %d request: %w", i, err)
+			}
+		}
+		for _, hv := range e.Response.Headers {
+			if canonical(hv.Name) == "Content-Type" {
+				in.Response.Headers = setHeader(in.Response.Headers, "Content-Type", hv.Value)
+			}
+		}
+		b, err := e.Response.Content.Body()
+		if err != nil {
+			return nil, fmt.Errorf("entry %d response: %w", i, err)
+		}
+		if len(b) > 0 {
+			if in.Response.Body, err = decodeBody(e.Response.Content.MimeType, b); err != nil {
+				return nil, fmt.Errorf("entry %d response: %w", i, err)
+			}
+		}
+		c.Interactions = append(c.Interactions, in)
+	}
+	return c, nil
+}
+
+func canonical(name string) string {
+	switch strings.ToLower(name) {
+	case "content-type":
+		return "Content-Type"
+	case "accept":
+		return "Accept"
+	}
+	return name
+}
+
+func setHeader(h map[string]string, k, v string) map[string]string {
+	if h == nil {
+		h = make(map[string]string)
+	}
+	h[k] = v
+	return h
+}
+
+// isJSON reports whether the media type is JSON
+func isJSON(contentType string) bool {
+	mt, _, _ := mime.ParseMediaType(contentType)
+	return mt == "application/json" || strings.HasSuffix(mt, "+json")
+}
+
+// decodeBody returns the JSON value of a JSON body and the text of any other body
+func decodeBody(contentType string, b []byte) (any, error) {
+	if !isJSON(contentType) {
+		return string(b), nil
+	}
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, fmt.Errorf("could not decode json body: %w", err)
+	}
+	return v, nil
+}
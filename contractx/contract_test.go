@@ -0,0 +1,94 @@
+package contractx_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/contractx"
+	"github.com/tflyons/httpx/harx"
+)
+
+func provider(userBody string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users/1":
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			_, _ = w.Write([]byte(userBody))
+		case "/users":
+			if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+}
+
+func TestFromHARAndVerify(t *testing.T) {
+	v1 := httptest.NewServer(provider(`{"id":1,"name":"bob","tags":[{"name":"a"}]}`))
+	defer v1.Close()
+
+	rec := &harx.Recorder{}
+	c := harx.Record(v1.Client(), rec)
+	for _, do := range []httpx.Client{
+		httpx.SetRequest(c, http.MethodGet, v1.URL+"/users/1"),
+		httpx.SetRequest(c, http.MethodGet, v1.URL+"/users/1"),
+		httpx.SetRequest(httpx.SetRequestBodyJSON(c, map[string]string{"name": "bob"}), http.MethodPost, v1.URL+"/users"),
+	} {
+		resp, err := do.Do(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	contract, err := contractx.FromHAR(rec.HAR(), "web", "users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(contract.Interactions) != 2 {
+		t.Fatalf("expected duplicate interactions to be removed, got %d", len(contract.Interactions))
+	}
+	var buf bytes.Buffer
+	if err := contractx.Write(&buf, contract); err != nil {
+		t.Fatal(err)
+	}
+	if contract, err = contractx.Read(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	base, _ := url.Parse(v1.URL)
+	mismatches, err := contractx.Verify(context.Background(), v1.Client(), base, contract)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mismatches) != 0 {
+		t.Fatal(mismatches)
+	}
+
+	// values may change but not the shape, extra fields are allowed
+	v2 := httptest.NewServer(provider(`{"id":"1","name":"alice","tags":[{"label":"a"}],"extra":true}`))
+	defer v2.Close()
+	base, _ = url.Parse(v2.URL)
+	mismatches, err = contractx.Verify(context.Background(), v2.Client(), base, contract)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mismatches) != 2 {
+		t.Fatal(mismatches)
+	}
+	if m := mismatches[0]; m.Field != "$.id" || m.Expected != "number" || m.Actual != "string" || !errors.Is(m, contractx.ErrMismatch) {
+		t.Fatal(m)
+	}
+	if m := mismatches[1]; m.Field != "$.tags[0].name" || m.Actual != "missing" {
+		t.Fatal(m)
+	}
+}
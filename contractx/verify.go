@@ -0,0 +1,190 @@
+package contractx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/tflyons/httpx"
+)
+
+// ErrMismatch is matched by every Mismatch using errors.Is
+var ErrMismatch = fmt.Errorf("provider does not honor the contract")
+
+// Mismatch describes a difference between an interaction and the response of the provider
+type Mismatch struct {
+	Interaction string
+	// Field is status, a header name or a JSON path into the body such as $.items[0].id
+	Field    string
+	Expected string
+	Actual   string
+}
+
+// Error implements the error interface
+func (m *Mismatch) Error() string {
+	return fmt.Sprintf("%s: %s: %s: expected %s, got %s", ErrMismatch, m.Interaction, m.Field, m.Expected, m.Actual)
+}
+
+// Is matches ErrMismatch
+func (m *Mismatch) Is(target error) bool {
+	return target == ErrMismatch
+}
+
+// Verify sends every interaction of the contract to the provider at base through c and reports how the responses
+// differ from the contract.
+//
+// The status must be equal, the media type of a Content-Type header must be equal and JSON bodies must have the
+// shape of the recorded body: objects must have every recorded key, arrays must hold elements shaped like the first
+// recorded element and values must have the same JSON type. Additional fields are allowed. Other bodies are not
+// compared. The error is only set if a request cannot be performed
+func Verify(ctx context.Context, c httpx.Client, base *url.URL, contract *Contract) ([]*Mismatch, error) {
+	if c == nil {
+		c = httpx.DefaultClient
+	}
+	var mismatches []*Mismatch
+	for i := range contract.Interactions {
+		in := &contract.Interactions[i]
+		req, err := in.Request.newRequest(ctx, base)
+		if err != nil {
+			return mismatches, fmt.Errorf("%s: %w", in.Description, err)
+		}
+		var body []byte
+		resp, err := httpx.SetResponseBodyBytes(c, &body).Do(req)
+		if err != nil {
+			return mismatches, fmt.Errorf("%s: %w", in.Description, err)
+		}
+		mismatches = append(mismatches, in.compare(resp, body)...)
+	}
+	return mismatches, nil
+}
+
+// newRequest builds the request against base
+func (r *Request) newRequest(ctx context.Context, base *url.URL) (*http.Request, error) {
+	u := *base
+	u.RawPath = strings.TrimSuffix(base.EscapedPath(), "/") + r.Path
+	p, err := url.PathUnescape(u.RawPath)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = p
+	u.RawQuery = r.Query
+	var body io.Reader
+	switch b := r.Body.(type) {
+	case nil:
+	case string:
+		body = strings.NewReader(b)
+	default:
+		j, err := json.Marshal(b)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(j)
+	}
+	req, err := http.NewRequestWithContext(ctx, r.Method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range r.Headers {
+		req.Header.Set(k, v)
+	}
+	return req, nil
+}
+
+// compare returns the mismatches between the interaction and the response
+func (in *Interaction) compare(resp *http.Response, body []byte) []*Mismatch {
+	var out []*Mismatch
+	add := func(field, expected, actual string) {
+		out = append(out, &Mismatch{Interaction: in.Description, Field: field, Expected: expected, Actual: actual})
+	}
+	if resp.StatusCode != in.Response.Status {
+		add("status", fmt.Sprint(in.Response.Status), fmt.Sprint(resp.StatusCode))
+	}
+	keys := make([]string, 0, len(in.Response.Headers))
+	for k := range in.Response.Headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		want, got := in.Response.Headers[k], resp.Header.Get(k)
+		if strings.EqualFold(k, "Content-Type") {
+			w, _, _ := mime.ParseMediaType(want)
+			g, _, _ := mime.ParseMediaType(got)
+			if w == g {
+				continue
+			}
+		} else if want == got {
+			continue
+		}
+		add(k, want, got)
+	}
+	if in.Response.Body == nil || !isJSON(in.Response.Headers["Content-Type"]) {
+		return out
+	}
+	var actual any
+	if err := json.Unmarshal(body, &actual); err != nil {
+		add("$", "json body", "invalid json")
+		return out
+	}
+	matchShape("$", in.Response.Body, actual, add)
+	return out
+}
+
+// matchShape reports where actual does not have the shape of expected
+func matchShape(path string, expected, actual any, add func(field, expected, actual string)) {
+	if expected == nil {
+		return
+	}
+	if jsonType(expected) != jsonType(actual) {
+		add(path, jsonType(expected), jsonType(actual))
+		return
+	}
+	switch e := expected.(type) {
+	case map[string]any:
+		a := actual.(map[string]any)
+		keys := make([]string, 0, len(e))
+		for k := range e {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			v, ok := a[k]
+			if !ok {
+				add(path+"."+k, jsonType(e[k]), "missing")
+				continue
+			}
+			matchShape(path+"."+k, e[k], v, add)
+		}
+	case []any:
+		if len(e) == 0 {
+			return
+		}
+		for i, v := range actual.([]any) {
+			matchShape(fmt.Sprintf("%s[%d]", path, i), e[0], v, add)
+		}
+	}
+}
+
+func jsonType(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
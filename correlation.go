@@ -0,0 +1,99 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Headers used to carry a Correlation between services
+const (
+	HeaderRequestID      = "X-Request-Id"
+	HeaderTraceParent    = "Traceparent"
+	HeaderTraceState     = "Tracestate"
+	HeaderForwardedUser  = "X-Forwarded-User"
+	HeaderRequestTimeout = "X-Request-Timeout"
+	HeaderTenantID       = "X-Tenant-Id"
+	HeaderForwardedFor   = "X-Forwarded-For"
+)
+
+// Correlation is the request metadata that an inbound server request shares with the outgoing client requests
+// made while handling it. It is stored on the context by serverx.Correlate and read by ForwardCorrelation
+type Correlation struct {
+	RequestID string
+	// TraceParent and TraceState are the W3C trace context headers
+	TraceParent string
+	TraceState  string
+	// Principal is the authenticated caller, if any
+	Principal string
+	// Deadline is the time by which the inbound request must be answered, zero if there is none
+	Deadline time.Time
+	// Tenant is the customer the request is made for, if any
+	Tenant string
+	// ForwardedFor is the chain of client addresses, the addresses reported by proxies followed by the remote
+	// address of the connection. Only the addresses added by proxies in front of the service can be trusted
+	ForwardedFor []string
+}
+
+// ClientIP returns the address of the original client as reported by the first proxy, or the remote address
+// of the connection if there was no proxy. It is only as trustworthy as the proxies in front of the service
+func (cor Correlation) ClientIP() string {
+	if len(cor.ForwardedFor) == 0 {
+		return ""
+	}
+	return cor.ForwardedFor[0]
+}
+
+type correlationKey struct{}
+
+// WithCorrelation returns a copy of ctx carrying cor
+func WithCorrelation(ctx context.Context, cor Correlation) context.Context {
+	return context.WithValue(ctx, correlationKey{}, cor)
+}
+
+// CorrelationFromContext returns the Correlation stored on ctx
+func CorrelationFromContext(ctx context.Context) (Correlation, bool) {
+	cor, ok := ctx.Value(correlationKey{}).(Correlation)
+	return cor, ok
+}
+
+// ForwardCorrelation copies the Correlation on the request context into the outgoing request headers.
+//
+// The request ID, trace context, principal and tenant are sent in HeaderRequestID, HeaderTraceParent,
+// HeaderTraceState, HeaderForwardedUser and HeaderTenantID. The time remaining until the context deadline, measured
+// with the clock of the request context, is sent in HeaderRequestTimeout as whole milliseconds so that the
+// downstream service can stop work the caller will no longer wait for.
+// Headers already set on the request are not overwritten
+func ForwardCorrelation(c Client) ClientFunc {
+	c = nilClientCheck(c)
+	return func(req *http.Request) (*http.Response, error) {
+		if req.Header == nil {
+			req.Header = make(http.Header)
+		}
+		cor, _ := CorrelationFromContext(req.Context())
+		setDefault := func(key, value string) {
+			if value != "" && req.Header.Get(key) == "" {
+				req.Header.Set(key, value)
+			}
+		}
+		setDefault(HeaderRequestID, cor.RequestID)
+		setDefault(HeaderTraceParent, cor.TraceParent)
+		setDefault(HeaderTraceState, cor.TraceState)
+		setDefault(HeaderForwardedUser, cor.Principal)
+		setDefault(HeaderTenantID, cor.Tenant)
+
+		deadline, ok := req.Context().Deadline()
+		if !ok {
+			deadline = cor.Deadline
+		}
+		if !deadline.IsZero() {
+			remaining := deadline.Sub(ClockFromContext(req.Context()).Now()).Milliseconds()
+			if remaining < 1 {
+				remaining = 1
+			}
+			setDefault(HeaderRequestTimeout, strconv.FormatInt(remaining, 10))
+		}
+		return c.Do(req)
+	}
+}
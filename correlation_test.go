@@ -0,0 +1,73 @@
+package httpx_test
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/httpxtest"
+)
+
+func TestForwardCorrelation(t *testing.T) {
+	var got http.Header
+	var c httpx.Client = httpx.ClientFunc(func(req *http.Request) (*http.Response, error) {
+		got = req.Header
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+	c = httpx.ForwardCorrelation(c)
+
+	ctx := httpx.WithCorrelation(context.Background(), httpx.Correlation{
+		RequestID:   "req-1",
+		TraceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		Principal:   "alice",
+	})
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	if _, err := httpx.SetHeader(c, "X-Request-Id", "explicit").Do(mustRequest(t, ctx)); err != nil {
+		t.Fatal(err)
+	}
+	if got.Get(httpx.HeaderRequestID) != "explicit" || got.Get(httpx.HeaderForwardedUser) != "alice" ||
+		got.Get(httpx.HeaderTraceParent) == "" || got.Get(httpx.HeaderTraceState) != "" {
+		t.Fatal(got)
+	}
+	ms, err := strconv.Atoi(got.Get(httpx.HeaderRequestTimeout))
+	if err != nil || ms <= 0 || ms > 60000 {
+		t.Fatal(got.Get(httpx.HeaderRequestTimeout))
+	}
+
+	if _, err = c.Do(mustRequest(t, context.Background())); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Fatal(got)
+	}
+}
+
+func TestForwardCorrelation_Clock(t *testing.T) {
+	clock := httpxtest.NewClock(time.Unix(1700000000, 0))
+	var got string
+	c := httpx.ForwardCorrelation(httpx.ClientFunc(func(req *http.Request) (*http.Response, error) {
+		got = req.Header.Get(httpx.HeaderRequestTimeout)
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	}))
+	c = httpx.SetClock(c, clock)
+
+	ctx := httpx.WithCorrelation(context.Background(), httpx.Correlation{Deadline: clock.Now().Add(1500 * time.Millisecond)})
+	if _, err := c.Do(mustRequest(t, ctx)); err != nil {
+		t.Fatal(err)
+	}
+	if got != "1500" {
+		t.Fatal("expected the time remaining on the clock", got)
+	}
+}
+
+func mustRequest(t *testing.T, ctx context.Context) *http.Request {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
@@ -0,0 +1,152 @@
+package httpx
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"sync"
+)
+
+// CSRFOptions configures SetCSRFToken. The zero value reads the token from the XSRF-TOKEN cookie and sends it in the
+// X-CSRF-Token header
+type CSRFOptions struct {
+	// CookieName is the cookie holding the token, XSRF-TOKEN if empty
+	CookieName string
+	// HeaderName is the request header the token is sent in, X-CSRF-Token if empty
+	HeaderName string
+	// Token extracts the token from the bootstrap response instead of reading CookieName, for example from a
+	// meta tag or a JSON body
+	Token func(resp *http.Response) (string, error)
+	// IsFailure reports whether a response is a CSRF failure, by default 403 Forbidden or 419
+	IsFailure func(resp *http.Response) bool
+}
+
+// SetCSRFToken sends a CSRF token on mutating requests, those not using GET, HEAD, OPTIONS or TRACE.
+//
+// The token is fetched with a GET of bootstrapURL before the first mutating request and the cookies set by that
+// response are added to mutating requests that do not already have them. When a response is a CSRF failure the
+// token is fetched again and the request is sent once more, if its body can be replayed
+func SetCSRFToken(c Client, bootstrapURL string, opts CSRFOptions) ClientFunc {
+	c = nilClientCheck(c)
+	if opts.CookieName == "" {
+		opts.CookieName = "XSRF-TOKEN"
+	}
+	if opts.HeaderName == "" {
+		opts.HeaderName = "X-CSRF-Token"
+	}
+	opts.HeaderName = textproto.CanonicalMIMEHeaderKey(opts.HeaderName)
+	if opts.IsFailure == nil {
+		opts.IsFailure = func(resp *http.Response) bool {
+			return resp.StatusCode == http.StatusForbidden || resp.StatusCode == 419
+		}
+	}
+
+	var mu sync.Mutex
+	var token string
+	var cookies []*http.Cookie
+	// bootstrap fetches a new token unless another request already replaced stale
+	bootstrap := func(req *http.Request, stale string) (string, []*http.Cookie, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if token != "" && token != stale {
+			return token, cookies, nil
+		}
+		boot, err := http.NewRequestWithContext(req.Context(), http.MethodGet, bootstrapURL, nil)
+		if err != nil {
+			return "", nil, err
+		}
+		resp, err := c.Do(boot)
+		if err != nil {
+			return "", nil, fmt.Errorf("could not fetch csrf token: %w", err)
+		}
+		defer resp.Body.Close()
+		var t string
+		if opts.Token != nil {
+			t, err = opts.Token(resp)
+		} else {
+			for _, cookie := range resp.Cookies() {
+				if cookie.Name == opts.CookieName {
+					t = cookie.Value
+				}
+			}
+		}
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+		if err != nil {
+			return "", nil, fmt.Errorf("could not read csrf token: %w", err)
+		}
+		if t == "" {
+			return "", nil, fmt.Errorf("csrf bootstrap response from %s has no token", bootstrapURL)
+		}
+		token, cookies = t, resp.Cookies()
+		return token, cookies, nil
+	}
+	apply := func(req *http.Request, t string, jar []*http.Cookie) {
+		if req.Header == nil {
+			req.Header = make(http.Header)
+		}
+		req.Header.Set(opts.HeaderName, t)
+		if len(jar) == 0 {
+			return
+		}
+		keep := requestCookiesExcept(req, jar)
+		req.Header.Del("Cookie")
+		for _, cookie := range keep {
+			req.AddCookie(cookie)
+		}
+		for _, cookie := range jar {
+			req.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value})
+		}
+	}
+
+	return func(req *http.Request) (*http.Response, error) {
+		switch req.Method {
+		case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+			return c.Do(req)
+		}
+		mu.Lock()
+		t, jar := token, cookies
+		mu.Unlock()
+		var err error
+		if t == "" {
+			if t, jar, err = bootstrap(req, ""); err != nil {
+				return nil, err
+			}
+		}
+		apply(req, t, jar)
+		resp, err := c.Do(req)
+		if err != nil || !opts.IsFailure(resp) ||
+			(req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+			return resp, err
+		}
+
+		fresh, jar, err := bootstrap(req, t)
+		if err != nil {
+			return resp, err
+		}
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return resp, fmt.Errorf("could not reset request body for csrf retry: %w", err)
+			}
+		}
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+		resp.Body.Close()
+		apply(req, fresh, jar)
+		return c.Do(req)
+	}
+}
+
+// requestCookiesExcept returns the cookies of req whose names are not in jar
+func requestCookiesExcept(req *http.Request, jar []*http.Cookie) []*http.Cookie {
+	var keep []*http.Cookie
+	for _, cookie := range req.Cookies() {
+		found := false
+		for _, j := range jar {
+			found = found || j.Name == cookie.Name
+		}
+		if !found {
+			keep = append(keep, cookie)
+		}
+	}
+	return keep
+}
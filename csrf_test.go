@@ -0,0 +1,57 @@
+package httpx_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/tflyons/httpx"
+)
+
+func TestSetCSRFToken(t *testing.T) {
+	var generation, bootstraps int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := "token-" + strconv.Itoa(int(atomic.LoadInt32(&generation)))
+		if r.URL.Path == "/csrf" {
+			atomic.AddInt32(&bootstraps, 1)
+			http.SetCookie(w, &http.Cookie{Name: "XSRF-TOKEN", Value: current})
+			return
+		}
+		cookie, err := r.Cookie("XSRF-TOKEN")
+		if r.Method == http.MethodPost && (err != nil || cookie.Value != current || r.Header.Get("X-Csrf-Token") != current) {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer srv.Close()
+
+	var c httpx.Client = srv.Client()
+	c = httpx.SetCSRFToken(c, srv.URL+"/csrf", httpx.CSRFOptions{})
+	c = httpx.RequireResponseStatus(c, http.StatusOK)
+	post := httpx.SetRequest(httpx.SetRequestBody(c, nil, []byte("{}")), http.MethodPost, srv.URL+"/items")
+
+	if _, err := httpx.SetRequest(c, http.MethodGet, srv.URL+"/items").Do(nil); err != nil {
+		t.Fatal(err)
+	}
+	if bootstraps != 0 {
+		t.Fatal("safe requests should not fetch a token")
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := post.Do(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if bootstraps != 1 {
+		t.Fatal("expected the token to be reused", bootstraps)
+	}
+
+	// the server rotates the token, the next request fails once and is retried with a new token
+	atomic.AddInt32(&generation, 1)
+	if _, err := post.Do(nil); err != nil {
+		t.Fatal(err)
+	}
+	if bootstraps != 2 {
+		t.Fatal(bootstraps)
+	}
+}
@@ -0,0 +1,129 @@
+package httpx
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// SetResponseBodyHandlerCSV performs the request and reads every CSV record of the response body into out
+func SetResponseBodyHandlerCSV(c Client, out *[][]string) ClientFunc {
+	return SetResponseBodyHandlerCSVFunc(c, func(record []string) error {
+		*out = append(*out, record)
+		return nil
+	})
+}
+
+// SetResponseBodyHandlerCSVFunc performs the request and calls fn for every CSV record as it is read.
+//
+// The body is streamed rather than buffered so large exports can be processed in constant memory.
+// Because the body is consumed, the returned response has an empty body.
+// Returning an error from fn stops reading and returns the error.
+func SetResponseBodyHandlerCSVFunc(c Client, fn func(record []string) error) ClientFunc {
+	c = SetHeader(c, "Accept", "text/csv")
+	c = RequireResponseBody(c)
+	return func(req *http.Request) (*http.Response, error) {
+		resp, err := c.Do(req)
+		if err != nil {
+			return resp, err
+		}
+		r := csv.NewReader(resp.Body)
+		for {
+			var record []string
+			record, err = r.Read()
+			if errors.Is(err, io.EOF) {
+				err = nil
+				break
+			}
+			if err != nil {
+				err = fmt.Errorf("could not read csv record: %w", err)
+				break
+			}
+			if err = fn(record); err != nil {
+				break
+			}
+		}
+		closeErr := resp.Body.Close()
+		resp.Body = http.NoBody
+		if err != nil {
+			return resp, err
+		}
+		if closeErr != nil {
+			return resp, errBodyCloser{next: closeErr}
+		}
+		return resp, nil
+	}
+}
+
+// SetResponseBodyHandlerCSVStructs performs the request and decodes each CSV record into an element of the slice
+// of structs pointed to by ptr.
+//
+// The first record is treated as a header row. Columns are matched to fields using the `csv:"column"` struct tag
+// or, without a tag, a case-insensitive match of the field name. Values are converted in the same way as
+// SetResponseHeaderHandler. Unknown columns are ignored.
+func SetResponseBodyHandlerCSVStructs(c Client, ptr any) ClientFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		slice, err := structSlice(ptr)
+		if err != nil {
+			return nil, err
+		}
+		// the header row is read per request so the client can be reused
+		var columns []int
+		fn := func(record []string) error {
+			if columns == nil {
+				columns = csvColumns(slice.Type().Elem(), record)
+				return nil
+			}
+			elem := reflect.New(slice.Type().Elem()).Elem()
+			for i, field := range columns {
+				if field < 0 || i >= len(record) {
+					continue
+				}
+				if err := setField(elem.Field(field), record[i:i+1]); err != nil {
+					return fmt.Errorf("column %d: %w", i+1, err)
+				}
+			}
+			slice.Set(reflect.Append(slice, elem))
+			return nil
+		}
+		return SetResponseBodyHandlerCSVFunc(c, fn).Do(req)
+	}
+}
+
+// structSlice returns the slice of structs pointed to by ptr
+func structSlice(ptr any) (reflect.Value, error) {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Slice ||
+		v.Elem().Type().Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("expected a non-nil pointer to a slice of structs, got %T", ptr)
+	}
+	return v.Elem(), nil
+}
+
+// csvColumns maps each header column to a field index of t, or -1 if no field matches
+func csvColumns(t reflect.Type, header []string) []int {
+	columns := make([]int, len(header))
+	for i, name := range header {
+		columns[i] = -1
+		for j := 0; j < t.NumField(); j++ {
+			f := t.Field(j)
+			if !f.IsExported() {
+				continue
+			}
+			tag, _ := tagName(f, "csv")
+			if _, ok := f.Tag.Lookup("csv"); ok && tag == "" {
+				// explicitly ignored with csv:"-"
+				continue
+			}
+			if tag == name || (tag == "" && strings.EqualFold(f.Name, strings.TrimSpace(name))) {
+				columns[i] = j
+				break
+			}
+		}
+	}
+	return columns
+}
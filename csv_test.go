@@ -0,0 +1,50 @@
+package httpx_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tflyons/httpx"
+)
+
+var csvHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/csv")
+	_, _ = w.Write([]byte("name,age,ignored\ntom,30,x\n\"smith, jane\",41,y\n"))
+})
+
+func TestSetResponseBodyHandlerCSV(t *testing.T) {
+	srv := httptest.NewServer(csvHandler)
+	defer srv.Close()
+
+	var records [][]string
+	var c httpx.Client = srv.Client()
+	c = httpx.SetResponseBodyHandlerCSV(c, &records)
+	c = httpx.SetRequest(c, http.MethodGet, srv.URL)
+	if _, err := c.Do(nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || records[2][0] != "smith, jane" {
+		t.Fatal(records)
+	}
+}
+
+func TestSetResponseBodyHandlerCSVStructs(t *testing.T) {
+	srv := httptest.NewServer(csvHandler)
+	defer srv.Close()
+
+	type person struct {
+		FullName string `csv:"name"`
+		Age      int
+	}
+	var people []person
+	var c httpx.Client = srv.Client()
+	c = httpx.SetResponseBodyHandlerCSVStructs(c, &people)
+	c = httpx.SetRequest(c, http.MethodGet, srv.URL)
+	if _, err := c.Do(nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(people) != 2 || people[1].FullName != "smith, jane" || people[1].Age != 41 {
+		t.Fatal(people)
+	}
+}
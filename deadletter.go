@@ -0,0 +1,181 @@
+package httpx
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// DeadLetter is a request that failed for good, stored by SetDeadLetter for inspection or replay. Replay a file
+// written by a FileDeadLetterSink with the httpxreplay command
+type DeadLetter struct {
+	Time   time.Time   `json:"time"`
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   []byte      `json:"body,omitempty"`
+	// StatusCode of the last response, zero if none was received
+	StatusCode int `json:"status_code,omitempty"`
+	// Attempts is the number of attempts made as reported by an *AttemptsError from SetRetry or SetFailover, or 1
+	Attempts int    `json:"attempts"`
+	Error    string `json:"error,omitempty"`
+}
+
+// DeadLetterSink stores dead letters
+type DeadLetterSink interface {
+	Put(ctx context.Context, dl *DeadLetter) error
+}
+
+// DeadLetterSinkFunc is an adapter to allow the use of ordinary functions as a DeadLetterSink
+type DeadLetterSinkFunc func(ctx context.Context, dl *DeadLetter) error
+
+// Put calls f(ctx, dl)
+func (f DeadLetterSinkFunc) Put(ctx context.Context, dl *DeadLetter) error {
+	return f(ctx, dl)
+}
+
+// ChannelDeadLetterSink sends dead letters on ch without blocking, failing if ch is full
+func ChannelDeadLetterSink(ch chan<- *DeadLetter) DeadLetterSink {
+	return DeadLetterSinkFunc(func(_ context.Context, dl *DeadLetter) error {
+		select {
+		case ch <- dl:
+			return nil
+		default:
+			return fmt.Errorf("dead letter channel is full")
+		}
+	})
+}
+
+// FileDeadLetterSink appends dead letters to a file as JSON lines, see ReadDeadLetters. It is safe for concurrent
+// use
+type FileDeadLetterSink struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// NewFileDeadLetterSink opens or creates the file at path for appending
+func NewFileDeadLetterSink(path string) (*FileDeadLetterSink, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("could not open dead letter file: %w", err)
+	}
+	return &FileDeadLetterSink{f: f}, nil
+}
+
+// Put implements DeadLetterSink
+func (s *FileDeadLetterSink) Put(_ context.Context, dl *DeadLetter) error {
+	b, err := json.Marshal(dl)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err = s.f.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("could not write dead letter: %w", err)
+	}
+	return nil
+}
+
+// Close closes the file
+func (s *FileDeadLetterSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.f.Close()
+}
+
+// ReadDeadLetters decodes the JSON lines written by a FileDeadLetterSink
+func ReadDeadLetters(r io.Reader) ([]*DeadLetter, error) {
+	var out []*DeadLetter
+	dec := json.NewDecoder(bufio.NewReader(r))
+	for {
+		var dl DeadLetter
+		if err := dec.Decode(&dl); err == io.EOF {
+			return out, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("could not decode dead letter %d: %w", len(out)+1, err)
+		}
+		out = append(out, &dl)
+	}
+}
+
+// DeadLetterOptions configures SetDeadLetter
+type DeadLetterOptions struct {
+	// Scrubber masks the headers and body before they are stored, NewDefaultScrubber if nil
+	Scrubber *Scrubber
+	// Failed reports whether a result is dead-lettered. If nil errors, 429 and 5xx responses are
+	Failed func(resp *http.Response, err error) bool
+	// OnSinkError is called when the sink fails, if nil the error is logged with log.Printf
+	OnSinkError func(dl *DeadLetter, err error)
+}
+
+// SetDeadLetter stores requests that failed in sink. It should wrap SetRetry, so that only requests that failed
+// after every retry are stored. The result of the request is returned unchanged whether or not the sink succeeds.
+//
+// Request bodies that cannot be sent again are buffered before the request is sent so that they can be stored
+func SetDeadLetter(c Client, sink DeadLetterSink, opts DeadLetterOptions) ClientFunc {
+	c = nilClientCheck(c)
+	scrubber := opts.Scrubber
+	if scrubber == nil {
+		scrubber = NewDefaultScrubber()
+	}
+	failed := opts.Failed
+	if failed == nil {
+		failed = func(resp *http.Response, err error) bool {
+			return err != nil || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		}
+	}
+	return func(req *http.Request) (*http.Response, error) {
+		if req.GetBody == nil {
+			// readRequestBody sets GetBody
+			if _, err := readRequestBody(req); err != nil {
+				return nil, err
+			}
+		}
+		start := ClockFromContext(req.Context()).Now()
+		resp, err := c.Do(req)
+		if !failed(resp, err) {
+			return resp, err
+		}
+		var body []byte
+		if req.GetBody != nil {
+			if rc, getErr := req.GetBody(); getErr == nil {
+				body, _ = io.ReadAll(rc)
+				rc.Close()
+			}
+		}
+		dl := &DeadLetter{
+			Time:     start,
+			Method:   req.Method,
+			URL:      scrubber.ScrubURL(req.URL),
+			Header:   scrubber.ScrubHeader(req.Header),
+			Body:     scrubber.ScrubBody(req.Header.Get("Content-Type"), body),
+			Attempts: 1,
+		}
+		if resp != nil {
+			dl.StatusCode = resp.StatusCode
+		}
+		if err != nil {
+			dl.Error = scrubber.scrubError(req.URL, err)
+			var attempts *AttemptsError
+			if errors.As(err, &attempts) {
+				dl.Attempts = len(attempts.Attempts)
+			}
+		}
+		if sinkErr := sink.Put(detachedContext{req.Context()}, dl); sinkErr != nil {
+			if opts.OnSinkError != nil {
+				opts.OnSinkError(dl, sinkErr)
+			} else {
+				log.Printf("httpx: could not store dead letter for %s %s: %v", dl.Method, dl.URL, sinkErr)
+			}
+		}
+		return resp, err
+	}
+}
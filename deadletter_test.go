@@ -0,0 +1,95 @@
+package httpx_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx"
+)
+
+func TestSetDeadLetter(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	ch := make(chan *httpx.DeadLetter, 1)
+	c := httpx.SetRetry(srv.Client(), httpx.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond})
+	c = httpx.SetDeadLetter(c, httpx.ChannelDeadLetterSink(ch), httpx.DeadLetterOptions{})
+
+	send := func(path string) error {
+		req, _ := http.NewRequest(http.MethodPut, srv.URL+path+"?token=abc", strings.NewReader(`{"name": "a", "password": "hunter2"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer abc")
+		_, err := c.Do(req)
+		return err
+	}
+	if err := send("/ok"); err != nil || len(ch) != 0 {
+		t.Fatal(err, len(ch))
+	}
+	if err := send("/down"); err != nil {
+		t.Fatal(err)
+	}
+	dl := <-ch
+	if dl.Method != http.MethodPut || dl.StatusCode != http.StatusServiceUnavailable || !strings.Contains(dl.URL, "/down?token=") || strings.Contains(dl.URL, "abc") {
+		t.Fatal(dl)
+	}
+	if dl.Header.Get("Authorization") != httpx.DefaultMask || !bytes.Contains(dl.Body, []byte(`"name":"a"`)) || bytes.Contains(dl.Body, []byte("hunter2")) {
+		t.Fatal(dl.Header, string(dl.Body))
+	}
+}
+
+func TestSetDeadLetter_TransportError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Close()
+
+	ch := make(chan *httpx.DeadLetter, 1)
+	c := httpx.SetDeadLetter(http.DefaultClient, httpx.ChannelDeadLetterSink(ch), httpx.DeadLetterOptions{})
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/a?access_token=t0k3n", nil)
+	if _, err := c.Do(req); err == nil {
+		t.Fatal("expected a connection error")
+	}
+	dl := <-ch
+	if dl.Error == "" || strings.Contains(dl.Error, "t0k3n") || strings.Contains(dl.URL, "t0k3n") {
+		t.Fatal("the URL and error of a dead letter should be scrubbed", dl.URL, dl.Error)
+	}
+}
+
+func TestSetDeadLetter_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "failed.jsonl")
+	sink, err := httpx.NewFileDeadLetterSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failing := httpx.ClientFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	})
+	c := httpx.SetDeadLetter(failing, sink, httpx.DeadLetterOptions{})
+	for i := 0; i < 2; i++ {
+		if _, err = httpx.SetRequest(c, http.MethodGet, "https://example.com/things").Do(nil); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+	if err = sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	dls, err := httpx.ReadDeadLetters(f)
+	if err != nil || len(dls) != 2 || dls[1].Error != "connection refused" || dls[1].Attempts != 1 || dls[1].URL != "https://example.com/things" {
+		t.Fatal(err, dls)
+	}
+}
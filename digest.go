@@ -0,0 +1,180 @@
+package httpx
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+)
+
+// ErrIntegrity is matched by every *IntegrityError using errors.Is
+var ErrIntegrity = fmt.Errorf("body does not match digest")
+
+// IntegrityError is returned when a body does not match a digest header
+type IntegrityError struct {
+	Header    string
+	Algorithm string
+	Expected  string
+	Actual    string
+}
+
+// Error implements the error interface
+func (e *IntegrityError) Error() string {
+	return fmt.Sprintf("%s: %s %s expected %s got %s", ErrIntegrity, e.Header, e.Algorithm, e.Expected, e.Actual)
+}
+
+// Is matches ErrIntegrity
+func (e *IntegrityError) Is(target error) bool {
+	return target == ErrIntegrity
+}
+
+// ErrDigestDecompressed is returned by VerifyResponseDigest for a response with digest headers whose body was
+// decompressed by the transport, as the digests cover the compressed bytes
+var ErrDigestDecompressed = fmt.Errorf("digest of a body decompressed by the transport cannot be verified")
+
+// digestAlgorithms are keyed by their lower case names as registered for the Digest and Repr-Digest headers
+var digestAlgorithms = map[string]func() hash.Hash{
+	"md5":     md5.New,
+	"sha":     sha1.New,
+	"sha-256": sha256.New,
+	"sha-512": sha512.New,
+}
+
+// VerifyResponseDigest returns an *IntegrityError if the response body does not match the Content-MD5, Digest
+// (RFC 3230), Content-Digest or Repr-Digest (RFC 9530) response headers.
+//
+// Every supported digest present is checked; unsupported algorithms are ignored and a response without any
+// digest headers is accepted. The body is buffered so that it can still be read afterwards.
+//
+// The digests cover the body as sent, so a response that http.Transport transparently decompressed, because the
+// request had no Accept-Encoding header, fails with ErrDigestDecompressed. Set Accept-Encoding on the request to
+// verify compressed responses and decompress them afterwards.
+func VerifyResponseDigest(c Client) ClientFunc {
+	c = nilClientCheck(c)
+	return func(req *http.Request) (*http.Response, error) {
+		resp, err := c.Do(req)
+		if err != nil {
+			return resp, err
+		}
+		digests := responseDigests(resp.Header)
+		if len(digests) == 0 {
+			return resp, nil
+		}
+		if resp.Uncompressed {
+			return resp, ErrDigestDecompressed
+		}
+		b, err := readAndRestore(&resp.Body)
+		if err != nil {
+			return resp, err
+		}
+		for _, d := range digests {
+			h := digestAlgorithms[d.algorithm]()
+			h.Write(b)
+			if actual := h.Sum(nil); !bytes.Equal(actual, d.sum) {
+				return resp, &IntegrityError{
+					Header:    d.header,
+					Algorithm: d.algorithm,
+					Expected:  base64.StdEncoding.EncodeToString(d.sum),
+					Actual:    base64.StdEncoding.EncodeToString(actual),
+				}
+			}
+		}
+		return resp, nil
+	}
+}
+
+type digestValue struct {
+	header    string
+	algorithm string
+	sum       []byte
+}
+
+// responseDigests parses every supported digest from the headers, skipping values that cannot be decoded
+func responseDigests(h http.Header) []digestValue {
+	var digests []digestValue
+	add := func(header, algorithm, encoded string) {
+		algorithm = strings.ToLower(strings.TrimSpace(algorithm))
+		if _, ok := digestAlgorithms[algorithm]; !ok {
+			return
+		}
+		sum, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return
+		}
+		digests = append(digests, digestValue{header: header, algorithm: algorithm, sum: sum})
+	}
+	if v := h.Get("Content-MD5"); v != "" {
+		add("Content-MD5", "md5", v)
+	}
+	for _, v := range h.Values("Digest") {
+		for _, item := range strings.Split(v, ",") {
+			if alg, encoded, ok := strings.Cut(item, "="); ok {
+				add("Digest", alg, encoded)
+			}
+		}
+	}
+	for _, header := range []string{"Content-Digest", "Repr-Digest"} {
+		for _, v := range h.Values(header) {
+			for _, item := range strings.Split(v, ",") {
+				alg, encoded, ok := strings.Cut(item, "=")
+				encoded = strings.TrimSpace(encoded)
+				// structured field byte sequences are wrapped in colons
+				if ok && len(encoded) > 1 && strings.HasPrefix(encoded, ":") && strings.HasSuffix(encoded, ":") {
+					add(header, alg, encoded[1:len(encoded)-1])
+				}
+			}
+		}
+	}
+	return digests
+}
+
+// SetContentDigest computes the Content-Digest header (RFC 9530) for the request body using the given
+// algorithms, "sha-256" if none are given. Only "sha-256" and "sha-512" are accepted.
+func SetContentDigest(c Client, algorithms ...string) ClientFunc {
+	return setRequestDigest(c, "Content-Digest", algorithms, func(alg string, sum []byte) string {
+		return alg + "=:" + base64.StdEncoding.EncodeToString(sum) + ":"
+	})
+}
+
+// SetDigest computes the legacy Digest header (RFC 3230) for the request body using the given algorithms,
+// "sha-256" if none are given. Only "sha-256" and "sha-512" are accepted.
+func SetDigest(c Client, algorithms ...string) ClientFunc {
+	return setRequestDigest(c, "Digest", algorithms, func(alg string, sum []byte) string {
+		return strings.ToUpper(alg) + "=" + base64.StdEncoding.EncodeToString(sum)
+	})
+}
+
+// setRequestDigest hashes the request body and sets the header to the formatted digests
+func setRequestDigest(c Client, header string, algorithms []string, format func(alg string, sum []byte) string) ClientFunc {
+	c = nilClientCheck(c)
+	if len(algorithms) == 0 {
+		algorithms = []string{"sha-256"}
+	}
+	return func(req *http.Request) (*http.Response, error) {
+		b, err := readAndRestore(&req.Body)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]string, 0, len(algorithms))
+		for _, alg := range algorithms {
+			alg = strings.ToLower(alg)
+			if alg != "sha-256" && alg != "sha-512" {
+				return nil, fmt.Errorf("unsupported %s algorithm %q", header, alg)
+			}
+			h := digestAlgorithms[alg]()
+			h.Write(b)
+			values = append(values, format(alg, h.Sum(nil)))
+		}
+		if req.Header == nil {
+			req.Header = make(http.Header)
+		}
+		req.Header.Set(header, strings.Join(values, ", "))
+		return c.Do(req)
+	}
+}
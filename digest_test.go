@@ -0,0 +1,107 @@
+package httpx_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tflyons/httpx"
+)
+
+func TestVerifyResponseDigest(t *testing.T) {
+	body := []byte("hello world")
+	sum := sha256.Sum256(body)
+	good := base64.StdEncoding.EncodeToString(sum[:])
+	bad := base64.StdEncoding.EncodeToString(make([]byte, sha256.Size))
+
+	tests := []struct {
+		header, value string
+		wantErr       bool
+	}{
+		{"", "", false},
+		{"Repr-Digest", "sha-256=:" + good + ":", false},
+		{"Repr-Digest", "sha-256=:" + bad + ":", true},
+		{"Content-Digest", "unknown=:abc:, sha-256=:" + bad + ":", true},
+		{"Digest", "SHA-256=" + good, false},
+		{"Digest", "SHA-256=" + bad, true},
+		{"Content-MD5", "XrY7u+Ae7tCTyyK7j1rNww==", false},
+		{"Content-MD5", "AAAAAAAAAAAAAAAAAAAAAA==", true},
+	}
+	for _, tt := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if tt.header != "" {
+				w.Header().Set(tt.header, tt.value)
+			}
+			_, _ = w.Write(body)
+		}))
+
+		var got string
+		var c httpx.Client = srv.Client()
+		c = httpx.VerifyResponseDigest(c)
+		c = httpx.SetResponseBodyString(c, &got)
+		c = httpx.SetRequest(c, http.MethodGet, srv.URL)
+		_, err := c.Do(nil)
+		srv.Close()
+		if tt.wantErr != errors.Is(err, httpx.ErrIntegrity) {
+			t.Fatal(tt.header, tt.value, err)
+		}
+		if !tt.wantErr && got != string(body) {
+			t.Fatal(got)
+		}
+	}
+}
+
+func TestSetContentDigest(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// echo the request digests back so the response can be verified against them
+		w.Header().Set("Content-Digest", r.Header.Get("Content-Digest"))
+		w.Header().Set("Digest", r.Header.Get("Digest"))
+		_, _ = io.Copy(w, r.Body)
+	}))
+	defer srv.Close()
+
+	var c httpx.Client = srv.Client()
+	c = httpx.VerifyResponseDigest(c)
+	c = httpx.SetContentDigest(c, "sha-256", "sha-512")
+	c = httpx.SetDigest(c)
+	c = httpx.SetRequestBody(c, nil, []byte(`{"amount":100}`))
+	c = httpx.SetRequest(c, http.MethodPost, srv.URL)
+	resp, err := c.Do(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Digest"), "sha-256=:") || !strings.HasPrefix(resp.Header.Get("Digest"), "SHA-256=") {
+		t.Fatal(resp.Header)
+	}
+}
+
+func TestVerifyResponseDigest_Decompressed(t *testing.T) {
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	_, _ = zw.Write([]byte("hello world"))
+	zw.Close()
+	sum := sha256.Sum256(compressed.Bytes())
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("Content-Digest", "sha-256=:"+base64.StdEncoding.EncodeToString(sum[:])+":")
+		_, _ = w.Write(compressed.Bytes())
+	}))
+	defer srv.Close()
+
+	c := httpx.VerifyResponseDigest(srv.Client())
+	if _, err := httpx.SetRequest(c, http.MethodGet, srv.URL).Do(nil); !errors.Is(err, httpx.ErrDigestDecompressed) {
+		t.Fatal("a transparently decompressed body should not be checked against the digest", err)
+	}
+	// with an explicit Accept-Encoding the compressed body is verified
+	c = httpx.SetHeader(c, "Accept-Encoding", "gzip")
+	if _, err := httpx.SetRequest(c, http.MethodGet, srv.URL).Do(nil); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,441 @@
+package httpx
+
+import (
+	"bufio"
+	"container/list"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DiskCacheStore is a CacheStore keeping each value in a file under a directory, so that the cache outlives the
+// process, for example between runs of a command line tool. The least recently used values are removed once the
+// total size passes a limit. It is safe for concurrent use but the directory must not be shared by processes
+// running at the same time.
+//
+// Changes are appended to an index log which is compacted once it holds many more records than there are values.
+// Value files are written and read outside of the lock of the store.
+type DiskCacheStore struct {
+	dir      string
+	maxBytes int64
+
+	mu sync.Mutex
+	// lru holds *diskCacheItem, the most recently used at the front
+	lru   *list.List
+	index map[string]*list.Element
+	size  int64
+	// log is the index log opened for appending and records the number of records in it
+	log     *os.File
+	records int
+}
+
+// diskCacheItem is the index record of a stored value, a record without File removes the key
+type diskCacheItem struct {
+	Key     string    `json:"key"`
+	File    string    `json:"file,omitempty"`
+	Size    int64     `json:"size,omitempty"`
+	Expires time.Time `json:"expires"`
+}
+
+const (
+	diskCacheIndex = "index.log"
+	// diskCacheCompactMin is the number of records the index log may hold beyond twice the number of values before
+	// it is compacted
+	diskCacheCompactMin = 256
+)
+
+// NewDiskCacheStore opens the store in dir, creating it if needed, holding at most maxBytes of values.
+// Values larger than maxBytes are not stored and expired values are removed when they are next read. Value files
+// left without an index record and temporary files left by a crash are removed, other files in dir are left alone.
+// Call Close to save the recent use of values for the next run
+func NewDiskCacheStore(dir string, maxBytes int64) (*DiskCacheStore, error) {
+	if maxBytes <= 0 {
+		return nil, fmt.Errorf("cache size limit must be positive")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("could not create cache directory: %w", err)
+	}
+	s := &DiskCacheStore{dir: dir, maxBytes: maxBytes, lru: list.New(), index: make(map[string]*list.Element)}
+	if err := s.replay(); err != nil {
+		return nil, err
+	}
+	// drop records whose file is gone and value files no record points at, leaving any other file alone
+	files := make(map[string]bool)
+	for key, e := range s.index {
+		item := e.Value.(*diskCacheItem)
+		if info, err := os.Stat(filepath.Join(dir, item.File)); err != nil || info.Size() != item.Size {
+			s.lru.Remove(e)
+			delete(s.index, key)
+			continue
+		}
+		files[item.File] = true
+		s.size += item.Size
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("could not read cache directory: %w", err)
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		if strings.HasPrefix(name, ".tmp-") || isDiskCacheFile(name) && !files[name] {
+			_ = os.Remove(filepath.Join(dir, name))
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	garbage := s.evict()
+	err = s.compact()
+	removeFiles(garbage)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// replay reads the index log into the index, a record that cannot be decoded, such as one torn by a crash, is
+// skipped
+func (s *DiskCacheStore) replay() error {
+	f, err := os.Open(filepath.Join(s.dir, diskCacheIndex))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not read cache index: %w", err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var item diskCacheItem
+		if json.Unmarshal(scanner.Bytes(), &item) != nil {
+			continue
+		}
+		if e, ok := s.index[item.Key]; ok {
+			s.lru.Remove(e)
+			delete(s.index, item.Key)
+		}
+		if item.File != "" {
+			s.index[item.Key] = s.lru.PushFront(&item)
+		}
+	}
+	return nil
+}
+
+// isDiskCacheFile reports whether name is that of a value file, 64 lowercase hex characters
+func isDiskCacheFile(name string) bool {
+	if len(name) != 2*sha256.Size {
+		return false
+	}
+	for _, r := range name {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// Get implements CacheStore
+func (s *DiskCacheStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	now := ClockFromContext(ctx).Now()
+	s.mu.Lock()
+	item, garbage := s.lookup(key, now)
+	s.mu.Unlock()
+	removeFiles(garbage)
+	if item == nil {
+		return nil, false, nil
+	}
+	b, err := os.ReadFile(filepath.Join(s.dir, item.File))
+	if errors.Is(err, fs.ErrNotExist) {
+		// the value was replaced or removed since the lookup
+		s.mu.Lock()
+		if e, ok := s.index[key]; ok && e.Value.(*diskCacheItem).File == item.File {
+			s.detach(e)
+		}
+		s.mu.Unlock()
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("could not read cache file: %w", err)
+	}
+	return b, true, nil
+}
+
+// Set implements CacheStore
+func (s *DiskCacheStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	now := ClockFromContext(ctx).Now()
+	if int64(len(value)) > s.maxBytes {
+		return s.Delete(ctx, key)
+	}
+	item, err := s.write(key, value, ttl, now)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	garbage, err := s.put(item)
+	s.mu.Unlock()
+	removeFiles(garbage)
+	return err
+}
+
+// SetIfAbsent implements CacheStore
+func (s *DiskCacheStore) SetIfAbsent(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	now := ClockFromContext(ctx).Now()
+	s.mu.Lock()
+	found, garbage := s.lookup(key, now)
+	s.mu.Unlock()
+	removeFiles(garbage)
+	if found != nil {
+		return false, nil
+	}
+	if int64(len(value)) > s.maxBytes {
+		return false, nil
+	}
+	item, err := s.write(key, value, ttl, now)
+	if err != nil {
+		return false, err
+	}
+	s.mu.Lock()
+	// another call may have set the key while the file was written
+	if found, garbage = s.lookup(key, now); found == nil {
+		garbage, err = s.put(item)
+	} else {
+		garbage = append(garbage, filepath.Join(s.dir, item.File))
+	}
+	s.mu.Unlock()
+	removeFiles(garbage)
+	return found == nil, err
+}
+
+// write stores value in a new file and returns its index record
+func (s *DiskCacheStore) write(key string, value []byte, ttl time.Duration, now time.Time) (*diskCacheItem, error) {
+	name := make([]byte, sha256.Size)
+	if _, err := rand.Read(name); err != nil {
+		return nil, err
+	}
+	item := &diskCacheItem{Key: key, File: hex.EncodeToString(name), Size: int64(len(value))}
+	if ttl > 0 {
+		item.Expires = now.Add(ttl)
+	}
+	path := filepath.Join(s.dir, item.File)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("could not write cache file: %w", err)
+	}
+	if _, err = f.Write(value); err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return nil, fmt.Errorf("could not write cache file: %w", err)
+	}
+	return item, nil
+}
+
+// lookup returns the index record of key if its value has not expired and marks it as used. The files of expired
+// values are returned for removal once the lock is released
+func (s *DiskCacheStore) lookup(key string, now time.Time) (*diskCacheItem, []string) {
+	e, ok := s.index[key]
+	if !ok {
+		return nil, nil
+	}
+	item := e.Value.(*diskCacheItem)
+	if !item.Expires.IsZero() && !now.Before(item.Expires) {
+		// the record holds the expiry, so it is dropped on the next open without logging the removal
+		return nil, []string{s.detach(e)}
+	}
+	s.lru.MoveToFront(e)
+	return item, nil
+}
+
+// put adds item to the index and logs it, evicting values over the limit. The files of replaced and evicted values
+// are returned for removal once the lock is released
+func (s *DiskCacheStore) put(item *diskCacheItem) ([]string, error) {
+	var garbage []string
+	if e, ok := s.index[item.Key]; ok {
+		garbage = append(garbage, s.detach(e))
+	}
+	s.index[item.Key] = s.lru.PushFront(item)
+	s.size += item.Size
+	err := s.append(item)
+	garbage = append(garbage, s.evict()...)
+	if err == nil && s.records > 2*len(s.index)+diskCacheCompactMin {
+		err = s.compact()
+	}
+	return garbage, err
+}
+
+// Delete implements CacheStore
+func (s *DiskCacheStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	e, ok := s.index[key]
+	if !ok {
+		s.mu.Unlock()
+		return nil
+	}
+	file := s.detach(e)
+	err := s.append(&diskCacheItem{Key: key})
+	s.mu.Unlock()
+	removeFiles([]string{file})
+	return err
+}
+
+// DeletePrefix implements CacheStore
+func (s *DiskCacheStore) DeletePrefix(_ context.Context, prefix string) error {
+	s.mu.Lock()
+	var garbage []string
+	var err error
+	for key, e := range s.index {
+		if strings.HasPrefix(key, prefix) {
+			garbage = append(garbage, s.detach(e))
+			if appendErr := s.append(&diskCacheItem{Key: key}); err == nil {
+				err = appendErr
+			}
+		}
+	}
+	s.mu.Unlock()
+	removeFiles(garbage)
+	return err
+}
+
+// Size returns the total size of the stored values in bytes
+func (s *DiskCacheStore) Size() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size
+}
+
+// Close compacts the index log, saving the recent use of values which is not logged, and flushes it to disk. The
+// store may still be used afterwards
+func (s *DiskCacheStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.compact()
+}
+
+// detach removes the record of e from the index and returns the path of its file
+func (s *DiskCacheStore) detach(e *list.Element) string {
+	item := s.lru.Remove(e).(*diskCacheItem)
+	delete(s.index, item.Key)
+	s.size -= item.Size
+	return filepath.Join(s.dir, item.File)
+}
+
+// evict removes the least recently used values until the store is within its limit, returning their files
+func (s *DiskCacheStore) evict() []string {
+	var garbage []string
+	for s.size > s.maxBytes {
+		e := s.lru.Back()
+		key := e.Value.(*diskCacheItem).Key
+		garbage = append(garbage, s.detach(e))
+		// a failed record only means the value is dropped on the next open because its file is gone
+		_ = s.append(&diskCacheItem{Key: key})
+	}
+	return garbage
+}
+
+// append adds a record to the index log. Records are not flushed to disk, a crash may lose the latest which at
+// worst drops their values on the next open
+func (s *DiskCacheStore) append(item *diskCacheItem) error {
+	if s.log == nil {
+		return fmt.Errorf("could not write cache index: index is not open")
+	}
+	b, err := json.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("could not encode cache index: %w", err)
+	}
+	if _, err = s.log.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("could not write cache index: %w", err)
+	}
+	s.records++
+	return nil
+}
+
+// compact replaces the index log with one record per value, least recently used first, and reopens it for
+// appending
+func (s *DiskCacheStore) compact() error {
+	var b []byte
+	for e := s.lru.Back(); e != nil; e = e.Prev() {
+		line, err := json.Marshal(e.Value)
+		if err != nil {
+			return fmt.Errorf("could not encode cache index: %w", err)
+		}
+		b = append(append(b, line...), '\n')
+	}
+	if s.log != nil {
+		// an open file cannot be replaced on windows
+		_ = s.log.Close()
+		s.log = nil
+	}
+	path := filepath.Join(s.dir, diskCacheIndex)
+	err := writeFileAtomic(path, b)
+	if s.log, _ = os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600); s.log == nil && err == nil {
+		err = fmt.Errorf("index could not be reopened")
+	}
+	if err != nil {
+		return fmt.Errorf("could not write cache index: %w", err)
+	}
+	s.records = len(s.index)
+	return nil
+}
+
+// removeFiles deletes the files of removed values
+func removeFiles(paths []string) {
+	for _, path := range paths {
+		_ = os.Remove(path)
+	}
+}
+
+// writeFileAtomic replaces the file at path so that readers see either the old or the new content, even after a
+// crash
+func writeFileAtomic(path string, b []byte) error {
+	dir := filepath.Dir(path)
+	f, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err = f.Write(b); err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err = os.Rename(f.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// syncDir flushes the entries of dir so that a file created or renamed in it survives a crash
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		// directories cannot be opened for syncing, renames are durable once they return
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,164 @@
+package httpx_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/tflyons/httpx"
+)
+
+func TestDiskCacheStore(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s, err := httpx.NewDiskCacheStore(dir, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"a", "b", "c"} {
+		if err = s.Set(ctx, key, []byte(key+key+key+key), 0); err != nil {
+			t.Fatal(err)
+		}
+		if key == "b" {
+			// a is now used more recently than b
+			if _, ok, _ := s.Get(ctx, "a"); !ok {
+				t.Fatal("expected a")
+			}
+		}
+	}
+	if _, ok, _ := s.Get(ctx, "b"); ok || s.Size() != 8 {
+		t.Fatal("expected the least recently used value to be evicted", s.Size())
+	}
+	// values larger than the limit are not stored
+	if err = s.Set(ctx, "big", bytes.Repeat([]byte("x"), 11), 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := s.Get(ctx, "big"); ok {
+		t.Fatal("expected big to be skipped")
+	}
+	if err = s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the values survive reopening
+	s, err = httpx.NewDiskCacheStore(dir, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok, err := s.Get(ctx, "c"); err != nil || string(v) != "cccc" {
+		t.Fatal(v, ok, err)
+	}
+	if err = s.DeletePrefix(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := s.Get(ctx, "a"); ok || s.Size() != 4 {
+		t.Fatal("expected a to be deleted", s.Size())
+	}
+}
+
+func TestDiskCacheStore_ForeignFiles(t *testing.T) {
+	dir := t.TempDir()
+	orphan := filepath.Join(dir, strings.Repeat("ab", 32))
+	foreign := filepath.Join(dir, "notes.txt")
+	for _, name := range []string{orphan, foreign} {
+		if err := os.WriteFile(name, []byte("data"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := httpx.NewDiskCacheStore(dir, 10); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(orphan); !os.IsNotExist(err) {
+		t.Fatal("expected the value file without an index record to be removed", err)
+	}
+	if b, err := os.ReadFile(foreign); err != nil || string(b) != "data" {
+		t.Fatal("files not named like values should be left alone", string(b), err)
+	}
+}
+
+func TestDiskCacheStore_ReopenWithoutClose(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s, err := httpx.NewDiskCacheStore(dir, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// enough changes to compact the index log on the way
+	for i := 0; i < 1000; i++ {
+		if err = s.Set(ctx, "key", []byte(strconv.Itoa(i)), 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, key := range []string{"a", "b"} {
+		if err = s.Set(ctx, key, []byte(key), 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = s.Delete(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	tmp := filepath.Join(dir, ".tmp-123")
+	if err = os.WriteFile(tmp, []byte("partial"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = httpx.NewDiskCacheStore(dir, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _, _ := s.Get(ctx, "key"); string(v) != "999" {
+		t.Fatal("expected the last value", string(v))
+	}
+	if v, _, _ := s.Get(ctx, "b"); string(v) != "b" {
+		t.Fatal("expected b", string(v))
+	}
+	if _, ok, _ := s.Get(ctx, "a"); ok || s.Size() != 4 {
+		t.Fatal("expected a to stay deleted", s.Size())
+	}
+	if _, err = os.Stat(tmp); !os.IsNotExist(err) {
+		t.Fatal("expected the temporary file to be removed", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 3 {
+		t.Fatal("expected the index and two value files", entries, err)
+	}
+}
+
+func TestDiskCacheStore_SetCache(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		_, _ = w.Write([]byte("cached"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	for run := 0; run < 2; run++ {
+		store, err := httpx.NewDiskCacheStore(dir, 1<<20)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body string
+		c := httpx.SetResponseBodyString(httpx.SetCache(srv.Client(), httpx.NewCache(store)), &body)
+		if _, err = httpx.SetRequest(c, http.MethodGet, srv.URL).Do(nil); err != nil {
+			t.Fatal(err)
+		}
+		if body != "cached" {
+			t.Fatal(body)
+		}
+		if err = store.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 1 {
+		t.Fatal("expected the second run to use the cache", calls)
+	}
+}
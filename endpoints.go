@@ -0,0 +1,241 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Endpoints is a set of base URLs serving the same API, such as one per region or data center, with passive health
+// tracking. An endpoint is ejected for EjectFor after EjectAfter consecutive failures and is skipped by the
+// decorators that select endpoints while ejected. It is safe for concurrent use
+type Endpoints struct {
+	// EjectAfter is the number of consecutive failures that eject an endpoint, 3 if zero
+	EjectAfter int
+	// EjectFor is how long an endpoint stays ejected, 30 seconds if zero
+	EjectFor time.Duration
+
+	mu        sync.Mutex
+	endpoints []*endpoint
+}
+
+type endpoint struct {
+	base         *url.URL
+	weight       int
+	failures     int
+	ejectedUntil time.Time
+	// current is the running weight of smooth weighted round robin
+	current int
+}
+
+// NewEndpoints returns the endpoints at the given absolute base URLs, in order of preference
+func NewEndpoints(baseURLs ...string) (*Endpoints, error) {
+	if len(baseURLs) == 0 {
+		return nil, fmt.Errorf("no endpoints given")
+	}
+	e := &Endpoints{}
+	for _, s := range baseURLs {
+		u, err := url.Parse(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid endpoint %q: %w", s, err)
+		}
+		if !u.IsAbs() || u.Host == "" {
+			return nil, fmt.Errorf("invalid endpoint %q: must be an absolute url", s)
+		}
+		e.endpoints = append(e.endpoints, &endpoint{base: u, weight: 1})
+	}
+	return e, nil
+}
+
+// SetWeight sets the share of traffic SetLoadBalancer sends to the endpoint at baseURL relative to the others,
+// for example 95 for a stable endpoint and 5 for a canary. Every endpoint starts with a weight of 1 and an endpoint
+// with a weight of 0 only receives requests if no other endpoint is available. Weights can be changed at any time
+func (e *Endpoints) SetWeight(baseURL string, weight int) error {
+	if weight < 0 {
+		return fmt.Errorf("invalid weight %d for endpoint %q", weight, baseURL)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, ep := range e.endpoints {
+		if ep.base.String() == baseURL {
+			ep.weight, ep.current = weight, 0
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown endpoint %q", baseURL)
+}
+
+// Healthy returns the base URLs of the endpoints that are not ejected at now
+func (e *Endpoints) Healthy(now time.Time) []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var out []string
+	for _, ep := range e.endpoints {
+		if !now.Before(ep.ejectedUntil) {
+			out = append(out, ep.base.String())
+		}
+	}
+	return out
+}
+
+// candidates returns the endpoints to try in order of preference: the healthy ones with a weight, or if there are
+// none the healthy ones, or all of them if every endpoint is ejected so that requests are still attempted. The
+// weights of the candidates are returned as they were read under the lock, as SetWeight may change them at any time
+func (e *Endpoints) candidates(now time.Time) ([]*endpoint, []int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var weighted, healthy []*endpoint
+	for _, ep := range e.endpoints {
+		if !now.Before(ep.ejectedUntil) {
+			healthy = append(healthy, ep)
+			if ep.weight > 0 {
+				weighted = append(weighted, ep)
+			}
+		}
+	}
+	out := weighted
+	switch {
+	case len(weighted) > 0:
+	case len(healthy) > 0:
+		out = healthy
+	default:
+		out = append([]*endpoint(nil), e.endpoints...)
+	}
+	weights := make([]int, len(out))
+	for i, ep := range out {
+		weights[i] = ep.weight
+	}
+	return out, weights
+}
+
+// next returns one of the candidates by smooth weighted round robin, which interleaves endpoints in proportion to
+// their weights
+func (e *Endpoints) next(candidates []*endpoint) *endpoint {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var best *endpoint
+	total := 0
+	for _, ep := range candidates {
+		w := ep.weight
+		if w == 0 {
+			w = 1
+		}
+		ep.current += w
+		total += w
+		if best == nil || ep.current > best.current {
+			best = ep
+		}
+	}
+	best.current -= total
+	return best
+}
+
+// report records the result of a request sent to ep
+func (e *Endpoints) report(ep *endpoint, failed bool, now time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !failed {
+		ep.failures = 0
+		return
+	}
+	ejectAfter, ejectFor := e.EjectAfter, e.EjectFor
+	if ejectAfter <= 0 {
+		ejectAfter = 3
+	}
+	if ejectFor <= 0 {
+		ejectFor = 30 * time.Second
+	}
+	if ep.failures++; ep.failures >= ejectAfter {
+		ep.failures = 0
+		ep.ejectedUntil = now.Add(ejectFor)
+	}
+}
+
+// endpointFailed reports whether a result counts against the health of an endpoint
+func endpointFailed(ctx context.Context, resp *http.Response, err error) bool {
+	if resp != nil {
+		// an error that comes with a response, such as a StatusError from a decorator, is decided on the status
+		return resp.StatusCode >= 500
+	}
+	if err != nil {
+		// the caller giving up says nothing about the endpoint
+		return ctx.Err() == nil || !errors.Is(err, ctx.Err())
+	}
+	return false
+}
+
+// withEndpoint returns a copy of req sent to ep: the scheme and host of the url are replaced and the base path
+// of the endpoint is prefixed to the request path
+func withEndpoint(req *http.Request, ep *endpoint) *http.Request {
+	req = req.Clone(req.Context())
+	u := *req.URL
+	u.Scheme, u.Host, u.User = ep.base.Scheme, ep.base.Host, ep.base.User
+	if p := ep.base.EscapedPath(); p != "" && p != "/" {
+		u.RawPath = strings.TrimRight(p, "/") + u.EscapedPath()
+		u.Path = strings.TrimRight(ep.base.Path, "/") + u.Path
+	}
+	req.URL = &u
+	req.Host = ""
+	return req
+}
+
+// SetFailover sends requests to the first healthy endpoint and, when a request fails with a transport error or a
+// 5xx response, sends it again to the next healthy endpoint until one succeeds or every endpoint has been tried.
+//
+// The scheme and host of the request are replaced by those of the endpoint, so requests may be built against any
+// of them. Results update the health of the endpoints. Only idempotent requests whose body can be sent again are
+// failed over, others are sent to the first healthy endpoint only, as are requests with the NoRetry override.
+// When the last endpoint tried fails with an error an *AttemptsError holds the result from every endpoint
+func SetFailover(c Client, e *Endpoints) ClientFunc {
+	c = nilClientCheck(c)
+	methods := make(map[string]bool)
+	for _, m := range idempotentMethods {
+		methods[m] = true
+	}
+	return func(req *http.Request) (*http.Response, error) {
+		ctx := req.Context()
+		clock := ClockFromContext(ctx)
+		candidates, _ := e.candidates(clock.Now())
+		if !methods[req.Method] || (req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) ||
+			OverridesFromContext(ctx).NoRetry {
+			candidates = candidates[:1]
+		}
+		var resp *http.Response
+		var err error
+		var attempts []AttemptResult
+		for i, ep := range candidates {
+			if i > 0 {
+				if resp != nil && resp.Body != nil {
+					_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+					resp.Body.Close()
+				}
+				if req.GetBody != nil {
+					body, err := req.GetBody()
+					if err != nil {
+						return nil, fmt.Errorf("could not reset request body for failover: %w", err)
+					}
+					req.Body = body
+				}
+			}
+			start := clock.Now()
+			epReq := withEndpoint(req, ep)
+			resp, err = c.Do(epReq)
+			attempts = append(attempts, newAttemptResult(i+1, epReq, resp, err, start, clock.Now()))
+			failed := endpointFailed(ctx, resp, err)
+			e.report(ep, failed, clock.Now())
+			if !failed || ctx.Err() != nil {
+				break
+			}
+		}
+		if err != nil && len(attempts) > 1 {
+			return resp, &AttemptsError{Attempts: attempts}
+		}
+		return resp, err
+	}
+}
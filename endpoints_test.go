@@ -0,0 +1,108 @@
+package httpx_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/httpxtest"
+)
+
+func TestSetFailover(t *testing.T) {
+	var primaryCalls int
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		primaryCalls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer primary.Close()
+	secondary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.URL.Path + "?" + r.URL.RawQuery))
+	}))
+	defer secondary.Close()
+
+	clock := httpxtest.NewClock(time.Now())
+	endpoints, err := httpx.NewEndpoints(primary.URL, secondary.URL+"/v2/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpoints.EjectAfter = 2
+	endpoints.EjectFor = time.Minute
+
+	var body string
+	var c httpx.Client = httpx.SetFailover(http.DefaultClient, endpoints)
+	c = httpx.SetResponseBodyString(c, &body)
+	c = httpx.SetClock(c, clock)
+	get := httpx.SetRequest(c, http.MethodGet, primary.URL+"/things?id=1")
+
+	for i := 0; i < 3; i++ {
+		resp, err := get.Do(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK || body != "/v2/things?id=1" {
+			t.Fatal(resp.StatusCode, body)
+		}
+	}
+	// the primary is skipped once ejected
+	if primaryCalls != 2 {
+		t.Fatal(primaryCalls)
+	}
+	if got := endpoints.Healthy(clock.Now()); !reflect.DeepEqual(got, []string{secondary.URL + "/v2/"}) {
+		t.Fatal(got)
+	}
+	clock.Advance(time.Minute)
+	if _, err := get.Do(nil); err != nil || primaryCalls != 3 {
+		t.Fatal(err, primaryCalls)
+	}
+
+	// requests that are not idempotent are not failed over
+	resp, err := httpx.SetRequest(c, http.MethodPost, primary.URL).Do(nil)
+	if err != nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatal(resp, err)
+	}
+}
+
+func TestSetFailover_ClientErrors(t *testing.T) {
+	var calls int
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	})
+	var urls []string
+	for i := 0; i < 3; i++ {
+		srv := httptest.NewServer(handler)
+		defer srv.Close()
+		urls = append(urls, srv.URL)
+	}
+	endpoints, err := httpx.NewEndpoints(urls...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := httpx.SetFailover(httpx.RequireResponseStatus(http.DefaultClient, http.StatusOK), endpoints)
+
+	for i := 0; i < 3; i++ {
+		if _, err := httpx.SetRequest(c, http.MethodGet, urls[0]+"/missing").Do(nil); err == nil {
+			t.Fatal("expected a status error")
+		}
+	}
+	if calls != 3 || len(endpoints.Healthy(time.Now())) != 3 {
+		t.Fatal("a 4xx response should neither fail over nor eject endpoints", calls, endpoints.Healthy(time.Now()))
+	}
+
+	calls = 0
+	ctx := httpx.WithOverride(context.Background(), httpx.NoRetry())
+	if _, err := httpx.SetRequestWithContext(ctx, c, http.MethodGet, urls[0]+"/down").Do(nil); err == nil {
+		t.Fatal("expected a status error")
+	}
+	if calls != 1 {
+		t.Fatal("the NoRetry override should disable failover", calls)
+	}
+}
@@ -0,0 +1,172 @@
+package httpx
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// EndpointStats collects a latency histogram per endpoint, such as "GET /users/{id}", for services without
+// tracing, see SetEndpointStats. The zero value is ready to use
+type EndpointStats struct {
+	// Normalize names the endpoint of a request, NormalizeEndpoint if nil.
+	// It must map the requests to a bounded number of names, for example by replacing ids in the path
+	Normalize func(*http.Request) string
+
+	mu        sync.Mutex
+	endpoints map[string]*endpointLatency
+}
+
+type endpointLatency struct {
+	errors  int64
+	latency latencyHistogram
+}
+
+// EndpointSnapshot is a point in time copy of the histogram of one endpoint
+type EndpointSnapshot struct {
+	Endpoint string
+	Requests int64
+	// Errors counts transport errors and 4xx and 5xx responses
+	Errors int64
+	// Percentiles are approximate, reported as the upper bound of a histogram bucket
+	Mean time.Duration
+	P50  time.Duration
+	P90  time.Duration
+	P99  time.Duration
+	Max  time.Duration
+	// Histogram holds the buckets with a count, in order
+	Histogram []LatencyBucket
+}
+
+// LatencyBucket counts the latencies up to UpperBound and above the bound of the previous bucket.
+// The last bucket has an UpperBound of zero when it counts latencies above every bound
+type LatencyBucket struct {
+	UpperBound time.Duration
+	Count      int64
+}
+
+var (
+	uuidSegment = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	hexSegment  = regexp.MustCompile(`^[0-9a-fA-F]{16,}$`)
+)
+
+// NormalizeEndpoint returns the method and path of the request with every segment that is a number, a UUID or a
+// long hexadecimal string replaced by {id}, such as "GET /users/{id}/orders"
+func NormalizeEndpoint(req *http.Request) string {
+	segments := strings.Split(req.URL.EscapedPath(), "/")
+	for i, seg := range segments {
+		if _, err := strconv.ParseUint(seg, 10, 64); err == nil || uuidSegment.MatchString(seg) || hexSegment.MatchString(seg) {
+			segments[i] = "{id}"
+		}
+	}
+	path := strings.Join(segments, "/")
+	if path == "" {
+		path = "/"
+	}
+	return req.Method + " " + path
+}
+
+// SetEndpointStats records the latency of every request made through c in s, measured until the response headers
+// are received. Retries are recorded as separate requests
+func SetEndpointStats(c Client, s *EndpointStats) ClientFunc {
+	c = nilClientCheck(c)
+	normalize := s.Normalize
+	if normalize == nil {
+		normalize = NormalizeEndpoint
+	}
+	return func(req *http.Request) (*http.Response, error) {
+		name := normalize(req)
+		clock := ClockFromContext(req.Context())
+		start := clock.Now()
+		resp, err := c.Do(req)
+		s.record(name, clock.Now().Sub(start), ErrorClass(resp, err) != "")
+		return resp, err
+	}
+}
+
+func (s *EndpointStats) record(name string, d time.Duration, failed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.endpoints == nil {
+		s.endpoints = make(map[string]*endpointLatency)
+	}
+	e, ok := s.endpoints[name]
+	if !ok {
+		e = &endpointLatency{}
+		s.endpoints[name] = e
+	}
+	if failed {
+		e.errors++
+	}
+	e.latency.add(d)
+}
+
+// Snapshot returns the histogram of every endpoint, sorted by endpoint
+func (s *EndpointStats) Snapshot() []EndpointSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snaps := make([]EndpointSnapshot, 0, len(s.endpoints))
+	for name, e := range s.endpoints {
+		h := &e.latency
+		snap := EndpointSnapshot{
+			Endpoint: name,
+			Requests: h.count,
+			Errors:   e.errors,
+			Mean:     h.mean(),
+			P50:      h.percentile(0.50),
+			P90:      h.percentile(0.90),
+			P99:      h.percentile(0.99),
+			Max:      h.max,
+		}
+		for i, n := range h.buckets {
+			if n == 0 {
+				continue
+			}
+			var bound time.Duration
+			if i < len(latencyBuckets) {
+				bound = latencyBuckets[i]
+			}
+			snap.Histogram = append(snap.Histogram, LatencyBucket{UpperBound: bound, Count: n})
+		}
+		snaps = append(snaps, snap)
+	}
+	sort.Slice(snaps, func(i, j int) bool {
+		return snaps[i].Endpoint < snaps[j].Endpoint
+	})
+	return snaps
+}
+
+// WriteText writes the snapshot as an aligned table for logs and terminals
+func (s *EndpointStats) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ENDPOINT\tREQUESTS\tERRORS\tMEAN\tP50\tP90\tP99\tMAX")
+	for _, e := range s.Snapshot() {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\t%s\t%s\t%s\n", e.Endpoint, e.Requests, e.Errors,
+			e.Mean.Round(time.Microsecond), e.P50, e.P90, e.P99, e.Max.Round(time.Microsecond))
+	}
+	return tw.Flush()
+}
+
+// WriteCSV writes the snapshot with a header row and latencies in milliseconds, to load into a database or
+// spreadsheet
+func (s *EndpointStats) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"endpoint", "requests", "errors", "mean_ms", "p50_ms", "p90_ms", "p99_ms", "max_ms"})
+	ms := func(d time.Duration) string {
+		return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 3, 64)
+	}
+	for _, e := range s.Snapshot() {
+		_ = cw.Write([]string{e.Endpoint, strconv.FormatInt(e.Requests, 10), strconv.FormatInt(e.Errors, 10),
+			ms(e.Mean), ms(e.P50), ms(e.P90), ms(e.P99), ms(e.Max)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,78 @@
+package httpx_test
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/httpxtest"
+)
+
+func TestNormalizeEndpoint(t *testing.T) {
+	for target, want := range map[string]string{
+		"http://example.com":                                             "GET /",
+		"http://example.com/users/42/orders?page=2":                      "GET /users/{id}/orders",
+		"http://example.com/things/123e4567-e89b-12d3-a456-426614174000": "GET /things/{id}",
+		"http://example.com/blobs/0123456789abcdef0123/meta":             "GET /blobs/{id}/meta",
+		"http://example.com/v2/users/me":                                 "GET /v2/users/me",
+	} {
+		req, _ := http.NewRequest(http.MethodGet, target, nil)
+		if got := httpx.NormalizeEndpoint(req); got != want {
+			t.Error(target, got)
+		}
+	}
+}
+
+func TestSetEndpointStats(t *testing.T) {
+	clock := httpxtest.NewClock(time.Now())
+	var c httpx.Client = httpx.ClientFunc(func(req *http.Request) (*http.Response, error) {
+		clock.Advance(10 * time.Millisecond)
+		status := http.StatusOK
+		if strings.HasSuffix(req.URL.Path, "/2") {
+			clock.Advance(time.Second)
+			status = http.StatusNotFound
+		}
+		return &http.Response{StatusCode: status, Body: http.NoBody}, nil
+	})
+	var s httpx.EndpointStats
+	c = httpx.SetEndpointStats(c, &s)
+	c = httpx.SetClock(c, clock)
+	for _, target := range []string{"/users/1", "/users/2", "/users/3", "/health"} {
+		if _, err := httpx.SetRequest(c, http.MethodGet, "http://example.com"+target).Do(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	snap := s.Snapshot()
+	if len(snap) != 2 || snap[0].Endpoint != "GET /health" || snap[1].Endpoint != "GET /users/{id}" {
+		t.Fatal(snap)
+	}
+	users := snap[1]
+	if users.Requests != 3 || users.Errors != 1 || users.Max != 1010*time.Millisecond || users.P50 < 10*time.Millisecond || users.P50 > 15*time.Millisecond {
+		t.Fatal(users)
+	}
+	var counted int64
+	for _, b := range users.Histogram {
+		counted += b.Count
+	}
+	if len(users.Histogram) != 2 || counted != 3 {
+		t.Fatal(users.Histogram)
+	}
+
+	var text, csv bytes.Buffer
+	if err := s.WriteText(&text); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.WriteCSV(&csv); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(text.String(), "\n"); !strings.HasPrefix(lines[2], "GET /users/{id}  3") {
+		t.Fatal(text.String())
+	}
+	if lines := strings.Split(csv.String(), "\n"); lines[0] != "endpoint,requests,errors,mean_ms,p50_ms,p90_ms,p99_ms,max_ms" || !strings.HasPrefix(lines[2], "GET /users/{id},3,1,343.333,") {
+		t.Fatal(csv.String())
+	}
+}
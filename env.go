@@ -0,0 +1,63 @@
+package httpx
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NewEnvClient returns a client configured from environment variables named with the given prefix,
+// for example with the prefix "BILLING":
+//
+//	BILLING_BASE_URL            resolved against relative request URLs, see SetBaseURL
+//	BILLING_TIMEOUT             limit on each call as a duration such as 30s, see SetTimeout
+//	BILLING_PROXY               proxy URL for all requests, the standard HTTP_PROXY variables are used if unset
+//	BILLING_CA_CERT             path to a PEM file of certificates trusted in addition to the system pool
+//	BILLING_RATE_LIMIT          requests per period such as 100/1m, see SetRateLimit
+//	BILLING_RETRY_MAX_ATTEMPTS  total attempts for retryable requests, see SetRetry
+//
+// Unset or empty variables leave that decorator out. An error is returned if any value cannot be parsed.
+// The variables are read once, use a Builder to apply changes at runtime.
+func NewEnvClient(prefix string) (Client, error) {
+	prefix = strings.TrimSuffix(strings.ToUpper(prefix), "_") + "_"
+	env := func(name string) string {
+		return strings.TrimSpace(os.Getenv(prefix + name))
+	}
+	var cfg Config
+	var err error
+	cfg.BaseURL = env("BASE_URL")
+	if v := env("TIMEOUT"); v != "" {
+		if cfg.Timeout, err = time.ParseDuration(v); err != nil {
+			return nil, fmt.Errorf("invalid %sTIMEOUT: %w", prefix, err)
+		}
+	}
+	if v := env("RATE_LIMIT"); v != "" {
+		max, period, ok := strings.Cut(v, "/")
+		if ok {
+			if cfg.RateLimit, err = strconv.Atoi(max); err == nil {
+				cfg.RatePeriod, err = time.ParseDuration(period)
+			}
+		}
+		if !ok || err != nil || cfg.RateLimit <= 0 || cfg.RatePeriod <= 0 {
+			return nil, fmt.Errorf("invalid %sRATE_LIMIT %q: expected requests/period such as 100/1m", prefix, v)
+		}
+	}
+	if v := env("RETRY_MAX_ATTEMPTS"); v != "" {
+		if cfg.Retry.MaxAttempts, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("invalid %sRETRY_MAX_ATTEMPTS: %w", prefix, err)
+		}
+	}
+
+	var base Client = DefaultClient
+	if tc := (TransportConfig{Proxy: env("PROXY"), CACertFile: env("CA_CERT")}); tc != (TransportConfig{}) {
+		t, err := NewTransport(tc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %sPROXY or %sCA_CERT: %w", prefix, prefix, err)
+		}
+		base = &http.Client{Transport: t}
+	}
+	return buildChain(base, cfg)
+}
@@ -0,0 +1,57 @@
+package httpx_test
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tflyons/httpx"
+)
+
+func TestNewEnvClient(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/ping" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	b := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caFile, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PINGER_BASE_URL", srv.URL+"/api")
+	t.Setenv("PINGER_TIMEOUT", "5s")
+	t.Setenv("PINGER_CA_CERT", caFile)
+	t.Setenv("PINGER_RATE_LIMIT", "10/1s")
+	t.Setenv("PINGER_RETRY_MAX_ATTEMPTS", "2")
+
+	c, err := httpx.NewEnvClient("pinger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c = httpx.RequireResponseStatus(c, http.StatusOK)
+	if _, err = httpx.SetRequest(c, http.MethodGet, "/ping").Do(nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewEnvClient_Invalid(t *testing.T) {
+	for name, value := range map[string]string{
+		"PINGER_TIMEOUT":    "soon",
+		"PINGER_RATE_LIMIT": "100",
+		"PINGER_CA_CERT":    "/does/not/exist.pem",
+		"PINGER_BASE_URL":   "relative/path",
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(name, value)
+			if _, err := httpx.NewEnvClient("PINGER_"); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
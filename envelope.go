@@ -0,0 +1,117 @@
+package httpx
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// ErrEnvelope is matched by every *EnvelopeError using errors.Is
+var ErrEnvelope = fmt.Errorf("response envelope contains an error")
+
+// EnvelopeError is returned by SetResponseEnvelope when the error member of the envelope is present
+type EnvelopeError struct {
+	StatusCode int
+	// Value is the errPtr given to SetResponseEnvelope after decoding, or nil if none was given
+	Value any
+	// Raw is the undecoded error member
+	Raw json.RawMessage
+}
+
+// Error implements the error interface
+func (e *EnvelopeError) Error() string {
+	if err, ok := e.Value.(error); ok {
+		return fmt.Sprintf("%s (status %d): %s", ErrEnvelope, e.StatusCode, err.Error())
+	}
+	return fmt.Sprintf("%s (status %d): %s", ErrEnvelope, e.StatusCode, string(e.Raw))
+}
+
+// Is matches ErrEnvelope
+func (e *EnvelopeError) Is(target error) bool {
+	return target == ErrEnvelope
+}
+
+// Unwrap returns the decoded error value if it implements error
+func (e *EnvelopeError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// EnvelopeOption configures SetResponseEnvelope
+type EnvelopeOption func(*envelopeConfig)
+
+type envelopeConfig struct {
+	dataKey, errKey, metaKey string
+	meta                     func(json.RawMessage) error
+}
+
+// WithEnvelopeKeys overrides the default "data", "error" and "meta" member names. Empty names keep the default
+func WithEnvelopeKeys(data, err, meta string) EnvelopeOption {
+	return func(cfg *envelopeConfig) {
+		if data != "" {
+			cfg.dataKey = data
+		}
+		if err != "" {
+			cfg.errKey = err
+		}
+		if meta != "" {
+			cfg.metaKey = meta
+		}
+	}
+}
+
+// WithEnvelopeMeta calls fn with the undecoded meta member whenever it is present.
+// An error returned from fn is returned from the client
+func WithEnvelopeMeta(fn func(meta json.RawMessage) error) EnvelopeOption {
+	return func(cfg *envelopeConfig) {
+		cfg.meta = fn
+	}
+}
+
+// SetResponseEnvelope performs the request and unwraps a JSON response envelope such as
+// {"data": {...}, "error": {...}, "meta": {...}}.
+//
+// The data member is decoded into dataPtr. If the error member is present and not null it is decoded into errPtr
+// and an *EnvelopeError is returned. Either pointer may be nil to skip decoding that member.
+func SetResponseEnvelope(c Client, dataPtr, errPtr any, opts ...EnvelopeOption) ClientFunc {
+	cfg := envelopeConfig{
+		dataKey: "data",
+		errKey:  "error",
+		metaKey: "meta",
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return func(req *http.Request) (*http.Response, error) {
+		var envelope map[string]json.RawMessage
+		resp, err := SetResponseBodyHandlerJSON(c, &envelope).Do(req)
+		if err != nil {
+			return resp, err
+		}
+		if meta, ok := envelope[cfg.metaKey]; ok && cfg.meta != nil {
+			if err = cfg.meta(meta); err != nil {
+				return resp, err
+			}
+		}
+		if raw, ok := envelope[cfg.errKey]; ok && string(raw) != "null" {
+			if errPtr != nil {
+				if err = jsonUnmarshal(raw, errPtr); err != nil {
+					return resp, fmt.Errorf("could not unmarshal envelope error: %w", err)
+				}
+			}
+			return resp, &EnvelopeError{
+				StatusCode: resp.StatusCode,
+				Value:      errPtr,
+				Raw:        raw,
+			}
+		}
+		if raw, ok := envelope[cfg.dataKey]; ok && dataPtr != nil {
+			if err = jsonUnmarshal(raw, dataPtr); err != nil {
+				return resp, fmt.Errorf("could not unmarshal envelope data: %w", err)
+			}
+		}
+		return resp, nil
+	}
+}
@@ -0,0 +1,60 @@
+package httpx_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tflyons/httpx"
+)
+
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *apiError) Error() string { return fmt.Sprintf("%s: %s", e.Code, e.Message) }
+
+func TestSetResponseEnvelope(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"data":null,"error":{"code":"bad","message":"no thanks"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"foo":"abc","bar":1},"error":null,"meta":{"page":2}}`))
+	}))
+	defer srv.Close()
+
+	var thing Thing
+	var meta struct {
+		Page int `json:"page"`
+	}
+	var c httpx.Client = srv.Client()
+	c = httpx.SetResponseEnvelope(c, &thing, &apiError{}, httpx.WithEnvelopeMeta(func(raw json.RawMessage) error {
+		return json.Unmarshal(raw, &meta)
+	}))
+	c = httpx.SetRequest(c, http.MethodGet, srv.URL)
+	if _, err := c.Do(nil); err != nil {
+		t.Fatal(err)
+	}
+	if thing.Foo != "abc" || thing.Bar != 1 || meta.Page != 2 {
+		t.Fatal(thing, meta)
+	}
+
+	c = srv.Client()
+	c = httpx.SetResponseEnvelope(c, &thing, &apiError{})
+	c = httpx.SetRequest(c, http.MethodGet, srv.URL+"/fail")
+	_, err := c.Do(nil)
+	var envErr *httpx.EnvelopeError
+	if !errors.As(err, &envErr) || envErr.StatusCode != http.StatusBadRequest {
+		t.Fatal(err)
+	}
+	var target *apiError
+	if !errors.Is(err, httpx.ErrEnvelope) || !errors.As(err, &target) || target.Code != "bad" {
+		t.Fatal(err)
+	}
+}
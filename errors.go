@@ -3,6 +3,7 @@ package httpx
 import (
 	"errors"
 	"fmt"
+	"net/http"
 )
 
 var ErrBodyClose = fmt.Errorf("body could not be closed")
@@ -21,3 +22,31 @@ func (e errBodyCloser) Is(target error) bool {
 func (e errBodyCloser) Error() string {
 	return fmt.Sprintf("%s: %s", ErrBodyClose, e.next.Error())
 }
+
+var ErrSchemaValidation = fmt.Errorf("body does not match schema")
+
+type errSchemaValidation struct {
+	next error
+}
+
+func (e errSchemaValidation) Is(target error) bool {
+	if errors.Is(target, ErrSchemaValidation) {
+		return true
+	}
+	return errors.Is(e.next, target)
+}
+
+func (e errSchemaValidation) Error() string {
+	return fmt.Sprintf("%s: %s", ErrSchemaValidation, e.next.Error())
+}
+
+// StatusError is returned by RequireResponseStatus for a response with an unexpected status code
+type StatusError struct {
+	StatusCode int
+	// Header is the header of the response
+	Header http.Header
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("received invalid satus code: %d", e.StatusCode)
+}
@@ -42,3 +42,21 @@ func TestClient_CloseBodyError(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestStatusError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "5")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	c := httpx.RequireResponseStatus(srv.Client(), http.StatusOK)
+	_, err := httpx.SetRequest(c, http.MethodGet, srv.URL).Do(nil)
+	var statusErr *httpx.StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusServiceUnavailable || statusErr.Header.Get("Retry-After") != "5" {
+		t.Fatal(err)
+	}
+	if err.Error() != "received invalid satus code: 503" {
+		t.Fatal(err)
+	}
+}
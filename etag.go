@@ -0,0 +1,86 @@
+package httpx
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// ErrConflict is matched by every ConflictError using errors.Is
+var ErrConflict = fmt.Errorf("resource was modified concurrently")
+
+// ConflictError is returned by SetOptimisticConcurrency when an update fails with 412 Precondition Failed because
+// the resource changed since it was read
+type ConflictError struct {
+	URL string
+	// ETag is the current ETag of the resource if the server sent it, the resource should be read again
+	// and the change applied to the new version before updating once more
+	ETag string
+}
+
+// Error implements the error interface
+func (e *ConflictError) Error() string {
+	if e.ETag == "" {
+		return fmt.Sprintf("%s: %s", ErrConflict, e.URL)
+	}
+	return fmt.Sprintf("%s: %s: current etag %s", ErrConflict, e.URL, e.ETag)
+}
+
+// Is matches ErrConflict
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// SetOptimisticConcurrency remembers the ETag of each resource read with GET or HEAD and sends it in an If-Match
+// header with the next PUT or PATCH of the same URL, ignoring the query, so that an update cannot overwrite a change
+// made by someone else in the meantime. A 412 Precondition Failed response is returned with a *ConflictError.
+//
+// An If-Match header already on the request is kept. Weak ETags are not sent as they never match If-Match.
+// The ETag is updated from successful updates and forgotten after a DELETE. It is kept after a conflict, so that
+// sending the same update again fails the same way until the resource has been read again
+func SetOptimisticConcurrency(c Client) ClientFunc {
+	c = nilClientCheck(c)
+	var mu sync.Mutex
+	etags := make(map[string]string)
+	remember := func(resource, etag string) {
+		mu.Lock()
+		defer mu.Unlock()
+		if etag == "" || strings.HasPrefix(etag, "W/") {
+			delete(etags, resource)
+			return
+		}
+		etags[resource] = etag
+	}
+	return func(req *http.Request) (*http.Response, error) {
+		resource := req.URL.Scheme + "://" + req.URL.Host + req.URL.EscapedPath()
+		update := req.Method == http.MethodPut || req.Method == http.MethodPatch
+		if update && req.Header.Get("If-Match") == "" {
+			mu.Lock()
+			etag, ok := etags[resource]
+			mu.Unlock()
+			if ok {
+				if req.Header == nil {
+					req.Header = make(http.Header)
+				}
+				req.Header.Set("If-Match", etag)
+			}
+		}
+		resp, err := c.Do(req)
+		if err != nil {
+			return resp, err
+		}
+		success := resp.StatusCode >= 200 && resp.StatusCode < 300
+		switch {
+		case (req.Method == http.MethodGet || req.Method == http.MethodHead) && success:
+			remember(resource, resp.Header.Get("ETag"))
+		case update && success:
+			remember(resource, resp.Header.Get("ETag"))
+		case update && resp.StatusCode == http.StatusPreconditionFailed:
+			return resp, &ConflictError{URL: req.URL.String(), ETag: resp.Header.Get("ETag")}
+		case req.Method == http.MethodDelete && success:
+			remember(resource, "")
+		}
+		return resp, nil
+	}
+}
@@ -0,0 +1,69 @@
+package httpx_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/tflyons/httpx"
+)
+
+func TestSetOptimisticConcurrency(t *testing.T) {
+	var mu sync.Mutex
+	version := 1
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		etag := fmt.Sprintf(`"v%d"`, version)
+		if r.Method == http.MethodPut {
+			if r.Header.Get("If-Match") != etag {
+				w.Header().Set("ETag", etag)
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
+			version++
+			etag = fmt.Sprintf(`"v%d"`, version)
+		}
+		w.Header().Set("ETag", etag)
+	}))
+	defer srv.Close()
+
+	c := httpx.SetOptimisticConcurrency(srv.Client())
+	do := func(method string) error {
+		_, err := httpx.SetRequest(c, method, srv.URL+"/things/1?verbose=true").Do(nil)
+		return err
+	}
+	if err := do(http.MethodGet); err != nil {
+		t.Fatal(err)
+	}
+	// the ETag of the read and then of the update are sent
+	if err := do(http.MethodPut); err != nil {
+		t.Fatal(err)
+	}
+	if err := do(http.MethodPut); err != nil {
+		t.Fatal(err)
+	}
+
+	// someone else updates the resource
+	mu.Lock()
+	version++
+	mu.Unlock()
+	err := do(http.MethodPut)
+	var conflict *httpx.ConflictError
+	if !errors.Is(err, httpx.ErrConflict) || !errors.As(err, &conflict) || conflict.ETag != `"v4"` {
+		t.Fatal(err)
+	}
+	if err := do(http.MethodPut); !errors.Is(err, httpx.ErrConflict) {
+		t.Fatal("expected the update to fail until the resource is read again", err)
+	}
+	// after reading again the update succeeds
+	if err := do(http.MethodGet); err != nil {
+		t.Fatal(err)
+	}
+	if err := do(http.MethodPut); err != nil {
+		t.Fatal(err)
+	}
+}
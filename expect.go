@@ -0,0 +1,24 @@
+package httpx
+
+import (
+	"net/http"
+)
+
+// SetExpectContinue sends an Expect: 100-continue header with request bodies larger than threshold bytes, or of
+// unknown length, so that a server rejecting the request, for example because of authentication or size limits,
+// can answer before the body is uploaded.
+//
+// The transport must wait for the server, which http.Transport only does if ExpectContinueTimeout is set, as it is
+// for http.DefaultTransport and transports built by NewTransport, see TransportConfig.ExpectContinueTimeout
+func SetExpectContinue(c Client, threshold int64) ClientFunc {
+	c = nilClientCheck(c)
+	return func(req *http.Request) (*http.Response, error) {
+		if req.Body != nil && req.Body != http.NoBody && (req.ContentLength > threshold || req.ContentLength <= 0) {
+			if req.Header == nil {
+				req.Header = make(http.Header)
+			}
+			req.Header.Set("Expect", "100-continue")
+		}
+		return c.Do(req)
+	}
+}
@@ -0,0 +1,55 @@
+package httpx_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx"
+)
+
+func TestSetExpectContinue(t *testing.T) {
+	received := make(chan int64, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Expect") == "100-continue" && r.Header.Get("Authorization") == "" {
+			// rejecting without reading the body means the client never sends it
+			w.WriteHeader(http.StatusUnauthorized)
+			received <- 0
+			return
+		}
+		n, _ := io.Copy(io.Discard, r.Body)
+		received <- n
+	}))
+	defer srv.Close()
+
+	tr, err := httpx.NewTransport(httpx.TransportConfig{ExpectContinueTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	upload := func(c httpx.Client, size int) (int, int64) {
+		t.Helper()
+		c = httpx.SetExpectContinue(c, 1024)
+		c = httpx.SetRequestBody(c, nil, bytes.Repeat([]byte("x"), size))
+		resp, err := httpx.SetRequest(c, http.MethodPut, srv.URL).Do(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode, <-received
+	}
+
+	client := &http.Client{Transport: tr}
+	if status, n := upload(client, 1<<20); status != http.StatusUnauthorized || n != 0 {
+		t.Fatal(status, n)
+	}
+	if status, n := upload(httpx.SetHeader(client, "Authorization", "Bearer t"), 1<<20); status != http.StatusOK || n != 1<<20 {
+		t.Fatal(status, n)
+	}
+	// small bodies are sent without waiting
+	if status, n := upload(client, 10); status != http.StatusOK || n != 10 {
+		t.Fatal(status, n)
+	}
+}
@@ -1,3 +1,10 @@
 module github.com/tflyons/httpx
 
 go 1.19
+
+require (
+	golang.org/x/net v0.33.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require golang.org/x/text v0.21.0 // indirect
@@ -103,7 +103,7 @@ func (m *MyClient) decorateLogin(c httpx.Client, user, pass string) httpx.Client
 				close(ch)
 			}
 		}
-		req.Header.Set("TOKEN", token)
+		req.Header.Set("Authorization", "Bearer "+token)
 		return c.Do(req)
 	}
 }
@@ -1,16 +1,21 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"log"
 	"net/http"
-	"strings"
 	"sync"
+
+	"github.com/tflyons/httpx/serverx"
 )
 
 type Server struct {
-	once sync.Once
-	mux  *http.ServeMux
+	once   sync.Once
+	mux    *http.ServeMux
+	tokens sync.Map
 }
 
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -18,10 +23,11 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		if s.mux == nil {
 			s.mux = http.NewServeMux()
 		}
+		requireToken := serverx.Authenticate(serverx.TokenValidatorFunc(s.validateToken))
 		// foo requires a login and a special header
-		s.mux.HandleFunc("/foo", s.middlewareRequireToken(s.middlewareRequireHeader(s.handleFoo(), "SOME-HEADER")))
+		s.mux.Handle("/foo", requireToken(s.middlewareRequireHeader(s.handleFoo(), "SOME-HEADER")))
 		// bar requires only a login
-		s.mux.HandleFunc("/bar", s.middlewareRequireToken(s.handleBar()))
+		s.mux.Handle("/bar", requireToken(s.handleBar()))
 		s.mux.HandleFunc("/login", s.handleLogin())
 	})
 	s.mux.ServeHTTP(w, r)
@@ -52,24 +58,28 @@ func (s *Server) handleBar() http.HandlerFunc {
 func (s *Server) handleLogin() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		user, pass, ok := r.BasicAuth()
-		// this is the worst possible way to check auth
+		// this is the worst possible way to check credentials
 		if ok && user == "tom" && pass == "password1" {
-			w.Header().Set("TOKEN", "YOU'RE SPECIAL")
+			b := make([]byte, 16)
+			if _, err := rand.Read(b); err != nil {
+				serverx.Error(w, r, err)
+				return
+			}
+			token := hex.EncodeToString(b)
+			s.tokens.Store(token, &serverx.Principal{Subject: user})
+			w.Header().Set("TOKEN", token)
 			return
 		}
 		http.NotFound(w, r)
 	}
 }
 
-func (s *Server) middlewareRequireToken(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		// this is also the worst possible auth
-		if v := r.Header.Get("TOKEN"); !strings.EqualFold(v, "YOU'RE SPECIAL") {
-			w.WriteHeader(http.StatusForbidden)
-			return
-		}
-		next(w, r)
+// validateToken accepts the tokens handed out by login
+func (s *Server) validateToken(_ context.Context, token string) (*serverx.Principal, error) {
+	if p, ok := s.tokens.Load(token); ok {
+		return p.(*serverx.Principal), nil
 	}
+	return nil, serverx.ErrInvalidToken
 }
 
 func (s *Server) middlewareRequireHeader(next http.HandlerFunc, h string) http.HandlerFunc {
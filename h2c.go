@@ -0,0 +1,29 @@
+package httpx
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+
+	"golang.org/x/net/http2"
+)
+
+// NewH2CClient returns a client that speaks HTTP/2 without TLS (h2c) using prior knowledge, for internal
+// services such as gRPC gateways and sidecars that only accept cleartext HTTP/2.
+//
+// Requests must use the http scheme. If dialer is nil a net.Dialer with default settings is used
+func NewH2CClient(dialer *net.Dialer) *http.Client {
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
+	return &http.Client{
+		Transport: &http2.Transport{
+			AllowHTTP: true,
+			// the transport calls DialTLSContext for every connection, dial plain TCP instead
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, addr)
+			},
+		},
+	}
+}
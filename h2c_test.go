@@ -0,0 +1,29 @@
+package httpx_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tflyons/httpx"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+func TestNewH2CClient(t *testing.T) {
+	srv := httptest.NewServer(h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Proto))
+	}), &http2.Server{}))
+	defer srv.Close()
+
+	var got string
+	var c httpx.Client = httpx.NewH2CClient(nil)
+	c = httpx.SetResponseBodyString(c, &got)
+	c = httpx.SetRequest(c, http.MethodGet, srv.URL)
+	if _, err := c.Do(nil); err != nil {
+		t.Fatal(err)
+	}
+	if got != "HTTP/2.0" {
+		t.Fatal(got)
+	}
+}
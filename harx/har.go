@@ -0,0 +1,165 @@
+// Package harx records and replays httpx traffic in the HTTP Archive (HAR) 1.2 format, which browsers and most
+// HTTP tools can also export.
+package harx
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HAR is the root of an HTTP Archive document
+type HAR struct {
+	Log Log `json:"log"`
+}
+
+// Log holds the recorded entries
+type Log struct {
+	Version string  `json:"version"`
+	Creator Creator `json:"creator"`
+	Entries []Entry `json:"entries"`
+}
+
+// Creator names the application that produced the archive
+type Creator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// Entry is a single request and response
+type Entry struct {
+	StartedDateTime time.Time `json:"startedDateTime"`
+	// Time is the total duration in milliseconds
+	Time     float64  `json:"time"`
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+	Cache    struct{} `json:"cache"`
+	Timings  Timings  `json:"timings"`
+}
+
+// Request is a recorded request
+type Request struct {
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	HTTPVersion string      `json:"httpVersion"`
+	Cookies     []NameValue `json:"cookies"`
+	Headers     []NameValue `json:"headers"`
+	QueryString []NameValue `json:"queryString"`
+	PostData    *PostData   `json:"postData,omitempty"`
+	HeadersSize int64       `json:"headersSize"`
+	BodySize    int64       `json:"bodySize"`
+}
+
+// Response is a recorded response
+type Response struct {
+	Status      int         `json:"status"`
+	StatusText  string      `json:"statusText"`
+	HTTPVersion string      `json:"httpVersion"`
+	Cookies     []NameValue `json:"cookies"`
+	Headers     []NameValue `json:"headers"`
+	Content     Content     `json:"content"`
+	RedirectURL string      `json:"redirectURL"`
+	HeadersSize int64       `json:"headersSize"`
+	BodySize    int64       `json:"bodySize"`
+}
+
+// NameValue is a header, cookie or query parameter
+type NameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// PostData is a request body
+type PostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// Content is a response body. Encoding is "base64" for binary bodies
+type Content struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// Timings in milliseconds, -1 when not measured
+type Timings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// Read decodes a HAR document
+func Read(r io.Reader) (*HAR, error) {
+	var h HAR
+	if err := json.NewDecoder(r).Decode(&h); err != nil {
+		return nil, fmt.Errorf("could not decode har: %w", err)
+	}
+	return &h, nil
+}
+
+// Write encodes h as an indented HAR document
+func Write(w io.Writer, h *HAR) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(h)
+}
+
+// skipHeaders are recorded by browsers but set by the transport when a request is sent
+var skipHeaders = map[string]bool{
+	"host":              true,
+	"content-length":    true,
+	"connection":        true,
+	"keep-alive":        true,
+	"transfer-encoding": true,
+	"upgrade":           true,
+	"te":                true,
+	"accept-encoding":   true,
+}
+
+// NewRequest builds an http.Request from the recorded request. HTTP/2 pseudo headers and headers managed by the
+// transport are left out
+func (r *Request) NewRequest(ctx context.Context) (*http.Request, error) {
+	var body io.Reader
+	if r.PostData != nil && r.PostData.Text != "" {
+		body = strings.NewReader(r.PostData.Text)
+	}
+	req, err := http.NewRequestWithContext(ctx, r.Method, r.URL, body)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range r.Headers {
+		if strings.HasPrefix(h.Name, ":") || skipHeaders[strings.ToLower(h.Name)] {
+			continue
+		}
+		req.Header.Add(h.Name, h.Value)
+	}
+	if r.PostData != nil && r.PostData.MimeType != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", r.PostData.MimeType)
+	}
+	return req, nil
+}
+
+// Body returns the decoded response body
+func (c *Content) Body() ([]byte, error) {
+	if c.Encoding == "base64" {
+		return base64.StdEncoding.DecodeString(c.Text)
+	}
+	return []byte(c.Text), nil
+}
+
+func nameValues(h http.Header) []NameValue {
+	out := []NameValue{}
+	for k, vs := range h {
+		for _, v := range vs {
+			out = append(out, NameValue{Name: k, Value: v})
+		}
+	}
+	return out
+}
@@ -0,0 +1,107 @@
+package harx_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/harx"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	var received []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b := new(bytes.Buffer)
+		b.ReadFrom(r.Body)
+		received = append(received, r.Method+" "+r.URL.RequestURI()+" "+b.String()+" "+r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+
+	rec := &harx.Recorder{Scrubber: httpx.NewDefaultScrubber()}
+	var c httpx.Client = srv.Client()
+	c = harx.Record(c, rec)
+	c = httpx.SetHeader(c, "Authorization", "Bearer secret-token")
+	var body string
+	get := httpx.SetResponseBodyString(c, &body)
+	if _, err := httpx.SetRequest(get, http.MethodGet, srv.URL+"/items?page=2").Do(nil); err != nil {
+		t.Fatal(err)
+	}
+	if body != `{"ok":true}` {
+		t.Fatal("recording should not consume the response body", body)
+	}
+	post := httpx.SetRequestBodyJSON(c, map[string]int{"n": 1})
+	if _, err := httpx.SetRequest(post, http.MethodPost, srv.URL+"/items").Do(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := harx.Write(&buf, rec.HAR()); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("secret-token")) {
+		t.Fatal("recording contains an unscrubbed token")
+	}
+	h, err := harx.Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h.Log.Entries) != 2 || h.Log.Entries[1].Request.PostData.Text != `{"n":1}` || h.Log.Entries[0].Response.Content.Text != `{"ok":true}` {
+		t.Fatal(h.Log.Entries)
+	}
+
+	// replay against another server
+	received = nil
+	replayTarget := httptest.NewServer(srv.Config.Handler)
+	defer replayTarget.Close()
+	target, _ := url.Parse(replayTarget.URL)
+	var statuses []int
+	failed, err := harx.Replay(context.Background(), replayTarget.Client(), h.Log.Entries, harx.ReplayOptions{
+		Target: target,
+		OnResult: func(r harx.Result) {
+			if r.Err == nil {
+				statuses = append(statuses, r.Response.StatusCode)
+			}
+		},
+	})
+	if err != nil || failed != 0 {
+		t.Fatal(failed, err)
+	}
+	if len(received) != 2 || received[0] != "GET /items?page=2  [REDACTED]" || received[1] != `POST /items {"n":1} [REDACTED]` {
+		t.Fatalf("%q", received)
+	}
+	if len(statuses) != 2 || statuses[0] != http.StatusOK {
+		t.Fatal(statuses)
+	}
+}
+
+func TestRecordScrubsURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	rec := &harx.Recorder{Scrubber: httpx.NewDefaultScrubber()}
+	u := strings.Replace(srv.URL, "://", "://user:hunter2@", 1) + "/items?access_token=t0k3n&page=2"
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = harx.Record(srv.Client(), rec).Do(req); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := harx.Write(&buf, rec.HAR()); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("t0k3n")) || bytes.Contains(buf.Bytes(), []byte("hunter2")) {
+		t.Fatal("recording contains URL credentials", buf.String())
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("page=2")) {
+		t.Fatal("recording should keep the other query parameters", buf.String())
+	}
+}
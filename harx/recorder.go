@@ -0,0 +1,140 @@
+package harx
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tflyons/httpx"
+)
+
+// Recorder collects the traffic of Record as HAR entries. The zero value is ready to use
+type Recorder struct {
+	// Scrubber masks headers, bodies and URLs before they are recorded, nothing is masked if nil
+	Scrubber *httpx.Scrubber
+
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// Record adds every request sent through c and its response to rec.
+// Request and response bodies are buffered so that they can still be read afterwards
+func Record(c httpx.Client, rec *Recorder) httpx.ClientFunc {
+	if c == nil {
+		c = httpx.DefaultClient
+	}
+	return func(req *http.Request) (*http.Response, error) {
+		reqBody, err := restore(&req.Body)
+		if err != nil {
+			return nil, err
+		}
+		start := httpx.ClockFromContext(req.Context()).Now()
+		resp, err := c.Do(req)
+		elapsed := httpx.ClockFromContext(req.Context()).Now().Sub(start)
+		if err != nil {
+			return resp, err
+		}
+		respBody, readErr := restore(&resp.Body)
+		if readErr != nil {
+			return resp, readErr
+		}
+		rec.add(req, reqBody, resp, respBody, start, elapsed)
+		return resp, nil
+	}
+}
+
+// HAR returns a document holding the entries recorded so far
+func (rec *Recorder) HAR() *HAR {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return &HAR{Log: Log{
+		Version: "1.2",
+		Creator: Creator{Name: "httpx", Version: "1"},
+		Entries: append([]Entry(nil), rec.entries...),
+	}}
+}
+
+func (rec *Recorder) add(req *http.Request, reqBody []byte, resp *http.Response, respBody []byte, start time.Time, elapsed time.Duration) {
+	s := rec.Scrubber
+	if s == nil {
+		s = &httpx.Scrubber{}
+	}
+	scrubbed := s.ScrubURL(req.URL)
+	var query []NameValue
+	u, err := url.Parse(scrubbed)
+	if err != nil {
+		u = &url.URL{}
+	}
+	for k, vs := range u.Query() {
+		for _, v := range vs {
+			query = append(query, NameValue{Name: k, Value: v})
+		}
+	}
+	sort.Slice(query, func(i, j int) bool { return query[i].Name < query[j].Name })
+
+	e := Entry{
+		StartedDateTime: start,
+		Time:            float64(elapsed) / float64(time.Millisecond),
+		Request: Request{
+			Method:      req.Method,
+			URL:         scrubbed,
+			HTTPVersion: fmt.Sprintf("HTTP/%d.%d", req.ProtoMajor, req.ProtoMinor),
+			Cookies:     []NameValue{},
+			Headers:     nameValues(s.ScrubHeader(req.Header)),
+			QueryString: append([]NameValue{}, query...),
+			HeadersSize: -1,
+			BodySize:    int64(len(reqBody)),
+		},
+		Response: Response{
+			Status:      resp.StatusCode,
+			StatusText:  http.StatusText(resp.StatusCode),
+			HTTPVersion: fmt.Sprintf("HTTP/%d.%d", resp.ProtoMajor, resp.ProtoMinor),
+			Cookies:     []NameValue{},
+			Headers:     nameValues(s.ScrubHeader(resp.Header)),
+			RedirectURL: resp.Header.Get("Location"),
+			HeadersSize: -1,
+			BodySize:    int64(len(respBody)),
+		},
+		Timings: Timings{Send: -1, Wait: float64(elapsed) / float64(time.Millisecond), Receive: -1},
+	}
+	if len(reqBody) > 0 {
+		e.Request.PostData = &PostData{
+			MimeType: req.Header.Get("Content-Type"),
+			Text:     string(s.ScrubBody(req.Header.Get("Content-Type"), reqBody)),
+		}
+	}
+	mime := resp.Header.Get("Content-Type")
+	body := s.ScrubBody(mime, respBody)
+	e.Response.Content = Content{Size: int64(len(respBody)), MimeType: mime}
+	if utf8.Valid(body) {
+		e.Response.Content.Text = string(body)
+	} else {
+		e.Response.Content.Text = base64.StdEncoding.EncodeToString(body)
+		e.Response.Content.Encoding = "base64"
+	}
+
+	rec.mu.Lock()
+	rec.entries = append(rec.entries, e)
+	rec.mu.Unlock()
+}
+
+// restore reads all of body and replaces it with an in memory copy
+func restore(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil || *body == http.NoBody {
+		return nil, nil
+	}
+	b, err := io.ReadAll(*body)
+	(*body).Close()
+	*body = io.NopCloser(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("could not record body: %w", err)
+	}
+	return b, nil
+}
@@ -0,0 +1,140 @@
+package harx
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/tflyons/httpx"
+)
+
+// ReplayOptions configures Replay
+type ReplayOptions struct {
+	// Target replaces the scheme and host of every recorded URL when set, for example to replay production
+	// traffic against a staging server
+	Target *url.URL
+	// Rate requests are sent per Period, unlimited if either is zero
+	Rate   int
+	Period time.Duration
+	// Concurrency is the number of requests in flight at once, 1 if zero
+	Concurrency int
+	// Filter skips entries for which it returns false, every entry is replayed if nil
+	Filter func(*Entry) bool
+	// OnResult is called with the outcome of every replayed entry. The response body is closed after it returns
+	OnResult func(Result)
+}
+
+// Result is the outcome of replaying one entry
+type Result struct {
+	Entry    *Entry
+	Response *http.Response
+	Err      error
+	Duration time.Duration
+}
+
+// Replay sends the recorded requests of entries through c in order, at the rate and concurrency of opts.
+// It returns when every entry has been replayed or ctx is done, with the number of requests that failed with an
+// error. Responses of any status are not failures, inspect them with OnResult
+func Replay(ctx context.Context, c httpx.Client, entries []Entry, opts ReplayOptions) (failed int, err error) {
+	if c == nil {
+		c = httpx.DefaultClient
+	}
+	if opts.Rate > 0 && opts.Period > 0 {
+		c = httpx.SetRateLimit(c, opts.Rate, opts.Period)
+	}
+	workers := opts.Concurrency
+	if workers <= 0 {
+		workers = 1
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	jobs := make(chan *Entry)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range jobs {
+				res := replayEntry(ctx, c, e, opts.Target)
+				if res.Err != nil {
+					mu.Lock()
+					failed++
+					mu.Unlock()
+				}
+				if opts.OnResult != nil {
+					mu.Lock()
+					opts.OnResult(res)
+					mu.Unlock()
+				}
+				if res.Response != nil && res.Response.Body != nil {
+					_, _ = io.Copy(io.Discard, res.Response.Body)
+					res.Response.Body.Close()
+				}
+			}
+		}()
+	}
+	for i := range entries {
+		if opts.Filter != nil && !opts.Filter(&entries[i]) {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+		case jobs <- &entries[i]:
+			continue
+		}
+		break
+	}
+	close(jobs)
+	wg.Wait()
+	return failed, err
+}
+
+func replayEntry(ctx context.Context, c httpx.Client, e *Entry, target *url.URL) Result {
+	res := Result{Entry: e}
+	req, err := e.Request.NewRequest(ctx)
+	if err != nil {
+		res.Err = fmt.Errorf("could not build request %s %s: %w", e.Request.Method, e.Request.URL, err)
+		return res
+	}
+	if target != nil {
+		req.URL.Scheme, req.URL.Host = target.Scheme, target.Host
+		req.Host = ""
+	}
+	start := httpx.ClockFromContext(ctx).Now()
+	res.Response, res.Err = c.Do(req)
+	res.Duration = httpx.ClockFromContext(ctx).Now().Sub(start)
+	return res
+}
+
+// DeadLetterEntries returns an entry for each request stored by httpx.SetDeadLetter, so that failed requests can
+// be replayed once the cause is fixed. The entries have no response
+func DeadLetterEntries(dls []*httpx.DeadLetter) []Entry {
+	entries := make([]Entry, 0, len(dls))
+	for _, dl := range dls {
+		e := Entry{
+			StartedDateTime: dl.Time,
+			Request: Request{
+				Method:      dl.Method,
+				URL:         dl.URL,
+				HTTPVersion: "HTTP/1.1",
+				Cookies:     []NameValue{},
+				Headers:     nameValues(dl.Header),
+				QueryString: []NameValue{},
+				HeadersSize: -1,
+				BodySize:    int64(len(dl.Body)),
+			},
+			Response: Response{Status: dl.StatusCode, StatusText: http.StatusText(dl.StatusCode), Cookies: []NameValue{}, Headers: []NameValue{}},
+			Timings:  Timings{Send: -1, Wait: -1, Receive: -1},
+		}
+		if len(dl.Body) > 0 {
+			e.Request.PostData = &PostData{MimeType: dl.Header.Get("Content-Type"), Text: string(dl.Body)}
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
@@ -0,0 +1,32 @@
+package httpx
+
+import (
+	"crypto/tls"
+	"net/http"
+)
+
+// SetHostOverride sends the request with the given Host header regardless of the URL, for example to reach a
+// virtual host through a load balancer addressed by IP.
+//
+// The TLS server name is not changed, see TransportWithServerName
+func SetHostOverride(c Client, host string) ClientFunc {
+	c = nilClientCheck(c)
+	return func(req *http.Request) (*http.Response, error) {
+		req.Host = host
+		return c.Do(req)
+	}
+}
+
+// TransportWithServerName returns a clone of t that sends serverName as the TLS SNI and verifies the server
+// certificate against it instead of the URL host. If t is nil http.DefaultTransport is cloned
+func TransportWithServerName(t *http.Transport, serverName string) *http.Transport {
+	if t == nil {
+		t = http.DefaultTransport.(*http.Transport)
+	}
+	t = t.Clone()
+	if t.TLSClientConfig == nil {
+		t.TLSClientConfig = &tls.Config{}
+	}
+	t.TLSClientConfig.ServerName = serverName
+	return t
+}
@@ -0,0 +1,30 @@
+package httpx_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tflyons/httpx"
+)
+
+func TestSetHostOverride(t *testing.T) {
+	// the test certificate is valid for example.com
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Host + " " + r.TLS.ServerName))
+	}))
+	defer srv.Close()
+
+	transport := httpx.TransportWithServerName(srv.Client().Transport.(*http.Transport), "example.com")
+	var got string
+	var c httpx.Client = &http.Client{Transport: transport}
+	c = httpx.SetResponseBodyString(c, &got)
+	c = httpx.SetHostOverride(c, "example.com")
+	c = httpx.SetRequest(c, http.MethodGet, srv.URL)
+	if _, err := c.Do(nil); err != nil {
+		t.Fatal(err)
+	}
+	if got != "example.com example.com" {
+		t.Fatal(got)
+	}
+}
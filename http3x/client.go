@@ -0,0 +1,110 @@
+// Package http3x provides an httpx.Client that speaks HTTP/3 over QUIC using quic-go.
+//
+// It is a separate module so that the QUIC dependencies are only required by programs that use it.
+// Existing decorator chains move to HTTP/3 by replacing their base client:
+//
+//	c := http3x.NewClient(http3x.Options{Fallback: http.DefaultClient})
+//	defer c.Close()
+//	var client httpx.Client = c
+//	client = httpx.SetHeader(client, "Accept", "application/json")
+package http3x
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	"github.com/tflyons/httpx"
+)
+
+// Options configures NewClient
+type Options struct {
+	TLSClientConfig *tls.Config
+	QUICConfig      *quic.Config
+	// Fallback performs the request when HTTP/3 fails, typically a client speaking HTTP/2 and HTTP/1.1.
+	// If nil HTTP/3 errors are returned as is
+	Fallback httpx.Client
+	// FallbackPeriod is how long requests to a host go straight to Fallback after an HTTP/3 failure,
+	// 5 minutes if zero
+	FallbackPeriod time.Duration
+}
+
+// Client performs requests over HTTP/3, falling back to another client when a host cannot be reached over QUIC.
+// Fallback only happens for transport errors on requests whose body can be sent again
+type Client struct {
+	transport *http3.Transport
+	h3        *http.Client
+	fallback  httpx.Client
+	period    time.Duration
+
+	mu     sync.Mutex
+	broken map[string]time.Time
+}
+
+// NewClient returns an HTTP/3 client. Close releases the UDP socket
+func NewClient(opts Options) *Client {
+	t := &http3.Transport{
+		TLSClientConfig: opts.TLSClientConfig,
+		QUICConfig:      opts.QUICConfig,
+	}
+	period := opts.FallbackPeriod
+	if period <= 0 {
+		period = 5 * time.Minute
+	}
+	return &Client{
+		transport: t,
+		h3:        &http.Client{Transport: t},
+		fallback:  opts.Fallback,
+		period:    period,
+		broken:    make(map[string]time.Time),
+	}
+}
+
+// Do implements httpx.Client
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	if c.fallback != nil && c.isBroken(req.URL.Host) {
+		return c.fallback.Do(req)
+	}
+	resp, err := c.h3.Do(req)
+	if err == nil || c.fallback == nil || req.Context().Err() != nil {
+		return resp, err
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return resp, err
+		}
+		body, bodyErr := req.GetBody()
+		if bodyErr != nil {
+			return resp, fmt.Errorf("%w: could not reset body for fallback: %s", err, bodyErr)
+		}
+		req.Body = body
+	}
+	c.markBroken(req.URL.Host)
+	return c.fallback.Do(req)
+}
+
+// Close closes the QUIC connections and the UDP socket
+func (c *Client) Close() error {
+	return c.transport.Close()
+}
+
+func (c *Client) isBroken(host string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	until, ok := c.broken[host]
+	if ok && time.Now().After(until) {
+		delete(c.broken, host)
+		return false
+	}
+	return ok
+}
+
+func (c *Client) markBroken(host string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.broken[host] = time.Now().Add(c.period)
+}
@@ -0,0 +1,86 @@
+package http3x_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/http3x"
+)
+
+var protoHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	_, _ = w.Write([]byte(r.Proto))
+})
+
+func newTLSServer(t *testing.T) (*httptest.Server, *tls.Config) {
+	srv := httptest.NewTLSServer(protoHandler)
+	t.Cleanup(srv.Close)
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+	return srv, &tls.Config{RootCAs: pool}
+}
+
+func TestClientHTTP3(t *testing.T) {
+	srv, clientTLS := newTLSServer(t)
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h3 := &http3.Server{Handler: protoHandler, TLSConfig: http3.ConfigureTLSConfig(srv.TLS.Clone())}
+	go func() { _ = h3.Serve(conn) }()
+	defer h3.Close()
+
+	c := http3x.NewClient(http3x.Options{TLSClientConfig: clientTLS})
+	defer c.Close()
+
+	var got string
+	var client httpx.Client = c
+	client = httpx.SetResponseBodyString(client, &got)
+	client = httpx.SetRequest(client, http.MethodGet, "https://"+conn.LocalAddr().String())
+	if _, err = client.Do(nil); err != nil {
+		t.Fatal(err)
+	}
+	if got != "HTTP/3.0" {
+		t.Fatal(got)
+	}
+}
+
+func TestClientFallback(t *testing.T) {
+	srv, clientTLS := newTLSServer(t)
+	calls := 0
+	fallback := httpx.ClientFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return srv.Client().Do(req)
+	})
+	c := http3x.NewClient(http3x.Options{
+		TLSClientConfig: clientTLS,
+		QUICConfig:      &quic.Config{HandshakeIdleTimeout: 200 * time.Millisecond},
+		Fallback:        fallback,
+	})
+	defer c.Close()
+
+	// nothing listens for QUIC on the address of the TCP server
+	for i := 0; i < 2; i++ {
+		var got string
+		var client httpx.Client = c
+		client = httpx.SetResponseBodyString(client, &got)
+		client = httpx.SetRequest(client, http.MethodGet, srv.URL)
+		if _, err := client.Do(nil); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(got, "HTTP/1") && got != "HTTP/2.0" {
+			t.Fatal(got)
+		}
+	}
+	if calls != 2 {
+		t.Fatal(calls)
+	}
+}
@@ -0,0 +1,257 @@
+package httpx
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// HTTPFileRequest is a request parsed from a .http file
+type HTTPFileRequest struct {
+	// Name is the text after the ### separator or a # @name comment, if any
+	Name   string
+	Method string
+	URL    string
+	Header http.Header
+	Body   string
+}
+
+// HTTPFileResult is the outcome of one request run by RunHTTPFile
+type HTTPFileResult struct {
+	Request  HTTPFileRequest
+	Response *http.Response
+	// Body is the response body, which has been read and closed
+	Body []byte
+	Err  error
+}
+
+var httpFileVar = regexp.MustCompile(`\{\{\s*([$A-Za-z0-9_.\-]+)\s*\}\}`)
+
+var httpMethods = map[string]bool{
+	http.MethodGet: true, http.MethodHead: true, http.MethodPost: true, http.MethodPut: true, http.MethodPatch: true,
+	http.MethodDelete: true, http.MethodConnect: true, http.MethodOptions: true, http.MethodTrace: true,
+}
+
+// ParseHTTPFile parses requests in the .http format of the JetBrains HTTP client and the VS Code REST Client.
+//
+// Requests are separated by lines starting with ###. File variables are declared as "@name = value" and, like the
+// values in vars which take precedence, are substituted wherever {{name}} appears. The dynamic variables {{$uuid}},
+// {{$timestamp}} and {{$randomInt}} are also supported. A body line of the form "< path" includes a file relative
+// to dir. Response handler scripts are ignored. An undefined variable is an error
+func ParseHTTPFile(r io.Reader, dir string, vars map[string]string) ([]HTTPFileRequest, error) {
+	fileVars := map[string]string{}
+	expand := func(s string) (string, error) {
+		var err error
+		out := httpFileVar.ReplaceAllStringFunc(s, func(m string) string {
+			name := httpFileVar.FindStringSubmatch(m)[1]
+			if v, ok := vars[name]; ok {
+				return v
+			}
+			if v, ok := fileVars[name]; ok {
+				return v
+			}
+			if v, ok := dynamicHTTPFileVar(name); ok {
+				return v
+			}
+			if err == nil {
+				err = fmt.Errorf("undefined variable %q", name)
+			}
+			return m
+		})
+		return out, err
+	}
+
+	var requests []HTTPFileRequest
+	var cur *HTTPFileRequest
+	var body []string
+	inBody, inScript := false, false
+	finish := func() error {
+		if cur == nil {
+			return nil
+		}
+		b, err := expand(strings.TrimRight(strings.Join(body, "\n"), "\n"))
+		if err != nil {
+			return err
+		}
+		cur.Body = b
+		requests = append(requests, *cur)
+		cur, body, inBody = nil, nil, false
+		return nil
+	}
+
+	var name string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		trimmed := strings.TrimSpace(line)
+		fail := func(err error) ([]HTTPFileRequest, error) {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		switch {
+		case strings.HasPrefix(trimmed, "###"):
+			if err := finish(); err != nil {
+				return fail(err)
+			}
+			name = strings.TrimSpace(strings.TrimPrefix(trimmed, "###"))
+			continue
+		case inScript:
+			inScript = !strings.HasSuffix(trimmed, "%}")
+			continue
+		case strings.HasPrefix(trimmed, "> {%") || strings.HasPrefix(trimmed, ">> ") || strings.HasPrefix(trimmed, "<> "):
+			// response handlers and response references are not supported
+			inScript = strings.HasPrefix(trimmed, "> {%") && !strings.HasSuffix(trimmed, "%}")
+			continue
+		case strings.HasPrefix(trimmed, "> "):
+			continue
+		}
+		if inBody {
+			if strings.HasPrefix(trimmed, "< ") {
+				path := strings.TrimSpace(trimmed[2:])
+				if !filepath.IsAbs(path) {
+					path = filepath.Join(dir, path)
+				}
+				b, err := os.ReadFile(path)
+				if err != nil {
+					return fail(err)
+				}
+				line = string(b)
+			}
+			body = append(body, line)
+			continue
+		}
+		if strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "//") {
+			comment := strings.TrimSpace(strings.TrimLeft(trimmed, "#/"))
+			if strings.HasPrefix(comment, "@name") {
+				name = strings.TrimSpace(strings.TrimPrefix(comment, "@name"))
+			}
+			continue
+		}
+		if cur == nil {
+			if trimmed == "" {
+				continue
+			}
+			if strings.HasPrefix(trimmed, "@") {
+				k, v, ok := strings.Cut(trimmed[1:], "=")
+				if !ok {
+					return fail(fmt.Errorf("invalid variable declaration"))
+				}
+				v, err := expand(strings.TrimSpace(v))
+				if err != nil {
+					return fail(err)
+				}
+				fileVars[strings.TrimSpace(k)] = v
+				continue
+			}
+			method, target := http.MethodGet, trimmed
+			if first, rest, ok := strings.Cut(trimmed, " "); ok && httpMethods[strings.ToUpper(first)] {
+				method, target = strings.ToUpper(first), strings.TrimSpace(rest)
+			}
+			if i := strings.LastIndex(target, " HTTP/"); i >= 0 {
+				target = target[:i]
+			}
+			u, err := expand(target)
+			if err != nil {
+				return fail(err)
+			}
+			cur = &HTTPFileRequest{Name: name, Method: method, URL: u, Header: make(http.Header)}
+			name = ""
+			continue
+		}
+		if trimmed == "" {
+			inBody = true
+			continue
+		}
+		if line != trimmed && (trimmed[0] == '?' || trimmed[0] == '&') && len(cur.Header) == 0 {
+			// an indented continuation of the query string
+			part, err := expand(trimmed)
+			if err != nil {
+				return fail(err)
+			}
+			cur.URL += part
+			continue
+		}
+		k, v, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			return fail(fmt.Errorf("invalid header %q", trimmed))
+		}
+		v, err := expand(strings.TrimSpace(v))
+		if err != nil {
+			return fail(err)
+		}
+		cur.Header.Add(strings.TrimSpace(k), v)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := finish(); err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+// dynamicHTTPFileVar returns the value of a built in $ variable
+func dynamicHTTPFileVar(name string) (string, bool) {
+	switch name {
+	case "$uuid", "$random.uuid":
+		b := make([]byte, 16)
+		_, _ = rand.Read(b)
+		b[6] = b[6]&0x0f | 0x40
+		b[8] = b[8]&0x3f | 0x80
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), true
+	case "$timestamp":
+		return strconv.FormatInt(ClockFromContext(context.Background()).Now().Unix(), 10), true
+	case "$randomInt":
+		n, _ := rand.Int(rand.Reader, big.NewInt(1000))
+		return n.String(), true
+	}
+	return "", false
+}
+
+// RunHTTPFile parses the .http file at path, see ParseHTTPFile, and performs its requests in order through c.
+//
+// The error is only set if the file cannot be read or parsed; the outcome of each request is in its result
+func RunHTTPFile(ctx context.Context, c Client, path string, vars map[string]string) ([]HTTPFileResult, error) {
+	c = nilClientCheck(c)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	requests, err := ParseHTTPFile(f, filepath.Dir(path), vars)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	results := make([]HTTPFileResult, 0, len(requests))
+	for _, r := range requests {
+		res := HTTPFileResult{Request: r}
+		var body io.Reader
+		if r.Body != "" {
+			body = strings.NewReader(r.Body)
+		}
+		req, err := http.NewRequestWithContext(ctx, r.Method, r.URL, body)
+		if err != nil {
+			res.Err = err
+			results = append(results, res)
+			continue
+		}
+		for k, v := range r.Header {
+			req.Header[k] = v
+		}
+		if host := req.Header.Get("Host"); host != "" {
+			req.Host = host
+		}
+		res.Response, res.Err = SetResponseBodyBytes(c, &res.Body).Do(req)
+		results = append(results, res)
+	}
+	return results, nil
+}
@@ -0,0 +1,109 @@
+package httpx_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tflyons/httpx"
+)
+
+func TestParseHTTPFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "body.json"), []byte(`{"from":"file"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	src := `@host = example.com
+@token = abc
+
+### list things
+GET https://{{host}}/things
+    ?page=1
+    &size={{size}}
+Authorization: Bearer {{token}}
+
+###
+# @name create
+POST https://{{host}}/things HTTP/1.1
+Content-Type: application/json
+
+{"name": "{{name}}"}
+
+> {%
+    client.global.set("id", response.body.id);
+%}
+
+###
+https://{{host}}/plain
+
+###
+PUT https://{{host}}/file
+
+< ./body.json
+`
+	requests, err := httpx.ParseHTTPFile(strings.NewReader(src), dir, map[string]string{"size": "10", "name": "bob", "token": "override"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 4 {
+		t.Fatalf("expected 4 requests, got %d", len(requests))
+	}
+	r := requests[0]
+	if r.Name != "list things" || r.Method != http.MethodGet || r.URL != "https://example.com/things?page=1&size=10" {
+		t.Fatalf("%+v", r)
+	}
+	if got := r.Header.Get("Authorization"); got != "Bearer override" {
+		t.Fatal(got)
+	}
+	r = requests[1]
+	if r.Name != "create" || r.Method != http.MethodPost || r.URL != "https://example.com/things" || r.Body != `{"name": "bob"}` {
+		t.Fatalf("%+v", r)
+	}
+	if r = requests[2]; r.Method != http.MethodGet || r.URL != "https://example.com/plain" || r.Body != "" {
+		t.Fatalf("%+v", r)
+	}
+	if r = requests[3]; r.Body != `{"from":"file"}` {
+		t.Fatalf("%+v", r)
+	}
+
+	if _, err := httpx.ParseHTTPFile(strings.NewReader("GET https://{{missing}}/"), dir, nil); err == nil {
+		t.Fatal("expected undefined variable error")
+	}
+}
+
+func TestRunHTTPFile(t *testing.T) {
+	srv := httptest.NewServer(echoHandler)
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "requests.http")
+	src := "@greeting = hello\n\nPOST {{base}}/echo\nX-Id: {{$uuid}}\n\n{{greeting}} world\n\n###\nGET {{base}}/\n"
+	if err := os.WriteFile(path, []byte(src), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c := httpx.SetHeader(srv.Client(), "X-Chain", "yes")
+	results, err := httpx.RunHTTPFile(context.Background(), c, path, map[string]string{"base": srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	for _, res := range results {
+		if res.Err != nil {
+			t.Fatal(res.Err)
+		}
+		if res.Response.Header.Get("X-Chain") != "yes" {
+			t.Fatal("request did not pass through the chain")
+		}
+	}
+	if string(results[0].Body) != "hello world" {
+		t.Fatal(string(results[0].Body))
+	}
+	if len(results[0].Response.Header.Get("X-Id")) != 36 {
+		t.Fatal(results[0].Response.Header.Get("X-Id"))
+	}
+}
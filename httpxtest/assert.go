@@ -0,0 +1,91 @@
+package httpxtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// AssertStatus reports an error if resp does not have the status code
+func AssertStatus(t testing.TB, resp *http.Response, code int) {
+	t.Helper()
+	if resp == nil {
+		t.Errorf("expected status %d, got no response", code)
+		return
+	}
+	if resp.StatusCode != code {
+		t.Errorf("expected status %d, got %d: %s", code, resp.StatusCode, peekBody(resp))
+	}
+}
+
+// AssertHeader reports an error if the first value of the response header key is not value
+func AssertHeader(t testing.TB, resp *http.Response, key, value string) {
+	t.Helper()
+	if resp == nil {
+		t.Errorf("expected header %s: %q, got no response", key, value)
+		return
+	}
+	if _, ok := resp.Header[http.CanonicalHeaderKey(key)]; !ok {
+		t.Errorf("expected header %s: %q, got none", key, value)
+		return
+	}
+	if got := resp.Header.Get(key); got != value {
+		t.Errorf("expected header %s: %q, got %q", key, value, got)
+	}
+}
+
+// AssertJSONBody reports an error if the JSON body of resp does not decode to want. The body is decoded into a new
+// value of the type of want, so want can be a struct, a map or a slice, and compared with reflect.DeepEqual.
+//
+// The body is read in full and replaced, so it can still be read after the assertion and assertions can be combined
+func AssertJSONBody(t testing.TB, resp *http.Response, want any) {
+	t.Helper()
+	if resp == nil {
+		t.Errorf("expected JSON body, got no response")
+		return
+	}
+	body, err := rewindBody(resp)
+	if err != nil {
+		t.Errorf("could not read response body: %v", err)
+		return
+	}
+	var got any
+	if want == nil {
+		err = json.Unmarshal(body, &got)
+	} else {
+		ptr := reflect.New(reflect.TypeOf(want))
+		err = json.Unmarshal(body, ptr.Interface())
+		got = ptr.Elem().Interface()
+	}
+	if err != nil {
+		t.Errorf("could not unmarshal response body %s: %v", body, err)
+		return
+	}
+	if !reflect.DeepEqual(got, want) {
+		wantJSON, _ := json.Marshal(want)
+		t.Errorf("unexpected response body\n--- want\n%s\n--- got\n%s", wantJSON, body)
+	}
+}
+
+// rewindBody reads the body of resp and replaces it with a reader over the same bytes
+func rewindBody(resp *http.Response) ([]byte, error) {
+	if resp.Body == nil || resp.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return body, err
+}
+
+// peekBody returns the start of the body of resp for an error message, leaving the body readable
+func peekBody(resp *http.Response) []byte {
+	body, _ := rewindBody(resp)
+	if len(body) > 512 {
+		return append(body[:512:512], "..."...)
+	}
+	return body
+}
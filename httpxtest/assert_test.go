@@ -0,0 +1,73 @@
+package httpxtest_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tflyons/httpx/httpxtest"
+)
+
+// recorder collects the errors reported by an assertion instead of failing the test
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+type thing struct {
+	Name string `json:"name"`
+	Size int    `json:"size"`
+}
+
+func TestAssertions(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Foo", "bar")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"name":"thing","size":3}`))
+	}))
+	defer srv.Close()
+	resp, err := srv.Client().Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	tests := map[string]struct {
+		assert func(t testing.TB)
+		fails  bool
+	}{
+		"status":             {func(t testing.TB) { httpxtest.AssertStatus(t, resp, http.StatusCreated) }, false},
+		"wrong status":       {func(t testing.TB) { httpxtest.AssertStatus(t, resp, http.StatusOK) }, true},
+		"header":             {func(t testing.TB) { httpxtest.AssertHeader(t, resp, "x-foo", "bar") }, false},
+		"wrong header":       {func(t testing.TB) { httpxtest.AssertHeader(t, resp, "X-Foo", "baz") }, true},
+		"missing header":     {func(t testing.TB) { httpxtest.AssertHeader(t, resp, "X-Bar", "") }, true},
+		"struct body":        {func(t testing.TB) { httpxtest.AssertJSONBody(t, resp, thing{Name: "thing", Size: 3}) }, false},
+		"wrong struct body":  {func(t testing.TB) { httpxtest.AssertJSONBody(t, resp, thing{Name: "thing"}) }, true},
+		"map body":           {func(t testing.TB) { httpxtest.AssertJSONBody(t, resp, map[string]any{"name": "thing", "size": 3.0}) }, false},
+		"wrong type of body": {func(t testing.TB) { httpxtest.AssertJSONBody(t, resp, []string{}) }, true},
+		"no response":        {func(t testing.TB) { httpxtest.AssertStatus(t, nil, http.StatusOK) }, true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := &recorder{TB: t}
+			tt.assert(r)
+			if tt.fails != (len(r.errors) > 0) {
+				t.Fatal("unexpected result of the assertion", r.errors)
+			}
+		})
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil || string(body) != `{"name":"thing","size":3}` {
+		t.Fatal("assertions should leave the body readable", string(body), err)
+	}
+}
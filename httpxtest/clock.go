@@ -0,0 +1,143 @@
+// Package httpxtest provides helpers for testing code built on httpx.
+package httpxtest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tflyons/httpx"
+)
+
+// Clock is a fake httpx.Clock whose time only moves when Advance or Set is called.
+// Use it with httpx.SetClock or httpx.SetDefaultClock to test time-based decorators deterministically
+type Clock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []*waiter
+	// gen changes whenever the clock is used so that AutoAdvance can tell a quiet clock from a busy one
+	gen uint64
+}
+
+type waiter struct {
+	at     time.Time
+	period time.Duration
+	ch     chan time.Time
+}
+
+// NewClock returns a fake clock set to start
+func NewClock(start time.Time) *Clock {
+	c := &Clock{now: start}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// Now returns the fake time
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	return c.now
+}
+
+// After returns a channel that receives the fake time once it has advanced by d
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.addWaiter(&waiter{at: c.now.Add(d), ch: ch})
+	return ch
+}
+
+// NewTicker returns a ticker that ticks every d of fake time. Like time.Ticker, ticks are dropped if the
+// receiver falls behind
+func (c *Clock) NewTicker(d time.Duration) httpx.Ticker {
+	if d <= 0 {
+		panic("httpxtest: non-positive interval for NewTicker")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w := &waiter{at: c.now.Add(d), period: d, ch: make(chan time.Time, 1)}
+	c.addWaiter(w)
+	return &ticker{clock: c, w: w}
+}
+
+// Advance moves the fake time forward by d, firing any timers and tickers that become due
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setLocked(c.now.Add(d))
+}
+
+// Set moves the fake time to t, firing any timers and tickers that become due. Time never moves backwards
+func (c *Clock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if t.After(c.now) {
+		c.setLocked(t)
+	}
+}
+
+// BlockUntil waits until at least n timers and tickers are waiting on the clock. It lets a test advance the
+// clock only after the code under test has started waiting
+func (c *Clock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.waiters) < n {
+		c.cond.Wait()
+	}
+}
+
+func (c *Clock) addWaiter(w *waiter) {
+	c.waiters = append(c.waiters, w)
+	c.gen++
+	c.cond.Broadcast()
+}
+
+func (c *Clock) setLocked(t time.Time) {
+	c.gen++
+	// fire in time order so that tickers observe every intermediate tick they can hold
+	for {
+		sort.Slice(c.waiters, func(i, j int) bool { return c.waiters[i].at.Before(c.waiters[j].at) })
+		if len(c.waiters) == 0 || c.waiters[0].at.After(t) {
+			break
+		}
+		w := c.waiters[0]
+		c.now = w.at
+		select {
+		case w.ch <- w.at:
+		default:
+		}
+		if w.period > 0 {
+			w.at = w.at.Add(w.period)
+		} else {
+			c.waiters = c.waiters[1:]
+		}
+	}
+	c.now = t
+}
+
+func (c *Clock) remove(w *waiter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	for i := range c.waiters {
+		if c.waiters[i] == w {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+type ticker struct {
+	clock *Clock
+	w     *waiter
+}
+
+func (t *ticker) C() <-chan time.Time { return t.w.ch }
+func (t *ticker) Stop()               { t.clock.remove(t.w) }
@@ -0,0 +1,46 @@
+package httpxtest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx/httpxtest"
+)
+
+func TestClock(t *testing.T) {
+	start := time.Date(2022, 10, 6, 0, 0, 0, 0, time.UTC)
+	c := httpxtest.NewClock(start)
+
+	after := c.After(time.Second)
+	tick := c.NewTicker(400 * time.Millisecond)
+	c.BlockUntil(2)
+
+	c.Advance(500 * time.Millisecond)
+	select {
+	case <-after:
+		t.Fatal("fired early")
+	case got := <-tick.C():
+		if !got.Equal(start.Add(400 * time.Millisecond)) {
+			t.Fatal(got)
+		}
+	}
+
+	c.Advance(time.Second)
+	if got := <-after; !got.Equal(start.Add(time.Second)) {
+		t.Fatal(got)
+	}
+	// the ticker holds one pending tick and drops the rest
+	if got := <-tick.C(); !got.Equal(start.Add(800 * time.Millisecond)) {
+		t.Fatal(got)
+	}
+	tick.Stop()
+	c.Advance(time.Second)
+	select {
+	case <-tick.C():
+		t.Fatal("stopped ticker fired")
+	default:
+	}
+	if !c.Now().Equal(start.Add(2500 * time.Millisecond)) {
+		t.Fatal(c.Now())
+	}
+}
@@ -0,0 +1,71 @@
+package httpxtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tflyons/httpx"
+)
+
+// update rewrites golden files instead of comparing against them, run go test with -update after an intended change
+var update = flag.Bool("update", false, "update httpxtest golden files")
+
+// AssertRequestGolden fails the test if req does not match the golden file at path.
+//
+// The request is serialized as method, request URI, host, sorted headers and body with the credentials and personal
+// data of httpx.NewDefaultScrubber masked, and JSON bodies indented so that changes to a decoration chain show up as
+// readable diffs in review. The body is replaced so the request can still be sent. Running the test with -update
+// writes the golden file instead
+func AssertRequestGolden(t testing.TB, req *http.Request, path string) {
+	t.Helper()
+	got, err := goldenRequest(req)
+	if err != nil {
+		t.Fatalf("could not serialize request: %v", err)
+	}
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("could not read golden file, run with -update to create it: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("request does not match %s, run with -update if the change is intended\n--- want\n%s\n--- got\n%s", path, want, got)
+	}
+}
+
+// goldenRequest returns the stable text form of the request
+func goldenRequest(req *http.Request) ([]byte, error) {
+	b, err := httpx.NewDefaultScrubber().DumpRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	b = bytes.ReplaceAll(b, []byte("\r\n"), []byte("\n"))
+	if head, body, ok := bytes.Cut(b, []byte("\n\n")); ok && len(body) > 0 {
+		mt, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+		if mt == "application/json" || strings.HasSuffix(mt, "+json") {
+			var buf bytes.Buffer
+			if json.Indent(&buf, body, "", "  ") == nil {
+				body = buf.Bytes()
+			}
+		}
+		b = append(append(head, "\n\n"...), body...)
+	}
+	if !bytes.HasSuffix(b, []byte("\n")) {
+		b = append(b, '\n')
+	}
+	return b, nil
+}
@@ -0,0 +1,23 @@
+package httpxtest_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/httpxtest"
+)
+
+func TestAssertRequestGolden(t *testing.T) {
+	var c httpx.Client = httpx.ClientFunc(func(req *http.Request) (*http.Response, error) {
+		httpxtest.AssertRequestGolden(t, req, "testdata/create_thing.golden")
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+	c = httpx.SetHeader(c, "Authorization", "Bearer secret-token")
+	c = httpx.SetHeader(c, "X-Request-Id", "42")
+	c = httpx.SetRequestBodyJSON(c, map[string]any{"name": "thing", "size": 3})
+	c = httpx.SetRequest(c, http.MethodPost, "https://api.example.com/things?dry_run=true")
+	if _, err := c.Do(nil); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,255 @@
+package httpxtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Responder replies to requests with canned responses chosen by route. It is both an httpx.Client, for tests that
+// need no network, and an http.Handler, for use with httptest.Server.
+//
+// Routes are matched in the order they were added. A request that matches no route receives a 404 and fails
+// AssertExpectations. The zero value is ready to use
+type Responder struct {
+	mu        sync.Mutex
+	routes    []*Route
+	unmatched []string
+}
+
+// Route is a pattern with a sequence of replies, see Responder.On
+type Route struct {
+	method   string
+	segments []string
+
+	mu       sync.Mutex
+	replies  []reply
+	calls    int
+	times    int
+	requests []*http.Request
+}
+
+type reply struct {
+	handler http.HandlerFunc
+	err     error
+}
+
+// On adds a route for pattern, a path with an optional method prefix such as "GET /users/{id}". A {name} segment
+// matches any single path segment and its value is available to reply functions with PathParam
+func (r *Responder) On(pattern string) *Route {
+	rt := &Route{times: -1}
+	if method, path, ok := strings.Cut(pattern, " "); ok {
+		rt.method, pattern = method, strings.TrimSpace(path)
+	}
+	rt.segments = strings.Split(strings.Trim(pattern, "/"), "/")
+	r.mu.Lock()
+	r.routes = append(r.routes, rt)
+	r.mu.Unlock()
+	return rt
+}
+
+// Reply adds a response to the sequence of the route. The body may be a string, a byte slice or a value that is
+// encoded as JSON; headers are given as key value pairs. Each call is answered with the next reply in the sequence
+// and the last reply is repeated once the sequence is used up
+func (rt *Route) Reply(status int, body any, headers ...string) *Route {
+	var b []byte
+	contentType := ""
+	switch v := body.(type) {
+	case nil:
+	case string:
+		b = []byte(v)
+	case []byte:
+		b = v
+	default:
+		var err error
+		if b, err = json.Marshal(v); err != nil {
+			panic(fmt.Sprintf("httpxtest: could not encode reply body: %v", err))
+		}
+		contentType = "application/json"
+	}
+	return rt.ReplyFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if contentType != "" {
+			w.Header().Set("Content-Type", contentType)
+		}
+		for i := 0; i+1 < len(headers); i += 2 {
+			w.Header().Add(headers[i], headers[i+1])
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write(b)
+	})
+}
+
+// ReplyFunc adds a reply that is written by fn
+func (rt *Route) ReplyFunc(fn http.HandlerFunc) *Route {
+	rt.mu.Lock()
+	rt.replies = append(rt.replies, reply{handler: fn})
+	rt.mu.Unlock()
+	return rt
+}
+
+// ReplyError adds a reply that fails with err, as if the connection failed. When used as an http.Handler the
+// connection is aborted instead
+func (rt *Route) ReplyError(err error) *Route {
+	rt.mu.Lock()
+	rt.replies = append(rt.replies, reply{err: err})
+	rt.mu.Unlock()
+	return rt
+}
+
+// Times expects the route to be called exactly n times, see AssertExpectations
+func (rt *Route) Times(n int) *Route {
+	rt.mu.Lock()
+	rt.times = n
+	rt.mu.Unlock()
+	return rt
+}
+
+// Calls returns the number of requests the route has answered
+func (rt *Route) Calls() int {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	return rt.calls
+}
+
+// Requests returns the requests the route has answered. Their bodies can be read again
+func (rt *Route) Requests() []*http.Request {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	return append([]*http.Request(nil), rt.requests...)
+}
+
+func (rt *Route) String() string {
+	return strings.TrimSpace(rt.method + " /" + strings.Join(rt.segments, "/"))
+}
+
+// match returns the path parameters of req if it matches the route
+func (rt *Route) match(req *http.Request) (map[string]string, bool) {
+	if rt.method != "" && rt.method != req.Method {
+		return nil, false
+	}
+	segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	if len(segments) != len(rt.segments) {
+		return nil, false
+	}
+	params := make(map[string]string)
+	for i, s := range rt.segments {
+		if strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") {
+			params[s[1:len(s)-1]] = segments[i]
+		} else if s != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// next records the call and returns the reply to send
+func (rt *Route) next(req *http.Request, body []byte) (reply, bool) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	recorded := req.Clone(req.Context())
+	recorded.Body = io.NopCloser(bytes.NewReader(body))
+	rt.requests = append(rt.requests, recorded)
+	rt.calls++
+	if len(rt.replies) == 0 {
+		return reply{}, false
+	}
+	i := rt.calls - 1
+	if i >= len(rt.replies) {
+		i = len(rt.replies) - 1
+	}
+	return rt.replies[i], true
+}
+
+type pathParamsKey struct{}
+
+// PathParam returns the value of the {name} segment of the route that matched the request
+func PathParam(req *http.Request, name string) string {
+	params, _ := req.Context().Value(pathParamsKey{}).(map[string]string)
+	return params[name]
+}
+
+// route finds the reply for req, with a nil handler and error if no route matched
+func (r *Responder) route(req *http.Request) (*http.Request, reply, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return req, reply{}, err
+		}
+		req.Body.Close()
+	}
+	r.mu.Lock()
+	routes := r.routes
+	r.mu.Unlock()
+	for _, rt := range routes {
+		params, ok := rt.match(req)
+		if !ok {
+			continue
+		}
+		req = req.WithContext(context.WithValue(req.Context(), pathParamsKey{}, params))
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		rep, ok := rt.next(req, body)
+		if !ok {
+			rep.handler = func(w http.ResponseWriter, _ *http.Request) {
+				http.Error(w, fmt.Sprintf("httpxtest: no reply for %s", rt), http.StatusNotImplemented)
+			}
+		}
+		return req, rep, nil
+	}
+	r.mu.Lock()
+	r.unmatched = append(r.unmatched, req.Method+" "+req.URL.Path)
+	r.mu.Unlock()
+	return req, reply{handler: func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, fmt.Sprintf("httpxtest: no route for %s %s", req.Method, req.URL.Path), http.StatusNotFound)
+	}}, nil
+}
+
+// Do answers req without a network round trip
+func (r *Responder) Do(req *http.Request) (*http.Response, error) {
+	req, rep, err := r.route(req)
+	if err != nil {
+		return nil, err
+	}
+	if rep.err != nil {
+		return nil, rep.err
+	}
+	rec := httptest.NewRecorder()
+	rep.handler(rec, req)
+	resp := rec.Result()
+	resp.Request = req
+	return resp, nil
+}
+
+// ServeHTTP answers requests to a server
+func (r *Responder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	req, rep, err := r.route(req)
+	if err != nil || rep.err != nil {
+		panic(http.ErrAbortHandler)
+	}
+	rep.handler(w, req)
+}
+
+// AssertExpectations fails the test if a request matched no route or a route was not called the number of times
+// set with Times
+func (r *Responder) AssertExpectations(t testing.TB) {
+	t.Helper()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, u := range r.unmatched {
+		t.Errorf("httpxtest: unexpected request %s", u)
+	}
+	for _, rt := range r.routes {
+		rt.mu.Lock()
+		if rt.times >= 0 && rt.calls != rt.times {
+			t.Errorf("httpxtest: expected %s to be called %d times, got %d", rt, rt.times, rt.calls)
+		}
+		rt.mu.Unlock()
+	}
+}
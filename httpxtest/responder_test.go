@@ -0,0 +1,84 @@
+package httpxtest_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/httpxtest"
+)
+
+func TestResponder_Client(t *testing.T) {
+	r := &httpxtest.Responder{}
+	users := r.On("GET /users/{id}").
+		Reply(http.StatusServiceUnavailable, nil).
+		ReplyError(errors.New("connection reset")).
+		ReplyFunc(func(w http.ResponseWriter, req *http.Request) {
+			_, _ = w.Write([]byte("user " + httpxtest.PathParam(req, "id")))
+		}).
+		Times(3)
+	created := r.On("POST /users").Reply(http.StatusCreated, map[string]int{"id": 7}, "Location", "/users/7").Times(1)
+
+	var body string
+	var c httpx.Client = r
+	c = httpx.SetResponseBodyString(c, &body)
+	c = httpx.SetRetry(c, httpx.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond})
+	resp, err := httpx.SetRequest(c, http.MethodGet, "http://example.com/users/42").Do(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || body != "user 42" || users.Calls() != 3 {
+		t.Fatal(resp.StatusCode, body, users.Calls())
+	}
+
+	var out map[string]int
+	c = httpx.SetRequestBodyJSON(httpx.SetResponseBodyHandlerJSON(r, &out), map[string]string{"name": "bob"})
+	resp, err = httpx.SetRequest(c, http.MethodPost, "http://example.com/users").Do(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusCreated || resp.Header.Get("Location") != "/users/7" || out["id"] != 7 {
+		t.Fatal(resp.StatusCode, resp.Header, out)
+	}
+	sent, _ := io.ReadAll(created.Requests()[0].Body)
+	if string(sent) != `{"name":"bob"}` {
+		t.Fatal(string(sent))
+	}
+	r.AssertExpectations(t)
+}
+
+func TestResponder_Handler(t *testing.T) {
+	r := &httpxtest.Responder{}
+	r.On("/ping").Reply(http.StatusOK, "pong")
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	var body string
+	resp, err := httpx.SetRequest(httpx.SetResponseBodyString(srv.Client(), &body), http.MethodGet, srv.URL+"/ping").Do(nil)
+	if err != nil || resp.StatusCode != http.StatusOK || body != "pong" {
+		t.Fatal(resp, err, body)
+	}
+	resp, err = httpx.SetRequest(srv.Client(), http.MethodGet, srv.URL+"/missing").Do(nil)
+	if err != nil || resp.StatusCode != http.StatusNotFound {
+		t.Fatal(resp, err)
+	}
+	resp.Body.Close()
+
+	ft := &fakeT{TB: t}
+	r.AssertExpectations(ft)
+	if ft.errors != 1 {
+		t.Fatal("expected the unmatched request to fail the test")
+	}
+}
+
+type fakeT struct {
+	testing.TB
+	errors int
+}
+
+func (f *fakeT) Helper()                       {}
+func (f *fakeT) Errorf(string, ...interface{}) { f.errors++ }
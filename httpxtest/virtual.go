@@ -0,0 +1,118 @@
+package httpxtest
+
+import (
+	"bytes"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/tflyons/httpx"
+)
+
+// quietPolls is the number of consecutive polls, taken pollInterval apart, during which every goroutine must be
+// blocked and the clock unused before AutoAdvance moves the clock
+const (
+	quietPolls   = 5
+	pollInterval = 100 * time.Microsecond
+)
+
+// AutoAdvance moves the clock to its next timer whenever every other goroutine has been blocked for a moment
+// without using the clock, so that code waiting on the clock runs without real delays. It returns a function that
+// stops advancing.
+//
+// Goroutines waiting on the network count as blocked, so time only moves as a test expects if requests are answered
+// in process, for example by a Responder. A timer that nothing waits for anymore is still fired when it is next
+func (c *Clock) AutoAdvance() (stop func()) {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		quiet, gen := 0, uint64(0)
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(pollInterval):
+			}
+			c.mu.Lock()
+			pending, cur := len(c.waiters), c.gen
+			c.mu.Unlock()
+			if pending == 0 || cur != gen || !othersBlocked() {
+				quiet, gen = 0, cur
+				continue
+			}
+			if quiet++; quiet < quietPolls {
+				continue
+			}
+			c.mu.Lock()
+			if c.gen == gen && len(c.waiters) > 0 {
+				next := c.waiters[0].at
+				for _, w := range c.waiters[1:] {
+					if w.at.Before(next) {
+						next = w.at
+					}
+				}
+				c.setLocked(next)
+			}
+			gen = c.gen
+			c.mu.Unlock()
+			quiet = 0
+		}
+	}()
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// RunVirtual calls fn with a fake clock, starting at start, installed as the default clock and advanced by
+// AutoAdvance. Tests of rate limits, retries and other waits then take as long as the work they do rather than the
+// time they wait. The system clock is restored when fn returns
+func RunVirtual(start time.Time, fn func(clock *Clock)) {
+	clock := NewClock(start)
+	httpx.SetDefaultClock(clock)
+	defer httpx.SetDefaultClock(nil)
+	stop := clock.AutoAdvance()
+	defer stop()
+	fn(clock)
+}
+
+// busyStates are the goroutine states that may still lead to a new timer or a use of the clock
+var busyStates = map[string]bool{
+	"running":   true,
+	"runnable":  true,
+	"syscall":   true,
+	"copystack": true,
+	"preempted": true,
+}
+
+// othersBlocked reports whether every goroutine other than the caller is blocked
+func othersBlocked() bool {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	// the first goroutine is the caller
+	for i, g := range bytes.Split(buf, []byte("\n\n")) {
+		if i == 0 {
+			continue
+		}
+		header, _, _ := bytes.Cut(g, []byte("\n"))
+		_, state, ok := bytes.Cut(header, []byte(" ["))
+		if !ok {
+			continue
+		}
+		state, _, _ = bytes.Cut(state, []byte("]"))
+		state, _, _ = bytes.Cut(state, []byte(","))
+		if busyStates[string(state)] {
+			return false
+		}
+	}
+	return true
+}
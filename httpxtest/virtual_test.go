@@ -0,0 +1,43 @@
+package httpxtest_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/httpxtest"
+)
+
+func TestRunVirtual(t *testing.T) {
+	start := time.Date(2022, 10, 6, 0, 0, 0, 0, time.UTC)
+	began := time.Now()
+	httpxtest.RunVirtual(start, func(clock *httpxtest.Clock) {
+		r := &httpxtest.Responder{}
+		r.On("GET /").Reply(http.StatusServiceUnavailable, nil).Reply(http.StatusServiceUnavailable, nil).Reply(http.StatusOK, nil)
+
+		var c httpx.Client = r
+		c = httpx.SetRetry(c, httpx.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Minute, MaxBackoff: time.Hour})
+		c = httpx.SetRateLimit(c, 1, time.Hour)
+		c = httpx.SetRequest(c, http.MethodGet, "http://example.com/")
+
+		// waits one minute and then two minutes to retry
+		resp, err := c.Do(nil)
+		if err != nil || resp.StatusCode != http.StatusOK {
+			t.Fatal(resp, err)
+		}
+		if got := clock.Now().Sub(start); got != 3*time.Minute {
+			t.Fatal(got)
+		}
+		// the rate limit window ends an hour after the first request
+		if _, err := c.Do(nil); err != nil {
+			t.Fatal(err)
+		}
+		if got := clock.Now().Sub(start); got != time.Hour {
+			t.Fatal(got)
+		}
+	})
+	if elapsed := time.Since(began); elapsed > 10*time.Second {
+		t.Fatal("virtual time ran in real time", elapsed)
+	}
+}
@@ -0,0 +1,107 @@
+package httpx
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// ErrClientClosed is returned for requests made through SetInFlight after Shutdown was called
+var ErrClientClosed = fmt.Errorf("client is shut down")
+
+// InFlight counts the requests in flight through SetInFlight so that they can be drained before a service exits,
+// for example on SIGTERM. The zero value is ready to use
+type InFlight struct {
+	mu     sync.Mutex
+	count  int64
+	closed bool
+	// idle is closed once the count reaches zero after Shutdown
+	idle chan struct{}
+}
+
+// Count returns the number of requests in flight
+func (f *InFlight) Count() int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.count
+}
+
+// Shutdown stops new requests, which fail with ErrClientClosed, and waits until the requests in flight are done
+// or ctx is done. It can be called again, for example with a longer deadline
+func (f *InFlight) Shutdown(ctx context.Context) error {
+	f.mu.Lock()
+	f.closed = true
+	if f.idle == nil {
+		f.idle = make(chan struct{})
+		if f.count == 0 {
+			close(f.idle)
+		}
+	}
+	idle := f.idle
+	f.mu.Unlock()
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%d requests still in flight: %w", f.Count(), ctx.Err())
+	}
+}
+
+func (f *InFlight) add() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return false
+	}
+	f.count++
+	return true
+}
+
+func (f *InFlight) done() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.count--; f.count == 0 && f.idle != nil {
+		close(f.idle)
+	}
+}
+
+// SetInFlight tracks the requests made through c in f. A request is in flight until its response body has been
+// read to the end or closed, so a caller that never closes a body keeps Shutdown waiting until its context is done
+func SetInFlight(c Client, f *InFlight) ClientFunc {
+	c = nilClientCheck(c)
+	return func(req *http.Request) (*http.Response, error) {
+		if !f.add() {
+			return nil, ErrClientClosed
+		}
+		resp, err := c.Do(req)
+		if resp == nil || resp.Body == nil || resp.Body == http.NoBody {
+			f.done()
+			return resp, err
+		}
+		resp.Body = &inFlightBody{ReadCloser: resp.Body, done: f.done}
+		return resp, err
+	}
+}
+
+// inFlightBody calls done once when the body is read to the end or closed
+type inFlightBody struct {
+	io.ReadCloser
+	once sync.Once
+	done func()
+}
+
+func (b *inFlightBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.once.Do(b.done)
+	}
+	return n, err
+}
+
+func (b *inFlightBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.done)
+	return err
+}
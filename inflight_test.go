@@ -0,0 +1,52 @@
+package httpx_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx"
+)
+
+func TestSetInFlight(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		_, _ = w.Write([]byte("done"))
+	}))
+	defer srv.Close()
+
+	var f httpx.InFlight
+	var body string
+	c := httpx.SetResponseBodyString(httpx.SetInFlight(srv.Client(), &f), &body)
+	errs := make(chan error)
+	go func() {
+		_, err := httpx.SetRequest(c, http.MethodGet, srv.URL).Do(nil)
+		errs <- err
+	}()
+	for f.Count() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// the request in flight holds up the shutdown
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := f.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal(err)
+	}
+	// new requests are refused
+	if _, err := httpx.SetRequest(c, http.MethodGet, srv.URL).Do(nil); !errors.Is(err, httpx.ErrClientClosed) {
+		t.Fatal(err)
+	}
+
+	close(release)
+	if err := f.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errs; err != nil || body != "done" || f.Count() != 0 {
+		t.Fatal(err, body, f.Count())
+	}
+}
@@ -0,0 +1,161 @@
+package httpx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Initializer is a function signature that accepts a Client and returns either a client function or an error
+type Initializer func(Client) (ClientFunc, error)
+
+// ErrInitializerBackoff is returned by fail fast initializers while they wait to retry a failed init
+var ErrInitializerBackoff = fmt.Errorf("initializer is backing off after a failure")
+
+// InitializerBackoffError is returned by fail fast initializers while they back off. It matches
+// ErrInitializerBackoff and unwraps to the error of the last init
+type InitializerBackoffError struct {
+	Err error
+}
+
+func (e *InitializerBackoffError) Error() string {
+	return fmt.Sprintf("%s: %v", ErrInitializerBackoff, e.Err)
+}
+
+// Is reports whether target is ErrInitializerBackoff
+func (e *InitializerBackoffError) Is(target error) bool {
+	return target == ErrInitializerBackoff
+}
+
+// Unwrap returns the error of the last init
+func (e *InitializerBackoffError) Unwrap() error {
+	return e.Err
+}
+
+// SetInitializer is a helper function for constructing clients that may need to initialize with some
+// external dependency. It will retry the init function until it suceeds.
+// Requests waiting for another request to run init give up when their context is done
+func SetInitializer(c Client, init Initializer) ClientFunc {
+	f, _ := SetInitializerWithPolicy(c, init, InitializerPolicy{})
+	return f
+}
+
+// InitializerPolicy configures SetInitializerWithPolicy
+type InitializerPolicy struct {
+	// TTL is how long an initialized client is used before init runs again, forever if zero.
+	// It is measured by the clock of the request, see SetClock
+	TTL time.Duration
+	// InitialBackoff is the wait after a failed init before it is tried again, doubling for each further
+	// failure up to MaxBackoff, which defaults to one minute. Init is retried by the next request if zero
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Backoff replaces the doubling of InitialBackoff when set. It is called with the number of failed inits in a
+	// row and their last error, init is retried by the next request once it returns false
+	Backoff Backoff
+	// FailFast makes requests made while init is backing off fail with an *InitializerBackoffError holding the
+	// last init error instead of waiting for the next attempt
+	FailFast bool
+}
+
+// SetInitializerWithPolicy is SetInitializer with control over how long the initialized state is kept and how
+// failed initialization is retried.
+//
+// The returned invalidate function discards the initialized client, for example when a token has been revoked
+// or a discovered endpoint has moved, so that the next request runs init again. Requests already using the old
+// client are not affected
+func SetInitializerWithPolicy(c Client, init Initializer, policy InitializerPolicy) (f ClientFunc, invalidate func()) {
+	c = nilClientCheck(c)
+	if policy.InitialBackoff > 0 && policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = time.Minute
+	}
+	backoff := policy.Backoff
+	if backoff == nil && policy.InitialBackoff > 0 {
+		backoff = ExponentialBackoff{Initial: policy.InitialBackoff, Max: policy.MaxBackoff}
+	}
+	var (
+		mu      sync.Mutex
+		current ClientFunc
+		expires time.Time
+		// lastErr, retryAt and failures describe the last failed inits
+		lastErr  error
+		retryAt  time.Time
+		failures int
+	)
+	// oneAtATime ensures only one request runs init while the others wait for its result
+	oneAtATime := make(chan struct{}, 1)
+	load := func(now time.Time) (ClientFunc, time.Duration, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if current != nil && policy.TTL > 0 && !now.Before(expires) {
+			current = nil
+		}
+		if current == nil && now.Before(retryAt) {
+			return nil, retryAt.Sub(now), lastErr
+		}
+		return current, 0, nil
+	}
+
+	// initialize runs init, or waits for the request already running it, unless the context is done first
+	initialize := func(ctx context.Context, clock Clock) (ClientFunc, error) {
+		select {
+		case oneAtATime <- struct{}{}:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("request cancelled while waiting for initialization: %w", ctx.Err())
+		}
+		defer func() { <-oneAtATime }()
+		for {
+			next, wait, err := load(clock.Now())
+			if next != nil {
+				return next, nil
+			}
+			if wait > 0 {
+				if policy.FailFast {
+					return nil, &InitializerBackoffError{Err: err}
+				}
+				select {
+				case <-clock.After(wait):
+					continue
+				case <-ctx.Done():
+					return nil, fmt.Errorf("request cancelled while waiting to retry initialization: %w", ctx.Err())
+				}
+			}
+			next, err = init(c)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failures++
+				if backoff != nil {
+					if delay, ok := backoff.NextDelay(failures, nil, err); ok && delay > 0 {
+						lastErr, retryAt = err, clock.Now().Add(delay)
+					}
+				}
+				return nil, err
+			}
+			current, expires = next, clock.Now().Add(policy.TTL)
+			lastErr, retryAt, failures = nil, time.Time{}, 0
+			return next, nil
+		}
+	}
+
+	f = func(req *http.Request) (*http.Response, error) {
+		clock := ClockFromContext(req.Context())
+		next, wait, err := load(clock.Now())
+		if next == nil && wait > 0 && policy.FailFast {
+			return nil, &InitializerBackoffError{Err: err}
+		}
+		if next == nil {
+			if next, err = initialize(req.Context(), clock); err != nil {
+				return nil, err
+			}
+		}
+		return next.Do(req)
+	}
+	invalidate = func() {
+		mu.Lock()
+		current = nil
+		mu.Unlock()
+	}
+	return f, invalidate
+}
@@ -0,0 +1,160 @@
+package httpx_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/httpxtest"
+)
+
+func TestSetInitializerWithPolicy(t *testing.T) {
+	srv := httptest.NewServer(echoHandler)
+	defer srv.Close()
+	clock := httpxtest.NewClock(time.Now())
+
+	var inits int
+	init := func(next httpx.Client) (httpx.ClientFunc, error) {
+		inits++
+		if inits == 1 {
+			return nil, fmt.Errorf("dependency unavailable")
+		}
+		return httpx.SetHeader(next, "X-Token", fmt.Sprint("token-", inits)), nil
+	}
+	c, invalidate := httpx.SetInitializerWithPolicy(srv.Client(), init, httpx.InitializerPolicy{TTL: time.Minute})
+	do := httpx.SetRequest(httpx.SetClock(c, clock), http.MethodGet, srv.URL)
+
+	token := func() string {
+		t.Helper()
+		resp, err := do.Do(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.Header.Get("X-Token")
+	}
+	// a failed init is retried by the next request
+	if _, err := do.Do(nil); err == nil {
+		t.Fatal("expected init error")
+	}
+	if got := token(); got != "token-2" {
+		t.Fatal(got)
+	}
+	clock.Advance(59 * time.Second)
+	if got := token(); got != "token-2" {
+		t.Fatal(got)
+	}
+	clock.Advance(time.Second)
+	if got := token(); got != "token-3" {
+		t.Fatal("expected the ttl to expire the client", got)
+	}
+	invalidate()
+	if got := token(); got != "token-4" {
+		t.Fatal("expected invalidate to expire the client", got)
+	}
+}
+
+func TestSetInitializerWithPolicy_Backoff(t *testing.T) {
+	clock := httpxtest.NewClock(time.Now())
+	errUnavailable := fmt.Errorf("dependency unavailable")
+	var inits int
+	init := func(next httpx.Client) (httpx.ClientFunc, error) {
+		if inits++; inits < 3 {
+			return nil, errUnavailable
+		}
+		return next.Do, nil
+	}
+	ok := httpx.ClientFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+
+	// fail fast requests return the last error while backing off
+	c, _ := httpx.SetInitializerWithPolicy(ok, init, httpx.InitializerPolicy{InitialBackoff: time.Second, FailFast: true})
+	do := httpx.SetRequest(httpx.SetClock(c, clock), http.MethodGet, "http://example.com")
+	if _, err := do.Do(nil); err == nil || errors.Is(err, httpx.ErrInitializerBackoff) {
+		t.Fatal(err)
+	}
+	if _, err := do.Do(nil); !errors.Is(err, httpx.ErrInitializerBackoff) || !errors.Is(err, errUnavailable) || inits != 1 {
+		t.Fatal(err, inits)
+	}
+	clock.Advance(time.Second)
+	if _, err := do.Do(nil); err == nil || inits != 2 {
+		t.Fatal(err, inits)
+	}
+	// the backoff doubles
+	clock.Advance(time.Second)
+	if _, err := do.Do(nil); !errors.Is(err, httpx.ErrInitializerBackoff) {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Second)
+	if _, err := do.Do(nil); err != nil || inits != 3 {
+		t.Fatal(err, inits)
+	}
+
+	// other requests wait for the backoff to end unless their context is done first
+	inits = 0
+	c, _ = httpx.SetInitializerWithPolicy(ok, init, httpx.InitializerPolicy{InitialBackoff: time.Second})
+	c = httpx.SetClock(c, clock)
+	if _, err := httpx.SetRequest(c, http.MethodGet, "http://example.com").Do(nil); err == nil {
+		t.Fatal("expected init error")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := httpx.SetRequestWithContext(ctx, c, http.MethodGet, "http://example.com").Do(nil)
+		done <- err
+	}()
+	clock.BlockUntil(1)
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatal(err)
+	}
+	go func() {
+		_, err := httpx.SetRequest(c, http.MethodGet, "http://example.com").Do(nil)
+		done <- err
+	}()
+	clock.BlockUntil(2)
+	clock.Advance(time.Second)
+	if err := <-done; err == nil || inits != 2 {
+		t.Fatal("expected the second init to fail after the backoff", err, inits)
+	}
+}
+
+func TestSetInitializerWithPolicy_CustomBackoff(t *testing.T) {
+	clock := httpxtest.NewClock(time.Now())
+	errUnavailable := fmt.Errorf("dependency unavailable")
+	var inits int
+	init := func(next httpx.Client) (httpx.ClientFunc, error) {
+		inits++
+		return nil, errUnavailable
+	}
+	var failures []int
+	backoff := httpx.BackoffFunc(func(attempt int, _ *http.Response, err error) (time.Duration, bool) {
+		if !errors.Is(err, errUnavailable) {
+			t.Error(err)
+		}
+		failures = append(failures, attempt)
+		return 5 * time.Second, attempt < 2
+	})
+	c, _ := httpx.SetInitializerWithPolicy(nil, init, httpx.InitializerPolicy{Backoff: backoff, FailFast: true})
+	do := httpx.SetRequest(httpx.SetClock(c, clock), http.MethodGet, "http://example.com")
+	_, _ = do.Do(nil)
+	clock.Advance(4 * time.Second)
+	if _, err := do.Do(nil); !errors.Is(err, httpx.ErrInitializerBackoff) || inits != 1 {
+		t.Fatal("expected the wait of the backoff", err, inits)
+	}
+	clock.Advance(time.Second)
+	_, _ = do.Do(nil)
+	// the backoff gave up waiting, so the next request runs init straight away
+	if _, err := do.Do(nil); errors.Is(err, httpx.ErrInitializerBackoff) || inits != 3 {
+		t.Fatal(err, inits)
+	}
+	if fmt.Sprint(failures) != "[1 2 3]" {
+		t.Fatal(failures)
+	}
+}
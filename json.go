@@ -0,0 +1,43 @@
+package httpx
+
+import (
+	"encoding/json"
+	"sync/atomic"
+)
+
+// jsonCodec holds the functions used by the JSON helpers
+type jsonCodec struct {
+	marshal   Marshaller
+	unmarshal Unmarshaller
+}
+
+var currentJSONCodec atomic.Pointer[jsonCodec]
+
+func init() {
+	SetJSONCodec(json.Marshal, json.Unmarshal)
+}
+
+// SetJSONCodec replaces the encoding/json functions used by SetRequestBodyJSON, SetResponseBodyHandlerJSON and the
+// other JSON helpers in this package, allowing a faster drop in replacement such as jsoniter or go-json.
+//
+// A nil argument restores the encoding/json default for that function.
+// The codec is looked up on every request so it may be changed at any time, though it is usually set once at startup.
+func SetJSONCodec(m Marshaller, u Unmarshaller) {
+	if m == nil {
+		m = json.Marshal
+	}
+	if u == nil {
+		u = json.Unmarshal
+	}
+	currentJSONCodec.Store(&jsonCodec{marshal: m, unmarshal: u})
+}
+
+// jsonMarshal encodes v with the configured codec
+func jsonMarshal(v any) ([]byte, error) {
+	return currentJSONCodec.Load().marshal(v)
+}
+
+// jsonUnmarshal decodes b with the configured codec
+func jsonUnmarshal(b []byte, v any) error {
+	return currentJSONCodec.Load().unmarshal(b, v)
+}
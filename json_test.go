@@ -0,0 +1,37 @@
+package httpx_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tflyons/httpx"
+)
+
+func TestSetJSONCodec(t *testing.T) {
+	srv := httptest.NewServer(echoHandler)
+	defer srv.Close()
+
+	var marshalled, unmarshalled int
+	httpx.SetJSONCodec(func(v any) ([]byte, error) {
+		marshalled++
+		return json.Marshal(v)
+	}, func(b []byte, v any) error {
+		unmarshalled++
+		return json.Unmarshal(b, v)
+	})
+	defer httpx.SetJSONCodec(nil, nil)
+
+	var out map[string]string
+	var c httpx.Client = srv.Client()
+	c = httpx.SetResponseBodyHandlerJSON(c, &out)
+	c = httpx.SetRequestBodyJSON(c, map[string]string{"hello": "world"})
+	c = httpx.SetRequest(c, http.MethodPost, srv.URL)
+	if _, err := c.Do(nil); err != nil {
+		t.Fatal(err)
+	}
+	if out["hello"] != "world" || marshalled != 1 || unmarshalled != 1 {
+		t.Fatal(out, marshalled, unmarshalled)
+	}
+}
@@ -0,0 +1,155 @@
+package httpx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Limiter decides when requests may be sent, see SetLimiter.
+//
+// Implementations backed by a shared store such as Redis let the replicas of a service coordinate on one upstream
+// quota, see WindowStore for the contract of the provided fixed window limiter.
+type Limiter interface {
+	// Allow takes a slot if one is available now and reports whether it did
+	Allow(ctx context.Context) (bool, error)
+	// Reserve takes a slot if one is available now and returns zero, otherwise it returns how long to wait before
+	// trying again
+	Reserve(ctx context.Context) (time.Duration, error)
+	// Wait blocks until a slot is taken or ctx is done
+	Wait(ctx context.Context) error
+}
+
+// SetLimiter waits for the limiter before each request.
+// Requests made with the SkipRateLimit override are not limited
+func SetLimiter(c Client, l Limiter) ClientFunc {
+	c = nilClientCheck(c)
+	return func(req *http.Request) (*http.Response, error) {
+		if OverridesFromContext(req.Context()).SkipRateLimit {
+			return c.Do(req)
+		}
+		if err := l.Wait(req.Context()); err != nil {
+			return nil, err
+		}
+		return c.Do(req)
+	}
+}
+
+// WindowStore counts requests in fixed windows and is the contract for sharing a WindowLimiter between processes.
+//
+// Increment adds one to the counter of key and returns the new count and the end of its window. When the counter
+// has no window, or its window has ended, a new window of length period starts with a count of one.
+// With Redis this is INCR followed by PEXPIRE when the count is one, and PTTL to find the end of the window,
+// run as a script or transaction so that a counter never lives without an expiry.
+type WindowStore interface {
+	Increment(ctx context.Context, key string, period time.Duration) (count int64, windowEnd time.Time, err error)
+}
+
+// NewMemoryWindowStore returns a WindowStore local to the process, using the clock of the context. Windows that
+// have ended are dropped, so memory use follows the number of keys seen in a period rather than ever
+func NewMemoryWindowStore() WindowStore {
+	return &memoryWindowStore{windows: make(map[string]*memoryWindow)}
+}
+
+type memoryWindowStore struct {
+	mu      sync.Mutex
+	windows map[string]*memoryWindow
+	// sweepAt is when the ended windows are next removed
+	sweepAt time.Time
+}
+
+type memoryWindow struct {
+	end   time.Time
+	count int64
+}
+
+func (s *memoryWindowStore) Increment(ctx context.Context, key string, period time.Duration) (int64, time.Time, error) {
+	now := ClockFromContext(ctx).Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !now.Before(s.sweepAt) {
+		for k, w := range s.windows {
+			if !now.Before(w.end) {
+				delete(s.windows, k)
+			}
+		}
+		s.sweepAt = now.Add(period)
+	}
+	w, ok := s.windows[key]
+	if !ok {
+		w = &memoryWindow{}
+		s.windows[key] = w
+	}
+	if !now.Before(w.end) {
+		w.end = now.Add(period)
+		w.count = 0
+	}
+	w.count++
+	return w.count, w.end, nil
+}
+
+// Len returns the number of windows held
+func (s *memoryWindowStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.windows)
+}
+
+// WindowLimiter allows max requests per fixed window of period, counted in a WindowStore under key
+type WindowLimiter struct {
+	store  WindowStore
+	key    string
+	max    int64
+	period time.Duration
+}
+
+// NewWindowLimiter returns a limiter allowing max requests per period. Limiters using the same store and key
+// share the limit. Period must be greater than 0 or else the function will panic
+func NewWindowLimiter(store WindowStore, key string, max int, period time.Duration) *WindowLimiter {
+	if period <= 0 {
+		panic("httpx: non-positive rate limit duration")
+	}
+	return &WindowLimiter{store: store, key: key, max: int64(max), period: period}
+}
+
+// Allow implements Limiter
+func (l *WindowLimiter) Allow(ctx context.Context) (bool, error) {
+	wait, err := l.Reserve(ctx)
+	return err == nil && wait == 0, err
+}
+
+// Reserve implements Limiter
+func (l *WindowLimiter) Reserve(ctx context.Context) (time.Duration, error) {
+	count, end, err := l.store.Increment(ctx, l.key, l.period)
+	if err != nil {
+		return 0, fmt.Errorf("could not reserve rate limit: %w", err)
+	}
+	if count <= l.max {
+		return 0, nil
+	}
+	wait := end.Sub(ClockFromContext(ctx).Now())
+	if wait <= 0 {
+		// the window ended between the increment and now, try again straight away
+		wait = time.Nanosecond
+	}
+	return wait, nil
+}
+
+// Wait implements Limiter
+func (l *WindowLimiter) Wait(ctx context.Context) error {
+	clock := ClockFromContext(ctx)
+	for {
+		wait, err := l.Reserve(ctx)
+		if err != nil || wait == 0 {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			// if it has timed out return an error
+			return fmt.Errorf("request timed out during rate limit: %w", ctx.Err())
+		case <-clock.After(wait):
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package httpx_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/httpxtest"
+)
+
+func TestWindowLimiter_SharedStore(t *testing.T) {
+	srv := httptest.NewServer(echoHandler)
+	defer srv.Close()
+	clock := httpxtest.NewClock(time.Now())
+	ctx := context.Background()
+
+	// two replicas share one quota of 3 requests per second through the store
+	store := httpx.NewMemoryWindowStore()
+	replicas := []httpx.Client{
+		httpx.SetLimiter(srv.Client(), httpx.NewWindowLimiter(store, "upstream", 3, time.Second)),
+		httpx.SetLimiter(srv.Client(), httpx.NewWindowLimiter(store, "upstream", 3, time.Second)),
+	}
+	for i := range replicas {
+		replicas[i] = httpx.SetRequest(httpx.SetClock(replicas[i], clock), http.MethodGet, srv.URL)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := replicas[i%2].Do(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := replicas[1].Do(nil)
+		done <- err
+	}()
+	clock.BlockUntil(1)
+	select {
+	case <-done:
+		t.Fatal("expected the shared limit to delay the request")
+	default:
+	}
+	clock.Advance(time.Second)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	other := httpx.NewWindowLimiter(store, "other", 1, time.Second)
+	if ok, err := other.Allow(ctx); !ok || err != nil {
+		t.Fatal(ok, err)
+	}
+	if ok, err := other.Allow(ctx); ok || err != nil {
+		t.Fatal(ok, err)
+	}
+}
+
+func TestMemoryWindowStore_DropsEndedWindows(t *testing.T) {
+	clock := httpxtest.NewClock(time.Now())
+	httpx.SetDefaultClock(clock)
+	defer httpx.SetDefaultClock(nil)
+	ctx := context.Background()
+
+	// a new client address every request, as a public server sees
+	store := httpx.NewMemoryWindowStore()
+	for i := 0; i < 1000; i++ {
+		if _, _, err := store.Increment(ctx, fmt.Sprintf("ip:%d", i), time.Second); err != nil {
+			t.Fatal(err)
+		}
+		clock.Advance(100 * time.Millisecond)
+	}
+	if n := store.(interface{ Len() int }).Len(); n > 20 {
+		t.Fatal("expected ended windows to be dropped, holding", n)
+	}
+}
@@ -0,0 +1,280 @@
+// Package loadx generates load through httpx clients and reports latency percentiles and errors.
+//
+// Requests are sent through the given client so every decoration, such as authentication and headers, applies to
+// the generated load as it does in production.
+package loadx
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/tflyons/httpx"
+)
+
+// Generator returns the nth request to send, counting from 0
+type Generator func(ctx context.Context, n int) (*http.Request, error)
+
+// Options configures Run
+type Options struct {
+	// RPS is the target request rate. Requests are started at this rate regardless of how long earlier requests
+	// take, up to Concurrency in flight. If zero, Concurrency workers send requests back to back
+	RPS float64
+	// Concurrency is the maximum number of requests in flight, 1 if zero
+	Concurrency int
+	// Duration is how long load is generated for. Run stops after Requests requests instead if it is set, and
+	// runs until ctx is done if neither is set
+	Duration time.Duration
+	Requests int
+}
+
+// Sample is the outcome of one request
+type Sample struct {
+	// Start is the time since the run began
+	Start   time.Duration
+	Latency time.Duration
+	// Status is 0 if no response was received
+	Status int
+	// Class is the error class of the result, see httpx.ErrorClass
+	Class string
+	Err   error
+}
+
+// Report summarizes a run
+type Report struct {
+	Requests int
+	Errors   int
+	// ErrorsByClass counts errors by httpx error class
+	ErrorsByClass map[string]int
+	Statuses      map[int]int
+	Elapsed       time.Duration
+	// Throughput is completed requests per second
+	Throughput float64
+	// Dropped counts the requests not started because Concurrency requests were already in flight
+	Dropped int
+	Latency Latency
+	// Samples holds every request in the order they completed
+	Samples []Sample
+}
+
+// Latency percentiles of the requests in a run
+type Latency struct {
+	Min, Mean, P50, P90, P95, P99, Max time.Duration
+}
+
+// Run sends the requests of gen through c as configured by opts and reports on them. Latency is measured until the
+// response body has been read and closed. Time is measured with the clock of ctx, see httpx.ClockFromContext.
+// An error is only returned if gen fails
+func Run(ctx context.Context, c httpx.Client, gen Generator, opts Options) (*Report, error) {
+	if c == nil {
+		c = httpx.DefaultClient
+	}
+	workers := opts.Concurrency
+	if workers <= 0 {
+		workers = 1
+	}
+	clock := httpx.ClockFromContext(ctx)
+	// run ends the run without cancelling the requests in flight, which use ctx
+	run, stop := context.WithCancel(ctx)
+	defer stop()
+	begin := clock.Now()
+	if opts.Duration > 0 {
+		go func() {
+			select {
+			case <-clock.After(opts.Duration):
+				stop()
+			case <-run.Done():
+			}
+		}()
+	}
+
+	var mu sync.Mutex
+	var genErr error
+	report := &Report{ErrorsByClass: make(map[string]int), Statuses: make(map[int]int)}
+	send := func(req *http.Request) {
+		start := clock.Now()
+		resp, err := c.Do(req)
+		// a decorator turning a status into an error still returns the response, its connection is reused
+		if resp != nil && resp.Body != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		s := Sample{Start: start.Sub(begin), Latency: clock.Now().Sub(start), Class: httpx.ErrorClass(resp, err), Err: err}
+		if resp != nil {
+			s.Status = resp.StatusCode
+		}
+		mu.Lock()
+		report.Samples = append(report.Samples, s)
+		mu.Unlock()
+	}
+	// next returns the next request or false once the run is over
+	var n int
+	next := func() (*http.Request, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		if run.Err() != nil || (opts.Requests > 0 && n >= opts.Requests) {
+			return nil, false
+		}
+		req, err := gen(ctx, n)
+		if err != nil {
+			if genErr == nil && ctx.Err() == nil {
+				genErr = fmt.Errorf("could not generate request %d: %w", n, err)
+			}
+			stop()
+			return nil, false
+		}
+		n++
+		return req, true
+	}
+
+	var wg sync.WaitGroup
+	if opts.RPS <= 0 {
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for req, ok := next(); ok; req, ok = next() {
+					send(req)
+				}
+			}()
+		}
+	} else {
+		slots := make(chan struct{}, workers)
+		ticker := clock.NewTicker(time.Duration(float64(time.Second) / opts.RPS))
+		defer ticker.Stop()
+	loop:
+		for {
+			req, ok := next()
+			if !ok {
+				break
+			}
+			select {
+			case slots <- struct{}{}:
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					defer func() { <-slots }()
+					send(req)
+				}()
+			default:
+				mu.Lock()
+				report.Dropped++
+				mu.Unlock()
+			}
+			select {
+			case <-ticker.C():
+			case <-run.Done():
+				break loop
+			}
+		}
+	}
+	wg.Wait()
+	if genErr != nil {
+		return nil, genErr
+	}
+	report.Elapsed = clock.Now().Sub(begin)
+	report.summarize()
+	return report, nil
+}
+
+// summarize computes the counters and percentiles from the samples
+func (r *Report) summarize() {
+	r.Requests = len(r.Samples)
+	if r.Requests == 0 {
+		return
+	}
+	latencies := make([]time.Duration, 0, len(r.Samples))
+	var total time.Duration
+	for _, s := range r.Samples {
+		if s.Class != "" {
+			r.Errors++
+			r.ErrorsByClass[s.Class]++
+		}
+		if s.Status != 0 {
+			r.Statuses[s.Status]++
+		}
+		latencies = append(latencies, s.Latency)
+		total += s.Latency
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	percentile := func(p float64) time.Duration {
+		return latencies[int(math.Ceil(p*float64(len(latencies))))-1]
+	}
+	r.Latency = Latency{
+		Min:  latencies[0],
+		Mean: total / time.Duration(len(latencies)),
+		P50:  percentile(0.50),
+		P90:  percentile(0.90),
+		P95:  percentile(0.95),
+		P99:  percentile(0.99),
+		Max:  latencies[len(latencies)-1],
+	}
+	if r.Elapsed > 0 {
+		r.Throughput = float64(r.Requests) / r.Elapsed.Seconds()
+	}
+}
+
+// WriteJSON writes the summary of the report, without samples, as JSON with durations in milliseconds
+func (r *Report) WriteJSON(w io.Writer) error {
+	ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	statuses := make(map[string]int, len(r.Statuses))
+	for k, v := range r.Statuses {
+		statuses[strconv.Itoa(k)] = v
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(map[string]any{
+		"requests":        r.Requests,
+		"errors":          r.Errors,
+		"errors_by_class": r.ErrorsByClass,
+		"statuses":        statuses,
+		"dropped":         r.Dropped,
+		"elapsed_ms":      ms(r.Elapsed),
+		"throughput":      r.Throughput,
+		"latency_ms": map[string]float64{
+			"min":  ms(r.Latency.Min),
+			"mean": ms(r.Latency.Mean),
+			"p50":  ms(r.Latency.P50),
+			"p90":  ms(r.Latency.P90),
+			"p95":  ms(r.Latency.P95),
+			"p99":  ms(r.Latency.P99),
+			"max":  ms(r.Latency.Max),
+		},
+	})
+}
+
+// WriteCSV writes one row per sample with the start and latency in milliseconds
+func (r *Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"start_ms", "latency_ms", "status", "class", "error"})
+	for _, s := range r.Samples {
+		errText := ""
+		if s.Err != nil {
+			errText = s.Err.Error()
+		}
+		_ = cw.Write([]string{
+			strconv.FormatFloat(float64(s.Start)/float64(time.Millisecond), 'f', 3, 64),
+			strconv.FormatFloat(float64(s.Latency)/float64(time.Millisecond), 'f', 3, 64),
+			strconv.Itoa(s.Status),
+			s.Class,
+			errText,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// String formats the summary for terminals
+func (r *Report) String() string {
+	return fmt.Sprintf("%d requests in %s (%.1f/s), %d errors %v, %d dropped\nlatency min %s mean %s p50 %s p90 %s p95 %s p99 %s max %s",
+		r.Requests, r.Elapsed.Round(time.Millisecond), r.Throughput, r.Errors, r.ErrorsByClass, r.Dropped,
+		r.Latency.Min, r.Latency.Mean, r.Latency.P50, r.Latency.P90, r.Latency.P95, r.Latency.P99, r.Latency.Max)
+}
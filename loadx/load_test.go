@@ -0,0 +1,86 @@
+package loadx_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/loadx"
+)
+
+func TestRun(t *testing.T) {
+	var hits, authorized int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "Bearer t" {
+			atomic.AddInt64(&authorized, 1)
+		}
+		if atomic.AddInt64(&hits, 1)%10 == 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	c := httpx.SetHeader(srv.Client(), "Authorization", "Bearer t")
+	gen := func(ctx context.Context, n int) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	}
+	report, err := loadx.Run(context.Background(), c, gen, loadx.Options{Concurrency: 4, Requests: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Requests != 50 || authorized != 50 {
+		t.Fatal(report.Requests, authorized)
+	}
+	if report.Errors != 5 || report.ErrorsByClass[httpx.ErrorClass5xx] != 5 || report.Statuses[http.StatusOK] != 45 {
+		t.Fatal(report)
+	}
+	l := report.Latency
+	if l.Min <= 0 || l.Min > l.P50 || l.P50 > l.P99 || l.P99 > l.Max {
+		t.Fatalf("%+v", l)
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var summary map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &summary); err != nil || summary["requests"] != 50.0 {
+		t.Fatal(err, summary)
+	}
+	buf.Reset()
+	if err := report.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil || len(rows) != 51 {
+		t.Fatal(err, len(rows))
+	}
+	if !strings.Contains(report.String(), "50 requests") {
+		t.Fatal(report.String())
+	}
+}
+
+func TestRun_RPS(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	gen := func(ctx context.Context, n int) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	}
+	report, err := loadx.Run(context.Background(), srv.Client(), gen, loadx.Options{RPS: 200, Concurrency: 10, Duration: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 21 requests are started in 100ms at 200/s, allow for slow schedulers
+	if report.Requests+report.Dropped < 10 || report.Requests+report.Dropped > 21 || report.Errors != 0 {
+		t.Fatal(report)
+	}
+}
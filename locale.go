@@ -0,0 +1,43 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+)
+
+type localeKey struct{}
+
+// WithLocale returns a copy of ctx carrying the end user's locale, such as "fr-CA" or "fr-CA, fr;q=0.8"
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// LocaleFromContext returns the locale stored by WithLocale or an empty string
+func LocaleFromContext(ctx context.Context) string {
+	locale, _ := ctx.Value(localeKey{}).(string)
+	return locale
+}
+
+// SetLocale sets the Accept-Language header to the locale returned by fromCtx for the request context.
+//
+// If fromCtx is nil LocaleFromContext is used. The header is left unchanged when the locale is empty
+func SetLocale(c Client, fromCtx func(context.Context) string) ClientFunc {
+	c = nilClientCheck(c)
+	if fromCtx == nil {
+		fromCtx = LocaleFromContext
+	}
+	return func(req *http.Request) (*http.Response, error) {
+		if locale := fromCtx(req.Context()); locale != "" {
+			if req.Header == nil {
+				req.Header = make(http.Header)
+			}
+			req.Header.Set("Accept-Language", locale)
+		}
+		return c.Do(req)
+	}
+}
+
+// SetAcceptLanguage sets the Accept-Language header to the same value on every request
+func SetAcceptLanguage(c Client, locale string) ClientFunc {
+	return SetHeader(c, "Accept-Language", locale)
+}
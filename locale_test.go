@@ -0,0 +1,36 @@
+package httpx_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/tflyons/httpx"
+)
+
+func TestSetLocale(t *testing.T) {
+	var got string
+	var c httpx.Client = httpx.ClientFunc(func(req *http.Request) (*http.Response, error) {
+		got = req.Header.Get("Accept-Language")
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+	c = httpx.SetLocale(c, nil)
+	c = httpx.SetAcceptLanguage(c, "en")
+
+	tests := []struct {
+		ctx  context.Context
+		want string
+	}{
+		{context.Background(), "en"},
+		{httpx.WithLocale(context.Background(), "fr-CA, fr;q=0.8"), "fr-CA, fr;q=0.8"},
+		{httpx.WithLocale(context.Background(), ""), "en"},
+	}
+	for _, tt := range tests {
+		if _, err := httpx.SetRequestWithContext(tt.ctx, c, http.MethodGet, "http://example.com").Do(nil); err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Fatal(got)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package httpx
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// LoggerOptions configures SetLogger
+type LoggerOptions struct {
+	// Scrubber masks the URL, headers and bodies before they are written, NewDefaultScrubber if nil
+	Scrubber *Scrubber
+	// Headers logs the request and response headers
+	Headers bool
+	// Body logs the request and response bodies. Both are read in full and replaced, so avoid it for streams
+	Body bool
+}
+
+// SetLogger logs the method, URL, status and duration of every request, or the error if it failed, and optionally
+// the headers and bodies. Everything is passed through the scrubber of opts first, query parameters are masked as
+// form fields. If l is nil log.Default is used
+func SetLogger(c Client, l *log.Logger, opts LoggerOptions) ClientFunc {
+	c = nilClientCheck(c)
+	if l == nil {
+		l = log.Default()
+	}
+	scrubber := opts.Scrubber
+	if scrubber == nil {
+		scrubber = NewDefaultScrubber()
+	}
+	return func(req *http.Request) (*http.Response, error) {
+		var reqBody []byte
+		if opts.Body {
+			var err error
+			if reqBody, err = readRequestBody(req); err != nil {
+				return nil, err
+			}
+		}
+		clock := ClockFromContext(req.Context())
+		start := clock.Now()
+		resp, err := c.Do(req)
+		elapsed := clock.Now().Sub(start)
+
+		var buf strings.Builder
+		u := scrubber.ScrubURL(req.URL)
+		switch {
+		case err != nil && resp != nil:
+			fmt.Fprintf(&buf, "%s %s %d %s error: %s", req.Method, u, resp.StatusCode, elapsed, scrubber.scrubError(req.URL, err))
+		case err != nil:
+			fmt.Fprintf(&buf, "%s %s %s error: %s", req.Method, u, elapsed, scrubber.scrubError(req.URL, err))
+		default:
+			fmt.Fprintf(&buf, "%s %s %d %s", req.Method, u, resp.StatusCode, elapsed)
+		}
+		if opts.Headers {
+			logHeader(&buf, "> ", scrubber.ScrubHeader(req.Header))
+		}
+		if opts.Body && len(reqBody) > 0 {
+			logBody(&buf, "> ", scrubber.ScrubBody(req.Header.Get("Content-Type"), reqBody))
+		}
+		if resp != nil {
+			if opts.Headers {
+				logHeader(&buf, "< ", scrubber.ScrubHeader(resp.Header))
+			}
+			if opts.Body {
+				respBody, readErr := readAndRestore(&resp.Body)
+				if readErr != nil && err == nil {
+					err = readErr
+				}
+				if len(respBody) > 0 {
+					logBody(&buf, "< ", scrubber.ScrubBody(resp.Header.Get("Content-Type"), respBody))
+				}
+			}
+		}
+		l.Print(buf.String())
+		return resp, err
+	}
+}
+
+// logHeader writes the sorted header lines of h, each on a new line after prefix
+func logHeader(buf *strings.Builder, prefix string, h http.Header) {
+	var b bytes.Buffer
+	writeHeader(&b, h)
+	for _, line := range strings.Split(strings.TrimSuffix(b.String(), "\r\n"), "\r\n") {
+		if line != "" {
+			buf.WriteString("\n" + prefix + line)
+		}
+	}
+}
+
+// logBody writes body on a new line after prefix
+func logBody(buf *strings.Builder, prefix string, body []byte) {
+	buf.WriteString("\n" + prefix)
+	buf.Write(body)
+}
@@ -0,0 +1,159 @@
+package httpx
+
+import (
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MediaRange is a media type and its relative quality used to build an Accept header
+type MediaRange struct {
+	Type string
+	// Q is the quality value between 0 and 1. Zero is treated as 1
+	Q float64
+}
+
+// FormatAccept builds an Accept header value from the media ranges in order of preference
+func FormatAccept(ranges ...MediaRange) string {
+	parts := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		if r.Q <= 0 || r.Q >= 1 {
+			parts = append(parts, r.Type)
+			continue
+		}
+		parts = append(parts, r.Type+";q="+strconv.FormatFloat(r.Q, 'f', -1, 64))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// SetAccept sets the Accept header on the request from the given media ranges
+func SetAccept(c Client, ranges ...MediaRange) ClientFunc {
+	return SetHeader(c, "Accept", FormatAccept(ranges...))
+}
+
+// DefaultAccept is sent by SetResponseBodyAuto when the request does not already have an Accept header
+var DefaultAccept = FormatAccept(
+	MediaRange{Type: "application/json"},
+	MediaRange{Type: "application/xml", Q: 0.9},
+	MediaRange{Type: "application/x-www-form-urlencoded", Q: 0.8},
+	MediaRange{Type: "text/*", Q: 0.5},
+	MediaRange{Type: "*/*", Q: 0.1},
+)
+
+var (
+	unmarshallersMu sync.RWMutex
+	unmarshallers   = map[string]Unmarshaller{
+		"application/json":                  func(b []byte, v any) error { return jsonUnmarshal(b, v) },
+		"application/xml":                   xml.Unmarshal,
+		"text/xml":                          xml.Unmarshal,
+		"application/x-www-form-urlencoded": unmarshalForm,
+		"text/plain":                        unmarshalText,
+	}
+)
+
+// RegisterUnmarshaller sets the Unmarshaller used by SetResponseBodyAuto for the media type.
+//
+// The media type may be a full type such as "application/cbor", a structured syntax suffix such as "+json"
+// or a wildcard such as "text/*".
+func RegisterUnmarshaller(mediaType string, u Unmarshaller) {
+	unmarshallersMu.Lock()
+	defer unmarshallersMu.Unlock()
+	unmarshallers[strings.ToLower(mediaType)] = u
+}
+
+// unmarshallerFor finds the registered Unmarshaller for the media type
+func unmarshallerFor(mediaType string) (Unmarshaller, bool) {
+	unmarshallersMu.RLock()
+	defer unmarshallersMu.RUnlock()
+	if u, ok := unmarshallers[mediaType]; ok {
+		return u, true
+	}
+	major, sub, _ := strings.Cut(mediaType, "/")
+	if i := strings.LastIndexByte(sub, '+'); i >= 0 {
+		suffix := sub[i:]
+		if u, ok := unmarshallers[suffix]; ok {
+			return u, true
+		}
+		// fall back to the base format for suffixes such as application/problem+json
+		if u, ok := unmarshallers["application/"+suffix[1:]]; ok {
+			return u, true
+		}
+	}
+	if u, ok := unmarshallers[major+"/*"]; ok {
+		return u, true
+	}
+	if major == "text" {
+		return unmarshalText, true
+	}
+	return nil, false
+}
+
+// SetResponseBodyAuto performs the request and decodes the response body into ptr using the Unmarshaller
+// registered for the response Content-Type.
+//
+// JSON, XML, form and text bodies are supported by default. Form bodies decode into *url.Values,
+// *map[string][]string or *map[string]string and text bodies into *string or *[]byte.
+// If the request has no Accept header DefaultAccept is sent.
+func SetResponseBodyAuto(c Client, ptr any) ClientFunc {
+	next := SetResponseBodyHandler(c, nil, ptr)
+	return func(req *http.Request) (*http.Response, error) {
+		if req.Header == nil {
+			req.Header = make(http.Header)
+		}
+		if req.Header.Get("Accept") == "" {
+			req.Header.Set("Accept", DefaultAccept)
+		}
+		return next.Do(req)
+	}
+}
+
+// unmarshalAuto decodes b using the Unmarshaller registered for the content type
+func unmarshalAuto(contentType string, b []byte, v any) error {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return fmt.Errorf("could not parse response content type %q: %w", contentType, err)
+	}
+	u, ok := unmarshallerFor(mediaType)
+	if !ok {
+		return fmt.Errorf("no unmarshaller registered for content type %s", mediaType)
+	}
+	return u(b, v)
+}
+
+func unmarshalForm(b []byte, v any) error {
+	values, err := url.ParseQuery(string(b))
+	if err != nil {
+		return err
+	}
+	switch t := v.(type) {
+	case *url.Values:
+		*t = values
+	case *map[string][]string:
+		*t = values
+	case *map[string]string:
+		*t = make(map[string]string, len(values))
+		for k := range values {
+			(*t)[k] = values.Get(k)
+		}
+	default:
+		return fmt.Errorf("could not unmarshal form body into %T", v)
+	}
+	return nil
+}
+
+func unmarshalText(b []byte, v any) error {
+	switch t := v.(type) {
+	case *string:
+		*t = string(b)
+	case *[]byte:
+		*t = append([]byte(nil), b...)
+	default:
+		return fmt.Errorf("could not unmarshal text body into %T", v)
+	}
+	return nil
+}
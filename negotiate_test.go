@@ -0,0 +1,78 @@
+package httpx_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/tflyons/httpx"
+)
+
+func TestFormatAccept(t *testing.T) {
+	got := httpx.FormatAccept(
+		httpx.MediaRange{Type: "application/json"},
+		httpx.MediaRange{Type: "application/xml", Q: 0.5},
+	)
+	if got != "application/json, application/xml;q=0.5" {
+		t.Fatal(got)
+	}
+}
+
+func TestSetResponseBodyAuto(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("format") {
+		case "json":
+			w.Header().Set("Content-Type", "application/problem+json")
+			_, _ = w.Write([]byte(`{"name":"tom"}`))
+		case "xml":
+			w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+			_, _ = w.Write([]byte(`<person><name>tom</name></person>`))
+		case "form":
+			w.Header().Set("Content-Type", "application/x-www-form-urlencoded")
+			_, _ = w.Write([]byte(`name=tom`))
+		case "accept":
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte(r.Header.Get("Accept")))
+		}
+	}))
+	defer srv.Close()
+
+	type person struct {
+		Name string `json:"name" xml:"name"`
+	}
+	for _, format := range []string{"json", "xml"} {
+		var p person
+		var c httpx.Client = srv.Client()
+		c = httpx.SetResponseBodyAuto(c, &p)
+		c = httpx.SetRequest(c, http.MethodGet, srv.URL+"?format="+format)
+		if _, err := c.Do(nil); err != nil {
+			t.Fatal(format, err)
+		}
+		if p.Name != "tom" {
+			t.Fatal(format, p)
+		}
+	}
+
+	var form url.Values
+	var c httpx.Client = srv.Client()
+	c = httpx.SetResponseBodyAuto(c, &form)
+	c = httpx.SetRequest(c, http.MethodGet, srv.URL+"?format=form")
+	if _, err := c.Do(nil); err != nil {
+		t.Fatal(err)
+	}
+	if form.Get("name") != "tom" {
+		t.Fatal(form)
+	}
+
+	var accept string
+	c = srv.Client()
+	c = httpx.SetResponseBodyAuto(c, &accept)
+	c = httpx.SetRequest(c, http.MethodGet, srv.URL+"?format=accept")
+	if _, err := c.Do(nil); err != nil {
+		t.Fatal(err)
+	}
+	if accept != httpx.DefaultAccept {
+		t.Fatal(accept)
+	}
+}
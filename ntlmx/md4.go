@@ -0,0 +1,55 @@
+package ntlmx
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// md4 returns the RFC 1320 MD4 digest of b. MD4 is broken and only used because NTLM requires it
+func md4(b []byte) [16]byte {
+	msg := append([]byte(nil), b...)
+	msg = append(msg, 0x80)
+	for len(msg)%64 != 56 {
+		msg = append(msg, 0)
+	}
+	msg = binary.LittleEndian.AppendUint64(msg, uint64(len(b))*8)
+
+	a, bb, c, d := uint32(0x67452301), uint32(0xefcdab89), uint32(0x98badcfe), uint32(0x10325476)
+	var x [16]uint32
+	for len(msg) > 0 {
+		for i := range x {
+			x[i] = binary.LittleEndian.Uint32(msg[i*4:])
+		}
+		msg = msg[64:]
+		aa, bbb, cc, dd := a, bb, c, d
+
+		f := func(x, y, z uint32) uint32 { return x&y | ^x&z }
+		g := func(x, y, z uint32) uint32 { return x&y | x&z | y&z }
+		h := func(x, y, z uint32) uint32 { return x ^ y ^ z }
+		for _, i := range [4]int{0, 4, 8, 12} {
+			a = bits.RotateLeft32(a+f(bb, c, d)+x[i], 3)
+			d = bits.RotateLeft32(d+f(a, bb, c)+x[i+1], 7)
+			c = bits.RotateLeft32(c+f(d, a, bb)+x[i+2], 11)
+			bb = bits.RotateLeft32(bb+f(c, d, a)+x[i+3], 19)
+		}
+		for _, i := range [4]int{0, 1, 2, 3} {
+			a = bits.RotateLeft32(a+g(bb, c, d)+x[i]+0x5a827999, 3)
+			d = bits.RotateLeft32(d+g(a, bb, c)+x[i+4]+0x5a827999, 5)
+			c = bits.RotateLeft32(c+g(d, a, bb)+x[i+8]+0x5a827999, 9)
+			bb = bits.RotateLeft32(bb+g(c, d, a)+x[i+12]+0x5a827999, 13)
+		}
+		for _, i := range [4]int{0, 2, 1, 3} {
+			a = bits.RotateLeft32(a+h(bb, c, d)+x[i]+0x6ed9eba1, 3)
+			d = bits.RotateLeft32(d+h(a, bb, c)+x[i+8]+0x6ed9eba1, 9)
+			c = bits.RotateLeft32(c+h(d, a, bb)+x[i+4]+0x6ed9eba1, 11)
+			bb = bits.RotateLeft32(bb+h(c, d, a)+x[i+12]+0x6ed9eba1, 15)
+		}
+		a, bb, c, d = a+aa, bb+bbb, c+cc, d+dd
+	}
+	var out [16]byte
+	binary.LittleEndian.PutUint32(out[0:], a)
+	binary.LittleEndian.PutUint32(out[4:], bb)
+	binary.LittleEndian.PutUint32(out[8:], c)
+	binary.LittleEndian.PutUint32(out[12:], d)
+	return out
+}
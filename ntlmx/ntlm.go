@@ -0,0 +1,260 @@
+// Package ntlmx performs NTLMv2 HTTP authentication for httpx clients, as still required by Windows integrated
+// services such as Exchange Web Services and older SharePoint installations.
+//
+// NTLM authenticates a connection rather than a request, so the handshake must complete on a single keep-alive
+// connection. The decorator drains every intermediate response so the transport reuses the connection, but a
+// transport shared by many concurrent requests to the same host may hand another connection to the final leg.
+// Use a transport with MaxConnsPerHost set to 1, or one per handshake, when that happens.
+package ntlmx
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/tflyons/httpx"
+)
+
+// ErrChallenge is returned when the server's NTLM challenge cannot be parsed
+var ErrChallenge = fmt.Errorf("invalid ntlm challenge")
+
+// Credentials for NTLM authentication. A Username of the form DOMAIN\user sets the domain if Domain is empty
+type Credentials struct {
+	Domain      string
+	Username    string
+	Password    string
+	Workstation string
+}
+
+const (
+	flagUnicode                = 0x00000001
+	flagRequestTarget          = 0x00000004
+	flagNTLM                   = 0x00000200
+	flagAlwaysSign             = 0x00008000
+	flagExtendedSession        = 0x00080000
+	flagTargetInfo             = 0x00800000
+	flag128                    = 0x20000000
+	flag56                     = 0x80000000
+	negotiateFlags      uint32 = flagUnicode | flagRequestTarget | flagNTLM | flagAlwaysSign | flagExtendedSession |
+		flagTargetInfo | flag128 | flag56
+)
+
+var signature = []byte("NTLMSSP\x00")
+
+// SetNTLM authenticates with NTLMv2 when the server responds 401 Unauthorized offering NTLM.
+//
+// The request is sent again with a negotiate message and then with the response to the server challenge,
+// draining the intermediate responses so that all legs use the same connection.
+// The request is not sent again if its body cannot be replayed, see httpx.SetRequestBody
+func SetNTLM(c httpx.Client, creds Credentials) httpx.ClientFunc {
+	if c == nil {
+		c = httpx.DefaultClient
+	}
+	if creds.Domain == "" {
+		if domain, user, ok := strings.Cut(creds.Username, `\`); ok {
+			creds.Domain, creds.Username = domain, user
+		}
+	}
+	return func(req *http.Request) (*http.Response, error) {
+		resp, err := c.Do(req)
+		if err != nil || resp.StatusCode != http.StatusUnauthorized {
+			return resp, err
+		}
+		if _, ok := ntlmChallenge(resp.Header); !ok || (req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+			return resp, nil
+		}
+		if req.Header == nil {
+			req.Header = make(http.Header)
+		}
+
+		resp, err = sendLeg(c, req, resp, negotiateMessage())
+		if err != nil || resp.StatusCode != http.StatusUnauthorized {
+			return resp, err
+		}
+		challenge, ok := ntlmChallenge(resp.Header)
+		if !ok || len(challenge) == 0 {
+			return resp, nil
+		}
+		auth, err := authenticateMessage(creds, challenge, time.Now())
+		if err != nil {
+			discard(resp)
+			return nil, err
+		}
+		return sendLeg(c, req, resp, auth)
+	}
+}
+
+// sendLeg discards the previous response and sends req again with the NTLM message
+func sendLeg(c httpx.Client, req *http.Request, prev *http.Response, msg []byte) (*http.Response, error) {
+	discard(prev)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("could not reset request body for ntlm: %w", err)
+		}
+		req.Body = body
+	}
+	req.Header.Set("Authorization", "NTLM "+base64.StdEncoding.EncodeToString(msg))
+	return c.Do(req)
+}
+
+// ntlmChallenge returns the message of an NTLM WWW-Authenticate challenge and whether NTLM was offered
+func ntlmChallenge(h http.Header) ([]byte, bool) {
+	for _, v := range h.Values("WWW-Authenticate") {
+		scheme, token, _ := strings.Cut(strings.TrimSpace(v), " ")
+		if !strings.EqualFold(scheme, "NTLM") {
+			continue
+		}
+		b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(token))
+		if err != nil {
+			return nil, true
+		}
+		return b, true
+	}
+	return nil, false
+}
+
+// discard drains and closes the body so that the connection can be reused for the next leg
+func discard(resp *http.Response) {
+	if resp != nil && resp.Body != nil {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+		resp.Body.Close()
+	}
+}
+
+// negotiateMessage returns the NEGOTIATE_MESSAGE (type 1) without domain or workstation
+func negotiateMessage() []byte {
+	msg := make([]byte, 32)
+	copy(msg, signature)
+	binary.LittleEndian.PutUint32(msg[8:], 1)
+	binary.LittleEndian.PutUint32(msg[12:], negotiateFlags)
+	return msg
+}
+
+// challengeMessage holds the parts of a CHALLENGE_MESSAGE (type 2) used by NTLMv2
+type challengeMessage struct {
+	flags           uint32
+	serverChallenge []byte
+	targetInfo      []byte
+}
+
+func parseChallenge(b []byte) (*challengeMessage, error) {
+	if len(b) < 32 || !bytes.Equal(b[:8], signature) || binary.LittleEndian.Uint32(b[8:]) != 2 {
+		return nil, ErrChallenge
+	}
+	m := &challengeMessage{
+		flags:           binary.LittleEndian.Uint32(b[20:]),
+		serverChallenge: b[24:32],
+	}
+	if len(b) >= 48 {
+		n, off := int(binary.LittleEndian.Uint16(b[40:])), int(binary.LittleEndian.Uint32(b[44:]))
+		if off+n > len(b) || off < 0 {
+			return nil, ErrChallenge
+		}
+		m.targetInfo = b[off : off+n]
+	}
+	return m, nil
+}
+
+// avTimestamp returns the MsvAvTimestamp of the target info, if present
+func avTimestamp(info []byte) ([]byte, bool) {
+	for len(info) >= 4 {
+		id, n := binary.LittleEndian.Uint16(info), int(binary.LittleEndian.Uint16(info[2:]))
+		if id == 0 || len(info) < 4+n {
+			break
+		}
+		if id == 7 && n == 8 {
+			return info[4:12], true
+		}
+		info = info[4+n:]
+	}
+	return nil, false
+}
+
+func utf16le(s string) []byte {
+	u := utf16.Encode([]rune(s))
+	b := make([]byte, 2*len(u))
+	for i, r := range u {
+		binary.LittleEndian.PutUint16(b[2*i:], r)
+	}
+	return b
+}
+
+func hmacMD5(key []byte, data ...[]byte) []byte {
+	h := hmac.New(md5.New, key)
+	for _, d := range data {
+		h.Write(d)
+	}
+	return h.Sum(nil)
+}
+
+// ntowfv2 is the NTLMv2 response key of MS-NLMP section 3.3.2
+func ntowfv2(user, password, domain string) []byte {
+	hash := md4(utf16le(password))
+	return hmacMD5(hash[:], utf16le(strings.ToUpper(user)+domain))
+}
+
+// ntlmv2Response computes the NT and LM challenge responses of MS-NLMP section 3.3.2
+func ntlmv2Response(key, serverChallenge, clientChallenge, timestamp, targetInfo []byte, lmZero bool) (nt, lm []byte) {
+	temp := make([]byte, 0, 28+len(targetInfo)+4)
+	temp = append(temp, 1, 1, 0, 0, 0, 0, 0, 0)
+	temp = append(temp, timestamp...)
+	temp = append(temp, clientChallenge...)
+	temp = append(temp, 0, 0, 0, 0)
+	temp = append(temp, targetInfo...)
+	temp = append(temp, 0, 0, 0, 0)
+	nt = append(hmacMD5(key, serverChallenge, temp), temp...)
+	if lmZero {
+		return nt, make([]byte, 24)
+	}
+	return nt, append(hmacMD5(key, serverChallenge, clientChallenge), clientChallenge...)
+}
+
+// fileTime returns t as a Windows FILETIME, the number of 100ns intervals since 1601
+func fileTime(t time.Time) []byte {
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, uint64(t.UnixNano()/100)+116444736000000000)
+	return b
+}
+
+// authenticateMessage returns the AUTHENTICATE_MESSAGE (type 3) answering the challenge
+func authenticateMessage(creds Credentials, challenge []byte, now time.Time) ([]byte, error) {
+	m, err := parseChallenge(challenge)
+	if err != nil {
+		return nil, err
+	}
+	clientChallenge := make([]byte, 8)
+	if _, err = rand.Read(clientChallenge); err != nil {
+		return nil, err
+	}
+	// a server timestamp must be used instead of the client time and suppresses the LM response
+	timestamp, lmZero := avTimestamp(m.targetInfo)
+	if !lmZero {
+		timestamp = fileTime(now)
+	}
+	key := ntowfv2(creds.Username, creds.Password, creds.Domain)
+	nt, lm := ntlmv2Response(key, m.serverChallenge, clientChallenge, timestamp, m.targetInfo, lmZero)
+
+	fields := [][]byte{lm, nt, utf16le(creds.Domain), utf16le(creds.Username), utf16le(creds.Workstation), nil}
+	msg := make([]byte, 64)
+	copy(msg, signature)
+	binary.LittleEndian.PutUint32(msg[8:], 3)
+	for i, f := range fields {
+		hdr := msg[12+8*i:]
+		binary.LittleEndian.PutUint16(hdr, uint16(len(f)))
+		binary.LittleEndian.PutUint16(hdr[2:], uint16(len(f)))
+		binary.LittleEndian.PutUint32(hdr[4:], uint32(len(msg)))
+		msg = append(msg, f...)
+	}
+	binary.LittleEndian.PutUint32(msg[60:], m.flags&negotiateFlags|flagUnicode)
+	return msg, nil
+}
@@ -0,0 +1,102 @@
+package ntlmx
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tflyons/httpx"
+)
+
+func unhex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestMD4(t *testing.T) {
+	for in, want := range map[string]string{
+		"":    "31d6cfe0d16ae931b73c59d7e0c089c0",
+		"abc": "a448017aaf21d8525fc10ae87aa6729d",
+		"12345678901234567890123456789012345678901234567890123456789012345678901234567890": "e33b4ddc9c38f2199c3e7b164fcc0536",
+	} {
+		if got := md4([]byte(in)); hex.EncodeToString(got[:]) != want {
+			t.Errorf("md4(%q) = %x", in, got)
+		}
+	}
+}
+
+// TestNTLMv2Response uses the example of MS-NLMP section 4.2.4
+func TestNTLMv2Response(t *testing.T) {
+	key := ntowfv2("User", "Password", "Domain")
+	if !bytes.Equal(key, unhex(t, "0c868a403bfd7a93a3001ef22ef02e3f")) {
+		t.Fatalf("%x", key)
+	}
+	targetInfo := unhex(t, "02000c0044006f006d00610069006e0001000c0053006500720076006500720000000000")
+	nt, lm := ntlmv2Response(key, unhex(t, "0123456789abcdef"), bytes.Repeat([]byte{0xaa}, 8), make([]byte, 8), targetInfo, false)
+	if !bytes.Equal(nt[:16], unhex(t, "68cd0ab851e51c96aabc927bebef6a1c")) {
+		t.Fatalf("%x", nt[:16])
+	}
+	if !bytes.Equal(lm, unhex(t, "86c35097ac9cec102554764a57cccc19aaaaaaaaaaaaaaaa")) {
+		t.Fatalf("%x", lm)
+	}
+}
+
+func TestSetNTLM(t *testing.T) {
+	serverChallenge := unhex(t, "0123456789abcdef")
+	challenge := make([]byte, 48)
+	copy(challenge, signature)
+	binary.LittleEndian.PutUint32(challenge[8:], 2)
+	binary.LittleEndian.PutUint32(challenge[20:], negotiateFlags)
+	copy(challenge[24:], serverChallenge)
+	binary.LittleEndian.PutUint32(challenge[44:], 48)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scheme, auth, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+		msg, _ := base64.StdEncoding.DecodeString(auth)
+		switch {
+		case scheme != "NTLM":
+			w.Header().Set("WWW-Authenticate", "NTLM")
+		case len(msg) >= 12 && binary.LittleEndian.Uint32(msg[8:]) == 1:
+			w.Header().Set("WWW-Authenticate", "NTLM "+base64.StdEncoding.EncodeToString(challenge))
+		case len(msg) >= 64 && binary.LittleEndian.Uint32(msg[8:]) == 3:
+			field := func(i int) []byte {
+				n, off := binary.LittleEndian.Uint16(msg[12+8*i:]), binary.LittleEndian.Uint32(msg[16+8*i:])
+				return msg[off : off+uint32(n)]
+			}
+			nt := field(1)
+			key := ntowfv2("alice", "hunter2", "CORP")
+			if bytes.Equal(field(3), utf16le("alice")) && bytes.Equal(nt[:16], hmacMD5(key, serverChallenge, nt[16:])) {
+				_, _ = w.Write([]byte("welcome"))
+				return
+			}
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	var c httpx.Client = srv.Client()
+	c = SetNTLM(c, Credentials{Username: `CORP\alice`, Password: "hunter2"})
+	c = httpx.RequireResponseStatus(c, http.StatusOK)
+	var body string
+	c = httpx.SetResponseBodyString(c, &body)
+	if _, err := httpx.SetRequest(c, http.MethodGet, srv.URL).Do(nil); err != nil {
+		t.Fatal(err)
+	}
+	if body != "welcome" {
+		t.Fatal(body)
+	}
+
+	c = SetNTLM(srv.Client(), Credentials{Domain: "CORP", Username: "alice", Password: "wrong"})
+	resp, err := httpx.SetRequest(c, http.MethodGet, srv.URL).Do(nil)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatal(resp, err)
+	}
+}
@@ -0,0 +1,65 @@
+package oidcx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/serverx"
+)
+
+// VerifyAccessToken validates a JWT access token as described in RFC 9068: the typ header, which must be at+jwt so
+// that an ID token for the same audience is not accepted, the signature against the provider JWKS and the issuer,
+// audience and expiry claims. Invalid tokens are reported wrapping serverx.ErrInvalidToken
+func (p *Provider) VerifyAccessToken(ctx context.Context, raw, aud string) (*serverx.Principal, error) {
+	payload, err := p.verifyJWT(ctx, raw, serverx.ErrInvalidToken)
+	if err != nil {
+		return nil, err
+	}
+	var header struct {
+		Typ string `json:"typ"`
+	}
+	if err = decodeSegment(strings.SplitN(raw, ".", 2)[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: header: %v", serverx.ErrInvalidToken, err)
+	}
+	if typ := strings.ToLower(header.Typ); typ != "at+jwt" && typ != "application/at+jwt" {
+		return nil, fmt.Errorf("%w: typ %q is not at+jwt", serverx.ErrInvalidToken, header.Typ)
+	}
+	var claims map[string]any
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("%w: payload: %v", serverx.ErrInvalidToken, err)
+	}
+	var std struct {
+		Issuer    string   `json:"iss"`
+		Audience  audience `json:"aud"`
+		Expiry    float64  `json:"exp"`
+		NotBefore float64  `json:"nbf"`
+	}
+	if err = json.Unmarshal(payload, &std); err != nil {
+		return nil, fmt.Errorf("%w: payload: %v", serverx.ErrInvalidToken, err)
+	}
+	now := httpx.ClockFromContext(ctx).Now()
+	switch {
+	case std.Issuer != p.Issuer:
+		return nil, fmt.Errorf("%w: issuer %q does not match %q", serverx.ErrInvalidToken, std.Issuer, p.Issuer)
+	case !std.Audience.contains(aud):
+		return nil, fmt.Errorf("%w: audience does not contain %q", serverx.ErrInvalidToken, aud)
+	case std.Expiry == 0 || now.Add(-clockSkew).After(numericDate(std.Expiry)):
+		return nil, fmt.Errorf("%w: expired", serverx.ErrInvalidToken)
+	case std.NotBefore != 0 && now.Add(clockSkew).Before(numericDate(std.NotBefore)):
+		return nil, fmt.Errorf("%w: not yet valid", serverx.ErrInvalidToken)
+	}
+	return serverx.PrincipalFromClaims(claims), nil
+}
+
+// AccessTokenValidator returns a serverx.TokenValidator accepting JWT access tokens issued by p for audience,
+// see VerifyAccessToken:
+//
+//	h := serverx.Authenticate(provider.AccessTokenValidator("https://api.example.com"), "orders:read")(handler)
+func (p *Provider) AccessTokenValidator(audience string) serverx.TokenValidator {
+	return serverx.TokenValidatorFunc(func(ctx context.Context, token string) (*serverx.Principal, error) {
+		return p.VerifyAccessToken(ctx, token, audience)
+	})
+}
@@ -0,0 +1,55 @@
+package oidcx_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx/oidcx"
+	"github.com/tflyons/httpx/serverx"
+)
+
+func TestAccessTokenValidator(t *testing.T) {
+	op := newTestProvider(t)
+	provider, err := oidcx.Discover(context.Background(), op.Client(), op.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().Unix()
+	h := serverx.Authenticate(provider.AccessTokenValidator("api"), "orders:read")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, _ := serverx.PrincipalFromContext(r.Context())
+		_, _ = w.Write([]byte(p.Subject))
+	}))
+
+	for _, tc := range []struct {
+		typ    string
+		claims map[string]any
+		status int
+	}{
+		{"at+jwt", map[string]any{"iss": op.URL, "sub": "alice", "aud": "api", "exp": now + 300, "scope": "orders:read orders:write"}, http.StatusOK},
+		{"application/at+jwt", map[string]any{"iss": op.URL, "sub": "alice", "aud": "api", "exp": now + 300, "scope": "orders:write"}, http.StatusForbidden},
+		{"at+jwt", map[string]any{"iss": op.URL, "sub": "alice", "aud": "other", "exp": now + 300, "scope": "orders:read"}, http.StatusUnauthorized},
+		{"at+jwt", map[string]any{"iss": op.URL, "sub": "alice", "aud": "api", "exp": now - 3600, "scope": "orders:read"}, http.StatusUnauthorized},
+		// an ID token for the same audience is not an access token
+		{"JWT", map[string]any{"iss": op.URL, "sub": "alice", "aud": "api", "exp": now + 300, "scope": "orders:read"}, http.StatusUnauthorized},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Authorization", "Bearer "+op.signTyped(t, "k1", tc.typ, tc.claims))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != tc.status {
+			t.Error(tc.claims, w.Code, w.Body.String())
+		}
+		if w.Code == http.StatusOK && w.Body.String() != "alice" {
+			t.Error(w.Body.String())
+		}
+	}
+
+	_, err = provider.VerifyAccessToken(context.Background(), "not.a.jwt", "api")
+	if !errors.Is(err, serverx.ErrInvalidToken) {
+		t.Fatal(err)
+	}
+}
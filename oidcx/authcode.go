@@ -0,0 +1,228 @@
+package oidcx
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tflyons/httpx"
+)
+
+// TokenStore persists tokens between runs so that users of interactive tools do not have to log in every time
+type TokenStore interface {
+	// Load returns the stored token, or nil and no error if there is none
+	Load(ctx context.Context) (*Token, error)
+	// Save replaces the stored token
+	Save(ctx context.Context, t *Token) error
+}
+
+// FileTokenStore returns a TokenStore keeping the token as JSON in the file at path, readable only by the owner
+func FileTokenStore(path string) TokenStore {
+	return fileTokenStore(path)
+}
+
+type fileTokenStore string
+
+func (f fileTokenStore) Load(_ context.Context) (*Token, error) {
+	b, err := os.ReadFile(string(f))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var t Token
+	if err = json.Unmarshal(b, &t); err != nil {
+		return nil, fmt.Errorf("could not decode token file %s: %w", string(f), err)
+	}
+	return &t, nil
+}
+
+func (f fileTokenStore) Save(_ context.Context, t *Token) error {
+	b, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(string(f), b)
+}
+
+// writeFileAtomic writes b to a temporary file readable only by the owner and renames it to path,
+// so that a crash never leaves a truncated token file
+func writeFileAtomic(path string, b []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp.Name(), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// AuthCodeOptions configures the authorization code flow
+type AuthCodeOptions struct {
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	// Open is called with the authorization URL and should open it in the user's browser or show it to them
+	Open func(authURL string) error
+	// Store persists the refresh token between runs, optional
+	Store TokenStore
+	// ListenAddr is the loopback address of the redirect listener, 127.0.0.1 with a random port if empty.
+	// The redirect URI http://<ListenAddr>/callback must be allowed by the provider
+	ListenAddr string
+}
+
+// AuthCodeFlow performs the authorization code grant with PKCE (RFC 7636) using a loopback redirect (RFC 8252).
+//
+// A listener is started on the loopback interface, opts.Open is called with the authorization URL and the flow
+// waits until the browser is redirected back with a code or an error, or ctx is done.
+// The token is not saved to opts.Store, see AuthCode
+func (p *Provider) AuthCodeFlow(ctx context.Context, opts AuthCodeOptions) (*Token, error) {
+	if p.AuthorizationEndpoint == "" {
+		return nil, fmt.Errorf("provider %s has no authorization endpoint", p.Issuer)
+	}
+	if opts.Open == nil {
+		return nil, fmt.Errorf("auth code flow requires an Open function")
+	}
+	addr := opts.ListenAddr
+	if addr == "" {
+		addr = "127.0.0.1:0"
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("could not start redirect listener: %w", err)
+	}
+	redirectURI := "http://" + l.Addr().String() + "/callback"
+	verifier, state := randomString(32), randomString(16)
+	challenge := sha256.Sum256([]byte(verifier))
+
+	type result struct {
+		code string
+		err  error
+	}
+	results := make(chan result, 1)
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/callback" {
+			http.NotFound(w, r)
+			return
+		}
+		q := r.URL.Query()
+		if q.Get("state") != state {
+			// not our redirect, possibly a forged request, keep waiting
+			http.Error(w, "invalid state", http.StatusBadRequest)
+			return
+		}
+		var res result
+		if code := q.Get("error"); code != "" {
+			res.err = &TokenError{StatusCode: http.StatusBadRequest, Code: code, Description: q.Get("error_description")}
+			http.Error(w, "Authorization failed, you can close this window.", http.StatusForbidden)
+		} else {
+			res.code = q.Get("code")
+			_, _ = fmt.Fprintln(w, "Authorization complete, you can close this window.")
+		}
+		select {
+		case results <- res:
+		default:
+		}
+	})}
+	go func() { _ = srv.Serve(l) }()
+	defer srv.Close()
+
+	authURL, err := url.Parse(p.AuthorizationEndpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid authorization endpoint: %w", err)
+	}
+	q := authURL.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", opts.ClientID)
+	q.Set("redirect_uri", redirectURI)
+	q.Set("state", state)
+	q.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	q.Set("code_challenge_method", "S256")
+	if len(opts.Scopes) > 0 {
+		q.Set("scope", strings.Join(opts.Scopes, " "))
+	}
+	authURL.RawQuery = q.Encode()
+	if err = opts.Open(authURL.String()); err != nil {
+		return nil, err
+	}
+
+	var res result
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("authorization cancelled: %w", ctx.Err())
+	case res = <-results:
+	}
+	if res.err != nil {
+		return nil, res.err
+	}
+	return p.Exchange(ctx, opts.ClientID, opts.ClientSecret, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {res.code},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {verifier},
+	})
+}
+
+// AuthCode returns a middleware authorizing requests with a token from the authorization code flow.
+//
+// A token in opts.Store is used first. Afterwards the token is refreshed as it expires, and the browser flow runs
+// again only when there is no token or refreshing fails. Every new token is saved to opts.Store
+func (p *Provider) AuthCode(opts AuthCodeOptions) httpx.Middleware {
+	// Authorize never calls the source concurrently so src needs no locking
+	var src TokenSource
+	return Authorize(TokenSourceFunc(func(ctx context.Context) (*Token, error) {
+		if src == nil {
+			var stored *Token
+			if opts.Store != nil {
+				var err error
+				if stored, err = opts.Store.Load(ctx); err != nil {
+					return nil, fmt.Errorf("could not load token: %w", err)
+				}
+			}
+			src = p.refreshing(opts.ClientID, opts.ClientSecret, stored, func(ctx context.Context) (*Token, error) {
+				return p.AuthCodeFlow(ctx, opts)
+			})
+			if stored.Valid(httpx.ClockFromContext(ctx).Now()) {
+				return stored, nil
+			}
+		}
+		t, err := src.Token(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if opts.Store != nil {
+			if err = opts.Store.Save(ctx, t); err != nil {
+				return nil, fmt.Errorf("could not save token: %w", err)
+			}
+		}
+		return t, nil
+	}))
+}
+
+// randomString returns n random bytes encoded as unpadded base64url
+func randomString(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,65 @@
+package oidcx_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/oidcx"
+)
+
+func TestAuthCode(t *testing.T) {
+	op := newTestProvider(t)
+	provider, err := oidcx.Discover(context.Background(), op.Client(), op.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access-authorization_code" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer api.Close()
+
+	store := oidcx.FileTokenStore(filepath.Join(t.TempDir(), "token.json"))
+	var opened int
+	opts := oidcx.AuthCodeOptions{
+		ClientID:     "app",
+		ClientSecret: "s3cret",
+		Scopes:       []string{"openid", "offline_access"},
+		Store:        store,
+		// the browser follows the redirect from the provider to the loopback listener
+		Open: func(authURL string) error {
+			opened++
+			resp, err := http.Get(authURL)
+			if err == nil {
+				resp.Body.Close()
+			}
+			return err
+		},
+	}
+
+	var c httpx.Client = api.Client()
+	c = provider.AuthCode(opts)(c)
+	c = httpx.RequireResponseStatus(c, http.StatusOK)
+	if _, err = httpx.SetRequest(c, http.MethodGet, api.URL).Do(nil); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := store.Load(context.Background())
+	if err != nil || saved == nil || saved.RefreshToken != "refresh-1" {
+		t.Fatal(err, saved)
+	}
+
+	// a new run uses the stored token without opening the browser
+	c = provider.AuthCode(opts)(api.Client())
+	c = httpx.RequireResponseStatus(c, http.StatusOK)
+	if _, err = httpx.SetRequest(c, http.MethodGet, api.URL).Do(nil); err != nil {
+		t.Fatal(err)
+	}
+	if opened != 1 || op.tokenCalls != 1 {
+		t.Fatal(opened, op.tokenCalls)
+	}
+}
@@ -0,0 +1,99 @@
+package oidcx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/tflyons/httpx"
+)
+
+// DeviceAuthorization is the response of the device authorization endpoint, RFC 8628 section 3.2
+type DeviceAuthorization struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+	// ExpiresIn is the lifetime of the codes in seconds
+	ExpiresIn int64 `json:"expires_in"`
+	// Interval is the minimum number of seconds between polls of the token endpoint
+	Interval int64 `json:"interval,omitempty"`
+}
+
+// defaultDeviceInterval is the polling interval used when the provider does not give one, RFC 8628 section 3.5
+const defaultDeviceInterval = 5 * time.Second
+
+// DeviceFlow performs the OAuth 2.0 device authorization grant of RFC 8628 for input constrained clients such as
+// command line tools.
+//
+// prompt is called once the codes are issued and should show the user the verification URI and user code,
+// for example by printing them. The token endpoint is then polled at the interval requested by the provider,
+// slowing down when asked to, until the user approves or denies the request, the codes expire or ctx is done.
+// The wait between polls uses the clock of the context, see httpx.SetClock
+func (p *Provider) DeviceFlow(ctx context.Context, clientID, clientSecret string, prompt func(*DeviceAuthorization) error, scopes ...string) (*Token, error) {
+	if p.DeviceAuthorizationEndpoint == "" {
+		return nil, fmt.Errorf("provider %s has no device authorization endpoint", p.Issuer)
+	}
+	form := url.Values{}
+	if len(scopes) > 0 {
+		form.Set("scope", strings.Join(scopes, " "))
+	}
+	var auth DeviceAuthorization
+	if err := postForm(ctx, p.client(), p.DeviceAuthorizationEndpoint, clientID, clientSecret, form, &auth); err != nil {
+		return nil, fmt.Errorf("could not start device authorization: %w", err)
+	}
+	if auth.DeviceCode == "" || auth.UserCode == "" || auth.VerificationURI == "" {
+		return nil, fmt.Errorf("device authorization response is missing codes")
+	}
+	if err := prompt(&auth); err != nil {
+		return nil, err
+	}
+
+	clock := httpx.ClockFromContext(ctx)
+	interval := defaultDeviceInterval
+	if auth.Interval > 0 {
+		interval = time.Duration(auth.Interval) * time.Second
+	}
+	var deadline time.Time
+	if auth.ExpiresIn > 0 {
+		deadline = clock.Now().Add(time.Duration(auth.ExpiresIn) * time.Second)
+	}
+	poll := url.Values{
+		"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+		"device_code": {auth.DeviceCode},
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("device authorization cancelled: %w", ctx.Err())
+		case <-clock.After(interval):
+		}
+		if !deadline.IsZero() && !clock.Now().Before(deadline) {
+			return nil, fmt.Errorf("device authorization expired before the user approved it")
+		}
+		t, err := p.Exchange(ctx, clientID, clientSecret, poll)
+		var tokenErr *TokenError
+		if !errors.As(err, &tokenErr) {
+			return t, err
+		}
+		switch tokenErr.Code {
+		case "authorization_pending":
+		case "slow_down":
+			interval += 5 * time.Second
+		default:
+			// access_denied, expired_token and anything unexpected end the flow
+			return nil, err
+		}
+	}
+}
+
+// Device returns a TokenSource that runs DeviceFlow on first use and afterwards uses the refresh token, running
+// the flow again only if refreshing fails
+func (p *Provider) Device(clientID, clientSecret string, prompt func(*DeviceAuthorization) error, scopes ...string) TokenSource {
+	return p.refreshing(clientID, clientSecret, nil, func(ctx context.Context) (*Token, error) {
+		return p.DeviceFlow(ctx, clientID, clientSecret, prompt, scopes...)
+	})
+}
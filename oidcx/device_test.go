@@ -0,0 +1,79 @@
+package oidcx_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/httpxtest"
+	"github.com/tflyons/httpx/oidcx"
+)
+
+func TestDeviceFlow(t *testing.T) {
+	op := newTestProvider(t)
+	op.devicePending = []string{"authorization_pending", "slow_down"}
+	provider, err := oidcx.Discover(context.Background(), op.Client(), op.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := httpxtest.NewClock(time.Now())
+	httpx.SetDefaultClock(clock)
+	defer httpx.SetDefaultClock(nil)
+
+	var userCode string
+	done := make(chan error)
+	go func() {
+		tok, err := provider.DeviceFlow(context.Background(), "app", "s3cret", func(auth *oidcx.DeviceAuthorization) error {
+			userCode = auth.UserCode
+			return nil
+		})
+		if err == nil && tok.AccessToken != "access-device_code" {
+			err = errors.New("unexpected token " + tok.AccessToken)
+		}
+		done <- err
+	}()
+
+	// the interval starts at one second and grows by five seconds after slow_down
+	for _, wait := range []time.Duration{time.Second, time.Second, 6 * time.Second} {
+		clock.BlockUntil(1)
+		clock.Advance(wait - time.Millisecond)
+		select {
+		case err := <-done:
+			t.Fatal("polled too early", err)
+		default:
+		}
+		clock.Advance(time.Millisecond)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if userCode != "WDJB-MJHT" {
+		t.Fatal(userCode)
+	}
+}
+
+func TestDeviceFlow_Denied(t *testing.T) {
+	op := newTestProvider(t)
+	op.devicePending = []string{"access_denied"}
+	provider, err := oidcx.Discover(context.Background(), op.Client(), op.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := httpxtest.NewClock(time.Now())
+	httpx.SetDefaultClock(clock)
+	defer httpx.SetDefaultClock(nil)
+
+	done := make(chan error)
+	go func() {
+		_, err := provider.DeviceFlow(context.Background(), "app", "s3cret", func(*oidcx.DeviceAuthorization) error { return nil })
+		done <- err
+	}()
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	var tokenErr *oidcx.TokenError
+	if err := <-done; !errors.As(err, &tokenErr) || tokenErr.Code != "access_denied" {
+		t.Fatal(err)
+	}
+}
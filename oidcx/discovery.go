@@ -0,0 +1,67 @@
+// Package oidcx discovers OpenID Connect providers, acquires OAuth 2.0 tokens and validates ID and access tokens
+// for httpx clients and serverx handlers.
+//
+// A Provider is usually created with Discover. Its grant methods return a TokenSource, and Authorize turns a
+// TokenSource into an httpx.Middleware that keeps a fresh access token on every request.
+package oidcx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/tflyons/httpx"
+)
+
+// Provider holds the metadata of an OpenID Connect provider as published at .well-known/openid-configuration
+type Provider struct {
+	Issuer                      string   `json:"issuer"`
+	AuthorizationEndpoint       string   `json:"authorization_endpoint"`
+	TokenEndpoint               string   `json:"token_endpoint"`
+	DeviceAuthorizationEndpoint string   `json:"device_authorization_endpoint,omitempty"`
+	UserInfoEndpoint            string   `json:"userinfo_endpoint,omitempty"`
+	JWKSURI                     string   `json:"jwks_uri"`
+	ScopesSupported             []string `json:"scopes_supported,omitempty"`
+	GrantTypesSupported         []string `json:"grant_types_supported,omitempty"`
+
+	// Client performs requests to the provider, httpx.DefaultClient if nil
+	Client httpx.Client
+
+	keys jwksCache
+}
+
+// Discover fetches the provider metadata for issuer using c.
+//
+// The issuer in the metadata must match the one given exactly, as required by OpenID Connect Discovery
+func Discover(ctx context.Context, c httpx.Client, issuer string) (*Provider, error) {
+	if c == nil {
+		c = httpx.DefaultClient
+	}
+	wellKnown := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	p := &Provider{Client: c}
+	dc := httpx.RequireResponseStatus(c, http.StatusOK)
+	dc = httpx.SetResponseBodyHandlerJSON(dc, p)
+	dc = httpx.SetRequestWithContext(ctx, dc, http.MethodGet, wellKnown)
+	if resp, err := dc.Do(nil); err != nil {
+		if resp != nil && resp.Body != nil {
+			resp.Body.Close()
+		}
+		return nil, fmt.Errorf("could not discover %s: %w", wellKnown, err)
+	}
+	if p.Issuer != issuer {
+		return nil, fmt.Errorf("discovered issuer %q does not match %q", p.Issuer, issuer)
+	}
+	if p.TokenEndpoint == "" {
+		return nil, fmt.Errorf("provider %s has no token endpoint", issuer)
+	}
+	return p, nil
+}
+
+// client returns the Client of p or the default
+func (p *Provider) client() httpx.Client {
+	if p.Client == nil {
+		return httpx.DefaultClient
+	}
+	return p.Client
+}
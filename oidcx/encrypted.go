@@ -0,0 +1,100 @@
+package oidcx
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// ErrDecrypt is returned when a stored token cannot be decrypted, usually because the key changed
+var ErrDecrypt = fmt.Errorf("could not decrypt token")
+
+// KeySource returns the 32 byte key used to encrypt stored tokens.
+// Implementations may read it from the OS keychain or a secrets manager so that it is never written to disk
+type KeySource interface {
+	Key(ctx context.Context) ([]byte, error)
+}
+
+// StaticKey is a KeySource that always returns the same key
+type StaticKey []byte
+
+// Key returns k
+func (k StaticKey) Key(context.Context) ([]byte, error) {
+	return k, nil
+}
+
+// tokenAAD binds the ciphertext to its use so that it cannot be swapped for another value encrypted with the key
+var tokenAAD = []byte("oidcx token v1")
+
+// EncryptedFileTokenStore returns a TokenStore keeping the token in the file at path encrypted with AES-256-GCM
+// using the key from keys. The key is fetched on every Load and Save so it can be rotated, though tokens stored
+// with the previous key then fail to load with ErrDecrypt and the user has to log in again
+func EncryptedFileTokenStore(path string, keys KeySource) TokenStore {
+	return &encryptedFileTokenStore{path: path, keys: keys}
+}
+
+type encryptedFileTokenStore struct {
+	path string
+	keys KeySource
+}
+
+func (s *encryptedFileTokenStore) aead(ctx context.Context) (cipher.AEAD, error) {
+	key, err := s.keys.Key(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not get token encryption key: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("token encryption key must be 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (s *encryptedFileTokenStore) Load(ctx context.Context) (*Token, error) {
+	b, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	aead, err := s.aead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	plain, err := aead.Open(nil, b[:aead.NonceSize()], b[aead.NonceSize():], tokenAAD)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	var t Token
+	if err = json.Unmarshal(plain, &t); err != nil {
+		return nil, fmt.Errorf("could not decode token file %s: %w", s.path, err)
+	}
+	return &t, nil
+}
+
+func (s *encryptedFileTokenStore) Save(ctx context.Context, t *Token) error {
+	aead, err := s.aead(ctx)
+	if err != nil {
+		return err
+	}
+	plain, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, aead.Seal(nonce, nonce, plain, tokenAAD))
+}
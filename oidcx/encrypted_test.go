@@ -0,0 +1,46 @@
+package oidcx_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tflyons/httpx/oidcx"
+)
+
+func TestEncryptedFileTokenStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "token")
+	key := oidcx.StaticKey(bytes.Repeat([]byte{7}, 32))
+	store := oidcx.EncryptedFileTokenStore(path, key)
+
+	if tok, err := store.Load(ctx); tok != nil || err != nil {
+		t.Fatal(tok, err)
+	}
+	if err := store.Save(ctx, &oidcx.Token{AccessToken: "a", RefreshToken: "very-secret-refresh"}); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(b, []byte("very-secret-refresh")) {
+		t.Fatal("refresh token stored in plain text")
+	}
+	tok, err := store.Load(ctx)
+	if err != nil || tok.RefreshToken != "very-secret-refresh" {
+		t.Fatal(tok, err)
+	}
+
+	other := oidcx.EncryptedFileTokenStore(path, oidcx.StaticKey(bytes.Repeat([]byte{8}, 32)))
+	if _, err = other.Load(ctx); !errors.Is(err, oidcx.ErrDecrypt) {
+		t.Fatal(err)
+	}
+	short := oidcx.EncryptedFileTokenStore(path, oidcx.StaticKey("short"))
+	if err = short.Save(ctx, tok); err == nil {
+		t.Fatal("expected key length error")
+	}
+}
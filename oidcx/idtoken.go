@@ -0,0 +1,318 @@
+package oidcx
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tflyons/httpx"
+)
+
+// ErrInvalidIDToken is returned when an ID token fails validation
+var ErrInvalidIDToken = fmt.Errorf("invalid id token")
+
+// clockSkew is the leeway allowed when checking the exp, iat and nbf claims
+const clockSkew = time.Minute
+
+// IDToken holds the validated standard claims of an ID token
+type IDToken struct {
+	Issuer   string
+	Subject  string
+	Audience []string
+	Expiry   time.Time
+	IssuedAt time.Time
+	Nonce    string
+
+	claims []byte
+}
+
+// Claims decodes all claims of the token into v
+func (t *IDToken) Claims(v any) error {
+	return json.Unmarshal(t.claims, v)
+}
+
+// VerifyIDToken validates the signature of raw against the provider JWKS and checks the issuer, audience and
+// expiry claims for clientID. The nonce, if one was sent, must be checked by the caller.
+//
+// The JWKS is cached and fetched again when a token is signed with an unknown key id
+func (p *Provider) VerifyIDToken(ctx context.Context, raw, clientID string) (*IDToken, error) {
+	payload, err := p.verifyJWT(ctx, raw, ErrInvalidIDToken)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims struct {
+		Issuer    string   `json:"iss"`
+		Subject   string   `json:"sub"`
+		Audience  audience `json:"aud"`
+		AZP       string   `json:"azp"`
+		Expiry    float64  `json:"exp"`
+		IssuedAt  float64  `json:"iat"`
+		NotBefore float64  `json:"nbf"`
+		Nonce     string   `json:"nonce"`
+	}
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("%w: payload: %v", ErrInvalidIDToken, err)
+	}
+	now := httpx.ClockFromContext(ctx).Now()
+	switch {
+	case claims.Issuer != p.Issuer:
+		return nil, fmt.Errorf("%w: issuer %q does not match %q", ErrInvalidIDToken, claims.Issuer, p.Issuer)
+	case !claims.Audience.contains(clientID):
+		return nil, fmt.Errorf("%w: audience does not contain %q", ErrInvalidIDToken, clientID)
+	case claims.AZP != "" && claims.AZP != clientID:
+		return nil, fmt.Errorf("%w: authorized party %q is not %q", ErrInvalidIDToken, claims.AZP, clientID)
+	case claims.Expiry == 0 || now.Add(-clockSkew).After(numericDate(claims.Expiry)):
+		return nil, fmt.Errorf("%w: expired", ErrInvalidIDToken)
+	case claims.NotBefore != 0 && now.Add(clockSkew).Before(numericDate(claims.NotBefore)):
+		return nil, fmt.Errorf("%w: not yet valid", ErrInvalidIDToken)
+	case claims.IssuedAt != 0 && now.Add(clockSkew).Before(numericDate(claims.IssuedAt)):
+		return nil, fmt.Errorf("%w: issued in the future", ErrInvalidIDToken)
+	}
+	return &IDToken{
+		Issuer:   claims.Issuer,
+		Subject:  claims.Subject,
+		Audience: claims.Audience,
+		Expiry:   numericDate(claims.Expiry),
+		IssuedAt: numericDate(claims.IssuedAt),
+		Nonce:    claims.Nonce,
+		claims:   payload,
+	}, nil
+}
+
+// verifyJWT validates the signature of raw against the provider JWKS and returns its decoded payload. Invalid
+// tokens are reported wrapping invalid
+func (p *Provider) verifyJWT(ctx context.Context, raw string, invalid error) ([]byte, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed jwt", invalid)
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: header: %v", invalid, err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature: %v", invalid, err)
+	}
+	key, err := p.keys.get(ctx, p.client(), p.JWKSURI, header.Kid, invalid)
+	if err != nil {
+		return nil, err
+	}
+	if err = verifyJWS(header.Alg, key, []byte(parts[0]+"."+parts[1]), sig); err != nil {
+		return nil, fmt.Errorf("%w: %v", invalid, err)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: payload: %v", invalid, err)
+	}
+	return payload, nil
+}
+
+func decodeSegment(seg string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func numericDate(f float64) time.Time {
+	if f == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(f*float64(time.Second)))
+}
+
+// audience decodes the aud claim which is either a string or an array of strings
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var s string
+	if json.Unmarshal(b, &s) == nil {
+		*a = audience{s}
+		return nil
+	}
+	return json.Unmarshal(b, (*[]string)(a))
+}
+
+func (a audience) contains(s string) bool {
+	for _, v := range a {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyJWS checks sig over signed with key for the JWS algorithm alg
+func verifyJWS(alg string, key crypto.PublicKey, signed, sig []byte) error {
+	if len(alg) < 5 {
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	var h crypto.Hash
+	switch alg[len(alg)-3:] {
+	case "256":
+		h = crypto.SHA256
+	case "384":
+		h = crypto.SHA384
+	case "512":
+		h = crypto.SHA512
+	}
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if h == 0 {
+			break
+		}
+		digest := h.New()
+		digest.Write(signed)
+		switch alg[:2] {
+		case "RS":
+			return rsa.VerifyPKCS1v15(k, h, digest.Sum(nil), sig)
+		case "PS":
+			return rsa.VerifyPSS(k, h, digest.Sum(nil), sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		}
+	case *ecdsa.PublicKey:
+		if alg[:2] != "ES" || h == 0 {
+			break
+		}
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*size {
+			return fmt.Errorf("invalid signature length")
+		}
+		digest := h.New()
+		digest.Write(signed)
+		r, s := new(big.Int).SetBytes(sig[:size]), new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(k, digest.Sum(nil), r, s) {
+			return fmt.Errorf("signature does not match")
+		}
+		return nil
+	case ed25519.PublicKey:
+		if alg != "EdDSA" {
+			break
+		}
+		if !ed25519.Verify(k, signed, sig) {
+			return fmt.Errorf("signature does not match")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported algorithm %q for %T", alg, key)
+}
+
+// jwksCache holds the provider keys by key id
+type jwksCache struct {
+	mu      sync.Mutex
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+}
+
+// jwksRefetchInterval limits how often an unknown key id causes the JWKS to be fetched again
+const jwksRefetchInterval = time.Minute
+
+// get returns the key for kid, fetching the JWKS if it is not known. An empty kid matches a JWKS with a single key.
+// An unknown kid is reported wrapping invalid
+func (j *jwksCache) get(ctx context.Context, c httpx.Client, uri, kid string, invalid error) (crypto.PublicKey, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := httpx.ClockFromContext(ctx).Now()
+	if key := j.lookup(kid); key != nil {
+		return key, nil
+	}
+	if !j.fetched.IsZero() && now.Sub(j.fetched) < jwksRefetchInterval {
+		return nil, fmt.Errorf("%w: unknown key id %q", invalid, kid)
+	}
+	keys, err := fetchJWKS(ctx, c, uri)
+	if err != nil {
+		return nil, err
+	}
+	j.keys, j.fetched = keys, now
+	if key := j.lookup(kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: unknown key id %q", invalid, kid)
+}
+
+func (j *jwksCache) lookup(kid string) crypto.PublicKey {
+	if kid == "" && len(j.keys) == 1 {
+		for _, key := range j.keys {
+			return key
+		}
+	}
+	return j.keys[kid]
+}
+
+// fetchJWKS downloads the signing keys at uri, keys of unsupported types and encryption keys are skipped
+func fetchJWKS(ctx context.Context, c httpx.Client, uri string) (map[string]crypto.PublicKey, error) {
+	if uri == "" {
+		return nil, fmt.Errorf("provider has no jwks_uri")
+	}
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			Crv string `json:"crv"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	jc := httpx.RequireResponseStatus(c, http.StatusOK)
+	jc = httpx.SetResponseBodyHandlerJSON(jc, &set)
+	jc = httpx.SetRequestWithContext(ctx, jc, http.MethodGet, uri)
+	if resp, err := jc.Do(nil); err != nil {
+		if resp != nil && resp.Body != nil {
+			resp.Body.Close()
+		}
+		return nil, fmt.Errorf("could not fetch jwks: %w", err)
+	}
+	b64 := func(s string) *big.Int {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil || len(b) == 0 {
+			return nil
+		}
+		return new(big.Int).SetBytes(b)
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		switch k.Kty {
+		case "RSA":
+			n, e := b64(k.N), b64(k.E)
+			if n != nil && e != nil && e.IsInt64() {
+				keys[k.Kid] = &rsa.PublicKey{N: n, E: int(e.Int64())}
+			}
+		case "EC":
+			curves := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}
+			x, y := b64(k.X), b64(k.Y)
+			if curve, ok := curves[k.Crv]; ok && x != nil && y != nil && curve.IsOnCurve(x, y) {
+				keys[k.Kid] = &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+			}
+		case "OKP":
+			x, err := base64.RawURLEncoding.DecodeString(k.X)
+			if k.Crv == "Ed25519" && err == nil && len(x) == ed25519.PublicKeySize {
+				keys[k.Kid] = ed25519.PublicKey(x)
+			}
+		}
+	}
+	return keys, nil
+}
@@ -0,0 +1,73 @@
+package oidcx_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx/oidcx"
+)
+
+func (p *testProvider) sign(t *testing.T, kid string, claims map[string]any) string {
+	return p.signTyped(t, kid, "JWT", claims)
+}
+
+func (p *testProvider) signTyped(t *testing.T, kid, typ string, claims map[string]any) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": kid, "typ": typ})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	op := newTestProvider(t)
+	provider, err := oidcx.Discover(context.Background(), op.Client(), op.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().Unix()
+	claims := func(edit func(map[string]any)) map[string]any {
+		c := map[string]any{"iss": op.URL, "sub": "alice", "aud": "app", "exp": now + 300, "iat": now, "email": "alice@example.com"}
+		if edit != nil {
+			edit(c)
+		}
+		return c
+	}
+
+	tok, err := provider.VerifyIDToken(context.Background(), op.sign(t, "k1", claims(nil)), "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var extra struct {
+		Email string `json:"email"`
+	}
+	if err = tok.Claims(&extra); err != nil || tok.Subject != "alice" || extra.Email != "alice@example.com" {
+		t.Fatal(err, tok, extra)
+	}
+
+	for name, raw := range map[string]string{
+		"expired":      op.sign(t, "k1", claims(func(c map[string]any) { c["exp"] = now - 3600 })),
+		"audience":     op.sign(t, "k1", claims(func(c map[string]any) { c["aud"] = []string{"other"} })),
+		"issuer":       op.sign(t, "k1", claims(func(c map[string]any) { c["iss"] = "https://evil.example" })),
+		"unknown key":  op.sign(t, "k2", claims(nil)),
+		"tampered":     op.sign(t, "k1", claims(nil))[:10] + "x" + op.sign(t, "k1", claims(nil))[11:],
+		"not a jwt":    "abc.def",
+		"unsigned alg": base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + ".e30.",
+	} {
+		if _, err := provider.VerifyIDToken(context.Background(), raw, "app"); !errors.Is(err, oidcx.ErrInvalidIDToken) {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package oidcx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tflyons/httpx"
+)
+
+// expiryDelta is how long before its expiry a token is considered expired, so that it is not sent when it is
+// about to be rejected
+const expiryDelta = time.Minute
+
+// Token is an OAuth 2.0 token response
+type Token struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+	// ExpiresIn is the lifetime in seconds given by the token endpoint
+	ExpiresIn int64 `json:"expires_in,omitempty"`
+	// Expiry is calculated from ExpiresIn when the token is received, zero if the token does not expire
+	Expiry time.Time `json:"expiry,omitempty"`
+}
+
+// Valid reports whether the token has an access token that does not expire within the next minute
+func (t *Token) Valid(now time.Time) bool {
+	return t != nil && t.AccessToken != "" && (t.Expiry.IsZero() || now.Add(expiryDelta).Before(t.Expiry))
+}
+
+// ErrToken is matched by every *TokenError using errors.Is
+var ErrToken = fmt.Errorf("token request failed")
+
+// TokenError is an error response from the token endpoint as defined by RFC 6749 section 5.2
+type TokenError struct {
+	StatusCode  int    `json:"-"`
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+	URI         string `json:"error_uri,omitempty"`
+}
+
+// Error implements the error interface
+func (e *TokenError) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("%s (status %d): %s: %s", ErrToken, e.StatusCode, e.Code, e.Description)
+	}
+	return fmt.Sprintf("%s (status %d): %s", ErrToken, e.StatusCode, e.Code)
+}
+
+// Is matches ErrToken
+func (e *TokenError) Is(target error) bool {
+	return target == ErrToken
+}
+
+// Exchange posts form to the token endpoint and returns the token.
+//
+// The client authenticates with HTTP basic authentication when clientSecret is set,
+// otherwise client_id is added to the form for a public client. Error responses are returned as a *TokenError
+func (p *Provider) Exchange(ctx context.Context, clientID, clientSecret string, form url.Values) (*Token, error) {
+	return postToken(ctx, p.client(), p.TokenEndpoint, clientID, clientSecret, form)
+}
+
+// postToken posts form to endpoint and decodes a token or *TokenError response
+func postToken(ctx context.Context, c httpx.Client, endpoint, clientID, clientSecret string, form url.Values) (*Token, error) {
+	var t Token
+	if err := postForm(ctx, c, endpoint, clientID, clientSecret, form, &t); err != nil {
+		return nil, err
+	}
+	if t.AccessToken == "" {
+		return nil, fmt.Errorf("token response has no access token")
+	}
+	if t.ExpiresIn > 0 {
+		t.Expiry = httpx.ClockFromContext(ctx).Now().Add(time.Duration(t.ExpiresIn) * time.Second)
+	}
+	return &t, nil
+}
+
+// postForm posts form to endpoint with client authentication and decodes a 200 response into v,
+// any other response is returned as a *TokenError
+func postForm(ctx context.Context, c httpx.Client, endpoint, clientID, clientSecret string, form url.Values, v any) error {
+	var body []byte
+	if clientSecret != "" {
+		// RFC 6749 section 2.3.1 requires the credentials to be form encoded before basic authentication
+		c = basicAuth(c, url.QueryEscape(clientID), url.QueryEscape(clientSecret))
+	} else if clientID != "" {
+		form = cloneValues(form)
+		form.Set("client_id", clientID)
+	}
+	c = httpx.SetHeader(c, "Content-Type", "application/x-www-form-urlencoded")
+	c = httpx.SetHeader(c, "Accept", "application/json")
+	c = httpx.SetRequestBody(c, nil, []byte(form.Encode()))
+	c = httpx.SetRequestWithContext(ctx, c, http.MethodPost, endpoint)
+	resp, err := httpx.SetResponseBodyBytes(c, &body).Do(nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		tokenErr := &TokenError{StatusCode: resp.StatusCode}
+		if json.Unmarshal(body, tokenErr) != nil || tokenErr.Code == "" {
+			tokenErr.Code = strings.ToLower(strings.ReplaceAll(http.StatusText(resp.StatusCode), " ", "_"))
+		}
+		return tokenErr
+	}
+	if err = json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("could not decode response from %s: %w", endpoint, err)
+	}
+	return nil
+}
+
+func basicAuth(c httpx.Client, user, pass string) httpx.ClientFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		req.SetBasicAuth(user, pass)
+		return c.Do(req)
+	}
+}
+
+func cloneValues(v url.Values) url.Values {
+	out := make(url.Values, len(v)+1)
+	for k, vs := range v {
+		out[k] = append([]string(nil), vs...)
+	}
+	return out
+}
+
+// TokenSource returns a token for authorizing requests
+type TokenSource interface {
+	Token(ctx context.Context) (*Token, error)
+}
+
+// TokenSourceFunc is an adapter to allow the use of ordinary functions as a TokenSource
+type TokenSourceFunc func(ctx context.Context) (*Token, error)
+
+// Token calls f(ctx)
+func (f TokenSourceFunc) Token(ctx context.Context) (*Token, error) {
+	return f(ctx)
+}
+
+// ClientCredentials returns a TokenSource using the client credentials grant.
+// Every call requests a new token, see Authorize for reuse
+func (p *Provider) ClientCredentials(clientID, clientSecret string, scopes ...string) TokenSource {
+	return TokenSourceFunc(func(ctx context.Context) (*Token, error) {
+		form := url.Values{"grant_type": {"client_credentials"}}
+		if len(scopes) > 0 {
+			form.Set("scope", strings.Join(scopes, " "))
+		}
+		return p.Exchange(ctx, clientID, clientSecret, form)
+	})
+}
+
+// Password returns a TokenSource using the resource owner password credentials grant.
+// Once a refresh token has been issued it is used instead of the password until it stops working
+func (p *Provider) Password(clientID, clientSecret, username, password string, scopes ...string) TokenSource {
+	return p.refreshing(clientID, clientSecret, nil, func(ctx context.Context) (*Token, error) {
+		form := url.Values{
+			"grant_type": {"password"},
+			"username":   {username},
+			"password":   {password},
+		}
+		if len(scopes) > 0 {
+			form.Set("scope", strings.Join(scopes, " "))
+		}
+		return p.Exchange(ctx, clientID, clientSecret, form)
+	})
+}
+
+// Refresh exchanges refreshToken for a new token. The new token keeps refreshToken if no new one was issued
+func (p *Provider) Refresh(ctx context.Context, clientID, clientSecret, refreshToken string) (*Token, error) {
+	t, err := p.Exchange(ctx, clientID, clientSecret, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if t.RefreshToken == "" {
+		t.RefreshToken = refreshToken
+	}
+	return t, nil
+}
+
+// refreshing returns a TokenSource that refreshes the last token when it has a refresh token and otherwise calls
+// grant, which may be nil if t has a refresh token
+func (p *Provider) refreshing(clientID, clientSecret string, t *Token, grant func(context.Context) (*Token, error)) TokenSource {
+	var mu sync.Mutex
+	last := t
+	return TokenSourceFunc(func(ctx context.Context) (*Token, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if last != nil && last.RefreshToken != "" {
+			t, err := p.Refresh(ctx, clientID, clientSecret, last.RefreshToken)
+			if err == nil || grant == nil {
+				if err == nil {
+					last = t
+				}
+				return t, err
+			}
+		}
+		if grant == nil {
+			return nil, fmt.Errorf("no refresh token available")
+		}
+		t, err := grant(ctx)
+		if err != nil {
+			return nil, err
+		}
+		last = t
+		return t, nil
+	})
+}
+
+// Authorize returns a middleware that sets the access token from src as a bearer token on every request.
+//
+// The token is reused until it is within a minute of expiring, and a 401 response discards it so that the next
+// request fetches a new one. Concurrent requests wait for a single token request
+func Authorize(src TokenSource) httpx.Middleware {
+	var mu sync.Mutex
+	var current *Token
+	return func(c httpx.Client) httpx.ClientFunc {
+		if c == nil {
+			c = httpx.DefaultClient
+		}
+		return func(req *http.Request) (*http.Response, error) {
+			ctx := req.Context()
+			mu.Lock()
+			if !current.Valid(httpx.ClockFromContext(ctx).Now()) {
+				t, err := src.Token(ctx)
+				if err != nil {
+					mu.Unlock()
+					return nil, fmt.Errorf("could not get access token: %w", err)
+				}
+				current = t
+			}
+			t := current
+			mu.Unlock()
+
+			if req.Header == nil {
+				req.Header = make(http.Header)
+			}
+			req.Header.Set("Authorization", "Bearer "+t.AccessToken)
+			resp, err := c.Do(req)
+			if err == nil && resp.StatusCode == http.StatusUnauthorized {
+				mu.Lock()
+				if current == t {
+					current = nil
+				}
+				mu.Unlock()
+			}
+			return resp, err
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package oidcx_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/oidcx"
+)
+
+// testProvider is a minimal OpenID provider signing ID tokens with an RSA key
+type testProvider struct {
+	*httptest.Server
+	key        *rsa.PrivateKey
+	tokenCalls int32
+	lastGrant  string
+	// devicePending are the error codes returned to device code polls before a token is issued
+	devicePending []string
+	// challenge is the PKCE code challenge of the last authorization request
+	challenge string
+}
+
+func newTestProvider(t *testing.T) *testProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &testProvider{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                        p.URL,
+			"authorization_endpoint":        p.URL + "/authorize",
+			"token_endpoint":                p.URL + "/token",
+			"device_authorization_endpoint": p.URL + "/device",
+			"jwks_uri":                      p.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("code_challenge_method") != "S256" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		p.challenge = q.Get("code_challenge")
+		http.Redirect(w, r, q.Get("redirect_uri")+"?code=code-1&state="+url.QueryEscape(q.Get("state")), http.StatusFound)
+	})
+	mux.HandleFunc("/device", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"device_code":      "device-1",
+			"user_code":        "WDJB-MJHT",
+			"verification_uri": p.URL + "/activate",
+			"expires_in":       600,
+			"interval":         1,
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "k1",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&p.tokenCalls, 1)
+		id, secret, _ := r.BasicAuth()
+		if id != "app" || secret != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+			return
+		}
+		p.lastGrant = r.PostFormValue("grant_type")
+		switch p.lastGrant {
+		case "password":
+			if r.PostFormValue("password") != "hunter2" {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+				return
+			}
+		case "authorization_code":
+			sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+			if r.PostFormValue("code") != "code-1" || base64.RawURLEncoding.EncodeToString(sum[:]) != p.challenge {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+				return
+			}
+		case "urn:ietf:params:oauth:grant-type:device_code":
+			if len(p.devicePending) > 0 {
+				code := p.devicePending[0]
+				p.devicePending = p.devicePending[1:]
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": code})
+				return
+			}
+			p.lastGrant = "device_code"
+		case "refresh_token":
+			if r.PostFormValue("refresh_token") != "refresh-1" {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+				return
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token":  "access-" + p.lastGrant,
+			"token_type":    "Bearer",
+			"expires_in":    3600,
+			"refresh_token": "refresh-1",
+		})
+	})
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+	return p
+}
+
+func TestClientCredentials_Authorize(t *testing.T) {
+	op := newTestProvider(t)
+	provider, err := oidcx.Discover(context.Background(), op.Client(), op.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access-client_credentials" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer api.Close()
+
+	var c httpx.Client = api.Client()
+	c = oidcx.Authorize(provider.ClientCredentials("app", "s3cret", "read"))(c)
+	c = httpx.RequireResponseStatus(c, http.StatusOK)
+	c = httpx.SetRequest(c, http.MethodGet, api.URL)
+	for i := 0; i < 3; i++ {
+		if _, err := c.Do(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if op.tokenCalls != 1 {
+		t.Fatal("expected the token to be reused", op.tokenCalls)
+	}
+}
+
+func TestPassword_Refresh(t *testing.T) {
+	op := newTestProvider(t)
+	provider, err := oidcx.Discover(context.Background(), op.Client(), op.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := provider.Password("app", "s3cret", "alice", "hunter2")
+	for _, grant := range []string{"password", "refresh_token"} {
+		tok, err := src.Token(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if op.lastGrant != grant || tok.AccessToken != "access-"+grant || tok.Expiry.IsZero() {
+			t.Fatal(op.lastGrant, tok)
+		}
+	}
+}
+
+func TestTokenError(t *testing.T) {
+	op := newTestProvider(t)
+	provider, err := oidcx.Discover(context.Background(), op.Client(), op.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = provider.ClientCredentials("app", "wrong").Token(context.Background())
+	var tokenErr *oidcx.TokenError
+	if !errors.Is(err, oidcx.ErrToken) || !errors.As(err, &tokenErr) || tokenErr.Code != "invalid_client" {
+		t.Fatal(err)
+	}
+}
+
+func TestDiscover_IssuerMismatch(t *testing.T) {
+	op := newTestProvider(t)
+	if _, err := oidcx.Discover(context.Background(), op.Client(), op.URL+"/other"); err == nil {
+		t.Fatal("expected error")
+	}
+}
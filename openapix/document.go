@@ -0,0 +1,204 @@
+// Package openapix loads OpenAPI 3 documents for use with httpx clients.
+//
+// The document model is shared by the httpxgen code generator and the response validation decorator.
+// Only JSON encoded documents are supported.
+package openapix
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Document is the subset of an OpenAPI 3 document used by this package
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Servers    []Server            `json:"servers"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+
+	// raw holds the untyped document so that schemas can be validated with all of their keywords
+	raw map[string]any
+}
+
+// Info describes the API
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// Server is a base URL the API is served from
+type Server struct {
+	URL string `json:"url"`
+}
+
+// Components holds the reusable objects referenced by operations
+type Components struct {
+	Schemas    map[string]*Schema    `json:"schemas"`
+	Parameters map[string]*Parameter `json:"parameters"`
+}
+
+// PathItem holds the operations available on a single path
+type PathItem struct {
+	Parameters []*Parameter `json:"parameters"`
+	Get        *Operation   `json:"get"`
+	Put        *Operation   `json:"put"`
+	Post       *Operation   `json:"post"`
+	Delete     *Operation   `json:"delete"`
+	Options    *Operation   `json:"options"`
+	Head       *Operation   `json:"head"`
+	Patch      *Operation   `json:"patch"`
+}
+
+// Operations returns the operations of the path item paired with their http method in a stable order
+func (p PathItem) Operations() []MethodOperation {
+	var ops []MethodOperation
+	for _, m := range []struct {
+		method string
+		op     *Operation
+	}{
+		{"GET", p.Get},
+		{"HEAD", p.Head},
+		{"POST", p.Post},
+		{"PUT", p.Put},
+		{"PATCH", p.Patch},
+		{"DELETE", p.Delete},
+		{"OPTIONS", p.Options},
+	} {
+		if m.op != nil {
+			ops = append(ops, MethodOperation{Method: m.method, Operation: m.op})
+		}
+	}
+	return ops
+}
+
+// Operation returns the operation for the http method or nil if there is none
+func (p PathItem) Operation(method string) *Operation {
+	for _, op := range p.Operations() {
+		if strings.EqualFold(op.Method, method) {
+			return op.Operation
+		}
+	}
+	return nil
+}
+
+// MethodOperation pairs an operation with its http method
+type MethodOperation struct {
+	Method string
+	*Operation
+}
+
+// Operation is a single API call
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary"`
+	Description string               `json:"description"`
+	Parameters  []*Parameter         `json:"parameters"`
+	RequestBody *RequestBody         `json:"requestBody"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter is a path, query, header or cookie parameter
+type Parameter struct {
+	Ref         string  `json:"$ref"`
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description"`
+	Required    bool    `json:"required"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody describes the body sent with an operation
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response describes a single response of an operation
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content"`
+}
+
+// MediaType holds the schema for a content type
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is the subset of JSON schema used to generate Go types
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Description          string             `json:"description"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	Items                *Schema            `json:"items"`
+	AdditionalProperties json.RawMessage    `json:"additionalProperties"`
+	Enum                 []any              `json:"enum"`
+}
+
+// Load reads and parses a JSON encoded OpenAPI 3 document
+func Load(r io.Reader) (*Document, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(b)
+}
+
+// Parse decodes a JSON encoded OpenAPI 3 document
+func Parse(b []byte) (*Document, error) {
+	var d Document
+	if err := json.Unmarshal(b, &d); err != nil {
+		return nil, fmt.Errorf("could not parse OpenAPI document: %w", err)
+	}
+	if !strings.HasPrefix(d.OpenAPI, "3.") {
+		return nil, fmt.Errorf("unsupported OpenAPI version %q", d.OpenAPI)
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&d.raw); err != nil {
+		return nil, fmt.Errorf("could not parse OpenAPI document: %w", err)
+	}
+	return &d, nil
+}
+
+// ResolveParameter follows a components parameter reference
+func (d *Document) ResolveParameter(p *Parameter) (*Parameter, error) {
+	if p.Ref == "" {
+		return p, nil
+	}
+	name := strings.TrimPrefix(p.Ref, "#/components/parameters/")
+	resolved, ok := d.Components.Parameters[name]
+	if !ok || name == p.Ref {
+		return nil, fmt.Errorf("unresolved parameter reference %q", p.Ref)
+	}
+	return resolved, nil
+}
+
+// JSONContent returns the schema of the JSON media type in content, if any
+func JSONContent(content map[string]MediaType) *Schema {
+	if mt, ok := content["application/json"]; ok {
+		return mt.Schema
+	}
+	for _, ct := range sortedKeys(content) {
+		if strings.HasSuffix(ct, "+json") {
+			return content[ct].Schema
+		}
+	}
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
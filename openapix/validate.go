@@ -0,0 +1,235 @@
+package openapix
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/tflyons/httpx"
+)
+
+// ErrContractViolation is matched by every Violation using errors.Is
+var ErrContractViolation = errors.New("response does not match the OpenAPI document")
+
+// Violation describes a response that does not match the document
+type Violation struct {
+	Method string
+	URL    string
+	// Path is the path template of the matched operation, empty if no operation matched
+	Path   string
+	Status int
+	Reason string
+}
+
+// Error implements the error interface
+func (v *Violation) Error() string {
+	return fmt.Sprintf("%s: %s %s (%d): %s", ErrContractViolation, v.Method, v.URL, v.Status, v.Reason)
+}
+
+// Is matches ErrContractViolation
+func (v *Violation) Is(target error) bool {
+	return target == ErrContractViolation
+}
+
+// ValidateResponses checks each response against the operation it matches in the document.
+//
+// The status code must be documented, the Content-Type must be one of the documented media types and
+// JSON bodies must match the documented schema using httpx.DefaultSchemaValidator.
+// If report is nil a violation is returned as a *Violation error, otherwise report is called and the response
+// is returned unchanged so that validation can run in staging without breaking callers.
+func ValidateResponses(c httpx.Client, doc *Document, report func(*Violation)) httpx.ClientFunc {
+	if c == nil {
+		c = httpx.DefaultClient
+	}
+	return func(req *http.Request) (*http.Response, error) {
+		resp, err := c.Do(req)
+		if err != nil {
+			return resp, err
+		}
+		v, err := doc.checkResponse(req, resp)
+		if err != nil {
+			return resp, err
+		}
+		if v == nil {
+			return resp, nil
+		}
+		if report == nil {
+			return resp, v
+		}
+		report(v)
+		return resp, nil
+	}
+}
+
+// checkResponse returns a violation if the response does not match the document
+func (d *Document) checkResponse(req *http.Request, resp *http.Response) (*Violation, error) {
+	v := &Violation{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Status: resp.StatusCode,
+	}
+	template, item, ok := d.matchPath(req.URL)
+	if !ok {
+		v.Reason = "no documented path matches the request"
+		return v, nil
+	}
+	v.Path = template
+	op := item.Operation(req.Method)
+	if op == nil {
+		v.Reason = fmt.Sprintf("method %s is not documented for %s", req.Method, template)
+		return v, nil
+	}
+	code, response := matchStatus(op.Responses, resp.StatusCode)
+	if response == nil {
+		v.Reason = fmt.Sprintf("status %d is not documented", resp.StatusCode)
+		return v, nil
+	}
+	if len(response.Content) == 0 {
+		return nil, nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		v.Reason = fmt.Sprintf("invalid content type %q", resp.Header.Get("Content-Type"))
+		return v, nil
+	}
+	documented, ok := matchMediaType(response.Content, mediaType)
+	if !ok {
+		v.Reason = fmt.Sprintf("content type %s is not documented", mediaType)
+		return v, nil
+	}
+	if mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json") {
+		return nil, nil
+	}
+
+	schema, ok := d.rawSchema(template, req.Method, code, documented)
+	if !ok || resp.Body == nil {
+		return nil, nil
+	}
+	b, err := io.ReadAll(resp.Body)
+	closeErr := resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	if closeErr != nil {
+		return nil, closeErr
+	}
+	// components are carried along so that local references resolve against the wrapping schema
+	wrapped, err := json.Marshal(map[string]any{
+		"allOf":      []any{schema},
+		"components": d.raw["components"],
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err = httpx.DefaultSchemaValidator.Validate(wrapped, b); err != nil {
+		v.Reason = err.Error()
+		return v, nil
+	}
+	return nil, nil
+}
+
+// matchPath finds the path template matching the request URL, trying each server base path as a prefix
+func (d *Document) matchPath(u *url.URL) (string, PathItem, bool) {
+	prefixes := []string{""}
+	for _, s := range d.Servers {
+		if su, err := url.Parse(s.URL); err == nil && su.Path != "" && su.Path != "/" {
+			prefixes = append(prefixes, strings.TrimSuffix(su.Path, "/"))
+		}
+	}
+	best, bestParams := "", -1
+	for _, prefix := range prefixes {
+		if !strings.HasPrefix(u.Path, prefix) {
+			continue
+		}
+		segments := strings.Split(strings.TrimPrefix(u.Path, prefix), "/")
+		for template := range d.Paths {
+			params, ok := matchTemplate(strings.Split(template, "/"), segments)
+			// prefer the most specific template when several match
+			if ok && (bestParams < 0 || params < bestParams || (params == bestParams && template < best)) {
+				best, bestParams = template, params
+			}
+		}
+	}
+	if bestParams < 0 {
+		return "", PathItem{}, false
+	}
+	return best, d.Paths[best], true
+}
+
+// matchTemplate reports whether the path segments match the template and how many parameters were used
+func matchTemplate(template, segments []string) (int, bool) {
+	if len(template) != len(segments) {
+		return 0, false
+	}
+	params := 0
+	for i := range template {
+		if strings.HasPrefix(template[i], "{") && strings.HasSuffix(template[i], "}") {
+			if segments[i] == "" {
+				return 0, false
+			}
+			params++
+			continue
+		}
+		if template[i] != segments[i] {
+			return 0, false
+		}
+	}
+	return params, true
+}
+
+// matchStatus finds the documented response for the status using exact codes, ranges such as 2XX and then default
+func matchStatus(responses map[string]*Response, status int) (string, *Response) {
+	code := strconv.Itoa(status)
+	if r, ok := responses[code]; ok {
+		return code, r
+	}
+	for _, k := range []string{code[:1] + "XX", code[:1] + "xx"} {
+		if r, ok := responses[k]; ok {
+			return k, r
+		}
+	}
+	if r, ok := responses["default"]; ok {
+		return "default", r
+	}
+	return "", nil
+}
+
+// matchMediaType finds the documented media type for the response, honouring wildcards such as application/*
+func matchMediaType(content map[string]MediaType, mediaType string) (string, bool) {
+	if _, ok := content[mediaType]; ok {
+		return mediaType, true
+	}
+	if major, _, ok := strings.Cut(mediaType, "/"); ok {
+		if _, ok := content[major+"/*"]; ok {
+			return major + "/*", true
+		}
+	}
+	if _, ok := content["*/*"]; ok {
+		return "*/*", true
+	}
+	return "", false
+}
+
+// rawSchema returns the untyped schema of the documented response
+func (d *Document) rawSchema(template, method, code, mediaType string) (any, bool) {
+	cur := any(d.raw)
+	for _, key := range []string{"paths", template, strings.ToLower(method), "responses", code, "content", mediaType, "schema"} {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
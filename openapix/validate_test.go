@@ -0,0 +1,88 @@
+package openapix_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/openapix"
+)
+
+func loadPetstore(t *testing.T) *openapix.Document {
+	f, err := os.Open("testdata/petstore.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	doc, err := openapix.Load(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestValidateResponses(t *testing.T) {
+	doc := loadPetstore(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/pets/1":
+			_, _ = w.Write([]byte(`{"id":1,"name":"rex"}`))
+		case "/v1/pets/2":
+			// name is required
+			_, _ = w.Write([]byte(`{"id":2}`))
+		case "/v1/pets/3":
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte(`rex`))
+		default:
+			w.WriteHeader(http.StatusTeapot)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		path    string
+		wantErr bool
+	}{
+		{"/v1/pets/1", false},
+		{"/v1/pets/2", true},
+		{"/v1/pets/3", true},
+		{"/v1/unknown", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			var c httpx.Client = srv.Client()
+			c = openapix.ValidateResponses(c, doc, nil)
+			c = httpx.SetRequest(c, http.MethodGet, srv.URL+tt.path)
+			_, err := c.Do(nil)
+			if tt.wantErr != errors.Is(err, openapix.ErrContractViolation) {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestValidateResponses_Report(t *testing.T) {
+	doc := loadPetstore(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"id":"one","name":"rex"}]`))
+	}))
+	defer srv.Close()
+
+	var violations []*openapix.Violation
+	var c httpx.Client = srv.Client()
+	c = openapix.ValidateResponses(c, doc, func(v *openapix.Violation) {
+		violations = append(violations, v)
+	})
+	c = httpx.SetRequest(c, http.MethodGet, srv.URL+"/v1/pets")
+	if _, err := c.Do(nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(violations) != 1 || violations[0].Path != "/pets" {
+		t.Fatal(violations)
+	}
+}
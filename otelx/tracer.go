@@ -0,0 +1,89 @@
+// Package otelx connects httpx.SetTracer to OpenTelemetry.
+//
+// It is a separate module so that the OpenTelemetry dependencies are only required by programs that use it:
+//
+//	c = httpx.SetTracer(c, otelx.NewTracer(otelx.Options{}))
+package otelx
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/tflyons/httpx"
+)
+
+// InstrumentationName is the name of the OpenTelemetry tracer spans are started with
+const InstrumentationName = "github.com/tflyons/httpx/otelx"
+
+// Options configures NewTracer
+type Options struct {
+	// TracerProvider creates the tracer, otel.GetTracerProvider() if nil
+	TracerProvider trace.TracerProvider
+	// Propagator injects the span into request headers, otel.GetTextMapPropagator() if nil
+	Propagator propagation.TextMapPropagator
+}
+
+// Tracer is an httpx.Tracer and httpx.TracePropagator starting OpenTelemetry client spans
+type Tracer struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+// NewTracer returns a Tracer using the provider and propagator of opts
+func NewTracer(opts Options) *Tracer {
+	if opts.TracerProvider == nil {
+		opts.TracerProvider = otel.GetTracerProvider()
+	}
+	if opts.Propagator == nil {
+		opts.Propagator = otel.GetTextMapPropagator()
+	}
+	return &Tracer{tracer: opts.TracerProvider.Tracer(InstrumentationName), propagator: opts.Propagator}
+}
+
+// StartSpan implements httpx.Tracer, starting a client span with the tags as attributes
+func (t *Tracer) StartSpan(ctx context.Context, name string, tags map[string]string) context.Context {
+	ctx, _ = t.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attributes(tags)...))
+	return ctx
+}
+
+// FinishSpan implements httpx.Tracer. The span status is an error if err is not nil or the result has an
+// httpx.TagErrorClass, which includes 4xx and 5xx responses
+func (t *Tracer) FinishSpan(ctx context.Context, tags map[string]string, err error) {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attributes(tags)...)
+	switch {
+	case err != nil:
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	case tags[httpx.TagErrorClass] != "":
+		span.SetStatus(codes.Error, tags[httpx.TagErrorClass])
+	}
+	span.End()
+}
+
+// Inject implements httpx.TracePropagator
+func (t *Tracer) Inject(ctx context.Context, header http.Header) {
+	t.propagator.Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// attributes converts tags to attributes, the status code and attempt as integers
+func attributes(tags map[string]string) []attribute.KeyValue {
+	attrs := make([]attribute.KeyValue, 0, len(tags))
+	for k, v := range tags {
+		if k == httpx.TagHTTPStatusCode || k == httpx.TagAttempt {
+			if n, err := strconv.Atoi(v); err == nil {
+				attrs = append(attrs, attribute.Int(k, n))
+				continue
+			}
+		}
+		attrs = append(attrs, attribute.String(k, v))
+	}
+	return attrs
+}
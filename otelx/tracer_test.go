@@ -0,0 +1,86 @@
+package otelx_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/otelx"
+)
+
+func TestTracer(t *testing.T) {
+	var traceparent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("Traceparent")
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	tracer := otelx.NewTracer(otelx.Options{
+		TracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)),
+		Propagator:     propagation.TraceContext{},
+	})
+	c := httpx.SetTracer(srv.Client(), tracer)
+	for _, path := range []string{"/", "/missing"} {
+		resp, err := httpx.SetRequest(c, http.MethodGet, srv.URL+path).Do(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatal(len(spans))
+	}
+	ok, missing := spans[0], spans[1]
+	if ok.Name() != "HTTP GET" || ok.SpanKind() != trace.SpanKindClient || ok.Status().Code != codes.Unset {
+		t.Fatal(ok.Name(), ok.SpanKind(), ok.Status())
+	}
+	if !hasAttribute(ok, attribute.Int(httpx.TagHTTPStatusCode, http.StatusOK)) ||
+		!hasAttribute(ok, attribute.String(httpx.TagHTTPMethod, http.MethodGet)) {
+		t.Fatal(ok.Attributes())
+	}
+	if missing.Status().Code != codes.Error {
+		t.Fatal("expected a 404 to set the error status", missing.Status())
+	}
+	if want := "00-" + missing.SpanContext().TraceID().String() + "-" + missing.SpanContext().SpanID().String() + "-01"; traceparent != want {
+		t.Fatal("expected the span to be propagated", traceparent, want)
+	}
+}
+
+func TestTracer_Error(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := otelx.NewTracer(otelx.Options{TracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))})
+	failing := httpx.ClientFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	})
+	if _, err := httpx.SetRequest(httpx.SetTracer(failing, tracer), http.MethodGet, "http://example.com").Do(nil); err == nil {
+		t.Fatal("expected an error")
+	}
+	spans := recorder.Ended()
+	if len(spans) != 1 || spans[0].Status().Code != codes.Error || len(spans[0].Events()) != 1 {
+		t.Fatal(spans)
+	}
+}
+
+func hasAttribute(span sdktrace.ReadOnlySpan, want attribute.KeyValue) bool {
+	for _, kv := range span.Attributes() {
+		if kv == want {
+			return true
+		}
+	}
+	return false
+}
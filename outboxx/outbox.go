@@ -0,0 +1,299 @@
+// Package outboxx delivers requests with side effects, such as notifications and webhooks, reliably through httpx
+// clients using the transactional outbox pattern.
+//
+// Requests are enqueued in a durable Store and sent by a background dispatcher, with retries, until they are
+// delivered or fail permanently:
+//
+//	ob := outboxx.New(store, httpx.SetTimeout(nil, 10*time.Second))
+//	ob.OnFailed = func(m *outboxx.Message, err error) { log.Printf("giving up on %s %s: %v", m.Method, m.URL, err) }
+//	go ob.Run(ctx)
+//	_, err := ob.Enqueue(ctx, req, "customer-42")
+package outboxx
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/tflyons/httpx"
+)
+
+// Message is an enqueued request and its delivery state
+type Message struct {
+	ID string `json:"id"`
+	// Key orders delivery, messages with the same non-empty key are sent one at a time in the order they were
+	// enqueued. Messages without a key are not ordered
+	Key      string      `json:"key,omitempty"`
+	Method   string      `json:"method"`
+	URL      string      `json:"url"`
+	Header   http.Header `json:"header,omitempty"`
+	Body     []byte      `json:"body,omitempty"`
+	Enqueued time.Time   `json:"enqueued"`
+	// Attempts is the number of times delivery was tried
+	Attempts int `json:"attempts"`
+	// NextAttempt is when the message is next sent, zero to send it straight away
+	NextAttempt time.Time `json:"next_attempt,omitempty"`
+	// LastError describes the last failed attempt
+	LastError string `json:"last_error,omitempty"`
+}
+
+func (m *Message) clone() *Message {
+	copied := *m
+	copied.Header = m.Header.Clone()
+	return &copied
+}
+
+// request returns the http request for m
+func (m *Message) request(ctx context.Context) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, m.Method, m.URL, bytes.NewReader(m.Body))
+	if err != nil {
+		return nil, err
+	}
+	if len(m.Body) == 0 {
+		req.Body, req.GetBody, req.ContentLength = http.NoBody, nil, 0
+	}
+	for k, v := range m.Header {
+		req.Header[k] = append([]string(nil), v...)
+	}
+	req.Header.Set("Idempotency-Key", m.ID)
+	return req, nil
+}
+
+// Outbox enqueues requests in a Store and delivers them with a dispatcher started by Run. The exported fields
+// must be set before Run is called
+type Outbox struct {
+	// MaxAttempts is the number of tries before a message fails permanently, 10 if zero
+	MaxAttempts int
+	// Backoff is the wait between attempts of a message, exponential from 1 second up to 5 minutes if nil.
+	// A message fails permanently when it returns false
+	Backoff httpx.Backoff
+	// PollInterval is how often the store is checked for messages enqueued by other processes, 1 second if zero
+	PollInterval time.Duration
+	// Concurrency is the number of messages sent at once, 4 if zero
+	Concurrency int
+	// OnDelivered is called when a message receives a 2xx response. The response body is closed after it returns
+	OnDelivered func(m *Message, resp *http.Response)
+	// OnFailed is called when a message fails permanently: on a 4xx response other than 408 and 429, once
+	// MaxAttempts have been made or when Backoff gives up. The message is then removed from the store
+	OnFailed func(m *Message, err error)
+
+	store  Store
+	client httpx.Client
+	wake   chan struct{}
+
+	mu sync.Mutex
+	// inflight holds the IDs of the messages being sent
+	inflight map[string]bool
+	sending  sync.WaitGroup
+}
+
+// New returns an outbox keeping messages in store and sending them with c, httpx.DefaultClient if nil. c should
+// not retry itself, the outbox retries across restarts
+func New(store Store, c httpx.Client) *Outbox {
+	if c == nil {
+		c = httpx.DefaultClient
+	}
+	return &Outbox{store: store, client: c, wake: make(chan struct{}, 1)}
+}
+
+// Enqueue stores req for delivery and returns the message ID, which is sent as the Idempotency-Key header so that
+// a receiver can drop duplicates of an attempt whose response was lost. The body of req is read in full. key
+// orders delivery, see Message.Key
+func (o *Outbox) Enqueue(ctx context.Context, req *http.Request, key string) (string, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		b, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return "", fmt.Errorf("could not read request body: %w", err)
+		}
+		body = b
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	m := &Message{
+		ID:       hex.EncodeToString(id),
+		Key:      key,
+		Method:   req.Method,
+		URL:      req.URL.String(),
+		Header:   req.Header.Clone(),
+		Body:     body,
+		Enqueued: httpx.ClockFromContext(ctx).Now(),
+	}
+	if err := o.store.Add(ctx, m); err != nil {
+		return "", fmt.Errorf("could not enqueue message: %w", err)
+	}
+	o.notify()
+	return m.ID, nil
+}
+
+// Run dispatches messages until ctx is done and returns its error once the messages being sent have finished.
+// Store errors are logged with log.Printf and the store is tried again at the next poll. Run must not be called more
+// than once at a time for the same store
+func (o *Outbox) Run(ctx context.Context) error {
+	clock := httpx.ClockFromContext(ctx)
+	workers := o.Concurrency
+	if workers <= 0 {
+		workers = 4
+	}
+	sem := make(chan struct{}, workers)
+	defer o.sending.Wait()
+	for {
+		wait, err := o.dispatch(ctx, sem)
+		if err != nil {
+			log.Printf("outboxx: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-o.wake:
+		case <-clock.After(wait):
+		}
+	}
+}
+
+// dispatch starts sending every message that is due and first in line for its key, while sem has room, and returns
+// how long to wait before the next message is due. Each message finishing wakes the dispatcher, so the next
+// message of its key is sent straight away without waiting for the others
+func (o *Outbox) dispatch(ctx context.Context, sem chan struct{}) (time.Duration, error) {
+	wait := o.PollInterval
+	if wait <= 0 {
+		wait = time.Second
+	}
+	// holding the lock while reading the store keeps a message that finishes meanwhile from being seen as pending
+	// and no longer in flight
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	messages, err := o.store.Pending(ctx)
+	if err != nil {
+		return wait, fmt.Errorf("could not read pending messages: %w", err)
+	}
+	now := httpx.ClockFromContext(ctx).Now()
+	if o.inflight == nil {
+		o.inflight = make(map[string]bool)
+	}
+	blocked := make(map[string]bool)
+	for _, m := range messages {
+		if m.Key != "" {
+			if blocked[m.Key] {
+				continue
+			}
+			blocked[m.Key] = true
+		}
+		if o.inflight[m.ID] {
+			continue
+		}
+		if m.NextAttempt.After(now) {
+			if d := m.NextAttempt.Sub(now); d < wait {
+				wait = d
+			}
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+		default:
+			// every worker is busy, the first to finish wakes the dispatcher
+			return wait, nil
+		}
+		o.inflight[m.ID] = true
+		o.sending.Add(1)
+		go func(m *Message) {
+			defer o.sending.Done()
+			err := o.deliver(ctx, m)
+			o.mu.Lock()
+			delete(o.inflight, m.ID)
+			o.mu.Unlock()
+			<-sem
+			if err != nil {
+				// the store is tried again at the next poll rather than straight away
+				log.Printf("outboxx: %v", err)
+				return
+			}
+			o.notify()
+		}(m)
+	}
+	return wait, nil
+}
+
+// notify wakes the dispatcher
+func (o *Outbox) notify() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// deliver sends m once and records the outcome in the store
+func (o *Outbox) deliver(ctx context.Context, m *Message) error {
+	req, err := m.request(ctx)
+	if err != nil {
+		o.fail(m, err)
+		return o.store.Remove(ctx, m.ID)
+	}
+	resp, err := o.client.Do(req)
+	if resp != nil && resp.Body != nil {
+		// a response may come with an error from a decorator of the client, its body is closed either way
+		defer func() {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+			resp.Body.Close()
+		}()
+	}
+	if ctx.Err() != nil {
+		// shutting down says nothing about the message, it is sent again on the next run
+		return nil
+	}
+	m.Attempts++
+	if err == nil {
+		if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+			if err := o.store.Remove(ctx, m.ID); err != nil {
+				return fmt.Errorf("could not remove delivered message %s: %w", m.ID, err)
+			}
+			if o.OnDelivered != nil {
+				o.OnDelivered(m, resp)
+			}
+			return nil
+		}
+		err = &httpx.StatusError{StatusCode: resp.StatusCode, Header: resp.Header}
+	}
+
+	retryable := resp == nil || resp.StatusCode >= 500 || resp.StatusCode == http.StatusRequestTimeout ||
+		resp.StatusCode == http.StatusTooManyRequests
+	maxAttempts := o.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 10
+	}
+	backoff := o.Backoff
+	if backoff == nil {
+		backoff = httpx.ExponentialBackoff{Initial: time.Second, Max: 5 * time.Minute}
+	}
+	delay, ok := backoff.NextDelay(m.Attempts, resp, err)
+	if !retryable || !ok || m.Attempts >= maxAttempts {
+		if removeErr := o.store.Remove(ctx, m.ID); removeErr != nil {
+			return fmt.Errorf("could not remove failed message %s: %w", m.ID, removeErr)
+		}
+		o.fail(m, err)
+		return nil
+	}
+	m.NextAttempt = httpx.ClockFromContext(ctx).Now().Add(delay)
+	m.LastError = err.Error()
+	if err := o.store.Update(ctx, m); err != nil {
+		return fmt.Errorf("could not update message %s: %w", m.ID, err)
+	}
+	return nil
+}
+
+func (o *Outbox) fail(m *Message, err error) {
+	m.LastError = err.Error()
+	if o.OnFailed != nil {
+		o.OnFailed(m, err)
+	}
+}
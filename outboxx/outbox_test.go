@@ -0,0 +1,250 @@
+package outboxx_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/outboxx"
+)
+
+// run starts ob and returns a function stopping it
+func run(ob *outboxx.Outbox) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		ob.Run(ctx)
+		close(done)
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func enqueue(t *testing.T, ob *outboxx.Outbox, url, body, key string) string {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	id, err := ob.Enqueue(context.Background(), req, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func TestOutbox_OrderPerKey(t *testing.T) {
+	var mu sync.Mutex
+	var received []string
+	failures := map[string]int{"a1": 2}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		if failures[string(b)] > 0 {
+			failures[string(b)]--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		received = append(received, string(b))
+	}))
+	defer srv.Close()
+
+	ob := outboxx.New(outboxx.NewMemoryStore(), srv.Client())
+	ob.Backoff = httpx.ConstantBackoff(time.Millisecond)
+	delivered := make(chan *outboxx.Message, 4)
+	ob.OnDelivered = func(m *outboxx.Message, resp *http.Response) { delivered <- m }
+	for _, body := range []string{"a1", "a2", "a3"} {
+		enqueue(t, ob, srv.URL, body, "a")
+	}
+	stop := run(ob)
+	defer stop()
+	for i := 0; i < 3; i++ {
+		select {
+		case <-delivered:
+		case <-time.After(5 * time.Second):
+			t.Fatal("messages were not delivered", received)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if strings.Join(received, ",") != "a1,a2,a3" {
+		t.Fatal("messages of a key should be delivered in order, even when the first is retried", received)
+	}
+}
+
+func TestOutbox_SlowMessageDoesNotHoldOtherKeys(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			<-release
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	ob := outboxx.New(outboxx.NewMemoryStore(), srv.Client())
+	delivered := make(chan string, 4)
+	ob.OnDelivered = func(m *outboxx.Message, resp *http.Response) { delivered <- m.URL[len(srv.URL):] }
+	enqueue(t, ob, srv.URL+"/slow", "", "a")
+	for _, p := range []string{"/b1", "/b2", "/b3"} {
+		enqueue(t, ob, srv.URL+p, "", "b")
+	}
+	stop := run(ob)
+	defer stop()
+	for _, want := range []string{"/b1", "/b2", "/b3"} {
+		select {
+		case got := <-delivered:
+			if got != want {
+				t.Fatal("unexpected delivery", got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("messages of another key should not wait for the slow message", want)
+		}
+	}
+}
+
+func TestOutbox_Retry(t *testing.T) {
+	var mu sync.Mutex
+	var keys []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		if len(keys) < 3 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		if r.Header.Get("Content-Type") != "text/plain" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	ob := outboxx.New(outboxx.NewMemoryStore(), srv.Client())
+	ob.Backoff = httpx.ConstantBackoff(time.Millisecond)
+	delivered := make(chan *outboxx.Message, 1)
+	ob.OnDelivered = func(m *outboxx.Message, resp *http.Response) { delivered <- m }
+	stop := run(ob)
+	defer stop()
+	id := enqueue(t, ob, srv.URL, "hello", "")
+
+	select {
+	case m := <-delivered:
+		if m.ID != id || m.Attempts != 3 || m.LastError == "" {
+			t.Fatalf("unexpected message %+v", m)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("message was not delivered")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, k := range keys {
+		if k != id {
+			t.Fatal("every attempt should carry the message id as idempotency key", keys, id)
+		}
+	}
+}
+
+func TestOutbox_Failed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/bad") {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			return
+		}
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	store := outboxx.NewMemoryStore()
+	ob := outboxx.New(store, srv.Client())
+	ob.Backoff = httpx.ConstantBackoff(time.Millisecond)
+	ob.MaxAttempts = 3
+	type failure struct {
+		m   *outboxx.Message
+		err error
+	}
+	failed := make(chan failure, 2)
+	ob.OnFailed = func(m *outboxx.Message, err error) { failed <- failure{m, err} }
+	stop := run(ob)
+	defer stop()
+	enqueue(t, ob, srv.URL+"/bad", "", "")
+	enqueue(t, ob, srv.URL+"/down", "", "")
+
+	attempts := make(map[string]int)
+	for i := 0; i < 2; i++ {
+		select {
+		case f := <-failed:
+			var statusErr *httpx.StatusError
+			if !errors.As(f.err, &statusErr) {
+				t.Fatal("expected a status error", f.err)
+			}
+			attempts[f.m.URL[len(srv.URL):]] = f.m.Attempts
+		case <-time.After(5 * time.Second):
+			t.Fatal("messages did not fail")
+		}
+	}
+	if attempts["/bad"] != 1 || attempts["/down"] != 3 {
+		t.Fatal("a 4xx response should fail straight away and a 5xx after MaxAttempts", attempts)
+	}
+	pending, err := store.Pending(context.Background())
+	if err != nil || len(pending) != 0 {
+		t.Fatal("failed messages should be removed", pending, err)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := outboxx.NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ob := outboxx.New(store, nil)
+	first := enqueue(t, ob, "http://example.com/1", "one", "k")
+	second := enqueue(t, ob, "http://example.com/2", "two", "k")
+	third := enqueue(t, ob, "http://example.com/3", "three", "k")
+	ctx := context.Background()
+	pending, _ := store.Pending(ctx)
+	pending[1].Attempts = 2
+	pending[1].LastError = "503 Service Unavailable"
+	if err = store.Update(ctx, pending[1]); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.Remove(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := outboxx.NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending, err = reopened.Pending(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 2 || pending[0].ID != second || pending[1].ID != third {
+		t.Fatal("reopened store should hold the remaining messages in order", pending)
+	}
+	if string(pending[0].Body) != "two" || pending[0].Attempts != 2 || pending[0].Header.Get("Content-Type") != "text/plain" {
+		t.Fatalf("unexpected message %+v", pending[0])
+	}
+
+	// sequence numbers continue after a reopen so that the order holds
+	fourth := enqueue(t, outboxx.New(reopened, nil), "http://example.com/4", "four", "k")
+	again, _ := outboxx.NewFileStore(dir)
+	pending, _ = again.Pending(ctx)
+	if len(pending) != 3 || pending[2].ID != fourth {
+		t.Fatal("messages added after a reopen should come last", pending)
+	}
+}
@@ -0,0 +1,237 @@
+package outboxx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Store keeps the messages of an Outbox until they are delivered or fail permanently. Implementations backed by a
+// database should add messages in the same transaction as the change that caused them, which is what makes the
+// delivery guaranteed. Implementations must be safe for concurrent use
+type Store interface {
+	// Add stores a new message durably
+	Add(ctx context.Context, m *Message) error
+	// Pending returns every stored message in the order they were added
+	Pending(ctx context.Context) ([]*Message, error)
+	// Update saves the delivery state of a stored message
+	Update(ctx context.Context, m *Message) error
+	// Remove deletes the message with the id, removing an unknown id is not an error
+	Remove(ctx context.Context, id string) error
+}
+
+// NewMemoryStore returns a Store local to the process, messages are lost when it exits
+func NewMemoryStore() Store {
+	return &memoryStore{}
+}
+
+type memoryStore struct {
+	mu       sync.Mutex
+	messages []*Message
+}
+
+func (s *memoryStore) Add(_ context.Context, m *Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, m.clone())
+	return nil
+}
+
+func (s *memoryStore) Pending(context.Context) ([]*Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]*Message, len(s.messages))
+	for i, m := range s.messages {
+		out[i] = m.clone()
+	}
+	return out, nil
+}
+
+func (s *memoryStore) Update(_ context.Context, m *Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, stored := range s.messages {
+		if stored.ID == m.ID {
+			s.messages[i] = m.clone()
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown message %q", m.ID)
+}
+
+func (s *memoryStore) Remove(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, stored := range s.messages {
+		if stored.ID == id {
+			s.messages = append(s.messages[:i], s.messages[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+// FileStore keeps each message in a JSON file of a directory, named by a sequence number that preserves the order
+// messages were added in. The messages are also held in memory, so it suits outboxes of modest size
+type FileStore struct {
+	dir string
+
+	mu       sync.Mutex
+	next     int64
+	files    map[string]string
+	messages []*Message
+}
+
+// NewFileStore opens the store in dir, creating the directory if needed and loading the messages it holds
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("could not create outbox directory: %w", err)
+	}
+	if err := syncDir(filepath.Dir(dir)); err != nil {
+		return nil, fmt.Errorf("could not create outbox directory: %w", err)
+	}
+	names, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	s := &FileStore{dir: dir, files: make(map[string]string)}
+	for _, name := range names {
+		seq, err := strconv.ParseInt(strings.TrimSuffix(filepath.Base(name), ".json"), 16, 64)
+		if err != nil {
+			continue
+		}
+		b, err := os.ReadFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("could not read outbox message: %w", err)
+		}
+		var m Message
+		if err = json.Unmarshal(b, &m); err != nil {
+			return nil, fmt.Errorf("could not decode outbox message %s: %w", filepath.Base(name), err)
+		}
+		s.files[m.ID] = name
+		s.messages = append(s.messages, &m)
+		s.next = seq + 1
+	}
+	return s, nil
+}
+
+// Add implements Store
+func (s *FileStore) Add(_ context.Context, m *Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name := filepath.Join(s.dir, fmt.Sprintf("%016x.json", s.next))
+	if err := writeMessage(name, m); err != nil {
+		return err
+	}
+	s.next++
+	s.files[m.ID] = name
+	s.messages = append(s.messages, m.clone())
+	return nil
+}
+
+// Pending implements Store
+func (s *FileStore) Pending(context.Context) ([]*Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]*Message, len(s.messages))
+	for i, m := range s.messages {
+		out[i] = m.clone()
+	}
+	return out, nil
+}
+
+// Update implements Store
+func (s *FileStore) Update(_ context.Context, m *Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name, ok := s.files[m.ID]
+	if !ok {
+		return fmt.Errorf("unknown message %q", m.ID)
+	}
+	if err := writeMessage(name, m); err != nil {
+		return err
+	}
+	for i, stored := range s.messages {
+		if stored.ID == m.ID {
+			s.messages[i] = m.clone()
+		}
+	}
+	return nil
+}
+
+// Remove implements Store
+func (s *FileStore) Remove(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name, ok := s.files[id]
+	if !ok {
+		return nil
+	}
+	if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("could not remove outbox message: %w", err)
+	}
+	// a removal lost in a crash would send the message again
+	if err := syncDir(s.dir); err != nil {
+		return fmt.Errorf("could not remove outbox message: %w", err)
+	}
+	delete(s.files, id)
+	for i, stored := range s.messages {
+		if stored.ID == id {
+			s.messages = append(s.messages[:i], s.messages[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// writeMessage replaces the file at path with m so that a crash never leaves a partial message behind
+func writeMessage(path string, m *Message) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("could not write outbox message: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(b); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err == nil {
+		// the rename is only durable once the directory is synced
+		err = syncDir(filepath.Dir(path))
+	}
+	if err != nil {
+		return fmt.Errorf("could not write outbox message: %w", err)
+	}
+	return nil
+}
+
+// syncDir flushes the entries of dir so that files created, renamed or removed in it stay so after a crash
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		// directories cannot be opened for syncing, renames are durable once they return
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,71 @@
+package httpx
+
+import (
+	"context"
+	"time"
+)
+
+// Overrides adjust the behavior of the standard decorators for a single request, see WithOverride
+type Overrides struct {
+	// Timeout replaces the duration given to SetTimeout when positive
+	Timeout time.Duration
+	// NoRetry disables retries, and failover to other endpoints with SetFailover
+	NoRetry bool
+	// SkipCache bypasses response caching
+	SkipCache bool
+	// SkipRateLimit lets the request through SetRateLimit without consuming or waiting for the limit
+	SkipRateLimit bool
+}
+
+// Override sets one field of Overrides
+type Override func(*Overrides)
+
+// OverrideTimeout replaces the SetTimeout duration for the request
+func OverrideTimeout(d time.Duration) Override {
+	return func(o *Overrides) {
+		o.Timeout = d
+	}
+}
+
+// NoRetry disables retries for the request
+func NoRetry() Override {
+	return func(o *Overrides) {
+		o.NoRetry = true
+	}
+}
+
+// SkipCache bypasses response caching for the request
+func SkipCache() Override {
+	return func(o *Overrides) {
+		o.SkipCache = true
+	}
+}
+
+// SkipRateLimit exempts the request from SetRateLimit
+func SkipRateLimit() Override {
+	return func(o *Overrides) {
+		o.SkipRateLimit = true
+	}
+}
+
+type overridesKey struct{}
+
+// WithOverride returns a copy of ctx whose requests bypass or adjust the decorators of an existing chain,
+// so that special cases do not need a parallel chain:
+//
+//	ctx = httpx.WithOverride(ctx, httpx.OverrideTimeout(time.Minute), httpx.NoRetry())
+//
+// Options are applied on top of any overrides already on ctx
+func WithOverride(ctx context.Context, opts ...Override) context.Context {
+	o := OverridesFromContext(ctx)
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return context.WithValue(ctx, overridesKey{}, o)
+}
+
+// OverridesFromContext returns the overrides set with WithOverride, the zero value if there are none
+func OverridesFromContext(ctx context.Context) Overrides {
+	o, _ := ctx.Value(overridesKey{}).(Overrides)
+	return o
+}
@@ -0,0 +1,47 @@
+package httpx_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/httpxtest"
+)
+
+func TestWithOverride(t *testing.T) {
+	ctx := httpx.WithOverride(context.Background(), httpx.OverrideTimeout(time.Hour))
+	ctx = httpx.WithOverride(ctx, httpx.NoRetry(), httpx.SkipCache())
+	got := httpx.OverridesFromContext(ctx)
+	if got != (httpx.Overrides{Timeout: time.Hour, NoRetry: true, SkipCache: true}) {
+		t.Fatalf("%+v", got)
+	}
+}
+
+func TestWithOverrideDecorators(t *testing.T) {
+	clock := httpxtest.NewClock(time.Now())
+	var deadline time.Time
+	var c httpx.Client = httpx.ClientFunc(func(req *http.Request) (*http.Response, error) {
+		deadline, _ = req.Context().Deadline()
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+	c = httpx.SetTimeout(c, time.Second)
+	c = httpx.SetRateLimit(c, 1, time.Hour)
+	c = httpx.SetClock(c, clock)
+
+	do := func(ctx context.Context) {
+		if _, err := httpx.SetRequestWithContext(ctx, c, http.MethodGet, "http://example.com").Do(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	do(context.Background())
+	if !deadline.Equal(clock.Now().Add(time.Second)) {
+		t.Fatal(deadline)
+	}
+	// the limit is used up, but the override skips it and raises the timeout
+	do(httpx.WithOverride(context.Background(), httpx.SkipRateLimit(), httpx.OverrideTimeout(time.Minute)))
+	if !deadline.Equal(clock.Now().Add(time.Minute)) {
+		t.Fatal(deadline)
+	}
+}
@@ -0,0 +1,123 @@
+package httpx
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+type tenantKey struct{}
+
+// WithTenant returns a copy of ctx carrying the tenant ID used by ClientPool.Do
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant ID set by WithTenant, or an empty string
+func TenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+// ClientPool caches a decorated client per tenant, for services calling per-customer endpoints with different
+// credentials, base URLs or limits.
+//
+// Clients are built on first use and the least recently used client is evicted once the pool is full.
+// Decorators with state, such as SetRateLimit, are therefore per tenant, and their state starts over when an
+// evicted tenant is built again. Concurrent requests for a tenant that is being built wait for a single build
+type ClientPool struct {
+	build func(ctx context.Context, tenant string) (Client, error)
+	max   int
+
+	mu      sync.Mutex
+	lru     *list.List
+	entries map[string]*list.Element
+}
+
+type poolEntry struct {
+	tenant string
+	ready  chan struct{}
+	client Client
+	err    error
+}
+
+// NewClientPool returns a pool holding at most max clients built by build, unlimited if max is not positive.
+// A build error is returned to the caller and the build is attempted again on the next request
+func NewClientPool(max int, build func(ctx context.Context, tenant string) (Client, error)) *ClientPool {
+	return &ClientPool{
+		build:   build,
+		max:     max,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// Get returns the client of tenant, building it if it is not in the pool
+func (p *ClientPool) Get(ctx context.Context, tenant string) (Client, error) {
+	p.mu.Lock()
+	if el, ok := p.entries[tenant]; ok {
+		p.lru.MoveToFront(el)
+		e := el.Value.(*poolEntry)
+		p.mu.Unlock()
+		select {
+		case <-e.ready:
+			return e.client, e.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	e := &poolEntry{tenant: tenant, ready: make(chan struct{})}
+	p.entries[tenant] = p.lru.PushFront(e)
+	for p.max > 0 && p.lru.Len() > p.max {
+		oldest := p.lru.Back()
+		p.lru.Remove(oldest)
+		delete(p.entries, oldest.Value.(*poolEntry).tenant)
+	}
+	p.mu.Unlock()
+
+	e.client, e.err = p.build(ctx, tenant)
+	if e.err != nil {
+		e.err = fmt.Errorf("could not build client for tenant %q: %w", tenant, e.err)
+		p.mu.Lock()
+		if el, ok := p.entries[tenant]; ok && el.Value == e {
+			p.lru.Remove(el)
+			delete(p.entries, tenant)
+		}
+		p.mu.Unlock()
+	}
+	close(e.ready)
+	return e.client, e.err
+}
+
+// Evict removes the client of tenant so that it is built again on next use, for example after its credentials
+// change. Requests already using the client are not affected
+func (p *ClientPool) Evict(tenant string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if el, ok := p.entries[tenant]; ok {
+		p.lru.Remove(el)
+		delete(p.entries, tenant)
+	}
+}
+
+// Len returns the number of clients in the pool
+func (p *ClientPool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.lru.Len()
+}
+
+// Do performs the request with the client of the tenant in the request context, see WithTenant
+func (p *ClientPool) Do(req *http.Request) (*http.Response, error) {
+	tenant := TenantFromContext(req.Context())
+	if tenant == "" {
+		return nil, fmt.Errorf("request has no tenant")
+	}
+	c, err := p.Get(req.Context(), tenant)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req)
+}
@@ -0,0 +1,67 @@
+package httpx_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/tflyons/httpx"
+)
+
+func TestClientPool(t *testing.T) {
+	srv := httptest.NewServer(echoHandler)
+	defer srv.Close()
+
+	var builds int32
+	pool := httpx.NewClientPool(2, func(ctx context.Context, tenant string) (httpx.Client, error) {
+		atomic.AddInt32(&builds, 1)
+		return httpx.SetHeader(srv.Client(), "X-Tenant", tenant), nil
+	})
+	do := func(tenant string) string {
+		req, _ := http.NewRequestWithContext(httpx.WithTenant(context.Background(), tenant), http.MethodGet, srv.URL, nil)
+		resp, err := pool.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.Header.Get("X-Tenant")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got := do("acme"); got != "acme" {
+				t.Error(got)
+			}
+		}()
+	}
+	wg.Wait()
+	if builds != 1 {
+		t.Fatal("expected a single build per tenant", builds)
+	}
+
+	do("globex")
+	do("acme")
+	// initech evicts globex, the least recently used
+	do("initech")
+	if pool.Len() != 2 {
+		t.Fatal(pool.Len())
+	}
+	do("acme")
+	if builds != 3 {
+		t.Fatal(builds)
+	}
+	do("globex")
+	if builds != 4 {
+		t.Fatal(builds)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	if _, err := pool.Do(req); err == nil {
+		t.Fatal("expected error without a tenant")
+	}
+}
@@ -0,0 +1,116 @@
+// Package postmanx loads Postman collections (format v2.1) and performs their requests through httpx clients.
+package postmanx
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Collection is the subset of a Postman v2.1 collection used by this package
+type Collection struct {
+	Info     Info       `json:"info"`
+	Item     []Item     `json:"item"`
+	Auth     *Auth      `json:"auth"`
+	Variable []Variable `json:"variable"`
+}
+
+// Info describes the collection
+type Info struct {
+	Name   string `json:"name"`
+	Schema string `json:"schema"`
+}
+
+// Item is either a request or, if Item is set, a folder of further items
+type Item struct {
+	Name    string   `json:"name"`
+	Item    []Item   `json:"item"`
+	Request *Request `json:"request"`
+	// Auth is the folder level authentication, requests use the auth of their closest folder if they have none
+	Auth *Auth `json:"auth"`
+}
+
+// Request is a request template that may reference variables as {{name}}
+type Request struct {
+	Method string   `json:"method"`
+	URL    URL      `json:"url"`
+	Header []Header `json:"header"`
+	Body   *Body    `json:"body"`
+	Auth   *Auth    `json:"auth"`
+}
+
+// URL is the raw request url, which a collection may store as a string or an object
+type URL struct {
+	Raw string `json:"raw"`
+}
+
+// UnmarshalJSON accepts both the string and object encodings
+func (u *URL) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		return json.Unmarshal(b, &u.Raw)
+	}
+	type plain URL
+	return json.Unmarshal(b, (*plain)(u))
+}
+
+// Header is a request header
+type Header struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Disabled bool   `json:"disabled"`
+}
+
+// Body is a request body, Mode is one of raw, urlencoded or formdata
+type Body struct {
+	Mode       string     `json:"mode"`
+	Raw        string     `json:"raw"`
+	URLEncoded []KeyValue `json:"urlencoded"`
+	FormData   []KeyValue `json:"formdata"`
+	Options    struct {
+		Raw struct {
+			Language string `json:"language"`
+		} `json:"raw"`
+	} `json:"options"`
+}
+
+// KeyValue is a form field or auth attribute
+type KeyValue struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Type     string `json:"type"`
+	Disabled bool   `json:"disabled"`
+}
+
+// Auth is an authentication method. The supported types are bearer, basic, apikey, noauth and inherit
+type Auth struct {
+	Type   string     `json:"type"`
+	Bearer []KeyValue `json:"bearer"`
+	Basic  []KeyValue `json:"basic"`
+	APIKey []KeyValue `json:"apikey"`
+}
+
+// attr returns the value of the auth attribute key
+func attr(kvs []KeyValue, key string) string {
+	for _, kv := range kvs {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return ""
+}
+
+// Variable is a collection variable
+type Variable struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Disabled bool   `json:"disabled"`
+}
+
+// Load reads and parses a JSON encoded Postman collection
+func Load(r io.Reader) (*Collection, error) {
+	var col Collection
+	if err := json.NewDecoder(r).Decode(&col); err != nil {
+		return nil, fmt.Errorf("could not parse Postman collection: %w", err)
+	}
+	return &col, nil
+}
@@ -0,0 +1,218 @@
+package postmanx
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/tflyons/httpx"
+)
+
+// ErrUnknownOperation is returned for a name that is not a request of the collection
+var ErrUnknownOperation = fmt.Errorf("unknown operation")
+
+// Operations performs the requests of a collection by name.
+//
+// A request is named by the names of its folders and itself joined by a slash, for example "Users/Create user"
+type Operations struct {
+	client httpx.Client
+	vars   map[string]string
+	ops    map[string]operation
+	names  []string
+}
+
+type operation struct {
+	req  *Request
+	auth *Auth
+}
+
+// NewOperations returns the requests of col performed through c.
+//
+// Variables referenced as {{name}} are resolved from the call, then vars, then the collection variables
+func NewOperations(col *Collection, c httpx.Client, vars map[string]string) *Operations {
+	if c == nil {
+		c = httpx.DefaultClient
+	}
+	o := &Operations{client: c, vars: make(map[string]string), ops: make(map[string]operation)}
+	for _, v := range col.Variable {
+		if !v.Disabled {
+			o.vars[v.Key] = v.Value
+		}
+	}
+	for k, v := range vars {
+		o.vars[k] = v
+	}
+	o.add("", col.Item, col.Auth)
+	return o
+}
+
+func (o *Operations) add(prefix string, items []Item, auth *Auth) {
+	for i := range items {
+		item := &items[i]
+		itemAuth := auth
+		if item.Auth != nil && item.Auth.Type != "inherit" {
+			itemAuth = item.Auth
+		}
+		name := prefix + item.Name
+		if item.Request == nil {
+			o.add(name+"/", item.Item, itemAuth)
+			continue
+		}
+		if item.Request.Auth != nil && item.Request.Auth.Type != "inherit" {
+			itemAuth = item.Request.Auth
+		}
+		if _, ok := o.ops[name]; !ok {
+			o.names = append(o.names, name)
+		}
+		o.ops[name] = operation{req: item.Request, auth: itemAuth}
+	}
+}
+
+// Names returns the operation names in collection order
+func (o *Operations) Names() []string {
+	return append([]string(nil), o.names...)
+}
+
+// Request builds the named request with vars overriding the other variables
+func (o *Operations) Request(ctx context.Context, name string, vars map[string]string) (*http.Request, error) {
+	op, ok := o.ops[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownOperation, name)
+	}
+	var err error
+	expand := func(s string) string {
+		out, e := o.expand(s, vars)
+		if e != nil && err == nil {
+			err = fmt.Errorf("%s: %w", name, e)
+		}
+		return out
+	}
+
+	method := op.req.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	header := make(http.Header)
+	for _, h := range op.req.Header {
+		if !h.Disabled {
+			header.Add(expand(h.Key), expand(h.Value))
+		}
+	}
+	var body []byte
+	if b := op.req.Body; b != nil {
+		switch b.Mode {
+		case "", "none":
+		case "raw":
+			body = []byte(expand(b.Raw))
+			if header.Get("Content-Type") == "" && b.Options.Raw.Language != "" {
+				types := map[string]string{"json": "application/json", "xml": "application/xml", "html": "text/html", "text": "text/plain"}
+				if ct, ok := types[b.Options.Raw.Language]; ok {
+					header.Set("Content-Type", ct)
+				}
+			}
+		case "urlencoded", "formdata":
+			fields := b.URLEncoded
+			if b.Mode == "formdata" {
+				fields = b.FormData
+			}
+			form := url.Values{}
+			for _, f := range fields {
+				if f.Disabled {
+					continue
+				}
+				if f.Type == "file" {
+					return nil, fmt.Errorf("%s: file form fields are not supported", name)
+				}
+				form.Add(expand(f.Key), expand(f.Value))
+			}
+			body = []byte(form.Encode())
+			if header.Get("Content-Type") == "" {
+				header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+		default:
+			return nil, fmt.Errorf("%s: unsupported body mode %q", name, b.Mode)
+		}
+	}
+	rawURL := expand(op.req.URL.Raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, r)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header
+	if err := o.authorize(req, op.auth, expand); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return req, nil
+}
+
+// authorize applies the auth to the request
+func (o *Operations) authorize(req *http.Request, auth *Auth, expand func(string) string) error {
+	if auth == nil {
+		return nil
+	}
+	switch auth.Type {
+	case "noauth", "inherit":
+	case "bearer":
+		req.Header.Set("Authorization", "Bearer "+expand(attr(auth.Bearer, "token")))
+	case "basic":
+		req.SetBasicAuth(expand(attr(auth.Basic, "username")), expand(attr(auth.Basic, "password")))
+	case "apikey":
+		key, value := expand(attr(auth.APIKey, "key")), expand(attr(auth.APIKey, "value"))
+		if attr(auth.APIKey, "in") == "query" {
+			q := req.URL.Query()
+			q.Set(key, value)
+			req.URL.RawQuery = q.Encode()
+		} else {
+			req.Header.Set(key, value)
+		}
+	default:
+		return fmt.Errorf("unsupported auth type %q", auth.Type)
+	}
+	return nil
+}
+
+var variable = regexp.MustCompile(`\{\{\s*([^{}\s]+)\s*\}\}`)
+
+// expand replaces variable references, returning an error for the first that is undefined
+func (o *Operations) expand(s string, vars map[string]string) (string, error) {
+	if !strings.Contains(s, "{{") {
+		return s, nil
+	}
+	var err error
+	out := variable.ReplaceAllStringFunc(s, func(m string) string {
+		name := variable.FindStringSubmatch(m)[1]
+		if v, ok := vars[name]; ok {
+			return v
+		}
+		if v, ok := o.vars[name]; ok {
+			return v
+		}
+		if err == nil {
+			err = fmt.Errorf("undefined variable %q", name)
+		}
+		return m
+	})
+	return out, err
+}
+
+// Do performs the named request through the client, see Request
+func (o *Operations) Do(ctx context.Context, name string, vars map[string]string) (*http.Response, error) {
+	req, err := o.Request(ctx, name, vars)
+	if err != nil {
+		return nil, err
+	}
+	return o.client.Do(req)
+}
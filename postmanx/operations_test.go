@@ -0,0 +1,76 @@
+package postmanx_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/postmanx"
+)
+
+func loadCollection(t *testing.T) *postmanx.Collection {
+	t.Helper()
+	f, err := os.Open("testdata/collection.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	col, err := postmanx.Load(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return col
+}
+
+func TestOperations(t *testing.T) {
+	type seen struct {
+		method, uri, auth, trace, off, contentType, body string
+	}
+	requests := make(chan seen, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		requests <- seen{r.Method, r.RequestURI, r.Header.Get("Authorization"), r.Header.Get("X-Trace"), r.Header.Get("X-Off"), r.Header.Get("Content-Type"), string(b)}
+	}))
+	defer srv.Close()
+
+	c := httpx.SetHeader(srv.Client(), "User-Agent", "postmanx-test")
+	ops := postmanx.NewOperations(loadCollection(t), c, map[string]string{"baseUrl": srv.URL})
+	want := []string{"Things/Get thing", "Things/Create thing", "Admin/Login", "Admin/Public"}
+	if got := ops.Names(); !reflect.DeepEqual(got, want) {
+		t.Fatal(got)
+	}
+
+	tests := []struct {
+		name string
+		vars map[string]string
+		want seen
+	}{
+		{"Things/Get thing", map[string]string{"id": "7"}, seen{"GET", "/things/7", "Bearer collection-token", "", "", "", ""}},
+		{"Things/Create thing", map[string]string{"foo": "bar", "token": "call-token"}, seen{"POST", "/things", "Bearer call-token", "on", "", "application/json", `{"foo": "bar"}`}},
+		{"Admin/Login", nil, seen{"POST", "/login", "Basic YWRtaW46c2VjcmV0", "", "", "application/x-www-form-urlencoded", "user=admin"}},
+		{"Admin/Public", nil, seen{"GET", "/public?api_key=k", "", "", "", "", ""}},
+	}
+	for _, tt := range tests {
+		resp, err := ops.Do(context.Background(), tt.name, tt.vars)
+		if err != nil {
+			t.Fatal(tt.name, err)
+		}
+		resp.Body.Close()
+		if got := <-requests; got != tt.want {
+			t.Fatalf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := ops.Do(context.Background(), "Things/Get thing", nil); err == nil {
+		t.Fatal("expected undefined variable error")
+	}
+	if _, err := ops.Do(context.Background(), "Nope", nil); !errors.Is(err, postmanx.ErrUnknownOperation) {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,137 @@
+package httpx
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// ErrDownloadRejected is matched by every DownloadRejectedError using errors.Is
+var ErrDownloadRejected = fmt.Errorf("download rejected")
+
+// DownloadRejectedError is returned by SetHeadProbe for a resource that is too large or of a type not allowed
+type DownloadRejectedError struct {
+	URL string
+	// ContentLength is -1 if unknown
+	ContentLength int64
+	ContentType   string
+	Reason        string
+}
+
+// Error implements the error interface
+func (e *DownloadRejectedError) Error() string {
+	return fmt.Sprintf("%s: %s: %s", ErrDownloadRejected, e.URL, e.Reason)
+}
+
+// Is matches ErrDownloadRejected
+func (e *DownloadRejectedError) Is(target error) bool {
+	return target == ErrDownloadRejected
+}
+
+// ProbePolicy configures SetHeadProbe
+type ProbePolicy struct {
+	// MaxBytes is the largest Content-Length accepted, unlimited if zero
+	MaxBytes int64
+	// ContentTypes are the media types accepted, such as "application/pdf" or "image/*", any if empty
+	ContentTypes []string
+	// RequireLength rejects resources whose length is not known in advance
+	RequireLength bool
+}
+
+// SetHeadProbe sends a HEAD request before each GET and rejects the download with a *DownloadRejectedError,
+// without sending the GET, if the Content-Length is larger than MaxBytes or the Content-Type is not allowed. It
+// protects pipelines fetching user supplied URLs from unexpectedly large or unwanted content.
+//
+// Servers can answer HEAD and GET differently, so the GET response is checked again and its body fails with a
+// *DownloadRejectedError once more than MaxBytes have been read. A server that does not allow HEAD is not probed
+func SetHeadProbe(c Client, policy ProbePolicy) ClientFunc {
+	c = nilClientCheck(c)
+	return func(req *http.Request) (*http.Response, error) {
+		if req.Method != http.MethodGet {
+			return c.Do(req)
+		}
+		head := req.Clone(req.Context())
+		head.Method = http.MethodHead
+		head.Body, head.GetBody, head.ContentLength = nil, nil, 0
+		resp, err := c.Do(head)
+		if err != nil {
+			return nil, fmt.Errorf("head probe: %w", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusMethodNotAllowed && resp.StatusCode != http.StatusNotImplemented && resp.StatusCode < 400 {
+			if err := policy.check(req, resp); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err = c.Do(req)
+		if err != nil || resp.StatusCode >= 300 {
+			return resp, err
+		}
+		if err := policy.check(req, resp); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		if policy.MaxBytes > 0 {
+			resp.Body = &maxBytesBody{ReadCloser: resp.Body, remaining: policy.MaxBytes, req: req, resp: resp}
+		}
+		return resp, nil
+	}
+}
+
+// check returns an error if the response headers break the policy
+func (p ProbePolicy) check(req *http.Request, resp *http.Response) error {
+	reject := func(reason string) error {
+		return &DownloadRejectedError{
+			URL:           req.URL.Redacted(),
+			ContentLength: resp.ContentLength,
+			ContentType:   resp.Header.Get("Content-Type"),
+			Reason:        reason,
+		}
+	}
+	if p.RequireLength && resp.ContentLength < 0 {
+		return reject("unknown content length")
+	}
+	if p.MaxBytes > 0 && resp.ContentLength > p.MaxBytes {
+		return reject(fmt.Sprintf("content length %d exceeds %d", resp.ContentLength, p.MaxBytes))
+	}
+	if len(p.ContentTypes) == 0 {
+		return nil
+	}
+	mt, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	for _, allowed := range p.ContentTypes {
+		if mt == allowed || (strings.HasSuffix(allowed, "/*") && strings.HasPrefix(mt, strings.TrimSuffix(allowed, "*"))) {
+			return nil
+		}
+	}
+	return reject(fmt.Sprintf("content type %q is not allowed", mt))
+}
+
+// maxBytesBody fails once more than remaining bytes have been read
+type maxBytesBody struct {
+	io.ReadCloser
+	remaining int64
+	req       *http.Request
+	resp      *http.Response
+}
+
+func (b *maxBytesBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, &DownloadRejectedError{URL: b.req.URL.Redacted(), ContentLength: -1, Reason: "body exceeds the size limit"}
+	}
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if b.remaining -= int64(n); b.remaining < 0 {
+		return n + int(b.remaining), &DownloadRejectedError{
+			URL:           b.req.URL.Redacted(),
+			ContentLength: b.resp.ContentLength,
+			ContentType:   b.resp.Header.Get("Content-Type"),
+			Reason:        "body exceeds the size limit",
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,66 @@
+package httpx_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tflyons/httpx"
+)
+
+func TestSetHeadProbe(t *testing.T) {
+	var gets int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			gets++
+		}
+		switch r.URL.Path {
+		case "/small.png":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte("png"))
+		case "/large.png":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte(strings.Repeat("x", 100)))
+		case "/page":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = w.Write([]byte("<html>"))
+		case "/liar.png":
+			// answers HEAD with a small length but streams a large body
+			w.Header().Set("Content-Type", "image/png")
+			if r.Method == http.MethodHead {
+				w.Header().Set("Content-Length", "3")
+				return
+			}
+			w.(http.Flusher).Flush()
+			_, _ = w.Write([]byte(strings.Repeat("x", 100)))
+		}
+	}))
+	defer srv.Close()
+
+	policy := httpx.ProbePolicy{MaxBytes: 10, ContentTypes: []string{"image/*"}}
+	get := func(path string) (string, error) {
+		var body string
+		c := httpx.SetResponseBodyString(httpx.SetHeadProbe(srv.Client(), policy), &body)
+		_, err := httpx.SetRequest(c, http.MethodGet, srv.URL+path).Do(nil)
+		return body, err
+	}
+
+	if body, err := get("/small.png"); err != nil || body != "png" {
+		t.Fatal(body, err)
+	}
+	for _, path := range []string{"/large.png", "/page"} {
+		_, err := get(path)
+		var rejected *httpx.DownloadRejectedError
+		if !errors.As(err, &rejected) || !errors.Is(err, httpx.ErrDownloadRejected) {
+			t.Fatal(path, err)
+		}
+	}
+	if gets != 1 {
+		t.Fatal("rejected downloads should not be requested", gets)
+	}
+	if _, err := get("/liar.png"); !errors.Is(err, httpx.ErrDownloadRejected) {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,79 @@
+package httpx
+
+import (
+	"io"
+	"net/http"
+	"time"
+)
+
+// Progress is the state of an upload reported by SetUploadProgress
+type Progress struct {
+	// Sent is the number of body bytes read by the transport so far
+	Sent int64
+	// Total is the length of the body, -1 if unknown
+	Total int64
+	// Rate is the average upload rate in bytes per second since the body was first read
+	Rate float64
+	// Done is set on the last report, once the whole body has been read
+	Done bool
+}
+
+// SetUploadProgress calls fn as the request body is sent so that command line tools and user interfaces can show
+// upload progress. fn is called from the goroutine sending the body after every read and must return quickly.
+// If the body is sent again, for example by SetRetry, progress starts over
+func SetUploadProgress(c Client, fn func(Progress)) ClientFunc {
+	c = nilClientCheck(c)
+	return func(req *http.Request) (*http.Response, error) {
+		if req.Body == nil || req.Body == http.NoBody {
+			return c.Do(req)
+		}
+		clock := ClockFromContext(req.Context())
+		total := req.ContentLength
+		if total <= 0 {
+			total = -1
+		}
+		wrap := func(body io.ReadCloser) io.ReadCloser {
+			return &progressBody{ReadCloser: body, clock: clock, total: total, fn: fn}
+		}
+		req.Body = wrap(req.Body)
+		if getBody := req.GetBody; getBody != nil {
+			req.GetBody = func() (io.ReadCloser, error) {
+				body, err := getBody()
+				if err != nil {
+					return nil, err
+				}
+				return wrap(body), nil
+			}
+		}
+		return c.Do(req)
+	}
+}
+
+// progressBody reports the bytes read through it
+type progressBody struct {
+	io.ReadCloser
+	clock Clock
+	total int64
+	fn    func(Progress)
+	sent  int64
+	start time.Time
+	done  bool
+}
+
+func (b *progressBody) Read(p []byte) (int, error) {
+	if b.start.IsZero() {
+		b.start = b.clock.Now()
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.sent += int64(n)
+	if b.done || (n == 0 && err == nil) {
+		return n, err
+	}
+	b.done = err == io.EOF
+	progress := Progress{Sent: b.sent, Total: b.total, Done: b.done}
+	if elapsed := b.clock.Now().Sub(b.start); elapsed > 0 {
+		progress.Rate = float64(b.sent) / elapsed.Seconds()
+	}
+	b.fn(progress)
+	return n, err
+}
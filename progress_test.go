@@ -0,0 +1,49 @@
+package httpx_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/tflyons/httpx"
+)
+
+func TestSetUploadProgress(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+	}))
+	defer srv.Close()
+
+	var mu sync.Mutex
+	var reports []httpx.Progress
+	size := 1 << 20
+	var c httpx.Client = srv.Client()
+	c = httpx.SetUploadProgress(c, func(p httpx.Progress) {
+		mu.Lock()
+		reports = append(reports, p)
+		mu.Unlock()
+	})
+	c = httpx.SetRequestBody(c, nil, bytes.Repeat([]byte("x"), size))
+	resp, err := httpx.SetRequest(c, http.MethodPut, srv.URL).Do(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reports) < 2 {
+		t.Fatal("expected several progress reports", reports)
+	}
+	for i := 1; i < len(reports); i++ {
+		if reports[i].Sent < reports[i-1].Sent || reports[i].Total != int64(size) {
+			t.Fatal(reports[i-1], reports[i])
+		}
+	}
+	if last := reports[len(reports)-1]; !last.Done || last.Sent != int64(size) {
+		t.Fatal(last)
+	}
+}
@@ -0,0 +1,103 @@
+package httpx
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// hopHeaders are removed when forwarding requests and responses, see RFC 9110 section 7.6.1
+var hopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// NewReverseProxy returns a handler that forwards each request through c so that retries, authentication and
+// metrics decorators apply to proxied traffic.
+//
+// rewrite must point the outgoing request at the upstream, typically by setting req.URL.Scheme and req.URL.Host.
+// Hop-by-hop headers are removed, X-Forwarded-For, X-Forwarded-Host and X-Forwarded-Proto are set before
+// rewrite is called and request and response bodies are streamed without buffering.
+// Errors from c are reported as 502 Bad Gateway.
+func NewReverseProxy(c Client, rewrite func(*http.Request)) http.Handler {
+	c = nilClientCheck(c)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		out := r.Clone(r.Context())
+		out.RequestURI = ""
+		out.Host = ""
+		if r.ContentLength == 0 {
+			out.Body = nil
+		}
+		removeHopHeaders(out.Header)
+		setForwardedHeaders(out, r)
+		rewrite(out)
+
+		resp, err := c.Do(out)
+		if err != nil {
+			if resp != nil && resp.Body != nil {
+				resp.Body.Close()
+			}
+			http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+
+		removeHopHeaders(resp.Header)
+		for k, v := range resp.Header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(resp.StatusCode)
+		_, _ = io.Copy(flushWriter{w}, resp.Body)
+	})
+}
+
+// removeHopHeaders deletes hop-by-hop headers including any listed in the Connection header
+func removeHopHeaders(h http.Header) {
+	for _, v := range h.Values("Connection") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				h.Del(name)
+			}
+		}
+	}
+	for _, name := range hopHeaders {
+		h.Del(name)
+	}
+}
+
+// setForwardedHeaders records the original client, host and scheme on the outgoing request
+func setForwardedHeaders(out, in *http.Request) {
+	if host, _, err := net.SplitHostPort(in.RemoteAddr); err == nil {
+		if prior := in.Header.Values("X-Forwarded-For"); len(prior) > 0 {
+			host = strings.Join(prior, ", ") + ", " + host
+		}
+		out.Header.Set("X-Forwarded-For", host)
+	}
+	out.Header.Set("X-Forwarded-Host", in.Host)
+	if in.TLS != nil {
+		out.Header.Set("X-Forwarded-Proto", "https")
+	} else {
+		out.Header.Set("X-Forwarded-Proto", "http")
+	}
+}
+
+// flushWriter flushes after every write so that streamed responses reach the caller promptly
+type flushWriter struct {
+	w http.ResponseWriter
+}
+
+func (f flushWriter) Write(b []byte) (int, error) {
+	n, err := f.w.Write(b)
+	if fl, ok := f.w.(http.Flusher); ok {
+		fl.Flush()
+	}
+	return n, err
+}
@@ -0,0 +1,95 @@
+package httpx_test
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/tflyons/httpx"
+)
+
+func TestNewReverseProxy(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" || r.Header.Get("Keep-Alive") != "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		b, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Upstream", r.Header.Get("X-Forwarded-For")+"|"+r.URL.RequestURI())
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write(b)
+	}))
+	defer upstream.Close()
+	target, _ := url.Parse(upstream.URL)
+
+	var c httpx.Client = upstream.Client()
+	c = httpx.SetHeader(c, "Authorization", "Bearer token")
+	proxy := httptest.NewServer(httpx.NewReverseProxy(c, func(req *http.Request) {
+		req.URL.Scheme = target.Scheme
+		req.URL.Host = target.Host
+	}))
+	defer proxy.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, proxy.URL+"/things?a=1", strings.NewReader("hello"))
+	req.Header.Set("Keep-Alive", "timeout=5")
+	resp, err := proxy.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusAccepted || string(b) != "hello" {
+		t.Fatal(resp.StatusCode, string(b))
+	}
+	if got := resp.Header.Get("X-Upstream"); got != "127.0.0.1|/things?a=1" {
+		t.Fatal(got)
+	}
+}
+
+func TestNewReverseProxyStreams(t *testing.T) {
+	next := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("first\n"))
+		w.(http.Flusher).Flush()
+		<-next
+		_, _ = w.Write([]byte("second\n"))
+	}))
+	defer upstream.Close()
+	target, _ := url.Parse(upstream.URL)
+
+	proxy := httptest.NewServer(httpx.NewReverseProxy(upstream.Client(), func(req *http.Request) {
+		req.URL.Scheme = target.Scheme
+		req.URL.Host = target.Host
+	}))
+	defer proxy.Close()
+
+	resp, err := proxy.Client().Get(proxy.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	r := bufio.NewReader(resp.Body)
+	// the first line arrives before the upstream handler finishes
+	if line, err := r.ReadString('\n'); err != nil || line != "first\n" {
+		t.Fatal(line, err)
+	}
+	close(next)
+	if line, err := r.ReadString('\n'); err != nil || line != "second\n" {
+		t.Fatal(line, err)
+	}
+}
+
+func TestNewReverseProxyBadGateway(t *testing.T) {
+	failing := httpx.ClientFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, io.ErrUnexpectedEOF
+	})
+	w := httptest.NewRecorder()
+	httpx.NewReverseProxy(failing, func(req *http.Request) {}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusBadGateway {
+		t.Fatal(w.Code)
+	}
+}
@@ -0,0 +1,120 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Quota is the rate limit state reported by a server in its response headers.
+// Fields the server did not report are zero, and Limit and Remaining are -1 when unknown
+type Quota struct {
+	// Limit is the number of requests allowed in the current window
+	Limit int
+	// Remaining is the number of requests left in the current window
+	Remaining int
+	// Reset is when the window ends and the quota is restored
+	Reset time.Time
+	// RetryAfter is how long the server asked the client to wait, from the Retry-After header
+	RetryAfter time.Duration
+	// Policy is the raw quota policy, from the RateLimit-Policy or X-RateLimit-Policy header
+	Policy string
+}
+
+type quotaKey struct{}
+
+// QuotaFromContext returns the quota stored by SetQuota on the context of the response request,
+// resp.Request.Context(), and whether any quota headers were present
+func QuotaFromContext(ctx context.Context) (Quota, bool) {
+	q, ok := ctx.Value(quotaKey{}).(Quota)
+	return q, ok
+}
+
+// SetQuota parses the rate limit headers of every response and makes the quota available with QuotaFromContext and
+// to fn, which may be nil and is only called when quota headers are present.
+//
+// The RateLimit header (limit=, remaining=, reset=) of the IETF draft, the RateLimit-* and X-RateLimit-* headers and
+// Retry-After are understood. Reset values up to a year are treated as seconds from now, larger ones as Unix times
+func SetQuota(c Client, fn func(req *http.Request, q Quota)) ClientFunc {
+	c = nilClientCheck(c)
+	return func(req *http.Request) (*http.Response, error) {
+		resp, err := c.Do(req)
+		if resp == nil {
+			return resp, err
+		}
+		q, ok := parseQuota(resp.Header, ClockFromContext(req.Context()).Now())
+		if !ok {
+			return resp, err
+		}
+		if resp.Request == nil {
+			resp.Request = req
+		}
+		resp.Request = resp.Request.WithContext(context.WithValue(resp.Request.Context(), quotaKey{}, q))
+		if fn != nil {
+			fn(req, q)
+		}
+		return resp, err
+	}
+}
+
+// parseQuota reads the quota headers of h, reporting whether any were present
+func parseQuota(h http.Header, now time.Time) (Quota, bool) {
+	q := Quota{Limit: -1, Remaining: -1}
+	found := false
+	setInt := func(dst *int, v string) {
+		if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+			*dst = n
+			found = true
+		}
+	}
+	setReset := func(v string) {
+		n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil || n < 0 {
+			return
+		}
+		if n <= 365*24*60*60 {
+			q.Reset = now.Add(time.Duration(n * float64(time.Second)))
+		} else {
+			q.Reset = time.Unix(int64(n), 0)
+		}
+		found = true
+	}
+
+	for _, prefix := range []string{"X-Ratelimit-", "Ratelimit-"} {
+		if v := h.Get(prefix + "Limit"); v != "" {
+			// the limit may be followed by a policy such as "100, 100;w=60"
+			limit, _, _ := strings.Cut(v, ",")
+			setInt(&q.Limit, limit)
+		}
+		if v := h.Get(prefix + "Remaining"); v != "" {
+			setInt(&q.Remaining, v)
+		}
+		if v := h.Get(prefix + "Reset"); v != "" {
+			setReset(v)
+		}
+		if v := h.Get(prefix + "Policy"); v != "" {
+			q.Policy = v
+			found = true
+		}
+	}
+	if v := h.Get("Ratelimit"); v != "" {
+		for _, item := range strings.Split(v, ",") {
+			key, value, _ := strings.Cut(strings.TrimSpace(item), "=")
+			switch strings.ToLower(strings.TrimSpace(key)) {
+			case "limit":
+				setInt(&q.Limit, value)
+			case "remaining", "r":
+				setInt(&q.Remaining, value)
+			case "reset", "t":
+				setReset(value)
+			}
+		}
+	}
+	if d, ok := retryAfter(h.Get("Retry-After"), now); ok {
+		q.RetryAfter = d
+		found = true
+	}
+	return q, found
+}
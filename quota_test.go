@@ -0,0 +1,65 @@
+package httpx_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx"
+)
+
+func TestSetQuota(t *testing.T) {
+	srv := httptest.NewServer(echoHandler)
+	defer srv.Close()
+
+	var c httpx.Client = srv.Client()
+	var reported httpx.Quota
+	c = httpx.SetQuota(c, func(_ *http.Request, q httpx.Quota) {
+		reported = q
+	})
+	c = httpx.SetHeader(c, "X-RateLimit-Limit", "100")
+	c = httpx.SetHeader(c, "X-RateLimit-Remaining", "42")
+	c = httpx.SetHeader(c, "X-RateLimit-Reset", "30")
+	c = httpx.SetHeader(c, "Retry-After", "5")
+	start := time.Now()
+	resp, err := httpx.SetRequest(c, http.MethodGet, srv.URL).Do(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, ok := httpx.QuotaFromContext(resp.Request.Context())
+	if !ok || q.Limit != 100 || q.Remaining != 42 || q.RetryAfter != 5*time.Second {
+		t.Fatal(ok, q)
+	}
+	if q.Reset.Before(start.Add(29*time.Second)) || q.Reset.After(time.Now().Add(30*time.Second)) {
+		t.Fatal(q.Reset)
+	}
+	if reported != q {
+		t.Fatal(reported)
+	}
+}
+
+func TestSetQuota_IETFHeader(t *testing.T) {
+	srv := httptest.NewServer(echoHandler)
+	defer srv.Close()
+
+	var c httpx.Client = srv.Client()
+	c = httpx.SetQuota(c, nil)
+	c = httpx.SetHeader(c, "RateLimit", "limit=10, remaining=0, reset=1700000000")
+	resp, err := httpx.SetRequest(c, http.MethodGet, srv.URL).Do(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, ok := httpx.QuotaFromContext(resp.Request.Context())
+	if !ok || q.Limit != 10 || q.Remaining != 0 || !q.Reset.Equal(time.Unix(1700000000, 0)) {
+		t.Fatal(ok, q)
+	}
+
+	resp, err = httpx.SetRequest(httpx.SetQuota(srv.Client(), nil), http.MethodGet, srv.URL).Do(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok = httpx.QuotaFromContext(resp.Request.Context()); ok {
+		t.Fatal("expected no quota")
+	}
+}
@@ -0,0 +1,104 @@
+package httpx
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ErrRange is returned for a partial content response that does not match the requested range
+var ErrRange = fmt.Errorf("invalid partial content response")
+
+// ContentRange is the byte range of a partial response
+type ContentRange struct {
+	// Start and End are the first and last byte positions, inclusive
+	Start, End int64
+	// Size is the complete length of the resource, -1 if the server did not send it
+	Size int64
+}
+
+// Len returns the number of bytes in the range
+func (r ContentRange) Len() int64 {
+	return r.End - r.Start + 1
+}
+
+// ParseContentRange parses a Content-Range header of the form "bytes 0-499/1234" or "bytes 0-499/*"
+func ParseContentRange(v string) (ContentRange, error) {
+	unit, rest, ok := strings.Cut(strings.TrimSpace(v), " ")
+	if !ok || unit != "bytes" {
+		return ContentRange{}, fmt.Errorf("%w: content range %q", ErrRange, v)
+	}
+	span, size, ok := strings.Cut(rest, "/")
+	first, last, ok2 := strings.Cut(span, "-")
+	if !ok || !ok2 {
+		return ContentRange{}, fmt.Errorf("%w: content range %q", ErrRange, v)
+	}
+	r := ContentRange{Size: -1}
+	var err1, err2, err3 error
+	r.Start, err1 = strconv.ParseInt(first, 10, 64)
+	r.End, err2 = strconv.ParseInt(last, 10, 64)
+	if size != "*" {
+		r.Size, err3 = strconv.ParseInt(size, 10, 64)
+	}
+	if err1 != nil || err2 != nil || err3 != nil || r.Start < 0 || r.End < r.Start || (r.Size >= 0 && r.End >= r.Size) {
+		return ContentRange{}, fmt.Errorf("%w: content range %q", ErrRange, v)
+	}
+	return r, nil
+}
+
+// PartialContent returns the range of a 206 Partial Content response, or an ErrRange error if the response is not
+// a valid partial response
+func PartialContent(resp *http.Response) (ContentRange, error) {
+	if resp.StatusCode != http.StatusPartialContent {
+		return ContentRange{}, fmt.Errorf("%w: status %d", ErrRange, resp.StatusCode)
+	}
+	r, err := ParseContentRange(resp.Header.Get("Content-Range"))
+	if err != nil {
+		return r, err
+	}
+	if resp.ContentLength >= 0 && resp.ContentLength != r.Len() {
+		return ContentRange{}, fmt.Errorf("%w: content length %d does not match range %d-%d", ErrRange, resp.ContentLength, r.Start, r.End)
+	}
+	return r, nil
+}
+
+// SetRange requests the bytes from start to end inclusive, or from start to the end of the resource if end is
+// negative. A 206 Partial Content response must cover exactly that range, clamped to the size of the resource,
+// or an ErrRange error is returned. Servers that do not support ranges answer with 200 and the whole resource,
+// which is returned unchanged, so callers should check the status
+func SetRange(c Client, start, end int64) ClientFunc {
+	c = nilClientCheck(c)
+	value := fmt.Sprintf("bytes=%d-", start)
+	if end >= 0 {
+		value += strconv.FormatInt(end, 10)
+	}
+	return func(req *http.Request) (*http.Response, error) {
+		if start < 0 || (end >= 0 && end < start) {
+			return nil, fmt.Errorf("invalid range %d-%d", start, end)
+		}
+		if req.Header == nil {
+			req.Header = make(http.Header)
+		}
+		req.Header.Set("Range", value)
+		resp, err := c.Do(req)
+		if err != nil || resp.StatusCode != http.StatusPartialContent {
+			return resp, err
+		}
+		r, err := PartialContent(resp)
+		if err != nil {
+			return resp, err
+		}
+		want := end
+		if want < 0 || (r.Size >= 0 && want >= r.Size) {
+			want = r.End
+			if r.Size >= 0 {
+				want = r.Size - 1
+			}
+		}
+		if r.Start != start || r.End != want {
+			return resp, fmt.Errorf("%w: requested %s, got %d-%d", ErrRange, value, r.Start, r.End)
+		}
+		return resp, nil
+	}
+}
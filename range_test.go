@@ -0,0 +1,78 @@
+package httpx_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx"
+)
+
+func TestSetRange(t *testing.T) {
+	content := strings.Repeat("0123456789", 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/wrong" {
+			w.Header().Set("Content-Range", "bytes 0-9/100")
+			w.WriteHeader(http.StatusPartialContent)
+			_, _ = w.Write([]byte(content[:10]))
+			return
+		}
+		http.ServeContent(w, r, "data", time.Time{}, strings.NewReader(content))
+	}))
+	defer srv.Close()
+
+	// download in chunks
+	var out bytes.Buffer
+	for start := int64(0); ; start += 30 {
+		var chunk []byte
+		c := httpx.SetRange(httpx.SetResponseBodyBytes(srv.Client(), &chunk), start, start+29)
+		resp, err := httpx.SetRequest(c, http.MethodGet, srv.URL).Do(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := httpx.PartialContent(resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out.Write(chunk)
+		if r.End+1 == r.Size {
+			break
+		}
+	}
+	if out.String() != content {
+		t.Fatal(out.String())
+	}
+
+	var tail string
+	if _, err := httpx.SetRequest(httpx.SetRange(httpx.SetResponseBodyString(srv.Client(), &tail), 95, -1), http.MethodGet, srv.URL).Do(nil); err != nil || tail != "56789" {
+		t.Fatal(err, tail)
+	}
+	if _, err := httpx.SetRequest(httpx.SetRange(srv.Client(), 50, 59), http.MethodGet, srv.URL+"/wrong").Do(nil); !errors.Is(err, httpx.ErrRange) {
+		t.Fatal(err)
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	for v, want := range map[string]string{
+		"bytes 0-499/1234": "{0 499 1234}",
+		"bytes 10-19/*":    "{10 19 -1}",
+		"bytes 5-4/10":     "error",
+		"bytes 0-10/10":    "error",
+		"items 0-1/2":      "error",
+		"bytes */100":      "error",
+	} {
+		r, err := httpx.ParseContentRange(v)
+		got := fmt.Sprint(r)
+		if err != nil {
+			got = "error"
+		}
+		if got != want {
+			t.Fatal(v, got)
+		}
+	}
+}
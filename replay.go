@@ -0,0 +1,130 @@
+package httpx
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ErrReplay is returned by VerifyReplaySignature when a request is not signed, is signed incorrectly,
+// is outside the clock skew tolerance or reuses a nonce
+var ErrReplay = fmt.Errorf("invalid replay protection signature")
+
+// ReplayOptions configures SetReplaySignature and VerifyReplaySignature
+type ReplayOptions struct {
+	// Header names, X-Timestamp, X-Nonce and X-Signature if empty
+	TimestampHeader string
+	NonceHeader     string
+	SignatureHeader string
+	// Offset is added to the local time when signing, to correct for a server clock known to differ
+	Offset time.Duration
+	// Tolerance is the largest difference between the timestamp and the server time accepted by
+	// VerifyReplaySignature, 5 minutes if zero
+	Tolerance time.Duration
+	// Seen is called by VerifyReplaySignature with every nonce of a valid signature and should report whether it
+	// was already used within the tolerance. Nonces are not checked if nil
+	Seen func(nonce string) bool
+}
+
+func (o *ReplayOptions) defaults() {
+	if o.TimestampHeader == "" {
+		o.TimestampHeader = "X-Timestamp"
+	}
+	if o.NonceHeader == "" {
+		o.NonceHeader = "X-Nonce"
+	}
+	if o.SignatureHeader == "" {
+		o.SignatureHeader = "X-Signature"
+	}
+	if o.Tolerance <= 0 {
+		o.Tolerance = 5 * time.Minute
+	}
+}
+
+// SetReplaySignature adds a timestamp in Unix milliseconds, a random nonce and a hex HMAC-SHA256 signature over
+// them to every request, so that the server can reject replayed requests.
+//
+// The signature covers the timestamp, nonce, method, path with query and the SHA-256 of the body, each followed by
+// a new line. The body is buffered to be hashed. The time is taken from the clock of the request, see SetClock
+func SetReplaySignature(c Client, secret []byte, opts ReplayOptions) ClientFunc {
+	c = nilClientCheck(c)
+	opts.defaults()
+	return func(req *http.Request) (*http.Response, error) {
+		body, err := readRequestBody(req)
+		if err != nil {
+			return nil, err
+		}
+		nonce := make([]byte, 16)
+		if _, err = rand.Read(nonce); err != nil {
+			return nil, err
+		}
+		ts := strconv.FormatInt(ClockFromContext(req.Context()).Now().Add(opts.Offset).UnixMilli(), 10)
+		n := hex.EncodeToString(nonce)
+		if req.Header == nil {
+			req.Header = make(http.Header)
+		}
+		req.Header.Set(opts.TimestampHeader, ts)
+		req.Header.Set(opts.NonceHeader, n)
+		req.Header.Set(opts.SignatureHeader, replaySignature(secret, ts, n, req, body))
+		return c.Do(req)
+	}
+}
+
+// VerifyReplaySignature checks the replay protection headers of an incoming server request signed by
+// SetReplaySignature, returning an error wrapping ErrReplay if they are invalid.
+// The timestamp is compared with the clock of the request context. The body is buffered and can still be read
+// afterwards
+func VerifyReplaySignature(r *http.Request, secret []byte, opts ReplayOptions) error {
+	opts.defaults()
+	ts, nonce := r.Header.Get(opts.TimestampHeader), r.Header.Get(opts.NonceHeader)
+	sig, err := hex.DecodeString(r.Header.Get(opts.SignatureHeader))
+	if ts == "" || nonce == "" || err != nil || len(sig) == 0 {
+		return fmt.Errorf("%w: missing headers", ErrReplay)
+	}
+	ms, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: invalid timestamp", ErrReplay)
+	}
+	if skew := ClockFromContext(r.Context()).Now().Sub(time.UnixMilli(ms)); skew > opts.Tolerance || skew < -opts.Tolerance {
+		return fmt.Errorf("%w: timestamp outside tolerance", ErrReplay)
+	}
+	body, err := readRequestBody(r)
+	if err != nil {
+		return err
+	}
+	want, _ := hex.DecodeString(replaySignature(secret, ts, nonce, r, body))
+	if !hmac.Equal(sig, want) {
+		return fmt.Errorf("%w: signature does not match", ErrReplay)
+	}
+	if opts.Seen != nil && opts.Seen(nonce) {
+		return fmt.Errorf("%w: nonce already used", ErrReplay)
+	}
+	return nil
+}
+
+func replaySignature(secret []byte, ts, nonce string, req *http.Request, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%s\n%x\n", ts, nonce, req.Method, req.URL.RequestURI(), bodyHash)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// readRequestBody reads the whole body of req and replaces it so that it can be read again
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	b, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("could not read request body: %w", err)
+	}
+	setBodyBytes(req, b)
+	return b, nil
+}
@@ -0,0 +1,83 @@
+package httpx_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/httpxtest"
+)
+
+func TestReplaySignature(t *testing.T) {
+	secret := []byte("shared")
+	var seen sync.Map
+	opts := httpx.ReplayOptions{
+		Seen: func(nonce string) bool {
+			_, loaded := seen.LoadOrStore(nonce, true)
+			return loaded
+		},
+	}
+	var lastErr error
+	var replay *http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastErr = httpx.VerifyReplaySignature(r, secret, opts)
+		replay = r.Clone(r.Context())
+		if lastErr != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer srv.Close()
+
+	var c httpx.Client = srv.Client()
+	c = httpx.SetReplaySignature(c, secret, httpx.ReplayOptions{})
+	c = httpx.SetRequestBody(c, nil, []byte(`{"amount":10}`))
+	if _, err := httpx.SetRequest(c, http.MethodPost, srv.URL+"/orders?x=1").Do(nil); err != nil || lastErr != nil {
+		t.Fatal(err, lastErr)
+	}
+
+	// the same signed request sent again is rejected
+	if err := httpx.VerifyReplaySignature(replay, secret, opts); !errors.Is(err, httpx.ErrReplay) {
+		t.Fatal(err)
+	}
+
+	// a client clock too far from the server is rejected
+	clock := httpxtest.NewClock(time.Now().Add(-time.Hour))
+	skewed := httpx.SetClock(httpx.SetReplaySignature(srv.Client(), secret, httpx.ReplayOptions{}), clock)
+	if _, err := httpx.SetRequest(skewed, http.MethodGet, srv.URL).Do(nil); err != nil || !errors.Is(lastErr, httpx.ErrReplay) {
+		t.Fatal(err, lastErr)
+	}
+	// unless the offset corrects it
+	skewed = httpx.SetClock(httpx.SetReplaySignature(srv.Client(), secret, httpx.ReplayOptions{Offset: time.Hour}), clock)
+	if _, err := httpx.SetRequest(skewed, http.MethodGet, srv.URL).Do(nil); err != nil || lastErr != nil {
+		t.Fatal(err, lastErr)
+	}
+
+	// the server compares the timestamp with its clock
+	server := httptest.NewRequest(http.MethodGet, "/", nil)
+	signed := httpx.SetClock(httpx.SetReplaySignature(httpx.ClientFunc(func(req *http.Request) (*http.Response, error) {
+		server.Header = req.Header
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	}), secret, httpx.ReplayOptions{}), clock)
+	if _, err := httpx.SetRequest(signed, http.MethodGet, "http://example.com/").Do(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := httpx.VerifyReplaySignature(server, secret, httpx.ReplayOptions{}); !errors.Is(err, httpx.ErrReplay) {
+		t.Fatal("expected the skewed timestamp to be rejected", err)
+	}
+	httpx.SetDefaultClock(clock)
+	err := httpx.VerifyReplaySignature(server, secret, httpx.ReplayOptions{})
+	httpx.SetDefaultClock(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a different secret is rejected
+	wrong := httpx.SetReplaySignature(srv.Client(), []byte("other"), httpx.ReplayOptions{})
+	if _, err := httpx.SetRequest(wrong, http.MethodGet, srv.URL).Do(nil); err != nil || !errors.Is(lastErr, httpx.ErrReplay) {
+		t.Fatal(err, lastErr)
+	}
+}
@@ -0,0 +1,220 @@
+package httpx
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy configures SetRetry
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts including the first, retries are disabled if it is less than 2
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry, doubling for each further retry up to MaxBackoff.
+	// Defaults to 100 milliseconds and 10 seconds
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Backoff replaces the doubling of InitialBackoff when set, a Retry-After header shorter than MaxBackoff is still
+	// honored. Retries stop when it returns false
+	Backoff Backoff
+	// Statuses are the response codes that are retried, 429, 502, 503 and 504 if empty
+	Statuses []int
+	// Methods are the request methods that are retried, the idempotent methods
+	// GET, HEAD, OPTIONS, TRACE, PUT and DELETE if empty
+	Methods []string
+	// OnRetry is called after an attempt fails and before waiting to send the request again, for example to log
+	// or count retries. Returning false stops retrying and returns the result of the attempt.
+	// The response body must not be read, it is discarded once OnRetry returns
+	OnRetry func(RetryEvent) bool
+	// OnGiveUp is called when a failed attempt is returned without another retry because the attempts are used up,
+	// OnRetry returned false or the request was cancelled
+	OnGiveUp func(RetryEvent)
+}
+
+// RetryEvent describes a failed attempt to the callbacks of RetryPolicy
+type RetryEvent struct {
+	Request *http.Request
+	// Attempt is the number of the attempt that failed, starting at 1
+	Attempt int
+	// Wait is the time until the next attempt, zero when giving up
+	Wait time.Duration
+	// Response is the response of the attempt, nil if it failed with Err
+	Response *http.Response
+	Err      error
+}
+
+// ErrRetryDeadline is matched by the *AttemptsError returned by SetRetry when another attempt could not complete
+// before the deadline of the request context
+var ErrRetryDeadline = fmt.Errorf("no time left to retry before the deadline")
+
+var (
+	defaultRetryStatuses = []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+	idempotentMethods    = []string{http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete}
+)
+
+// SetRetry retries requests that fail with a transport error or a retryable status.
+//
+// Requests with a body are only retried if it can be sent again, which is the case for bodies set with
+// SetRequestBody from marshalled values or byte slices. A Retry-After header shorter than MaxBackoff is honored.
+// Each attempt carries its number on the context, see WithAttempt, and waiting uses the clock of the request.
+// When the request context has a deadline, retrying stops with ErrRetryDeadline as soon as the wait plus the
+// fastest attempt so far would not end before it, rather than starting an attempt that would be cancelled.
+// A request that failed with an error after several attempts returns an *AttemptsError holding every attempt.
+// The NoRetry override disables retries for a single request. See OnRetry and OnGiveUp to observe or stop retries.
+func SetRetry(c Client, policy RetryPolicy) ClientFunc {
+	c = nilClientCheck(c)
+	if policy.InitialBackoff <= 0 {
+		policy.InitialBackoff = 100 * time.Millisecond
+	}
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = 10 * time.Second
+	}
+	backoff := policy.Backoff
+	if backoff == nil {
+		backoff = ExponentialBackoff{Initial: policy.InitialBackoff, Max: policy.MaxBackoff}
+	}
+	statuses := make(map[int]bool)
+	if len(policy.Statuses) == 0 {
+		policy.Statuses = defaultRetryStatuses
+	}
+	for _, s := range policy.Statuses {
+		statuses[s] = true
+	}
+	methods := make(map[string]bool)
+	if len(policy.Methods) == 0 {
+		policy.Methods = idempotentMethods
+	}
+	for _, m := range policy.Methods {
+		methods[m] = true
+	}
+
+	giveUp := func(e RetryEvent) {
+		if policy.OnGiveUp != nil {
+			policy.OnGiveUp(e)
+		}
+	}
+
+	return func(req *http.Request) (*http.Response, error) {
+		ctx := req.Context()
+		if policy.MaxAttempts < 2 || !methods[req.Method] || OverridesFromContext(ctx).NoRetry ||
+			(req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+			return c.Do(req)
+		}
+		clock := ClockFromContext(ctx)
+		var attempts []AttemptResult
+		// failed returns the error of the last attempt together with the earlier ones
+		failed := func(err error) error {
+			if err == nil || len(attempts) < 2 {
+				return err
+			}
+			return &AttemptsError{Attempts: attempts}
+		}
+		for attempt := 1; ; attempt++ {
+			start := clock.Now()
+			resp, err := c.Do(req.WithContext(WithAttempt(ctx, attempt)))
+			attempts = append(attempts, newAttemptResult(attempt, req, resp, err, start, clock.Now()))
+			// an error that comes with a response, such as a StatusError or a decoding error from a decorator, is
+			// decided on the status alone
+			retry := err != nil
+			if resp != nil {
+				retry = statuses[resp.StatusCode]
+			}
+			if !retry {
+				return resp, err
+			}
+			event := RetryEvent{Request: req, Attempt: attempt, Response: resp, Err: err}
+			if attempt >= policy.MaxAttempts || ctx.Err() != nil {
+				giveUp(event)
+				return resp, failed(err)
+			}
+
+			wait, ok := backoff.NextDelay(attempt, resp, err)
+			if !ok {
+				giveUp(event)
+				return resp, failed(err)
+			}
+			event.Wait = wait
+			if resp != nil {
+				if after, ok := retryAfter(resp.Header.Get("Retry-After"), clock.Now()); ok && after <= policy.MaxBackoff {
+					event.Wait = after
+				}
+			}
+			if deadline, ok := ctx.Deadline(); ok && !clock.Now().Add(event.Wait+fastest(attempts)).Before(deadline) {
+				event.Wait = 0
+				giveUp(event)
+				return resp, &AttemptsError{Attempts: attempts, Err: ErrRetryDeadline}
+			}
+			if policy.OnRetry != nil && !policy.OnRetry(event) {
+				event.Wait = 0
+				giveUp(event)
+				return resp, failed(err)
+			}
+			if resp != nil && resp.Body != nil {
+				_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+				resp.Body.Close()
+			}
+			select {
+			case <-ctx.Done():
+				err = &AttemptsError{Attempts: attempts, Err: fmt.Errorf("request cancelled while waiting to retry: %w", ctx.Err())}
+				giveUp(RetryEvent{Request: req, Attempt: attempt, Err: err})
+				return nil, err
+			case <-clock.After(event.Wait):
+			}
+
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, fmt.Errorf("could not reset request body for retry: %w", err)
+				}
+				req.Body = body
+			}
+		}
+	}
+}
+
+// fastest returns the shortest time an attempt took
+func fastest(attempts []AttemptResult) time.Duration {
+	min := attempts[0].Duration
+	for _, a := range attempts[1:] {
+		if a.Duration < min {
+			min = a.Duration
+		}
+	}
+	return min
+}
+
+// SafeRetryPolicy is the policy of SetSafeRetries
+var SafeRetryPolicy = RetryPolicy{
+	MaxAttempts:    2,
+	InitialBackoff: 250 * time.Millisecond,
+	MaxBackoff:     5 * time.Second,
+	Statuses:       []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+	Methods:        []string{http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete},
+}
+
+// SetSafeRetries retries a GET, HEAD, PUT or DELETE request once, after a quarter of a second or the Retry-After
+// of the response if it is at most 5 seconds, when it fails with a transport error or a 502, 503 or 504 status.
+// These are failures where sending the request again cannot apply it twice. Requests whose body cannot be sent
+// again are not retried. Use SetRetry for other policies
+func SetSafeRetries(c Client) ClientFunc {
+	return SetRetry(c, SafeRetryPolicy)
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an http date
+func retryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
@@ -0,0 +1,195 @@
+package httpx_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/httpxtest"
+)
+
+func TestSetRetry(t *testing.T) {
+	var calls int32
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write(b)
+	}))
+	defer srv.Close()
+
+	var got Thing
+	var c httpx.Client = srv.Client()
+	c = httpx.SetRetry(c, httpx.RetryPolicy{MaxAttempts: 3})
+	c = httpx.RequireResponseStatus(c, http.StatusOK)
+	c = httpx.SetResponseBodyHandlerJSON(c, &got)
+	c = httpx.SetRequestBodyJSON(c, Thing{Foo: "again"})
+	c = httpx.SetRequest(c, http.MethodPut, srv.URL)
+	if _, err := c.Do(nil); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 || got.Foo != "again" || bodies[0] != bodies[2] {
+		t.Fatal(calls, got, bodies)
+	}
+}
+
+func TestSetRetryBackoff(t *testing.T) {
+	clock := httpxtest.NewClock(time.Now())
+	var attempts []int
+	var c httpx.Client = httpx.ClientFunc(func(req *http.Request) (*http.Response, error) {
+		attempts = append(attempts, httpx.AttemptFromContext(req.Context()))
+		return &http.Response{StatusCode: http.StatusBadGateway, Body: http.NoBody}, nil
+	})
+	c = httpx.SetRetry(c, httpx.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Second})
+	c = httpx.SetClock(c, clock)
+
+	done := make(chan *http.Response)
+	go func() {
+		resp, _ := httpx.SetRequest(c, http.MethodGet, "http://example.com").Do(nil)
+		done <- resp
+	}()
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	clock.BlockUntil(1)
+	select {
+	case <-done:
+		t.Fatal("expected a doubled backoff")
+	default:
+	}
+	clock.Advance(2 * time.Second)
+	if resp := <-done; resp.StatusCode != http.StatusBadGateway {
+		t.Fatal(resp.StatusCode)
+	}
+	if len(attempts) != 3 || attempts[2] != 3 {
+		t.Fatal(attempts)
+	}
+}
+
+func TestSetRetrySkips(t *testing.T) {
+	var calls int
+	var c httpx.Client = httpx.ClientFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: http.NoBody}, nil
+	})
+	c = httpx.SetRetry(c, httpx.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond})
+
+	// POST is not idempotent
+	if _, err := httpx.SetRequest(c, http.MethodPost, "http://example.com").Do(nil); err != nil {
+		t.Fatal(err)
+	}
+	// the override disables retries
+	ctx := httpx.WithOverride(context.Background(), httpx.NoRetry())
+	if _, err := httpx.SetRequestWithContext(ctx, c, http.MethodGet, "http://example.com").Do(nil); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Fatal(calls)
+	}
+}
+
+func TestSetSafeRetries(t *testing.T) {
+	calls := make(map[string]int)
+	var c httpx.Client = httpx.ClientFunc(func(req *http.Request) (*http.Response, error) {
+		calls[req.Method]++
+		status := http.StatusServiceUnavailable
+		if req.Method == http.MethodPatch {
+			status = http.StatusTooManyRequests
+		}
+		return &http.Response{StatusCode: status, Header: http.Header{"Retry-After": {"0"}}, Body: http.NoBody}, nil
+	})
+	c = httpx.SetSafeRetries(c)
+	for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodPatch} {
+		if _, err := httpx.SetRequest(c, method, "http://example.com").Do(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls[http.MethodGet] != 2 || calls[http.MethodPost] != 1 || calls[http.MethodPatch] != 1 {
+		t.Fatal(calls)
+	}
+}
+
+func TestSetSafeRetries_DecoratorError(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	c := httpx.SetSafeRetries(httpx.RequireResponseStatus(srv.Client(), http.StatusOK))
+	_, err := httpx.SetRequest(c, http.MethodGet, srv.URL).Do(nil)
+	var statusErr *httpx.StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Fatal("an error that comes with a response that is not retryable should not be retried", calls)
+	}
+}
+
+func TestSetRetryCallbacks(t *testing.T) {
+	var calls int
+	var c httpx.Client = httpx.ClientFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: http.NoBody}, nil
+	})
+	var retries, gaveUp []httpx.RetryEvent
+	c = httpx.SetRetry(c, httpx.RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: time.Millisecond,
+		OnRetry: func(e httpx.RetryEvent) bool {
+			retries = append(retries, e)
+			// stop after the second failure
+			return e.Attempt < 2
+		},
+		OnGiveUp: func(e httpx.RetryEvent) {
+			gaveUp = append(gaveUp, e)
+		},
+	})
+	resp, err := httpx.SetRequest(c, http.MethodGet, "http://example.com").Do(nil)
+	if err != nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatal(resp, err)
+	}
+	if calls != 2 || len(retries) != 2 || retries[0].Wait != time.Millisecond || retries[0].Response.StatusCode != http.StatusServiceUnavailable {
+		t.Fatal(calls, retries)
+	}
+	if len(gaveUp) != 1 || gaveUp[0].Attempt != 2 {
+		t.Fatal(gaveUp)
+	}
+}
+
+func TestSetRetryDeadline(t *testing.T) {
+	start := time.Now().Add(time.Hour)
+	clock := httpxtest.NewClock(start)
+	var calls int
+	var c httpx.Client = httpx.ClientFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		clock.Advance(time.Second)
+		return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: http.NoBody}, nil
+	})
+	c = httpx.SetRetry(c, httpx.RetryPolicy{MaxAttempts: 5, InitialBackoff: 100 * time.Millisecond})
+	c = httpx.SetClock(c, clock)
+
+	// a second attempt taking a second would end after the deadline
+	ctx, cancel := context.WithDeadline(context.Background(), start.Add(1500*time.Millisecond))
+	defer cancel()
+	resp, err := httpx.SetRequestWithContext(ctx, c, http.MethodGet, "http://example.com").Do(nil)
+	if !errors.Is(err, httpx.ErrRetryDeadline) || calls != 1 || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatal(calls, resp, err)
+	}
+	if !strings.Contains(err.Error(), "attempt 1: status 503 after 1s") {
+		t.Fatal(err)
+	}
+}
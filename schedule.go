@@ -0,0 +1,145 @@
+package httpx
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// Schedule waits until at and then performs req with c using ctx.
+// If at has passed the request is sent straight away. The wait uses the clock of ctx, see SetClock
+func Schedule(ctx context.Context, c Client, req *http.Request, at time.Time) (*http.Response, error) {
+	c = nilClientCheck(c)
+	clock := ClockFromContext(ctx)
+	if wait := at.Sub(clock.Now()); wait > 0 {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("scheduled request cancelled: %w", ctx.Err())
+		case <-clock.After(wait):
+		}
+	}
+	return c.Do(req.WithContext(ctx))
+}
+
+// ScheduleOption configures Every
+type ScheduleOption func(*scheduleConfig)
+
+type scheduleConfig struct {
+	jitter    time.Duration
+	immediate bool
+	backoff   Backoff
+}
+
+// WithJitter adds a random delay of up to j to every interval, so that many pollers started together spread out
+func WithJitter(j time.Duration) ScheduleOption {
+	return func(cfg *scheduleConfig) {
+		cfg.jitter = j
+	}
+}
+
+// Immediately runs the first request when Every starts rather than after the first interval
+func Immediately() ScheduleOption {
+	return func(cfg *scheduleConfig) {
+		cfg.immediate = true
+	}
+}
+
+// WithBackoff makes Every wait as long as b decides after a failed run instead of the interval. A run fails on an
+// error or a 429 or 5xx response and b is called with the number of failed runs in a row. Every stops once b returns
+// false
+func WithBackoff(b Backoff) ScheduleOption {
+	return func(cfg *scheduleConfig) {
+		cfg.backoff = b
+	}
+}
+
+// ErrScheduleGaveUp is matched by the error returned from Every when the Backoff of WithBackoff stopped the runs
+var ErrScheduleGaveUp = fmt.Errorf("scheduled requests gave up after failing")
+
+// ScheduleGaveUpError is returned by Every when the Backoff of WithBackoff stopped the runs. It matches
+// ErrScheduleGaveUp and unwraps to the error of the last run, an *StatusError for a failed response
+type ScheduleGaveUpError struct {
+	Failures int
+	Err      error
+}
+
+func (e *ScheduleGaveUpError) Error() string {
+	return fmt.Sprintf("%s %d times in a row: %v", ErrScheduleGaveUp, e.Failures, e.Err)
+}
+
+// Is reports whether target is ErrScheduleGaveUp
+func (e *ScheduleGaveUpError) Is(target error) bool {
+	return target == ErrScheduleGaveUp
+}
+
+// Unwrap returns the error of the last run
+func (e *ScheduleGaveUpError) Unwrap() error {
+	return e.Err
+}
+
+// Every performs a request built by build through c every interval until ctx is done, which it then returns.
+//
+// handle is called with the result of every request, including build errors, and the response body is closed
+// after it returns. Runs never overlap, the next interval starts when handle returns.
+// The waits use the clock of ctx, see SetClock. If WithBackoff stops the runs a *ScheduleGaveUpError is returned
+func Every(ctx context.Context, c Client, interval time.Duration, build func(ctx context.Context) (*http.Request, error), handle func(*http.Response, error), opts ...ScheduleOption) error {
+	c = nilClientCheck(c)
+	var cfg scheduleConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	clock := ClockFromContext(ctx)
+	var failures int
+	wait := interval
+	if cfg.immediate {
+		wait = 0
+	}
+	for {
+		if cfg.jitter > 0 {
+			wait += time.Duration(rand.Int63n(int64(cfg.jitter)))
+		}
+		if wait > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-clock.After(wait):
+			}
+		} else if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		wait = interval
+
+		var resp *http.Response
+		req, err := build(ctx)
+		if err == nil {
+			resp, err = c.Do(req.WithContext(ctx))
+		}
+		handle(resp, err)
+		if resp != nil && resp.Body != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+			resp.Body.Close()
+		}
+		if cfg.backoff == nil {
+			continue
+		}
+		if err == nil && resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+			failures = 0
+			continue
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		failures++
+		delay, ok := cfg.backoff.NextDelay(failures, resp, err)
+		if !ok {
+			if err == nil {
+				err = &StatusError{StatusCode: resp.StatusCode, Header: resp.Header}
+			}
+			return &ScheduleGaveUpError{Failures: failures, Err: err}
+		}
+		wait = delay
+	}
+}
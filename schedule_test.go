@@ -0,0 +1,112 @@
+package httpx_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/httpxtest"
+)
+
+func TestSchedule(t *testing.T) {
+	srv := httptest.NewServer(echoHandler)
+	defer srv.Close()
+	clock := httpxtest.NewClock(time.Now())
+	httpx.SetDefaultClock(clock)
+	defer httpx.SetDefaultClock(nil)
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	done := make(chan error)
+	go func() {
+		_, err := httpx.Schedule(context.Background(), srv.Client(), req, clock.Now().Add(time.Minute))
+		done <- err
+	}()
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute - time.Second)
+	select {
+	case <-done:
+		t.Fatal("sent too early")
+	default:
+	}
+	clock.Advance(time.Second)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEvery(t *testing.T) {
+	srv := httptest.NewServer(echoHandler)
+	defer srv.Close()
+	clock := httpxtest.NewClock(time.Now())
+	httpx.SetDefaultClock(clock)
+	defer httpx.SetDefaultClock(nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var runs int32
+	done := make(chan error)
+	go func() {
+		done <- httpx.Every(ctx, srv.Client(), time.Minute, func(ctx context.Context) (*http.Request, error) {
+			return http.NewRequest(http.MethodGet, srv.URL, nil)
+		}, func(resp *http.Response, err error) {
+			if err != nil {
+				t.Error(err)
+			}
+			atomic.AddInt32(&runs, 1)
+		}, httpx.WithJitter(time.Second))
+	}()
+
+	for i := int32(1); i <= 3; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(time.Minute + time.Second)
+		for atomic.LoadInt32(&runs) != i {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	clock.BlockUntil(1)
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatal(err)
+	}
+}
+
+func TestEvery_Backoff(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	clock := httpxtest.NewClock(time.Now())
+	httpx.SetDefaultClock(clock)
+	defer httpx.SetDefaultClock(nil)
+
+	var runs int32
+	backoff := httpx.BackoffFunc(func(attempt int, resp *http.Response, err error) (time.Duration, bool) {
+		return 10 * time.Second, attempt < 2
+	})
+	done := make(chan error)
+	go func() {
+		done <- httpx.Every(context.Background(), srv.Client(), time.Hour, func(ctx context.Context) (*http.Request, error) {
+			return http.NewRequest(http.MethodGet, srv.URL, nil)
+		}, func(*http.Response, error) {
+			atomic.AddInt32(&runs, 1)
+		}, httpx.Immediately(), httpx.WithBackoff(backoff))
+	}()
+
+	// the failed run waits for the backoff rather than the interval
+	clock.BlockUntil(1)
+	clock.Advance(10 * time.Second)
+	err := <-done
+	var gaveUp *httpx.ScheduleGaveUpError
+	var statusErr *httpx.StatusError
+	if !errors.Is(err, httpx.ErrScheduleGaveUp) || !errors.As(err, &gaveUp) || gaveUp.Failures != 2 ||
+		!errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatal(err)
+	}
+	if runs != 2 {
+		t.Fatal(runs)
+	}
+}
@@ -0,0 +1,401 @@
+package httpx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SchemaValidator validates a JSON document against a JSON schema.
+//
+// Implementations return a non-nil error describing every violation if the document does not match.
+type SchemaValidator interface {
+	Validate(schema []byte, document []byte) error
+}
+
+// DefaultSchemaValidator is used by ValidateRequestBody and ValidateResponseBody.
+//
+// The built in validator supports the structural keywords of JSON schema (type, enum, const, properties, required,
+// additionalProperties, items, allOf, anyOf, oneOf, not), the string, number, array and object limits,
+// OpenAPI's nullable and local $ref references. Formats are not checked.
+// Replace it to use a complete JSON schema implementation.
+var DefaultSchemaValidator SchemaValidator = jsonSchemaValidator{}
+
+// ValidateRequestBody returns a non-nil error without sending the request if the request body does not match the
+// JSON schema
+func ValidateRequestBody(c Client, schema []byte) ClientFunc {
+	c = nilClientCheck(c)
+	return func(req *http.Request) (*http.Response, error) {
+		b, err := readAndRestore(&req.Body)
+		if err != nil {
+			return nil, err
+		}
+		if err = DefaultSchemaValidator.Validate(schema, b); err != nil {
+			return nil, errSchemaValidation{next: err}
+		}
+		return c.Do(req)
+	}
+}
+
+// ValidateResponseBody returns a non-nil error if the response body does not match the JSON schema
+func ValidateResponseBody(c Client, schema []byte) ClientFunc {
+	c = nilClientCheck(c)
+	return func(req *http.Request) (*http.Response, error) {
+		resp, err := c.Do(req)
+		if err != nil {
+			return resp, err
+		}
+		b, err := readAndRestore(&resp.Body)
+		if err != nil {
+			return resp, err
+		}
+		if err = DefaultSchemaValidator.Validate(schema, b); err != nil {
+			return resp, errSchemaValidation{next: err}
+		}
+		return resp, nil
+	}
+}
+
+// jsonSchemaValidator is the built in SchemaValidator
+type jsonSchemaValidator struct{}
+
+// Validate implements SchemaValidator
+func (jsonSchemaValidator) Validate(schema []byte, document []byte) error {
+	var root any
+	if err := decodeJSONNumbers(schema, &root); err != nil {
+		return fmt.Errorf("invalid schema: %w", err)
+	}
+	var doc any
+	if err := decodeJSONNumbers(document, &doc); err != nil {
+		return fmt.Errorf("invalid JSON document: %w", err)
+	}
+	rootObj, _ := root.(map[string]any)
+	if errs := (schemaValidator{root: rootObj}).validate(root, doc, "$"); len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func decodeJSONNumbers(b []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if dec.More() {
+		return fmt.Errorf("unexpected data after the JSON value")
+	}
+	return nil
+}
+
+// schemaValidator validates decoded JSON values against raw schemas, resolving local references against root
+type schemaValidator struct {
+	root map[string]any
+}
+
+// validate returns every violation of the schema by v.
+// Values must be decoded with json.Number for numbers.
+func (s schemaValidator) validate(schema any, v any, path string) []string {
+	sch, ok := schema.(map[string]any)
+	if !ok {
+		// boolean schemas
+		if b, isBool := schema.(bool); isBool && !b {
+			return []string{fmt.Sprintf("%s: no value is allowed", path)}
+		}
+		return nil
+	}
+	if ref, ok := sch["$ref"].(string); ok {
+		resolved, err := s.resolve(ref)
+		if err != nil {
+			return []string{fmt.Sprintf("%s: %s", path, err)}
+		}
+		return s.validate(resolved, v, path)
+	}
+
+	if v == nil && sch["nullable"] == true {
+		return nil
+	}
+	var errs []string
+	if t, ok := sch["type"]; ok && !matchesType(t, v) {
+		return append(errs, fmt.Sprintf("%s: expected type %v, got %s", path, t, typeOf(v)))
+	}
+	if enum, ok := sch["enum"].([]any); ok && !containsValue(enum, v) {
+		errs = append(errs, fmt.Sprintf("%s: value is not one of %v", path, enum))
+	}
+	if c, ok := sch["const"]; ok && !equalValues(c, v) {
+		errs = append(errs, fmt.Sprintf("%s: value must be %v", path, c))
+	}
+
+	switch t := v.(type) {
+	case map[string]any:
+		errs = append(errs, s.validateObject(sch, t, path)...)
+	case []any:
+		errs = append(errs, s.validateArray(sch, t, path)...)
+	case string:
+		errs = append(errs, validateString(sch, t, path)...)
+	case json.Number:
+		errs = append(errs, validateNumber(sch, t, path)...)
+	}
+
+	if all, ok := sch["allOf"].([]any); ok {
+		for _, sub := range all {
+			errs = append(errs, s.validate(sub, v, path)...)
+		}
+	}
+	if anyOf, ok := sch["anyOf"].([]any); ok {
+		matched := false
+		for _, sub := range anyOf {
+			if len(s.validate(sub, v, path)) == 0 {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			errs = append(errs, fmt.Sprintf("%s: value does not match any schema in anyOf", path))
+		}
+	}
+	if oneOf, ok := sch["oneOf"].([]any); ok {
+		matched := 0
+		for _, sub := range oneOf {
+			if len(s.validate(sub, v, path)) == 0 {
+				matched++
+			}
+		}
+		if matched != 1 {
+			errs = append(errs, fmt.Sprintf("%s: value matches %d schemas in oneOf, expected exactly 1", path, matched))
+		}
+	}
+	if not, ok := sch["not"]; ok && len(s.validate(not, v, path)) == 0 {
+		errs = append(errs, fmt.Sprintf("%s: value must not match the schema in not", path))
+	}
+	return errs
+}
+
+func (s schemaValidator) validateObject(sch map[string]any, obj map[string]any, path string) []string {
+	var errs []string
+	if required, ok := sch["required"].([]any); ok {
+		for _, r := range required {
+			name, _ := r.(string)
+			if _, ok := obj[name]; !ok {
+				errs = append(errs, fmt.Sprintf("%s: missing required property %q", path, name))
+			}
+		}
+	}
+	props, _ := sch["properties"].(map[string]any)
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if p, ok := props[k]; ok {
+			errs = append(errs, s.validate(p, obj[k], path+"."+k)...)
+			continue
+		}
+		if additional, ok := sch["additionalProperties"]; ok {
+			if b, isBool := additional.(bool); isBool && !b {
+				errs = append(errs, fmt.Sprintf("%s: unexpected property %q", path, k))
+				continue
+			}
+			errs = append(errs, s.validate(additional, obj[k], path+"."+k)...)
+		}
+	}
+	if n, ok := intKeyword(sch, "minProperties"); ok && len(obj) < n {
+		errs = append(errs, fmt.Sprintf("%s: expected at least %d properties", path, n))
+	}
+	if n, ok := intKeyword(sch, "maxProperties"); ok && len(obj) > n {
+		errs = append(errs, fmt.Sprintf("%s: expected at most %d properties", path, n))
+	}
+	return errs
+}
+
+func (s schemaValidator) validateArray(sch map[string]any, arr []any, path string) []string {
+	var errs []string
+	if items, ok := sch["items"]; ok {
+		for i, item := range arr {
+			errs = append(errs, s.validate(items, item, path+"["+strconv.Itoa(i)+"]")...)
+		}
+	}
+	if n, ok := intKeyword(sch, "minItems"); ok && len(arr) < n {
+		errs = append(errs, fmt.Sprintf("%s: expected at least %d items", path, n))
+	}
+	if n, ok := intKeyword(sch, "maxItems"); ok && len(arr) > n {
+		errs = append(errs, fmt.Sprintf("%s: expected at most %d items", path, n))
+	}
+	if sch["uniqueItems"] == true {
+		for i := range arr {
+			for j := i + 1; j < len(arr); j++ {
+				if equalValues(arr[i], arr[j]) {
+					errs = append(errs, fmt.Sprintf("%s: items %d and %d are not unique", path, i, j))
+				}
+			}
+		}
+	}
+	return errs
+}
+
+func validateString(sch map[string]any, str string, path string) []string {
+	var errs []string
+	length := len([]rune(str))
+	if n, ok := intKeyword(sch, "minLength"); ok && length < n {
+		errs = append(errs, fmt.Sprintf("%s: expected at least %d characters", path, n))
+	}
+	if n, ok := intKeyword(sch, "maxLength"); ok && length > n {
+		errs = append(errs, fmt.Sprintf("%s: expected at most %d characters", path, n))
+	}
+	if p, ok := sch["pattern"].(string); ok {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: invalid pattern %q: %s", path, p, err))
+		} else if !re.MatchString(str) {
+			errs = append(errs, fmt.Sprintf("%s: value does not match pattern %q", path, p))
+		}
+	}
+	return errs
+}
+
+func validateNumber(sch map[string]any, n json.Number, path string) []string {
+	var errs []string
+	v, ok := new(big.Float).SetString(n.String())
+	if !ok {
+		return []string{fmt.Sprintf("%s: invalid number %s", path, n)}
+	}
+	compare := func(keyword string, valid func(cmp int) bool, msg string) {
+		limit, ok := sch[keyword].(json.Number)
+		if !ok {
+			return
+		}
+		l, ok := new(big.Float).SetString(limit.String())
+		if ok && !valid(v.Cmp(l)) {
+			errs = append(errs, fmt.Sprintf("%s: value must be %s %s", path, msg, limit))
+		}
+	}
+	// OpenAPI 3.0 uses boolean exclusive flags while JSON schema uses numeric limits
+	if sch["exclusiveMinimum"] == true {
+		compare("minimum", func(c int) bool { return c > 0 }, "greater than")
+	} else {
+		compare("minimum", func(c int) bool { return c >= 0 }, "at least")
+		compare("exclusiveMinimum", func(c int) bool { return c > 0 }, "greater than")
+	}
+	if sch["exclusiveMaximum"] == true {
+		compare("maximum", func(c int) bool { return c < 0 }, "less than")
+	} else {
+		compare("maximum", func(c int) bool { return c <= 0 }, "at most")
+		compare("exclusiveMaximum", func(c int) bool { return c < 0 }, "less than")
+	}
+	if m, ok := sch["multipleOf"].(json.Number); ok {
+		div, ok := new(big.Rat).SetString(m.String())
+		val, okVal := new(big.Rat).SetString(n.String())
+		if ok && okVal && div.Sign() != 0 && !new(big.Rat).Quo(val, div).IsInt() {
+			errs = append(errs, fmt.Sprintf("%s: value must be a multiple of %s", path, m))
+		}
+	}
+	return errs
+}
+
+// resolve follows a local JSON pointer reference such as "#/components/schemas/Pet"
+func (s schemaValidator) resolve(ref string) (any, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("unsupported reference %q", ref)
+	}
+	var cur any = s.root
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#"), "/")[1:] {
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("unresolved reference %q", ref)
+		}
+		if cur, ok = m[part]; !ok {
+			return nil, fmt.Errorf("unresolved reference %q", ref)
+		}
+	}
+	return cur, nil
+}
+
+func intKeyword(sch map[string]any, keyword string) (int, bool) {
+	n, ok := sch[keyword].(json.Number)
+	if !ok {
+		return 0, false
+	}
+	i, err := n.Int64()
+	return int(i), err == nil
+}
+
+func matchesType(t any, v any) bool {
+	switch tt := t.(type) {
+	case string:
+		return matchesTypeName(tt, v)
+	case []any:
+		for _, name := range tt {
+			if s, ok := name.(string); ok && matchesTypeName(s, v) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func matchesTypeName(name string, v any) bool {
+	switch name {
+	case "integer":
+		n, ok := v.(json.Number)
+		if !ok {
+			return false
+		}
+		r, ok := new(big.Rat).SetString(n.String())
+		return ok && r.IsInt()
+	case "number":
+		_, ok := v.(json.Number)
+		return ok
+	}
+	return typeOf(v) == name
+}
+
+func typeOf(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func containsValue(values []any, v any) bool {
+	for _, e := range values {
+		if equalValues(e, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func equalValues(a, b any) bool {
+	if na, ok := a.(json.Number); ok {
+		nb, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		fa, okA := new(big.Rat).SetString(na.String())
+		fb, okB := new(big.Rat).SetString(nb.String())
+		return okA && okB && fa.Cmp(fb) == 0
+	}
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(ja) == string(jb)
+}
@@ -0,0 +1,104 @@
+package httpx_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tflyons/httpx"
+)
+
+var thingSchema = []byte(`{
+	"type": "object",
+	"required": ["foo", "bar"],
+	"properties": {
+		"foo": {"type": "string", "minLength": 1},
+		"bar": {"$ref": "#/definitions/positive"}
+	},
+	"additionalProperties": false,
+	"definitions": {
+		"positive": {"type": "integer", "minimum": 1}
+	}
+}`)
+
+func TestDefaultSchemaValidator(t *testing.T) {
+	tests := []struct {
+		doc   string
+		valid bool
+	}{
+		{`{"foo":"a","bar":1}`, true},
+		{`{"foo":"a"}`, false},
+		{`{"foo":"","bar":1}`, false},
+		{`{"foo":"a","bar":0}`, false},
+		{`{"foo":"a","bar":1.5}`, false},
+		{`{"foo":"a","bar":1,"baz":true}`, false},
+		{`[]`, false},
+	}
+	for _, tt := range tests {
+		err := httpx.DefaultSchemaValidator.Validate(thingSchema, []byte(tt.doc))
+		if (err == nil) != tt.valid {
+			t.Errorf("%s: %v", tt.doc, err)
+		}
+	}
+}
+
+func TestValidateRequestBody(t *testing.T) {
+	srv := httptest.NewServer(echoHandler)
+	defer srv.Close()
+
+	sent := false
+	var c httpx.Client = httpx.ClientFunc(func(req *http.Request) (*http.Response, error) {
+		sent = true
+		return srv.Client().Do(req)
+	})
+	c = httpx.ValidateRequestBody(c, thingSchema)
+	c = httpx.SetRequestBodyJSON(c, map[string]any{"foo": "a"})
+	c = httpx.SetRequest(c, http.MethodPost, srv.URL)
+	_, err := c.Do(nil)
+	if !errors.Is(err, httpx.ErrSchemaValidation) {
+		t.Fatal(err)
+	}
+	if sent {
+		t.Fatal("expected the request not to be sent")
+	}
+}
+
+func TestValidateResponseBody(t *testing.T) {
+	srv := httptest.NewServer(echoHandler)
+	defer srv.Close()
+
+	var out map[string]any
+	var c httpx.Client = srv.Client()
+	c = httpx.ValidateResponseBody(c, thingSchema)
+	c = httpx.SetResponseBodyHandlerJSON(c, &out)
+	c = httpx.SetRequestBodyJSON(c, map[string]any{"foo": "a", "bar": 2})
+	c = httpx.SetRequest(c, http.MethodPost, srv.URL)
+	if _, err := c.Do(nil); err != nil {
+		t.Fatal(err)
+	}
+	if out["foo"] != "a" {
+		t.Fatal(out)
+	}
+}
+
+type rejectAll struct{}
+
+func (rejectAll) Validate(schema, document []byte) error { return errors.New("rejected") }
+
+func TestValidateResponseBody_CustomValidator(t *testing.T) {
+	srv := httptest.NewServer(echoHandler)
+	defer srv.Close()
+
+	httpx.DefaultSchemaValidator = rejectAll{}
+	defer func() { httpx.DefaultSchemaValidator = defaultValidator }()
+
+	var c httpx.Client = srv.Client()
+	c = httpx.ValidateResponseBody(c, thingSchema)
+	c = httpx.SetRequest(c, http.MethodGet, srv.URL)
+	if _, err := c.Do(nil); !errors.Is(err, httpx.ErrSchemaValidation) {
+		t.Fatal(err)
+	}
+}
+
+var defaultValidator = httpx.DefaultSchemaValidator
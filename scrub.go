@@ -0,0 +1,399 @@
+package httpx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultMask is the value used to replace scrubbed content when a Scrubber does not set Mask
+const DefaultMask = "[REDACTED]"
+
+// Scrubber masks sensitive values in headers and bodies before they are logged, recorded or otherwise persisted.
+//
+// A single Scrubber is meant to be shared by everything that writes request or response data somewhere
+// so that the masking rules are defined once. The zero value masks nothing.
+type Scrubber struct {
+	// Headers are header names whose values are always masked
+	Headers []string
+	// Fields are JSON object keys or form field names whose values are masked wherever they appear.
+	// Matching is case-insensitive
+	Fields []string
+	// Paths are JSONPath-style expressions such as "$.user.email" or "$.items[*].card" whose values are masked.
+	// An invalid path masks JSON bodies whole
+	Paths []string
+	// Patterns are applied to header values, JSON string values and non-structured bodies.
+	// Every match is replaced with the mask
+	Patterns []*regexp.Regexp
+	// Mask replaces any scrubbed value. DefaultMask is used if empty
+	Mask string
+}
+
+// Common patterns for use with Scrubber.Patterns
+var (
+	ScrubEmail      = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	ScrubCardNumber = regexp.MustCompile(`\b(?:\d[ \-]?){12,18}\d\b`)
+	ScrubBearer     = regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9\-._~+/]+=*`)
+)
+
+// NewDefaultScrubber returns a Scrubber covering common credentials and personal data
+func NewDefaultScrubber() *Scrubber {
+	return &Scrubber{
+		Headers: []string{
+			"Authorization",
+			"Proxy-Authorization",
+			"Cookie",
+			"Set-Cookie",
+			"X-Api-Key",
+		},
+		Fields: []string{
+			"password",
+			"secret",
+			"client_secret",
+			"token",
+			"access_token",
+			"refresh_token",
+			"id_token",
+			"api_key",
+		},
+		Patterns: []*regexp.Regexp{
+			ScrubEmail,
+			ScrubCardNumber,
+			ScrubBearer,
+		},
+	}
+}
+
+func (s *Scrubber) mask() string {
+	if s.Mask == "" {
+		return DefaultMask
+	}
+	return s.Mask
+}
+
+func (s *Scrubber) isField(name string) bool {
+	for _, f := range s.Fields {
+		if strings.EqualFold(f, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// ScrubString applies the configured patterns to v
+func (s *Scrubber) ScrubString(v string) string {
+	if s == nil {
+		return v
+	}
+	for _, p := range s.Patterns {
+		v = p.ReplaceAllLiteralString(v, s.mask())
+	}
+	return v
+}
+
+// ScrubHeader returns a copy of h with sensitive header values masked
+func (s *Scrubber) ScrubHeader(h http.Header) http.Header {
+	if h == nil {
+		return nil
+	}
+	out := make(http.Header, len(h))
+	masked := make(map[string]bool, len(s.headers()))
+	for _, k := range s.headers() {
+		masked[textproto.CanonicalMIMEHeaderKey(k)] = true
+	}
+	for k, v := range h {
+		values := make([]string, len(v))
+		for i := range v {
+			if masked[textproto.CanonicalMIMEHeaderKey(k)] {
+				values[i] = s.mask()
+			} else {
+				values[i] = s.ScrubString(v[i])
+			}
+		}
+		out[k] = values
+	}
+	return out
+}
+
+// ScrubURL returns u without its password and with the query masked as a form body, so that Fields such as token
+// are masked along with the patterns
+func (s *Scrubber) ScrubURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	return s.scrubURL(u).Redacted()
+}
+
+// scrubURL returns a copy of u with the query and path masked, see ScrubURL
+func (s *Scrubber) scrubURL(u *url.URL) *url.URL {
+	scrubbed := *u
+	if s != nil {
+		scrubbed.RawQuery = string(s.ScrubBody("application/x-www-form-urlencoded", []byte(u.RawQuery)))
+		scrubbed.Path = s.ScrubString(u.Path)
+		scrubbed.RawPath = ""
+	}
+	return &scrubbed
+}
+
+// scrubError returns the text of err with the query and password of u masked, as url.Error and others include the
+// URL in their message, and the patterns applied
+func (s *Scrubber) scrubError(u *url.URL, err error) string {
+	msg := err.Error()
+	if s == nil {
+		return msg
+	}
+	if u != nil {
+		if u.RawQuery != "" {
+			msg = strings.ReplaceAll(msg, u.RawQuery, s.scrubURL(u).RawQuery)
+		}
+		if pass, ok := u.User.Password(); ok && pass != "" {
+			msg = strings.ReplaceAll(msg, pass, "xxxxx")
+		}
+	}
+	return s.ScrubString(msg)
+}
+
+func (s *Scrubber) headers() []string {
+	if s == nil {
+		return nil
+	}
+	return s.Headers
+}
+
+// ScrubBody returns a masked copy of b.
+//
+// JSON bodies have field and path rules applied to the decoded document; form bodies have field rules applied.
+// Patterns are applied to everything else as plain text. A JSON or form body the rules cannot be applied to,
+// because it is malformed or a path is invalid, is replaced by the mask as a whole so that nothing leaks.
+func (s *Scrubber) ScrubBody(contentType string, b []byte) []byte {
+	if s == nil || len(b) == 0 {
+		return b
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	var out []byte
+	var err error
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		out, err = s.scrubJSON(b)
+	case mediaType == "application/x-www-form-urlencoded":
+		out, err = s.scrubForm(b)
+	case mediaType == "" && json.Valid(b):
+		out, err = s.scrubJSON(b)
+	default:
+		return []byte(s.ScrubString(string(b)))
+	}
+	if err != nil {
+		if len(s.Fields) > 0 || len(s.Paths) > 0 {
+			// the field and path rules could not be applied, masking everything is the only safe choice
+			return []byte(s.mask())
+		}
+		return []byte(s.ScrubString(string(b)))
+	}
+	return out
+}
+
+func (s *Scrubber) scrubForm(b []byte) ([]byte, error) {
+	values, err := url.ParseQuery(string(b))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range values {
+		for i := range v {
+			if s.isField(k) {
+				v[i] = s.mask()
+			} else {
+				v[i] = s.ScrubString(v[i])
+			}
+		}
+	}
+	return []byte(values.Encode()), nil
+}
+
+func (s *Scrubber) scrubJSON(b []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	for _, p := range s.Paths {
+		segments, err := parseScrubPath(p)
+		if err != nil {
+			return nil, err
+		}
+		doc = s.maskPath(doc, segments)
+	}
+	doc = s.walkJSON(doc)
+	return json.Marshal(doc)
+}
+
+// walkJSON masks field rules and applies patterns to every string value
+func (s *Scrubber) walkJSON(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, child := range t {
+			if s.isField(k) {
+				t[k] = s.mask()
+				continue
+			}
+			t[k] = s.walkJSON(child)
+		}
+	case []any:
+		for i := range t {
+			t[i] = s.walkJSON(t[i])
+		}
+	case string:
+		return s.ScrubString(t)
+	}
+	return v
+}
+
+// maskPath replaces the value found at the given path segments
+func (s *Scrubber) maskPath(v any, segments []string) any {
+	if len(segments) == 0 {
+		return s.mask()
+	}
+	seg, rest := segments[0], segments[1:]
+	switch t := v.(type) {
+	case map[string]any:
+		if seg == "*" {
+			for k := range t {
+				t[k] = s.maskPath(t[k], rest)
+			}
+		} else if child, ok := t[seg]; ok {
+			t[seg] = s.maskPath(child, rest)
+		}
+	case []any:
+		if seg == "*" {
+			for i := range t {
+				t[i] = s.maskPath(t[i], rest)
+			}
+		} else if i, err := strconv.Atoi(seg); err == nil && i >= 0 && i < len(t) {
+			t[i] = s.maskPath(t[i], rest)
+		}
+	}
+	return v
+}
+
+// parseScrubPath splits a JSONPath-style expression into object keys, array indexes and wildcards
+func parseScrubPath(p string) ([]string, error) {
+	if !strings.HasPrefix(p, "$") {
+		return nil, fmt.Errorf("scrub path %q must start with $", p)
+	}
+	var segments []string
+	rest := p[1:]
+	for len(rest) > 0 {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("scrub path %q has an empty segment", p)
+			}
+			segments = append(segments, rest[:end])
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("scrub path %q has an unclosed bracket", p)
+			}
+			segments = append(segments, strings.Trim(rest[1:end], `'"`))
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("scrub path %q is invalid at %q", p, rest)
+		}
+	}
+	return segments, nil
+}
+
+// DumpRequest returns a scrubbed HTTP/1.x representation of the request.
+//
+// The request body is read and replaced so that the request can still be sent afterwards.
+func (s *Scrubber) DumpRequest(req *http.Request) ([]byte, error) {
+	body, err := readAndRestore(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	// the request line is rebuilt from the URL so that credentials in the query are masked as form fields, the
+	// raw RequestURI of a server request is never written
+	u := req.URL
+	if u == nil {
+		u, err = url.ParseRequestURI(req.RequestURI)
+		if err != nil {
+			return nil, err
+		}
+	}
+	uri := s.scrubURL(u).RequestURI()
+	fmt.Fprintf(&buf, "%s %s HTTP/%d.%d\r\n", req.Method, uri, req.ProtoMajor, req.ProtoMinor)
+	host := req.Host
+	if host == "" && req.URL != nil {
+		host = req.URL.Host
+	}
+	if host != "" {
+		fmt.Fprintf(&buf, "Host: %s\r\n", host)
+	}
+	writeHeader(&buf, s.ScrubHeader(req.Header))
+	buf.WriteString("\r\n")
+	buf.Write(s.ScrubBody(req.Header.Get("Content-Type"), body))
+	return buf.Bytes(), nil
+}
+
+// DumpResponse returns a scrubbed HTTP/1.x representation of the response.
+//
+// The response body is read and replaced so that it can still be consumed afterwards.
+func (s *Scrubber) DumpResponse(resp *http.Response) ([]byte, error) {
+	body, err := readAndRestore(&resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "HTTP/%d.%d %s\r\n", resp.ProtoMajor, resp.ProtoMinor, resp.Status)
+	writeHeader(&buf, s.ScrubHeader(resp.Header))
+	buf.WriteString("\r\n")
+	buf.Write(s.ScrubBody(resp.Header.Get("Content-Type"), body))
+	return buf.Bytes(), nil
+}
+
+// readAndRestore reads all of body and replaces it with an in memory copy
+func readAndRestore(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil || *body == http.NoBody {
+		return nil, nil
+	}
+	b, err := io.ReadAll(*body)
+	closeErr := (*body).Close()
+	*body = io.NopCloser(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	if closeErr != nil {
+		return b, errBodyCloser{next: closeErr}
+	}
+	return b, nil
+}
+
+// writeHeader writes the header in sorted order so dumps are stable
+func writeHeader(w io.Writer, h http.Header) {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range h[k] {
+			fmt.Fprintf(w, "%s: %s\r\n", k, v)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package httpx_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tflyons/httpx"
+)
+
+func TestScrubber_ScrubBodyJSON(t *testing.T) {
+	s := httpx.NewDefaultScrubber()
+	s.Paths = []string{"$.user.name", "$.cards[*].number"}
+
+	in := `{"user":{"name":"tom","email":"tom@example.com"},"password":"hunter2","cards":[{"number":"1","type":"visa"}],"note":"call 4111 1111 1111 1111"}`
+	out := s.ScrubBody("application/json; charset=utf-8", []byte(in))
+
+	var got map[string]any
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatal(err)
+	}
+	user := got["user"].(map[string]any)
+	if user["name"] != httpx.DefaultMask || user["email"] != httpx.DefaultMask {
+		t.Fatal(user)
+	}
+	if got["password"] != httpx.DefaultMask {
+		t.Fatal(got["password"])
+	}
+	card := got["cards"].([]any)[0].(map[string]any)
+	if card["number"] != httpx.DefaultMask || card["type"] != "visa" {
+		t.Fatal(card)
+	}
+	if got["note"] != "call "+httpx.DefaultMask {
+		t.Fatal(got["note"])
+	}
+}
+
+func TestScrubber_ScrubBodyInvalidPath(t *testing.T) {
+	s := httpx.NewDefaultScrubber()
+	s.Paths = []string{"user.name"}
+	in := `{"user":{"name":"tom"},"password":"hunter2"}`
+	for _, ct := range []string{"application/json", ""} {
+		if out := string(s.ScrubBody(ct, []byte(in))); out != httpx.DefaultMask {
+			t.Fatalf("expected the body to be masked whole for content type %q, got %s", ct, out)
+		}
+	}
+	// malformed JSON cannot have its fields masked either
+	if out := string(s.ScrubBody("application/json", []byte(`{"password":"hunter2"`))); strings.Contains(out, "hunter2") {
+		t.Fatal(out)
+	}
+}
+
+func TestScrubber_ScrubBodyForm(t *testing.T) {
+	s := httpx.NewDefaultScrubber()
+	out := s.ScrubBody("application/x-www-form-urlencoded", []byte("user=tom&password=hunter2"))
+	if strings.Contains(string(out), "hunter2") || !strings.Contains(string(out), "user=tom") {
+		t.Fatal(string(out))
+	}
+}
+
+func TestScrubber_DumpRequest(t *testing.T) {
+	s := httpx.NewDefaultScrubber()
+	body := `{"token":"abc"}`
+	req, err := http.NewRequest(http.MethodPost, "http://example.com/login", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer abc")
+	req.Header.Set("Content-Type", "application/json")
+
+	dump, err := s.DumpRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(dump), "abc") {
+		t.Fatal(string(dump))
+	}
+	// the original body must still be readable
+	b, err := io.ReadAll(req.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != body {
+		t.Fatal(string(b))
+	}
+}
+
+func TestScrubber_DumpRequestQuery(t *testing.T) {
+	s := httpx.NewDefaultScrubber()
+	client, _ := http.NewRequest(http.MethodGet, "http://example.com/x?access_token=SECRET&page=2", nil)
+	server := httptest.NewRequest(http.MethodGet, "/x?token=SECRET2", nil)
+	for _, req := range []*http.Request{client, server} {
+		dump, err := s.DumpRequest(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(dump), "SECRET") || !strings.HasPrefix(string(dump), "GET /x?") {
+			t.Fatal(string(dump))
+		}
+	}
+}
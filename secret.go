@@ -0,0 +1,106 @@
+package httpx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrSecretNotFound is returned by the SecretProvider implementations in this package when a secret is not set
+var ErrSecretNotFound = fmt.Errorf("secret not found")
+
+// SecretProvider returns the current value of a named secret such as a token or password.
+//
+// The auth decorators call Get on every request so that a rotated secret is used without rebuilding the client.
+// Implementations that are expensive to query should cache values themselves.
+type SecretProvider interface {
+	Get(ctx context.Context, name string) (string, error)
+}
+
+// SecretProviderFunc is an adapter to allow the use of ordinary functions as a SecretProvider
+type SecretProviderFunc func(ctx context.Context, name string) (string, error)
+
+// Get calls f(ctx, name)
+func (f SecretProviderFunc) Get(ctx context.Context, name string) (string, error) {
+	return f(ctx, name)
+}
+
+// EnvSecrets returns a SecretProvider reading the environment variable prefix+name.
+// An unset or empty variable returns ErrSecretNotFound
+func EnvSecrets(prefix string) SecretProvider {
+	return SecretProviderFunc(func(_ context.Context, name string) (string, error) {
+		v := os.Getenv(prefix + name)
+		if v == "" {
+			return "", fmt.Errorf("%w: environment variable %s", ErrSecretNotFound, prefix+name)
+		}
+		return v, nil
+	})
+}
+
+// FileSecrets returns a SecretProvider reading the file named name in dir, as mounted by Kubernetes secrets or
+// the Vault agent. The file is read on every call and trailing new lines are removed.
+// Names must be plain file names, paths are rejected
+func FileSecrets(dir string) SecretProvider {
+	return SecretProviderFunc(func(_ context.Context, name string) (string, error) {
+		if name == "" || name == "." || name == ".." || filepath.Base(name) != name {
+			return "", fmt.Errorf("invalid secret name %q", name)
+		}
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("%w: %s", ErrSecretNotFound, filepath.Join(dir, name))
+		}
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(b), "\r\n"), nil
+	})
+}
+
+// getSecret wraps errors from p with the secret name, the value is never included in errors
+func getSecret(ctx context.Context, p SecretProvider, name string) (string, error) {
+	v, err := p.Get(ctx, name)
+	if err != nil {
+		return "", fmt.Errorf("could not get secret %q: %w", name, err)
+	}
+	return v, nil
+}
+
+// SetBearerToken sets the Authorization header to "Bearer " followed by the named secret from p
+func SetBearerToken(c Client, p SecretProvider, name string) ClientFunc {
+	c = nilClientCheck(c)
+	return func(req *http.Request) (*http.Response, error) {
+		token, err := getSecret(req.Context(), p, name)
+		if err != nil {
+			return nil, err
+		}
+		if req.Header == nil {
+			req.Header = make(http.Header)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		return c.Do(req)
+	}
+}
+
+// SetBasicAuth sets the Authorization header for HTTP basic authentication using the named user and password
+// secrets from p
+func SetBasicAuth(c Client, p SecretProvider, userName, passwordName string) ClientFunc {
+	c = nilClientCheck(c)
+	return func(req *http.Request) (*http.Response, error) {
+		user, err := getSecret(req.Context(), p, userName)
+		if err != nil {
+			return nil, err
+		}
+		pass, err := getSecret(req.Context(), p, passwordName)
+		if err != nil {
+			return nil, err
+		}
+		if req.Header == nil {
+			req.Header = make(http.Header)
+		}
+		req.SetBasicAuth(user, pass)
+		return c.Do(req)
+	}
+}
@@ -0,0 +1,66 @@
+package httpx_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tflyons/httpx"
+)
+
+func TestSetBearerToken_FileRotation(t *testing.T) {
+	srv := httptest.NewServer(echoHandler)
+	defer srv.Close()
+	dir := t.TempDir()
+	write := func(token string) {
+		if err := os.WriteFile(filepath.Join(dir, "token"), []byte(token+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var c httpx.Client = srv.Client()
+	c = httpx.SetBearerToken(c, httpx.FileSecrets(dir), "token")
+	c = httpx.SetRequest(c, http.MethodGet, srv.URL)
+	for _, token := range []string{"first", "second"} {
+		write(token)
+		resp, err := c.Do(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := resp.Header.Get("Authorization"); got != "Bearer "+token {
+			t.Fatal(got)
+		}
+	}
+}
+
+func TestSetBasicAuth_Env(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "alice" || pass != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer srv.Close()
+	t.Setenv("APP_USER", "alice")
+	t.Setenv("APP_PASS", "s3cret")
+
+	var c httpx.Client = srv.Client()
+	c = httpx.RequireResponseStatus(c, http.StatusOK)
+	c = httpx.SetBasicAuth(c, httpx.EnvSecrets("APP_"), "USER", "PASS")
+	if _, err := httpx.SetRequest(c, http.MethodGet, srv.URL).Do(nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSecrets_NotFound(t *testing.T) {
+	c := httpx.SetBearerToken(nil, httpx.EnvSecrets("APP_"), "MISSING_TOKEN")
+	if _, err := httpx.SetRequest(c, http.MethodGet, "http://example.invalid").Do(nil); !errors.Is(err, httpx.ErrSecretNotFound) {
+		t.Fatal(err)
+	}
+	if _, err := httpx.FileSecrets(t.TempDir()).Get(context.Background(), "../token"); err == nil || errors.Is(err, httpx.ErrSecretNotFound) {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,164 @@
+package serverx
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tflyons/httpx"
+)
+
+// AccessLogFormat selects the line format written by AccessLog
+type AccessLogFormat int
+
+const (
+	// AccessLogCommon is the Apache common log format:
+	//	127.0.0.1 - alice [10/Oct/2000:13:55:36 -0700] "GET /index.html HTTP/1.1" 200 2326
+	AccessLogCommon AccessLogFormat = iota
+	// AccessLogCombined adds the quoted Referer and User-Agent headers to AccessLogCommon
+	AccessLogCombined
+	// AccessLogJSON writes one JSON object per request including the duration, request ID and request headers
+	AccessLogJSON
+)
+
+// AccessLogOptions configures AccessLog
+type AccessLogOptions struct {
+	Format AccessLogFormat
+	// SampleRate is the fraction of requests logged, such as 0.1 for one in ten. Requests answered with a 5xx
+	// status are always logged. Every request is logged if it is zero
+	SampleRate float64
+	// RedactHeaders are request headers whose values are replaced by REDACTED in the JSON format,
+	// in addition to Authorization, Cookie and Proxy-Authorization
+	RedactHeaders []string
+	// Scrubber masks secrets in the logged request URI and Referer, httpx.NewDefaultScrubber() if nil
+	Scrubber *httpx.Scrubber
+}
+
+// accessLogEntry is a line of the JSON format
+type accessLogEntry struct {
+	Time       time.Time           `json:"time"`
+	RemoteAddr string              `json:"remote_addr"`
+	User       string              `json:"user,omitempty"`
+	Method     string              `json:"method"`
+	URI        string              `json:"uri"`
+	Proto      string              `json:"proto"`
+	Status     int                 `json:"status"`
+	Bytes      int64               `json:"bytes"`
+	DurationMS float64             `json:"duration_ms"`
+	RequestID  string              `json:"request_id,omitempty"`
+	Headers    map[string][]string `json:"headers,omitempty"`
+}
+
+// AccessLog writes a line to w for every request once it has been served, the server side counterpart of
+// httpx.SetLogger. The user is the basic auth user name, if any. Query parameters and path segments that look like
+// secrets are masked by the Scrubber of opts in every format. Writes to w are serialized
+func AccessLog(w io.Writer, opts AccessLogOptions) Middleware {
+	if opts.Scrubber == nil {
+		opts.Scrubber = httpx.NewDefaultScrubber()
+	}
+	redact := map[string]bool{"Authorization": true, "Cookie": true, "Proxy-Authorization": true}
+	for _, h := range opts.RedactHeaders {
+		redact[http.CanonicalHeaderKey(h)] = true
+	}
+	var mu sync.Mutex
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &ResponseRecorder{ResponseWriter: rw}
+			next.ServeHTTP(rec, r)
+			if opts.SampleRate > 0 && rec.Status() < 500 && rand.Float64() >= opts.SampleRate {
+				return
+			}
+
+			host, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				host = r.RemoteAddr
+			}
+			user, _, _ := r.BasicAuth()
+			uri, referer := opts.Scrubber.ScrubURL(r.URL), scrubReferer(opts.Scrubber, r.Referer())
+			var line []byte
+			if opts.Format == AccessLogJSON {
+				entry := accessLogEntry{
+					Time:       start,
+					RemoteAddr: host,
+					User:       user,
+					Method:     r.Method,
+					URI:        uri,
+					Proto:      r.Proto,
+					Status:     rec.Status(),
+					Bytes:      rec.Bytes,
+					DurationMS: float64(time.Since(start)) / float64(time.Millisecond),
+					RequestID:  RequestIDFromContext(r.Context()),
+					Headers:    make(map[string][]string, len(r.Header)),
+				}
+				for k, v := range r.Header {
+					switch {
+					case redact[k]:
+						v = []string{"REDACTED"}
+					case k == "Referer":
+						v = []string{referer}
+					}
+					entry.Headers[k] = v
+				}
+				// the entry only holds strings and numbers
+				line, _ = json.Marshal(entry)
+			} else {
+				if user == "" {
+					user = "-"
+				}
+				line = []byte(fmt.Sprintf(`%s - %s [%s] "%s %s %s" %d %s`, host, user, start.Format("02/Jan/2006:15:04:05 -0700"),
+					r.Method, escapeLog(uri), r.Proto, rec.Status(), commonBytes(rec.Bytes)))
+				if opts.Format == AccessLogCombined {
+					line = append(line, fmt.Sprintf(` "%s" "%s"`, escapeLog(referer), escapeLog(r.UserAgent()))...)
+				}
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			_, _ = w.Write(append(line, '\n'))
+		})
+	}
+}
+
+// scrubReferer masks secrets in the URL of a Referer header
+func scrubReferer(s *httpx.Scrubber, referer string) string {
+	u, err := url.Parse(referer)
+	if err != nil {
+		return s.ScrubString(referer)
+	}
+	return s.ScrubURL(u)
+}
+
+// commonBytes formats a response size as in the common log format, "-" for none
+func commonBytes(n int64) string {
+	if n == 0 {
+		return "-"
+	}
+	return fmt.Sprint(n)
+}
+
+// escapeLog escapes quotes, backslashes and control characters so that a value cannot break the log line
+func escapeLog(s string) string {
+	if s == "" {
+		return "-"
+	}
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, "\\x%02x", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
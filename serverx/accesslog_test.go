@@ -0,0 +1,105 @@
+package serverx_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/serverx"
+)
+
+func TestAccessLog(t *testing.T) {
+	created := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("hello"))
+	})
+	newRequest := func() *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/things?a=1", nil)
+		r.SetBasicAuth("alice", "secret")
+		r.Header.Set("Referer", "http://example.com/")
+		r.Header.Set("User-Agent", `agent "quoted"`)
+		r.Header.Set("X-Api-Key", "key")
+		return r
+	}
+
+	var buf bytes.Buffer
+	serve(serverx.AccessLog(&buf, serverx.AccessLogOptions{})(created), newRequest())
+	common := regexp.MustCompile(`^192\.0\.2\.1 - alice \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "POST /things\?a=1 HTTP/1\.1" 201 5\n$`)
+	if !common.MatchString(buf.String()) {
+		t.Fatal(buf.String())
+	}
+
+	buf.Reset()
+	serve(serverx.AccessLog(&buf, serverx.AccessLogOptions{Format: serverx.AccessLogCombined})(created), newRequest())
+	if !strings.HasSuffix(buf.String(), `201 5 "http://example.com/" "agent \"quoted\""`+"\n") {
+		t.Fatal(buf.String())
+	}
+
+	buf.Reset()
+	opts := serverx.AccessLogOptions{Format: serverx.AccessLogJSON, RedactHeaders: []string{"x-api-key"}}
+	serve(serverx.RequestID("")(serverx.AccessLog(&buf, opts)(created)), newRequest())
+	var entry struct {
+		Status    int                 `json:"status"`
+		Bytes     int64               `json:"bytes"`
+		User      string              `json:"user"`
+		RequestID string              `json:"request_id"`
+		Headers   map[string][]string `json:"headers"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal(err, buf.String())
+	}
+	if entry.Status != http.StatusCreated || entry.Bytes != 5 || entry.User != "alice" || entry.RequestID == "" {
+		t.Fatal(entry)
+	}
+	if entry.Headers["Authorization"][0] != "REDACTED" || entry.Headers["X-Api-Key"][0] != "REDACTED" || entry.Headers["Referer"][0] != "http://example.com/" {
+		t.Fatal(entry.Headers)
+	}
+}
+
+func TestAccessLogScrubsURL(t *testing.T) {
+	newRequest := func() *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/reset?token=s3cret&a=1", nil)
+		r.Header.Set("Referer", "http://example.com/login?access_token=s3cret")
+		return r
+	}
+	for _, format := range []serverx.AccessLogFormat{serverx.AccessLogCommon, serverx.AccessLogCombined, serverx.AccessLogJSON} {
+		var buf bytes.Buffer
+		serve(serverx.AccessLog(&buf, serverx.AccessLogOptions{Format: format})(http.NotFoundHandler()), newRequest())
+		if strings.Contains(buf.String(), "s3cret") || !strings.Contains(buf.String(), "a=1") {
+			t.Fatal(format, buf.String())
+		}
+	}
+
+	// a custom scrubber replaces the default
+	var buf bytes.Buffer
+	opts := serverx.AccessLogOptions{Scrubber: &httpx.Scrubber{Fields: []string{"a"}}}
+	serve(serverx.AccessLog(&buf, opts)(http.NotFoundHandler()), newRequest())
+	if !strings.Contains(buf.String(), "token=s3cret") || strings.Contains(buf.String(), "a=1") {
+		t.Fatal(buf.String())
+	}
+}
+
+func TestAccessLogSampling(t *testing.T) {
+	var buf bytes.Buffer
+	status := http.StatusOK
+	h := serverx.AccessLog(&buf, serverx.AccessLogOptions{SampleRate: 0.000001})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	for i := 0; i < 10; i++ {
+		serve(h, httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	if buf.Len() != 0 {
+		t.Fatal("expected successful requests to be sampled", buf.String())
+	}
+	// server errors are always logged
+	status = http.StatusBadGateway
+	serve(h, httptest.NewRequest(http.MethodGet, "/", nil))
+	if !strings.Contains(buf.String(), `" 502 -`) {
+		t.Fatal(buf.String())
+	}
+}
@@ -0,0 +1,217 @@
+package serverx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/tflyons/httpx"
+)
+
+// ErrInvalidToken should be wrapped by TokenValidator implementations for a token that is malformed, expired or
+// otherwise not accepted. Other errors are treated as a failure to validate and reported as 503
+var ErrInvalidToken = fmt.Errorf("invalid token")
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	// Subject identifies the caller, such as the sub claim of a JWT
+	Subject string
+	// ClientID is the OAuth 2.0 client the token was issued to, if known
+	ClientID string
+	Scopes   []string
+	// Expiry is when the token expires, zero if unknown
+	Expiry time.Time
+	// Claims holds every claim of the token as decoded from JSON
+	Claims map[string]any
+}
+
+// HasScope reports whether the principal was granted scope
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// TokenValidator validates a bearer token and returns the principal it was issued to
+type TokenValidator interface {
+	ValidateToken(ctx context.Context, token string) (*Principal, error)
+}
+
+// TokenValidatorFunc is an adapter to allow the use of ordinary functions as a TokenValidator
+type TokenValidatorFunc func(ctx context.Context, token string) (*Principal, error)
+
+// ValidateToken calls f(ctx, token)
+func (f TokenValidatorFunc) ValidateToken(ctx context.Context, token string) (*Principal, error) {
+	return f(ctx, token)
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying p
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the principal stored by Authenticate
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
+
+// PrincipalSubject returns the subject of the principal of r or an empty string. It can be given to Correlate,
+// after Authenticate in the chain, to forward the caller to downstream services
+func PrincipalSubject(r *http.Request) string {
+	if p, ok := PrincipalFromContext(r.Context()); ok {
+		return p.Subject
+	}
+	return ""
+}
+
+// BearerToken returns the token of an "Authorization: Bearer <token>" header
+func BearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	token = strings.TrimSpace(token)
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return token, true
+}
+
+// Authenticate validates the bearer token of every request with v and stores the principal on the request
+// context, see PrincipalFromContext. A request without a valid token is answered with 401 Unauthorized and one
+// whose principal lacks any of scopes with 403 Forbidden, both with a WWW-Authenticate challenge as described in
+// RFC 6750. A validator failing for another reason than ErrInvalidToken results in 503 Service Unavailable
+func Authenticate(v TokenValidator, scopes ...string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := BearerToken(r)
+			if !ok {
+				w.Header().Set("WWW-Authenticate", `Bearer`)
+				Error(w, r, &HTTPError{Status: http.StatusUnauthorized, Message: "missing bearer token"})
+				return
+			}
+			p, err := v.ValidateToken(r.Context(), token)
+			switch {
+			case errors.Is(err, ErrInvalidToken) || (err == nil && p == nil):
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				Error(w, r, &HTTPError{Status: http.StatusUnauthorized, Code: "invalid_token", Message: "invalid bearer token"})
+				return
+			case err != nil:
+				Error(w, r, &HTTPError{Status: http.StatusServiceUnavailable, Message: "could not validate bearer token"})
+				return
+			}
+			r = r.WithContext(WithPrincipal(r.Context(), p))
+			if !requireScopes(w, r, scopes) {
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireScopes responds with 403 Forbidden if the principal stored by Authenticate lacks any of scopes, or with
+// 401 Unauthorized if there is no principal. It is used to require more scopes for some routes than others
+func RequireScopes(scopes ...string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := PrincipalFromContext(r.Context()); !ok {
+				w.Header().Set("WWW-Authenticate", `Bearer`)
+				Error(w, r, &HTTPError{Status: http.StatusUnauthorized, Message: "missing bearer token"})
+				return
+			}
+			if requireScopes(w, r, scopes) {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// requireScopes writes a 403 response and returns false if the principal of r lacks any of scopes
+func requireScopes(w http.ResponseWriter, r *http.Request, scopes []string) bool {
+	p, _ := PrincipalFromContext(r.Context())
+	for _, s := range scopes {
+		if !p.HasScope(s) {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope=%q`, strings.Join(scopes, " ")))
+			Error(w, r, &HTTPError{Status: http.StatusForbidden, Code: "insufficient_scope", Message: "missing scope " + s})
+			return false
+		}
+	}
+	return true
+}
+
+// Introspection validates opaque tokens with an OAuth 2.0 token introspection endpoint as described in RFC 7662
+type Introspection struct {
+	// URL is the introspection endpoint
+	URL string
+	// ClientID and ClientSecret authenticate the resource server to the endpoint with HTTP basic auth
+	ClientID     string
+	ClientSecret string
+	// Client sends the introspection requests, httpx.DefaultClient if nil. It is usually decorated with a
+	// timeout and a cache keyed on the token is best implemented by the caller
+	Client httpx.Client
+}
+
+// ValidateToken implements TokenValidator. A token the endpoint reports as inactive is an ErrInvalidToken
+func (in *Introspection) ValidateToken(ctx context.Context, token string) (*Principal, error) {
+	c := in.Client
+	if c == nil {
+		c = httpx.DefaultClient
+	}
+	var claims map[string]any
+	form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+	ic := httpx.RequireResponseStatus(c, http.StatusOK)
+	ic = httpx.SetResponseBodyHandlerJSON(ic, &claims)
+	ic = httpx.SetRequestBody(ic, nil, []byte(form.Encode()))
+	ic = httpx.SetHeader(ic, "Content-Type", "application/x-www-form-urlencoded")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, in.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not introspect token: %w", err)
+	}
+	if in.ClientID != "" {
+		// RFC 6749 section 2.3.1 requires the credentials to be form encoded before basic authentication
+		req.SetBasicAuth(url.QueryEscape(in.ClientID), url.QueryEscape(in.ClientSecret))
+	}
+	resp, err := ic.Do(req)
+	if err != nil {
+		if resp != nil && resp.Body != nil {
+			resp.Body.Close()
+		}
+		return nil, fmt.Errorf("could not introspect token: %w", err)
+	}
+	if active, _ := claims["active"].(bool); !active {
+		return nil, fmt.Errorf("%w: token is not active", ErrInvalidToken)
+	}
+	p := PrincipalFromClaims(claims)
+	if !p.Expiry.IsZero() && !httpx.ClockFromContext(ctx).Now().Before(p.Expiry) {
+		return nil, fmt.Errorf("%w: expired", ErrInvalidToken)
+	}
+	return p, nil
+}
+
+// PrincipalFromClaims returns the principal described by the standard claims of a JWT access token or an
+// introspection response: sub, client_id, exp and scope as a space separated string or scp as an array
+func PrincipalFromClaims(claims map[string]any) *Principal {
+	p := &Principal{Claims: claims}
+	p.Subject, _ = claims["sub"].(string)
+	p.ClientID, _ = claims["client_id"].(string)
+	if exp, ok := claims["exp"].(float64); ok && exp > 0 {
+		p.Expiry = time.Unix(0, int64(exp*float64(time.Second)))
+	}
+	if scope, ok := claims["scope"].(string); ok {
+		p.Scopes = strings.Fields(scope)
+	} else if scp, ok := claims["scp"].([]any); ok {
+		for _, s := range scp {
+			if s, ok := s.(string); ok {
+				p.Scopes = append(p.Scopes, s)
+			}
+		}
+	}
+	return p
+}
@@ -0,0 +1,112 @@
+package serverx_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/serverx"
+)
+
+func TestAuthenticate(t *testing.T) {
+	v := serverx.TokenValidatorFunc(func(ctx context.Context, token string) (*serverx.Principal, error) {
+		switch token {
+		case "reader":
+			return &serverx.Principal{Subject: "alice", Scopes: []string{"read"}}, nil
+		case "down":
+			return nil, errors.New("introspection endpoint unreachable")
+		}
+		return nil, serverx.ErrInvalidToken
+	})
+	var subject string
+	h := serverx.Chain(serverx.Authenticate(v, "read"), serverx.RequireScopes("write"))
+	readOnly := serverx.Authenticate(v, "read")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		subject = serverx.PrincipalSubject(r)
+	}))
+
+	for _, tc := range []struct {
+		auth      string
+		handler   http.Handler
+		status    int
+		challenge string
+	}{
+		{"", readOnly, http.StatusUnauthorized, "Bearer"},
+		{"Basic dG9tOnBhc3N3b3JkMQ==", readOnly, http.StatusUnauthorized, "Bearer"},
+		{"Bearer nope", readOnly, http.StatusUnauthorized, `Bearer error="invalid_token"`},
+		{"Bearer down", readOnly, http.StatusServiceUnavailable, ""},
+		{"bearer reader", readOnly, http.StatusOK, ""},
+		{"Bearer reader", h(ok), http.StatusForbidden, `Bearer error="insufficient_scope", scope="write"`},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tc.auth != "" {
+			r.Header.Set("Authorization", tc.auth)
+		}
+		w := serve(tc.handler, r)
+		if w.Code != tc.status || w.Header().Get("WWW-Authenticate") != tc.challenge {
+			t.Error(tc.auth, w.Code, w.Header().Get("WWW-Authenticate"))
+		}
+	}
+	if subject != "alice" {
+		t.Fatal(subject)
+	}
+}
+
+func TestIntrospection(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, _ := r.BasicAuth(); user != "api" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.FormValue("token") != "opaque" {
+			_, _ = w.Write([]byte(`{"active": false}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"active": true, "sub": "alice", "client_id": "web", "scope": "read write", "tenant": "acme"})
+	}))
+	defer srv.Close()
+
+	in := &serverx.Introspection{URL: srv.URL, ClientID: "api", ClientSecret: "secret", Client: srv.Client()}
+	p, err := in.ValidateToken(context.Background(), "opaque")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Subject != "alice" || p.ClientID != "web" || !p.HasScope("write") || p.Claims["tenant"] != "acme" {
+		t.Fatal(p)
+	}
+	if _, err = in.ValidateToken(context.Background(), "revoked"); !errors.Is(err, serverx.ErrInvalidToken) {
+		t.Fatal(err)
+	}
+	in.ClientSecret = "wrong"
+	if _, err = in.ValidateToken(context.Background(), "opaque"); err == nil || errors.Is(err, serverx.ErrInvalidToken) || !strings.Contains(err.Error(), "401") {
+		t.Fatal(err)
+	}
+}
+
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestIntrospection_ErrorClosesBody(t *testing.T) {
+	body := &closeRecorder{Reader: strings.NewReader(`{"error":"unavailable"}`)}
+	in := &serverx.Introspection{URL: "http://idp.example.com/introspect", Client: httpx.ClientFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusServiceUnavailable, Header: http.Header{}, Body: body}, nil
+	})}
+	if _, err := in.ValidateToken(context.Background(), "abc"); err == nil {
+		t.Fatal("expected an error for an unavailable endpoint")
+	}
+	if !body.closed {
+		t.Fatal("the body of an error response should be closed")
+	}
+}
@@ -0,0 +1,90 @@
+package serverx
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tflyons/httpx"
+)
+
+// Correlate stores an httpx.Correlation on the request context so that clients decorated with
+// httpx.ForwardCorrelation pass it on to downstream services.
+//
+// The request ID is taken from httpx.HeaderRequestID, generated if missing and echoed in the response.
+// Trace context and httpx.HeaderTenantID headers are copied as is. A positive httpx.HeaderRequestTimeout in
+// milliseconds becomes the deadline of the request context. The addresses in httpx.HeaderForwardedFor, or the
+// standard Forwarded header, followed by the remote address become ForwardedFor. principal returns the
+// authenticated caller and may be nil; the inbound httpx.HeaderForwardedUser header is never trusted.
+// Handlers and outgoing clients read the result with httpx.CorrelationFromContext
+func Correlate(principal func(r *http.Request) string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cor := httpx.Correlation{
+				RequestID:   r.Header.Get(httpx.HeaderRequestID),
+				TraceParent: r.Header.Get(httpx.HeaderTraceParent),
+				TraceState:  r.Header.Get(httpx.HeaderTraceState),
+				Tenant:      r.Header.Get(httpx.HeaderTenantID),
+			}
+			cor.ForwardedFor = forwardedFor(r)
+			if cor.RequestID == "" {
+				cor.RequestID = RequestIDFromContext(r.Context())
+			}
+			if cor.RequestID == "" {
+				cor.RequestID = newRequestID()
+			}
+			w.Header().Set(httpx.HeaderRequestID, cor.RequestID)
+			if principal != nil {
+				cor.Principal = principal(r)
+			}
+
+			ctx := r.Context()
+			if ms, err := strconv.ParseInt(r.Header.Get(httpx.HeaderRequestTimeout), 10, 64); err == nil && ms > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, time.Duration(ms)*time.Millisecond)
+				defer cancel()
+			}
+			cor.Deadline, _ = ctx.Deadline()
+			next.ServeHTTP(w, r.WithContext(httpx.WithCorrelation(ctx, cor)))
+		})
+	}
+}
+
+// forwardedFor returns the client addresses reported by proxies followed by the remote address
+func forwardedFor(r *http.Request) []string {
+	var addrs []string
+	for _, v := range r.Header.Values(httpx.HeaderForwardedFor) {
+		for _, addr := range strings.Split(v, ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				addrs = append(addrs, addr)
+			}
+		}
+	}
+	if len(addrs) == 0 {
+		// RFC 7239, such as: Forwarded: for=192.0.2.60;proto=http, for="[2001:db8::1]:4711"
+		for _, v := range r.Header.Values("Forwarded") {
+			for _, element := range strings.Split(v, ",") {
+				for _, pair := range strings.Split(element, ";") {
+					name, value, _ := strings.Cut(strings.TrimSpace(pair), "=")
+					if !strings.EqualFold(name, "for") {
+						continue
+					}
+					value = strings.Trim(value, `"`)
+					if host, _, err := net.SplitHostPort(value); err == nil {
+						value = host
+					}
+					addrs = append(addrs, strings.Trim(value, "[]"))
+				}
+			}
+		}
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		addrs = append(addrs, host)
+	} else if r.RemoteAddr != "" {
+		addrs = append(addrs, r.RemoteAddr)
+	}
+	return addrs
+}
@@ -0,0 +1,75 @@
+package serverx_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/serverx"
+)
+
+func TestCorrelate(t *testing.T) {
+	var downstream http.Header
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downstream = r.Header
+	}))
+	defer backend.Close()
+
+	var inner httpx.Correlation
+	frontend := httptest.NewServer(serverx.Correlate(func(r *http.Request) string {
+		return "alice"
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inner, _ = httpx.CorrelationFromContext(r.Context())
+		var c httpx.Client = backend.Client()
+		c = httpx.ForwardCorrelation(c)
+		c = httpx.SetRequestWithContext(r.Context(), c, http.MethodGet, backend.URL)
+		if _, err := c.Do(nil); err != nil {
+			t.Error(err)
+		}
+	})))
+	defer frontend.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, frontend.URL, nil)
+	req.Header.Set(httpx.HeaderTraceParent, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set(httpx.HeaderRequestTimeout, "5000")
+	req.Header.Set(httpx.HeaderForwardedUser, "mallory")
+	req.Header.Set(httpx.HeaderTenantID, "acme")
+	req.Header.Set(httpx.HeaderForwardedFor, "203.0.113.7, 198.51.100.2")
+	resp, err := frontend.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	id := resp.Header.Get(httpx.HeaderRequestID)
+	if id == "" || inner.RequestID != id || serverx.RequestIDFromContext(httpx.WithCorrelation(req.Context(), inner)) != id {
+		t.Fatal(id, inner)
+	}
+	if inner.Deadline.IsZero() || inner.Principal != "alice" || inner.Tenant != "acme" {
+		t.Fatal(inner)
+	}
+	if inner.ClientIP() != "203.0.113.7" || len(inner.ForwardedFor) != 3 || inner.ForwardedFor[2] != "127.0.0.1" {
+		t.Fatal(inner.ForwardedFor)
+	}
+	if downstream.Get(httpx.HeaderRequestID) != id ||
+		downstream.Get(httpx.HeaderTraceParent) != req.Header.Get(httpx.HeaderTraceParent) ||
+		downstream.Get(httpx.HeaderForwardedUser) != "alice" ||
+		downstream.Get(httpx.HeaderTenantID) != "acme" ||
+		downstream.Get(httpx.HeaderRequestTimeout) == "" {
+		t.Fatal(downstream)
+	}
+}
+
+func TestCorrelate_Forwarded(t *testing.T) {
+	var cor httpx.Correlation
+	h := serverx.Correlate(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cor, _ = httpx.CorrelationFromContext(r.Context())
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Forwarded", `for=192.0.2.60;proto=http, for="[2001:db8::1]:4711"`)
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if len(cor.ForwardedFor) != 3 || cor.ClientIP() != "192.0.2.60" || cor.ForwardedFor[1] != "2001:db8::1" || cor.ForwardedFor[2] != "192.0.2.1" {
+		t.Fatal(cor.ForwardedFor)
+	}
+}
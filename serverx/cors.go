@@ -0,0 +1,142 @@
+package serverx
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSOptions configures CORS
+type CORSOptions struct {
+	// AllowedOrigins are the origins allowed to make cross-origin requests, such as "https://app.example.com".
+	// An origin may contain a single wildcard, such as "https://*.example.com", and "*" allows any origin
+	AllowedOrigins []string
+	// AllowedMethods are the methods allowed in cross-origin requests, GET, HEAD and POST if empty
+	AllowedMethods []string
+	// AllowedHeaders are the request headers allowed in cross-origin requests in addition to the CORS-safelisted
+	// ones, "*" allows any header
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers scripts are allowed to read in addition to the safelisted ones
+	ExposedHeaders []string
+	// AllowCredentials allows requests with cookies or HTTP authentication. The request origin is then echoed
+	// instead of answering "*"
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache the result of a preflight request, not sent if zero
+	MaxAge time.Duration
+}
+
+// CORS answers cross-origin requests as described in the Fetch standard. Preflight requests, OPTIONS requests with
+// an Access-Control-Request-Method header, are answered with 204 No Content without calling the handler. Requests
+// from an origin that is not allowed are served without CORS headers, so browsers do not expose the response
+func CORS(opts CORSOptions) Middleware {
+	methods := opts.AllowedMethods
+	if len(methods) == 0 {
+		methods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+	}
+	allowedMethods := make(map[string]bool, len(methods))
+	for _, m := range methods {
+		allowedMethods[strings.ToUpper(m)] = true
+	}
+	allowedHeaders := make(map[string]bool, len(opts.AllowedHeaders))
+	for _, h := range opts.AllowedHeaders {
+		allowedHeaders[http.CanonicalHeaderKey(h)] = true
+	}
+	anyOrigin := false
+	for _, o := range opts.AllowedOrigins {
+		anyOrigin = anyOrigin || o == "*"
+	}
+	methodList := strings.Join(methods, ", ")
+	exposed := strings.Join(opts.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(opts.MaxAge / time.Second))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+			h := w.Header()
+			if !anyOrigin || opts.AllowCredentials {
+				h.Add("Vary", "Origin")
+			}
+			if preflight {
+				h.Add("Vary", "Access-Control-Request-Method")
+				h.Add("Vary", "Access-Control-Request-Headers")
+			}
+			if origin == "" || !originAllowed(opts.AllowedOrigins, origin) {
+				if preflight {
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if anyOrigin && !opts.AllowCredentials {
+				h.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				h.Set("Access-Control-Allow-Origin", origin)
+			}
+			if opts.AllowCredentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+			if !preflight {
+				if exposed != "" {
+					h.Set("Access-Control-Expose-Headers", exposed)
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !allowedMethods[strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))] {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			var requested []string
+			for _, v := range r.Header.Values("Access-Control-Request-Headers") {
+				for _, name := range strings.Split(v, ",") {
+					if name = strings.TrimSpace(name); name != "" {
+						requested = append(requested, name)
+					}
+				}
+			}
+			for _, name := range requested {
+				if !allowedHeaders["*"] && !allowedHeaders[http.CanonicalHeaderKey(name)] && !safelistedHeader(name) {
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+			}
+			h.Set("Access-Control-Allow-Methods", methodList)
+			if len(requested) > 0 {
+				h.Set("Access-Control-Allow-Headers", strings.Join(requested, ", "))
+			}
+			if opts.MaxAge > 0 {
+				h.Set("Access-Control-Max-Age", maxAge)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+// originAllowed reports whether origin matches one of the allowed origins
+func originAllowed(allowed []string, origin string) bool {
+	for _, a := range allowed {
+		if a == "*" || strings.EqualFold(a, origin) {
+			return true
+		}
+		prefix, suffix, ok := strings.Cut(strings.ToLower(a), "*")
+		o := strings.ToLower(origin)
+		if ok && len(o) > len(prefix)+len(suffix) && strings.HasPrefix(o, prefix) && strings.HasSuffix(o, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// safelistedHeader reports whether name is a CORS-safelisted request header, which needs no permission
+func safelistedHeader(name string) bool {
+	switch http.CanonicalHeaderKey(name) {
+	case "Accept", "Accept-Language", "Content-Language", "Content-Type":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package serverx_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx/serverx"
+)
+
+func TestCORS(t *testing.T) {
+	h := serverx.CORS(serverx.CORSOptions{
+		AllowedOrigins:   []string{"https://app.example.com", "https://*.preview.example.com"},
+		AllowedMethods:   []string{http.MethodGet, http.MethodPut},
+		AllowedHeaders:   []string{"X-Api-Version"},
+		ExposedHeaders:   []string{"X-Request-Id"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	})(ok)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Origin", "https://pr-12.preview.example.com")
+	w := serve(h, r)
+	if w.Body.String() != "ok" || w.Header().Get("Access-Control-Allow-Origin") != "https://pr-12.preview.example.com" ||
+		w.Header().Get("Access-Control-Allow-Credentials") != "true" || w.Header().Get("Access-Control-Expose-Headers") != "X-Request-Id" {
+		t.Fatal(w.Header())
+	}
+
+	r.Header.Set("Origin", "https://evil.example.com")
+	if w = serve(h, r); w.Body.String() != "ok" || w.Header().Get("Access-Control-Allow-Origin") != "" || w.Header().Get("Vary") != "Origin" {
+		t.Fatal(w.Header())
+	}
+
+	preflight := func(method, headers string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodOptions, "/", nil)
+		r.Header.Set("Origin", "https://app.example.com")
+		r.Header.Set("Access-Control-Request-Method", method)
+		if headers != "" {
+			r.Header.Set("Access-Control-Request-Headers", headers)
+		}
+		return serve(h, r)
+	}
+	w = preflight(http.MethodPut, "x-api-version, content-type")
+	if w.Code != http.StatusNoContent || w.Body.Len() != 0 || w.Header().Get("Access-Control-Allow-Methods") != "GET, PUT" ||
+		w.Header().Get("Access-Control-Allow-Headers") != "x-api-version, content-type" || w.Header().Get("Access-Control-Max-Age") != "600" {
+		t.Fatal(w.Code, w.Header())
+	}
+	if w = preflight(http.MethodDelete, ""); w.Header().Get("Access-Control-Allow-Methods") != "" {
+		t.Fatal(w.Header())
+	}
+	if w = preflight(http.MethodGet, "X-Other"); w.Header().Get("Access-Control-Allow-Headers") != "" {
+		t.Fatal(w.Header())
+	}
+}
+
+func TestCORS_AnyOrigin(t *testing.T) {
+	h := serverx.CORS(serverx.CORSOptions{AllowedOrigins: []string{"*"}})(ok)
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Origin", "https://anywhere.example")
+	if w := serve(h, r); w.Header().Get("Access-Control-Allow-Origin") != "*" || w.Header().Get("Vary") != "" {
+		t.Fatal(w.Header())
+	}
+}
@@ -0,0 +1,70 @@
+package serverx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/tflyons/httpx"
+)
+
+// Error writes err as a JSON error envelope with the request ID of r, see WriteError. Known errors are mapped to
+// a status:
+//
+//	*HTTPError                         its own status
+//	*http.MaxBytesError                413 Request Entity Too Large, see MaxBodyBytes
+//	*httpx.StatusError from upstream   404 for 404, 503 for 429 and 503, otherwise 502 Bad Gateway
+//	httpx.ErrConflict                  409 Conflict
+//	httpx.ErrClientClosed              503 Service Unavailable
+//	context.DeadlineExceeded           504 Gateway Timeout
+//
+// Any other error is reported as 500 Internal Server Error without exposing its message
+func Error(w http.ResponseWriter, r *http.Request, err error) {
+	httpErr := mapError(err)
+	httpErr.RequestID = RequestIDFromContext(r.Context())
+	WriteError(w, httpErr)
+}
+
+// mapError returns a copy of the HTTPError err maps to
+func mapError(err error) *HTTPError {
+	var httpErr *HTTPError
+	var statusErr *httpx.StatusError
+	var maxErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &httpErr):
+		copied := *httpErr
+		return &copied
+	case errors.As(err, &maxErr):
+		return tooLarge(maxErr.Limit)
+	case errors.As(err, &statusErr):
+		switch statusErr.StatusCode {
+		case http.StatusNotFound:
+			return &HTTPError{Status: http.StatusNotFound, Code: "upstream_not_found", Message: "upstream resource not found"}
+		case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+			return &HTTPError{Status: http.StatusServiceUnavailable, Code: "upstream_unavailable", Message: "upstream service unavailable"}
+		}
+		return &HTTPError{Status: http.StatusBadGateway, Code: "upstream_error", Message: "upstream service failed"}
+	case errors.Is(err, httpx.ErrConflict):
+		return &HTTPError{Status: http.StatusConflict, Message: "resource was modified concurrently"}
+	case errors.Is(err, httpx.ErrClientClosed):
+		return &HTTPError{Status: http.StatusServiceUnavailable, Message: "service is shutting down"}
+	case errors.Is(err, context.DeadlineExceeded):
+		return &HTTPError{Status: http.StatusGatewayTimeout, Code: "timeout", Message: "request timed out"}
+	}
+	return &HTTPError{Status: http.StatusInternalServerError, Message: http.StatusText(http.StatusInternalServerError)}
+}
+
+// statusCode returns the status text in snake case, such as not_found
+func statusCode(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return "error"
+	}
+	return strings.ReplaceAll(strings.ToLower(strings.NewReplacer("-", "", "'", "").Replace(text)), " ", "_")
+}
+
+func tooLarge(limit int64) *HTTPError {
+	return &HTTPError{Status: http.StatusRequestEntityTooLarge, Message: fmt.Sprintf("request body must not be larger than %d bytes", limit)}
+}
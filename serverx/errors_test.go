@@ -0,0 +1,47 @@
+package serverx_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/serverx"
+)
+
+func TestError(t *testing.T) {
+	for _, tt := range []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{&serverx.HTTPError{Status: http.StatusBadRequest, Message: "bad"}, http.StatusBadRequest, "bad_request"},
+		{&serverx.HTTPError{Status: http.StatusTeapot, Code: "teapot"}, http.StatusTeapot, "teapot"},
+		{fmt.Errorf("get user: %w", &httpx.StatusError{StatusCode: http.StatusNotFound}), http.StatusNotFound, "upstream_not_found"},
+		{&httpx.StatusError{StatusCode: http.StatusTooManyRequests}, http.StatusServiceUnavailable, "upstream_unavailable"},
+		{&httpx.StatusError{StatusCode: http.StatusUnauthorized}, http.StatusBadGateway, "upstream_error"},
+		{&httpx.ConflictError{URL: "http://example.com"}, http.StatusConflict, "conflict"},
+		{httpx.ErrClientClosed, http.StatusServiceUnavailable, "service_unavailable"},
+		{context.DeadlineExceeded, http.StatusGatewayTimeout, "timeout"},
+		{errors.New("database password is hunter2"), http.StatusInternalServerError, "internal_server_error"},
+	} {
+		h := serverx.RequestID("")(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			serverx.Error(rw, r, tt.err)
+		}))
+		w := serve(h, httptest.NewRequest(http.MethodGet, "/", nil))
+		var envelope struct {
+			Error serverx.HTTPError `json:"error"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &envelope); err != nil {
+			t.Fatal(err)
+		}
+		if w.Code != tt.status || envelope.Error.Status != tt.status || envelope.Error.Code != tt.code ||
+			envelope.Error.RequestID == "" || envelope.Error.RequestID != w.Header().Get(serverx.DefaultRequestIDHeader) {
+			t.Error(tt.err, w.Code, w.Body.String())
+		}
+	}
+}
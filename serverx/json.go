@@ -0,0 +1,111 @@
+package serverx
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// DefaultMaxBodyBytes is the request body limit used by ReadJSON when maxBytes is not positive
+const DefaultMaxBodyBytes = 1 << 20
+
+// HTTPError is an error with the status code it should be reported with.
+// It is written by WriteError as {"error": {"status": 400, "code": "bad_request", "message": "..."}},
+// matching the default keys of httpx.SetResponseEnvelope
+type HTTPError struct {
+	Status int `json:"status"`
+	// Code is a stable machine readable name for the error, the status text in snake case if empty
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// Error implements the error interface
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.Status, http.StatusText(e.Status), e.Message)
+}
+
+// errorEnvelope is the body written by WriteError
+type errorEnvelope struct {
+	Error *HTTPError `json:"error"`
+}
+
+// WriteJSON writes v as a JSON response with the given status.
+// If v cannot be marshalled a 500 error envelope is written instead and the marshal error is returned
+func WriteJSON(w http.ResponseWriter, status int, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		WriteError(w, &HTTPError{Status: http.StatusInternalServerError, Message: http.StatusText(http.StatusInternalServerError)})
+		return fmt.Errorf("could not marshal response body: %w", err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// WriteError writes err as a JSON error envelope. An *HTTPError keeps its status, any other error is
+// reported as 500 Internal Server Error without exposing its message
+func WriteError(w http.ResponseWriter, err error) {
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		httpErr = &HTTPError{Status: http.StatusInternalServerError, Message: http.StatusText(http.StatusInternalServerError)}
+	}
+	if httpErr.Code == "" {
+		copied := *httpErr
+		copied.Code = statusCode(httpErr.Status)
+		httpErr = &copied
+	}
+	// an HTTPError always marshals
+	b, _ := json.Marshal(errorEnvelope{Error: httpErr})
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(httpErr.Status)
+	_, _ = w.Write(append(b, '\n'))
+}
+
+// ReadJSON decodes the JSON request body into ptr.
+//
+// The returned error is an *HTTPError suitable for WriteError:
+// 415 if the Content-Type is not JSON, 413 if the body is larger than maxBytes, or the limit set with MaxBodyBytes,
+// and 400 if it cannot be decoded.
+// If maxBytes is not positive DefaultMaxBodyBytes is used
+func ReadJSON(r *http.Request, ptr any, maxBytes int64) error {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || (mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json")) {
+		return &HTTPError{Status: http.StatusUnsupportedMediaType, Message: "content type must be application/json"}
+	}
+	if r.Body == nil {
+		return &HTTPError{Status: http.StatusBadRequest, Message: "request body is empty"}
+	}
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBodyBytes
+	}
+	b, err := io.ReadAll(io.LimitReader(r.Body, maxBytes+1))
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return tooLarge(maxErr.Limit)
+	}
+	if err != nil {
+		return &HTTPError{Status: http.StatusBadRequest, Message: "could not read request body"}
+	}
+	if int64(len(b)) > maxBytes {
+		return tooLarge(maxBytes)
+	}
+	if len(bytes.TrimSpace(b)) == 0 {
+		return &HTTPError{Status: http.StatusBadRequest, Message: "request body is empty"}
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	if err = dec.Decode(ptr); err != nil {
+		return &HTTPError{Status: http.StatusBadRequest, Message: "invalid JSON body: " + err.Error()}
+	}
+	if dec.More() {
+		return &HTTPError{Status: http.StatusBadRequest, Message: "request body must contain a single JSON value"}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package serverx_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/serverx"
+)
+
+type thing struct {
+	Name string `json:"name"`
+}
+
+func handleThing(w http.ResponseWriter, r *http.Request) {
+	var in thing
+	if err := serverx.ReadJSON(r, &in, 32); err != nil {
+		serverx.WriteError(w, err)
+		return
+	}
+	_ = serverx.WriteJSON(w, http.StatusCreated, map[string]any{"data": in})
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(handleThing))
+	defer srv.Close()
+
+	var out thing
+	var c httpx.Client = srv.Client()
+	c = httpx.RequireResponseStatus(c, http.StatusCreated)
+	c = httpx.SetResponseEnvelope(c, &out, nil)
+	c = httpx.SetRequestBodyJSON(c, thing{Name: "widget"})
+	c = httpx.SetRequest(c, http.MethodPost, srv.URL)
+	if _, err := c.Do(nil); err != nil {
+		t.Fatal(err)
+	}
+	if out.Name != "widget" {
+		t.Fatal(out)
+	}
+}
+
+func TestReadJSONErrors(t *testing.T) {
+	tests := []struct {
+		contentType, body string
+		want              int
+	}{
+		{"text/plain", `{"name":"a"}`, http.StatusUnsupportedMediaType},
+		{"application/json", `{"name":"` + strings.Repeat("a", 40) + `"}`, http.StatusRequestEntityTooLarge},
+		{"application/json", `{"name":`, http.StatusBadRequest},
+		{"application/json", `{} {}`, http.StatusBadRequest},
+		{"application/json", ``, http.StatusBadRequest},
+		{"application/merge-patch+json; charset=utf-8", `{"name":"a"}`, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+		r.Header.Set("Content-Type", tt.contentType)
+		w := httptest.NewRecorder()
+		handleThing(w, r)
+		if w.Code != tt.want {
+			t.Fatal(tt.contentType, tt.body, w.Code, w.Body.String())
+		}
+		if tt.want != http.StatusCreated && !strings.HasPrefix(w.Body.String(), `{"error":{"status":`) {
+			t.Fatal(w.Body.String())
+		}
+	}
+}
+
+func TestWriteErrorHidesInternalErrors(t *testing.T) {
+	w := httptest.NewRecorder()
+	serverx.WriteError(w, errors.New("database password is hunter2"))
+	if w.Code != http.StatusInternalServerError || strings.Contains(w.Body.String(), "hunter2") {
+		t.Fatal(w.Code, w.Body.String())
+	}
+}
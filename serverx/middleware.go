@@ -0,0 +1,217 @@
+// Package serverx provides http server middleware in the same composable style as the httpx client decorators.
+//
+// Every middleware has the form func(http.Handler) http.Handler and can be combined with Chain:
+//
+//	h := serverx.Chain(
+//		serverx.Recover(nil),
+//		serverx.RequestID(""),
+//		serverx.LogRequests(nil),
+//		serverx.RequireHeader("X-Api-Version"),
+//	)(handler)
+package serverx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/tflyons/httpx"
+)
+
+// Middleware wraps a handler with additional behavior
+type Middleware = func(http.Handler) http.Handler
+
+// Chain combines middleware so that the first one given is the outermost
+func Chain(mw ...Middleware) Middleware {
+	return func(next http.Handler) http.Handler {
+		for i := len(mw) - 1; i >= 0; i-- {
+			next = mw[i](next)
+		}
+		return next
+	}
+}
+
+// RequireHeader responds with 400 Bad Request if the request does not have a non-empty value for the header key
+func RequireHeader(key string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get(key) == "" {
+				http.Error(w, "missing header "+key, http.StatusBadRequest)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireAuth responds with 401 Unauthorized if validate returns a non-nil error for the request
+func RequireAuth(validate func(r *http.Request) error) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := validate(r); err != nil {
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// LogRequests logs the method, path, status, size and duration of every request. If l is nil log.Default is used
+func LogRequests(l *log.Logger) Middleware {
+	if l == nil {
+		l = log.Default()
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &ResponseRecorder{ResponseWriter: w}
+			next.ServeHTTP(rec, r)
+			l.Printf("%s %s %d %d %s", r.Method, r.URL.RequestURI(), rec.Status(), rec.Bytes, time.Since(start))
+		})
+	}
+}
+
+// Recover responds with a 500 Internal Server Error JSON error envelope, see Error, if the handler panics.
+//
+// onPanic is called with the recovered value, if nil the value and the stack trace are logged with log.Printf.
+// http.ErrAbortHandler is re-panicked so that the server aborts the response as usual.
+func Recover(onPanic func(r *http.Request, v any)) Middleware {
+	if onPanic == nil {
+		onPanic = func(r *http.Request, v any) {
+			log.Printf("panic serving %s %s: %v\n%s", r.Method, r.URL.RequestURI(), v, debug.Stack())
+		}
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				v := recover()
+				if v == nil {
+					return
+				}
+				if v == http.ErrAbortHandler {
+					panic(v)
+				}
+				onPanic(r, v)
+				Error(w, r, fmt.Errorf("panic: %v", v))
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// WithTimeout responds with 503 Service Unavailable if the handler does not finish within d.
+// The request context is cancelled when the time limit is reached
+func WithTimeout(d time.Duration) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.TimeoutHandler(next, d, http.StatusText(http.StatusServiceUnavailable))
+	}
+}
+
+// MaxBodyBytes limits request bodies to n bytes. A request declaring a larger Content-Length is answered with
+// 413 Request Entity Too Large without calling the handler, and reading past the limit fails with an
+// *http.MaxBytesError that ReadJSON and Error report as 413
+func MaxBodyBytes(n int64) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > n {
+				Error(w, r, &http.MaxBytesError{Limit: n})
+				return
+			}
+			if r.Body != nil {
+				r.Body = http.MaxBytesReader(w, r.Body, n)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// DefaultRequestIDHeader is the header read and written by RequestID when no header is given
+const DefaultRequestIDHeader = "X-Request-Id"
+
+type requestIDKey struct{}
+
+// RequestID takes the request ID from the header, generating one if it is missing, stores it on the request
+// context and echoes it in the response header. An empty header uses DefaultRequestIDHeader
+func RequestID(header string) Middleware {
+	if header == "" {
+		header = DefaultRequestIDHeader
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(header)
+			if id == "" {
+				id = newRequestID()
+			}
+			w.Header().Set(header, id)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+		})
+	}
+}
+
+// RequestIDFromContext returns the request ID stored by RequestID or Correlate, or an empty string
+func RequestIDFromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+		return id
+	}
+	cor, _ := httpx.CorrelationFromContext(ctx)
+	return cor.RequestID
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// ResponseRecorder wraps a ResponseWriter to record the status code and number of body bytes written
+type ResponseRecorder struct {
+	http.ResponseWriter
+	StatusCode int
+	Bytes      int64
+}
+
+// Status returns the status code written, http.StatusOK if the handler never called WriteHeader
+func (r *ResponseRecorder) Status() int {
+	if r.StatusCode == 0 {
+		return http.StatusOK
+	}
+	return r.StatusCode
+}
+
+// WriteHeader records the status code
+func (r *ResponseRecorder) WriteHeader(status int) {
+	if r.StatusCode == 0 {
+		r.StatusCode = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the number of bytes written
+func (r *ResponseRecorder) Write(b []byte) (int, error) {
+	if r.StatusCode == 0 {
+		r.StatusCode = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.Bytes += int64(n)
+	return n, err
+}
+
+// Unwrap returns the wrapped ResponseWriter for use with http.ResponseController
+func (r *ResponseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Flush implements http.Flusher if the wrapped ResponseWriter does
+func (r *ResponseRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,160 @@
+package serverx_test
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/serverx"
+)
+
+var ok = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	_, _ = w.Write([]byte("ok"))
+})
+
+func serve(h http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestRequireHeader(t *testing.T) {
+	h := serverx.RequireHeader("X-Custom")(ok)
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if w := serve(h, r); w.Code != http.StatusBadRequest {
+		t.Fatal(w.Code)
+	}
+	r.Header.Set("X-Custom", "value")
+	if w := serve(h, r); w.Code != http.StatusOK {
+		t.Fatal(w.Code)
+	}
+}
+
+func TestRequireAuth(t *testing.T) {
+	h := serverx.RequireAuth(func(r *http.Request) error {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			return errors.New("bad token")
+		}
+		return nil
+	})(ok)
+
+	// the client decorators satisfy the middleware end to end
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	var c httpx.Client = srv.Client()
+	c = httpx.RequireResponseStatus(c, http.StatusOK)
+	c = httpx.SetHeader(c, "Authorization", "Bearer token")
+	c = httpx.SetRequest(c, http.MethodGet, srv.URL)
+	if _, err := c.Do(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if w := serve(h, httptest.NewRequest(http.MethodGet, "/", nil)); w.Code != http.StatusUnauthorized {
+		t.Fatal(w.Code)
+	}
+}
+
+func TestLogRequests(t *testing.T) {
+	var buf bytes.Buffer
+	h := serverx.LogRequests(log.New(&buf, "", 0))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("hello"))
+	}))
+	serve(h, httptest.NewRequest(http.MethodPost, "/things?a=1", nil))
+	if !strings.HasPrefix(buf.String(), "POST /things?a=1 201 5 ") {
+		t.Fatal(buf.String())
+	}
+}
+
+func TestRecover(t *testing.T) {
+	var got any
+	h := serverx.Recover(func(r *http.Request, v any) { got = v })(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+	w := serve(h, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusInternalServerError || !strings.HasPrefix(w.Body.String(), `{"error":{"status":500,"code":"internal_server_error"`) {
+		t.Fatal(w.Code, w.Body.String())
+	}
+	if got != "boom" {
+		t.Fatal(got)
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	h := serverx.WithTimeout(10 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	if w := serve(h, httptest.NewRequest(http.MethodGet, "/", nil)); w.Code != http.StatusServiceUnavailable {
+		t.Fatal(w.Code)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	var got string
+	h := serverx.RequestID("")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = serverx.RequestIDFromContext(r.Context())
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Request-Id", "abc")
+	if w := serve(h, r); got != "abc" || w.Header().Get("X-Request-Id") != "abc" {
+		t.Fatal(got, w.Header())
+	}
+
+	w := serve(h, httptest.NewRequest(http.MethodGet, "/", nil))
+	if got == "" || w.Header().Get("X-Request-Id") != got {
+		t.Fatal(got, w.Header())
+	}
+}
+
+func TestChain(t *testing.T) {
+	var order []string
+	mw := func(name string) serverx.Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	serve(serverx.Chain(mw("a"), mw("b"), mw("c"))(ok), httptest.NewRequest(http.MethodGet, "/", nil))
+	if strings.Join(order, "") != "abc" {
+		t.Fatal(order)
+	}
+}
+
+func TestMaxBodyBytes(t *testing.T) {
+	var called bool
+	h := serverx.MaxBodyBytes(16)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		handleThing(w, r)
+	}))
+	newRequest := func(body string, chunked bool) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		if chunked {
+			r.ContentLength = -1
+		}
+		return r
+	}
+
+	if w := serve(h, newRequest(`{"name":"a"}`, false)); w.Code != http.StatusCreated {
+		t.Fatal(w.Code, w.Body.String())
+	}
+	// a declared length is rejected before the handler runs
+	called = false
+	if w := serve(h, newRequest(`{"name":"much too long"}`, false)); w.Code != http.StatusRequestEntityTooLarge || called {
+		t.Fatal(w.Code, called)
+	}
+	// a body of unknown length fails when read
+	w := serve(h, newRequest(`{"name":"much too long"}`, true))
+	if w.Code != http.StatusRequestEntityTooLarge || !called || !strings.Contains(w.Body.String(), "larger than 16 bytes") {
+		t.Fatal(w.Code, w.Body.String())
+	}
+}
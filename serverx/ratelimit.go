@@ -0,0 +1,112 @@
+package serverx
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tflyons/httpx"
+)
+
+// RateLimitKey returns the key a request is counted under by RateLimit
+type RateLimitKey func(r *http.Request) string
+
+// KeyByIP counts requests per remote address of the connection. X-Forwarded-For is ignored as any caller can set
+// it, use KeyByForwardedIP behind proxies
+func KeyByIP(r *http.Request) string {
+	return "ip:" + remoteHost(r)
+}
+
+// KeyByForwardedIP counts requests per client address behind the trusted proxies. X-Forwarded-For is read from
+// the right, the address added by the closest trusted proxy, and every address within trusted is skipped, so a
+// client cannot pick its own key by sending the header. The remote address is used when it is not trusted
+func KeyByForwardedIP(trusted ...netip.Prefix) RateLimitKey {
+	isTrusted := func(host string) bool {
+		ip, err := netip.ParseAddr(host)
+		if err != nil {
+			return false
+		}
+		for _, p := range trusted {
+			if p.Contains(ip.Unmap()) {
+				return true
+			}
+		}
+		return false
+	}
+	return func(r *http.Request) string {
+		host := remoteHost(r)
+		var forwarded []string
+		for _, v := range r.Header.Values(httpx.HeaderForwardedFor) {
+			for _, addr := range strings.Split(v, ",") {
+				if addr = strings.TrimSpace(addr); addr != "" {
+					forwarded = append(forwarded, addr)
+				}
+			}
+		}
+		for i := len(forwarded) - 1; i >= 0 && isTrusted(host); i-- {
+			host = forwarded[i]
+		}
+		return "ip:" + host
+	}
+}
+
+// remoteHost returns the address of the connection without the port
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// KeyByToken counts requests per caller: the subject of the principal when Authenticate runs before RateLimit,
+// otherwise the bearer token. Requests without either are counted by KeyByIP
+func KeyByToken(r *http.Request) string {
+	if sub := PrincipalSubject(r); sub != "" {
+		return "sub:" + sub
+	}
+	if token, ok := BearerToken(r); ok {
+		return "token:" + token
+	}
+	return KeyByIP(r)
+}
+
+// KeyByHeader counts requests per value of the header, such as an API key. Requests without the header share
+// one limit
+func KeyByHeader(name string) RateLimitKey {
+	name = http.CanonicalHeaderKey(name)
+	return func(r *http.Request) string {
+		return "header:" + name + ":" + r.Header.Get(name)
+	}
+}
+
+// RateLimit enforces a limit per key, responding with 429 Too Many Requests and a Retry-After header once the
+// limiter for the key of a request has no slot available. The limiters are the same as on the client side, for
+// example a limit of 100 requests a minute per address shared by every replica through a store:
+//
+//	serverx.RateLimit(serverx.KeyByIP, func(key string) httpx.Limiter {
+//		return httpx.NewWindowLimiter(store, "api:"+key, 100, time.Minute)
+//	})
+//
+// newLimiter is called for every request and should be cheap, keeping its state in a shared store. A limiter that
+// fails is logged with log.Printf and the request is served, so an unavailable store does not take the service down
+func RateLimit(key RateLimitKey, newLimiter func(key string) httpx.Limiter) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			wait, err := newLimiter(key(r)).Reserve(r.Context())
+			if err != nil {
+				log.Printf("rate limit %s %s: %v", r.Method, r.URL.RequestURI(), err)
+			}
+			if err == nil && wait > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(int((wait+time.Second-1)/time.Second)))
+				Error(w, r, &HTTPError{Status: http.StatusTooManyRequests, Message: "rate limit exceeded"})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package serverx_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/httpxtest"
+	"github.com/tflyons/httpx/serverx"
+)
+
+func TestRateLimit(t *testing.T) {
+	clock := httpxtest.NewClock(time.Now())
+	httpx.SetDefaultClock(clock)
+	defer httpx.SetDefaultClock(nil)
+	store := httpx.NewMemoryWindowStore()
+	h := serverx.RateLimit(serverx.KeyByHeader("X-Api-Key"), func(key string) httpx.Limiter {
+		return httpx.NewWindowLimiter(store, key, 2, time.Minute)
+	})(ok)
+	send := func(apiKey string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("X-Api-Key", apiKey)
+		return serve(h, r)
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := send("a"); w.Code != http.StatusOK {
+			t.Fatal(i, w.Code)
+		}
+	}
+	clock.Advance(15 * time.Second)
+	if w := send("a"); w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "45" {
+		t.Fatal(w.Code, w.Header())
+	}
+	if w := send("b"); w.Code != http.StatusOK {
+		t.Fatal(w.Code)
+	}
+	clock.Advance(45 * time.Second)
+	if w := send("a"); w.Code != http.StatusOK {
+		t.Fatal(w.Code)
+	}
+}
+
+func TestKeyByToken(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if got := serverx.KeyByToken(r); got != "ip:192.0.2.1" {
+		t.Fatal(got)
+	}
+	r.Header.Set("Authorization", "Bearer abc")
+	if got := serverx.KeyByToken(r); got != "token:abc" {
+		t.Fatal(got)
+	}
+	r = r.WithContext(serverx.WithPrincipal(r.Context(), &serverx.Principal{Subject: "alice"}))
+	if got := serverx.KeyByToken(r); got != "sub:alice" {
+		t.Fatal(got)
+	}
+}
+
+func TestKeyByIP(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "10.0.0.2:1234"
+	r.Header.Set("X-Forwarded-For", "198.51.100.9, 203.0.113.7")
+	if got := serverx.KeyByIP(r); got != "ip:10.0.0.2" {
+		t.Fatal("the header should be ignored without trusted proxies", got)
+	}
+	key := serverx.KeyByForwardedIP(netip.MustParsePrefix("10.0.0.0/8"))
+	if got := key(r); got != "ip:203.0.113.7" {
+		t.Fatal("the address added by the trusted proxy should be used", got)
+	}
+	r.Header.Set("X-Forwarded-For", "198.51.100.9, 203.0.113.7, 10.0.0.9")
+	if got := key(r); got != "ip:203.0.113.7" {
+		t.Fatal("every trusted proxy should be skipped", got)
+	}
+	r.RemoteAddr = "192.0.2.1:1234"
+	if got := key(r); got != "ip:192.0.2.1" {
+		t.Fatal("the header should be ignored from an untrusted address", got)
+	}
+}
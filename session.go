@@ -0,0 +1,103 @@
+package httpx
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"sync"
+)
+
+// SetLoginSession keeps a cookie based login session for the requests of c.
+//
+// login is called before the first request with a Client that records the cookies set by its responses,
+// and should perform the login request, for example posting a form to the login page. The recorded cookies are
+// added to every request and updated from every response. When isExpired reports that a response means the session
+// has ended, by default a 401 Unauthorized, login is called again and the request is sent once more if its body can
+// be replayed. Concurrent requests that find the session expired wait for a single login.
+func SetLoginSession(c Client, login func(Client) (*http.Response, error), isExpired func(*http.Response) bool) ClientFunc {
+	c = nilClientCheck(c)
+	if isExpired == nil {
+		isExpired = func(resp *http.Response) bool {
+			return resp.StatusCode == http.StatusUnauthorized
+		}
+	}
+	// cookiejar.New only fails on a nil options value with a bad public suffix list, which is not possible here
+	jar, _ := cookiejar.New(nil)
+	recording := ClientFunc(func(req *http.Request) (*http.Response, error) {
+		resp, err := c.Do(req)
+		if resp != nil {
+			if cookies := resp.Cookies(); len(cookies) > 0 {
+				jar.SetCookies(req.URL, cookies)
+			}
+		}
+		return resp, err
+	})
+
+	var mu sync.Mutex
+	var session int
+	// relogin logs in unless another request already started a newer session than seen
+	relogin := func(seen int) (int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if session != seen {
+			return session, nil
+		}
+		resp, err := login(recording)
+		if resp != nil && resp.Body != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+			resp.Body.Close()
+		}
+		if err != nil {
+			return session, fmt.Errorf("login failed: %w", err)
+		}
+		session++
+		return session, nil
+	}
+	send := func(req *http.Request) (*http.Response, error) {
+		if req.Header == nil {
+			req.Header = make(http.Header)
+		}
+		for _, cookie := range jar.Cookies(req.URL) {
+			if _, err := req.Cookie(cookie.Name); err != nil {
+				req.AddCookie(cookie)
+			}
+		}
+		return recording.Do(req)
+	}
+
+	return func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		seen := session
+		mu.Unlock()
+		var err error
+		if seen == 0 {
+			if seen, err = relogin(0); err != nil {
+				return nil, err
+			}
+		}
+		// keep the caller's cookies so that a retry does not send the cookies of the expired session
+		cookies := req.Header.Values("Cookie")
+		resp, err := send(req)
+		if err != nil || !isExpired(resp) ||
+			(req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+			return resp, err
+		}
+
+		if _, err = relogin(seen); err != nil {
+			return resp, err
+		}
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return resp, fmt.Errorf("could not reset request body after login: %w", err)
+			}
+		}
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+		resp.Body.Close()
+		req.Header["Cookie"] = cookies
+		if len(cookies) == 0 {
+			req.Header.Del("Cookie")
+		}
+		return send(req)
+	}
+}
@@ -0,0 +1,97 @@
+package httpx_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/tflyons/httpx"
+)
+
+func TestSetLoginSession(t *testing.T) {
+	var logins int32
+	var valid sync.Map
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			if r.PostFormValue("user") != "tom" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			id := strconv.Itoa(int(atomic.AddInt32(&logins, 1)))
+			valid.Store(id, true)
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: id, Path: "/"})
+			return
+		}
+		cookie, err := r.Cookie("session")
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if _, ok := valid.Load(cookie.Value); !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer srv.Close()
+
+	var c httpx.Client = srv.Client()
+	c = httpx.SetLoginSession(c, func(c httpx.Client) (*http.Response, error) {
+		c = httpx.SetHeader(c, "Content-Type", "application/x-www-form-urlencoded")
+		c = httpx.SetRequestBody(c, nil, []byte("user=tom"))
+		c = httpx.RequireResponseStatus(c, http.StatusOK)
+		return httpx.SetRequest(c, http.MethodPost, srv.URL+"/login").Do(nil)
+	}, nil)
+	c = httpx.RequireResponseStatus(c, http.StatusOK)
+	c = httpx.SetRequest(c, http.MethodGet, srv.URL+"/data")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Do(nil); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if logins != 1 {
+		t.Fatal("expected a single lazy login", logins)
+	}
+
+	// the server ends the session and the client logs in again once
+	valid.Delete("1")
+	for i := 0; i < 3; i++ {
+		if _, err := c.Do(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if logins != 2 {
+		t.Fatal(logins)
+	}
+}
+
+func TestSetLoginSession_NilHeader(t *testing.T) {
+	var got string
+	c := httpx.SetLoginSession(httpx.ClientFunc(func(req *http.Request) (*http.Response, error) {
+		resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody}
+		if req.URL.Path == "/login" {
+			resp.Header.Set("Set-Cookie", "session=1; Path=/")
+		} else if cookie, err := req.Cookie("session"); err == nil {
+			got = cookie.Value
+		}
+		return resp, nil
+	}), func(c httpx.Client) (*http.Response, error) {
+		return httpx.SetRequest(c, http.MethodPost, "http://example.com/login").Do(nil)
+	}, nil)
+	u, _ := url.Parse("http://example.com/data")
+	if _, err := c.Do(&http.Request{Method: http.MethodGet, URL: u}); err != nil {
+		t.Fatal(err)
+	}
+	if got != "1" {
+		t.Fatal("the session cookie should be added to a request without a header", got)
+	}
+}
@@ -0,0 +1,530 @@
+package httpx
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrSignature is wrapped by every error returned when an HTTP message signature is missing or invalid
+var ErrSignature = fmt.Errorf("invalid message signature")
+
+// SignatureKey creates and checks HTTP message signatures (RFC 9421) for a single key
+type SignatureKey interface {
+	// Algorithm is the registered algorithm name such as "ed25519"
+	Algorithm() string
+	// KeyID identifies the key to the verifier
+	KeyID() string
+	// Sign returns the signature of the signature base
+	Sign(base []byte) ([]byte, error)
+	// Verify returns a non-nil error if sig is not a valid signature of the signature base
+	Verify(base, sig []byte) error
+}
+
+// NewEd25519SignatureKey returns an "ed25519" SignatureKey. priv may be nil if the key is only used for verification
+func NewEd25519SignatureKey(keyID string, priv ed25519.PrivateKey, pub ed25519.PublicKey) SignatureKey {
+	if pub == nil && priv != nil {
+		pub = priv.Public().(ed25519.PublicKey)
+	}
+	return ed25519Key{id: keyID, priv: priv, pub: pub}
+}
+
+type ed25519Key struct {
+	id   string
+	priv ed25519.PrivateKey
+	pub  ed25519.PublicKey
+}
+
+func (k ed25519Key) Algorithm() string { return "ed25519" }
+func (k ed25519Key) KeyID() string     { return k.id }
+
+func (k ed25519Key) Sign(base []byte) ([]byte, error) {
+	if k.priv == nil {
+		return nil, fmt.Errorf("no private key for %q", k.id)
+	}
+	return ed25519.Sign(k.priv, base), nil
+}
+
+func (k ed25519Key) Verify(base, sig []byte) error {
+	if !ed25519.Verify(k.pub, base, sig) {
+		return fmt.Errorf("ed25519 signature mismatch")
+	}
+	return nil
+}
+
+// NewRSAPSSSignatureKey returns an "rsa-pss-sha512" SignatureKey. priv may be nil if the key is only used for
+// verification
+func NewRSAPSSSignatureKey(keyID string, priv *rsa.PrivateKey, pub *rsa.PublicKey) SignatureKey {
+	if pub == nil && priv != nil {
+		pub = &priv.PublicKey
+	}
+	return rsaPSSKey{id: keyID, priv: priv, pub: pub}
+}
+
+type rsaPSSKey struct {
+	id   string
+	priv *rsa.PrivateKey
+	pub  *rsa.PublicKey
+}
+
+var rsaPSSOptions = &rsa.PSSOptions{SaltLength: 64, Hash: crypto.SHA512}
+
+func (k rsaPSSKey) Algorithm() string { return "rsa-pss-sha512" }
+func (k rsaPSSKey) KeyID() string     { return k.id }
+
+func (k rsaPSSKey) Sign(base []byte) ([]byte, error) {
+	if k.priv == nil {
+		return nil, fmt.Errorf("no private key for %q", k.id)
+	}
+	sum := sha512.Sum512(base)
+	return rsa.SignPSS(rand.Reader, k.priv, crypto.SHA512, sum[:], rsaPSSOptions)
+}
+
+func (k rsaPSSKey) Verify(base, sig []byte) error {
+	sum := sha512.Sum512(base)
+	return rsa.VerifyPSS(k.pub, crypto.SHA512, sum[:], sig, rsaPSSOptions)
+}
+
+// NewHMACSignatureKey returns an "hmac-sha256" SignatureKey using a shared secret
+func NewHMACSignatureKey(keyID string, secret []byte) SignatureKey {
+	return hmacKey{id: keyID, secret: secret}
+}
+
+type hmacKey struct {
+	id     string
+	secret []byte
+}
+
+func (k hmacKey) Algorithm() string { return "hmac-sha256" }
+func (k hmacKey) KeyID() string     { return k.id }
+
+func (k hmacKey) Sign(base []byte) ([]byte, error) {
+	m := hmac.New(sha256.New, k.secret)
+	m.Write(base)
+	return m.Sum(nil), nil
+}
+
+func (k hmacKey) Verify(base, sig []byte) error {
+	expected, _ := k.Sign(base)
+	if !hmac.Equal(expected, sig) {
+		return fmt.Errorf("hmac-sha256 signature mismatch")
+	}
+	return nil
+}
+
+// SignatureOptions configures the signature created by SignRequest and SignResponse
+type SignatureOptions struct {
+	// Label names the signature in the Signature-Input and Signature headers, "sig1" if empty
+	Label string
+	// Components are the covered component identifiers, such as "@method" or "content-type".
+	// If empty, requests cover "@method" and "@target-uri" and responses cover "@status".
+	// "content-digest" is also covered by default when the message has that header
+	Components []string
+	// Expires adds an expiry this far after the creation time when non-zero
+	Expires time.Duration
+	// Nonce adds a random nonce when true
+	Nonce bool
+	// Tag is an optional application specific tag
+	Tag string
+}
+
+// SignRequest adds Signature-Input and Signature headers (RFC 9421) to the request.
+//
+// To cover the body, place SetContentDigest inside this decorator and include "content-digest".
+func SignRequest(c Client, key SignatureKey, opts SignatureOptions) ClientFunc {
+	c = nilClientCheck(c)
+	return func(req *http.Request) (*http.Response, error) {
+		if req.Header == nil {
+			req.Header = make(http.Header)
+		}
+		now := ClockFromContext(req.Context()).Now()
+		if err := signMessage(requestMessage(req), req.Header, key, opts, requestSignatureComponents, now); err != nil {
+			return nil, err
+		}
+		return c.Do(req)
+	}
+}
+
+// SignResponse adds Signature-Input and Signature headers (RFC 9421) to a response to r that is about to be written
+// with the given status. It must be called before w.WriteHeader.
+func SignResponse(w http.ResponseWriter, r *http.Request, status int, key SignatureKey, opts SignatureOptions) error {
+	msg := sigMessage{status: status, header: w.Header()}
+	return signMessage(msg, w.Header(), key, opts, responseSignatureComponents, ClockFromContext(r.Context()).Now())
+}
+
+// the components covered by default, and required by default when verifying
+var (
+	requestSignatureComponents  = []string{"@method", "@target-uri"}
+	responseSignatureComponents = []string{"@status"}
+)
+
+// VerifyOptions configures the checks of VerifyRequestSignature and VerifyResponseSignature
+type VerifyOptions struct {
+	// Label selects the signature to check, the first signature if empty
+	Label string
+	// Required are the component identifiers the signature must cover, so that a signature over only some parts of
+	// the message cannot be replayed on an altered one. If empty, requests must cover "@method" and "@target-uri"
+	// and responses "@status", plus "content-digest" when the message has that header. To protect the body, require
+	// "content-digest" and check the digest itself, with VerifyResponseDigest for responses
+	Required []string
+	// MaxAge rejects signatures created longer ago than this, when non-zero. Signatures must then carry a created
+	// parameter
+	MaxAge time.Duration
+	// Skew is the tolerated difference between the clocks of signer and verifier when checking created and expires
+	Skew time.Duration
+}
+
+// VerifyResponseSignature returns an error wrapping ErrSignature if the response does not carry a valid signature
+// made by key, as configured by opts. Times are checked with the clock of the request context
+func VerifyResponseSignature(c Client, key SignatureKey, opts VerifyOptions) ClientFunc {
+	c = nilClientCheck(c)
+	return func(req *http.Request) (*http.Response, error) {
+		resp, err := c.Do(req)
+		if err != nil {
+			return resp, err
+		}
+		msg := sigMessage{status: resp.StatusCode, header: resp.Header}
+		now := ClockFromContext(req.Context()).Now()
+		if err = verifyMessage(msg, key, opts, responseSignatureComponents, now); err != nil {
+			return resp, err
+		}
+		return resp, nil
+	}
+}
+
+// VerifyRequestSignature returns an error wrapping ErrSignature if an incoming server request does not carry
+// a valid signature made by key, as configured by opts. Times are checked with the clock of the request context
+func VerifyRequestSignature(r *http.Request, key SignatureKey, opts VerifyOptions) error {
+	return verifyMessage(requestMessage(r), key, opts, requestSignatureComponents, ClockFromContext(r.Context()).Now())
+}
+
+// sigMessage holds the values that signature components are derived from
+type sigMessage struct {
+	method, scheme, authority, requestTarget, path, query string
+	status                                                int
+	header                                                http.Header
+}
+
+// requestMessage derives the component values of a client or server request
+func requestMessage(req *http.Request) sigMessage {
+	scheme := req.URL.Scheme
+	if scheme == "" {
+		scheme = "http"
+		if req.TLS != nil {
+			scheme = "https"
+		}
+	}
+	authority := req.Host
+	if authority == "" {
+		authority = req.URL.Host
+	}
+	authority = strings.ToLower(authority)
+	if (scheme == "http" && strings.HasSuffix(authority, ":80")) || (scheme == "https" && strings.HasSuffix(authority, ":443")) {
+		authority = authority[:strings.LastIndexByte(authority, ':')]
+	}
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	return sigMessage{
+		method:        req.Method,
+		scheme:        strings.ToLower(scheme),
+		authority:     authority,
+		requestTarget: req.URL.RequestURI(),
+		path:          path,
+		query:         "?" + req.URL.RawQuery,
+		header:        req.Header,
+	}
+}
+
+// component returns the value of a covered component
+func (m sigMessage) component(name string) (string, error) {
+	if m.status != 0 && strings.HasPrefix(name, "@") && name != "@status" {
+		return "", fmt.Errorf("component %s is not available on a response", name)
+	}
+	switch name {
+	case "@method":
+		return m.method, nil
+	case "@target-uri":
+		return m.scheme + "://" + m.authority + m.requestTarget, nil
+	case "@authority":
+		return m.authority, nil
+	case "@scheme":
+		return m.scheme, nil
+	case "@request-target":
+		return m.requestTarget, nil
+	case "@path":
+		return m.path, nil
+	case "@query":
+		return m.query, nil
+	case "@status":
+		if m.status == 0 {
+			return "", fmt.Errorf("component @status is only available on a response")
+		}
+		return strconv.Itoa(m.status), nil
+	}
+	if strings.HasPrefix(name, "@") {
+		return "", fmt.Errorf("unsupported component %s", name)
+	}
+	values := m.header.Values(name)
+	if len(values) == 0 {
+		return "", fmt.Errorf("missing header %s", name)
+	}
+	// Values returns the slice of the header map, trim a copy
+	trimmed := make([]string, len(values))
+	for i, v := range values {
+		trimmed[i] = strings.TrimSpace(v)
+	}
+	return strings.Join(trimmed, ", "), nil
+}
+
+// signatureBase builds the RFC 9421 signature base for the components and serialized signature parameters
+func (m sigMessage) signatureBase(components []string, params string) ([]byte, error) {
+	var b strings.Builder
+	for _, name := range components {
+		v, err := m.component(name)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&b, "%q: %s\n", name, v)
+	}
+	fmt.Fprintf(&b, "\"@signature-params\": %s", params)
+	return []byte(b.String()), nil
+}
+
+// signMessage computes the signature and sets the Signature-Input and Signature headers
+func signMessage(m sigMessage, h http.Header, key SignatureKey, opts SignatureOptions, defaults []string, now time.Time) error {
+	components := make([]string, 0, len(opts.Components)+len(defaults)+1)
+	for _, name := range opts.Components {
+		components = append(components, strings.ToLower(name))
+	}
+	if len(components) == 0 {
+		components = append(components, defaults...)
+		if h.Get("Content-Digest") != "" {
+			components = append(components, "content-digest")
+		}
+	}
+	label := opts.Label
+	if label == "" {
+		label = "sig1"
+	}
+
+	quoted := make([]string, len(components))
+	for i, name := range components {
+		quoted[i] = strconv.Quote(name)
+	}
+	created := now.Unix()
+	params := fmt.Sprintf("(%s);created=%d", strings.Join(quoted, " "), created)
+	if opts.Expires > 0 {
+		params += fmt.Sprintf(";expires=%d", created+int64(opts.Expires/time.Second))
+	}
+	if opts.Nonce {
+		nonce := make([]byte, 16)
+		if _, err := rand.Read(nonce); err != nil {
+			return err
+		}
+		params += fmt.Sprintf(";nonce=%q", hex.EncodeToString(nonce))
+	}
+	params += fmt.Sprintf(";keyid=%q;alg=%q", key.KeyID(), key.Algorithm())
+	if opts.Tag != "" {
+		params += fmt.Sprintf(";tag=%q", opts.Tag)
+	}
+
+	base, err := m.signatureBase(components, params)
+	if err != nil {
+		return fmt.Errorf("could not build signature base: %w", err)
+	}
+	sig, err := key.Sign(base)
+	if err != nil {
+		return fmt.Errorf("could not sign message: %w", err)
+	}
+	h.Add("Signature-Input", label+"="+params)
+	h.Add("Signature", label+"=:"+base64.StdEncoding.EncodeToString(sig)+":")
+	return nil
+}
+
+// verifyMessage checks the signature selected by opts and that it covers the required components
+func verifyMessage(m sigMessage, key SignatureKey, opts VerifyOptions, defaults []string, now time.Time) error {
+	label := opts.Label
+	inputs := parseSFDictionary(strings.Join(m.header.Values("Signature-Input"), ", "))
+	if len(inputs) == 0 {
+		return fmt.Errorf("%w: missing Signature-Input header", ErrSignature)
+	}
+	if label == "" {
+		label = inputs[0].key
+	}
+	params, ok := lookupSFMember(inputs, label)
+	if !ok {
+		return fmt.Errorf("%w: no signature labeled %q", ErrSignature, label)
+	}
+	encoded, ok := lookupSFMember(parseSFDictionary(strings.Join(m.header.Values("Signature"), ", ")), label)
+	if !ok || len(encoded) < 2 || encoded[0] != ':' || encoded[len(encoded)-1] != ':' {
+		return fmt.Errorf("%w: missing signature %q", ErrSignature, label)
+	}
+	sig, err := base64.StdEncoding.DecodeString(encoded[1 : len(encoded)-1])
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrSignature, err)
+	}
+
+	components, sigParams, err := parseSignatureParams(params)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrSignature, err)
+	}
+	if alg, ok := sigParams["alg"]; ok && alg != key.Algorithm() {
+		return fmt.Errorf("%w: algorithm %q does not match key algorithm %q", ErrSignature, alg, key.Algorithm())
+	}
+	if id, ok := sigParams["keyid"]; ok && id != key.KeyID() {
+		return fmt.Errorf("%w: key id %q does not match %q", ErrSignature, id, key.KeyID())
+	}
+	required := opts.Required
+	if len(required) == 0 {
+		required = defaults
+		if m.header.Get("Content-Digest") != "" {
+			required = append(required[:len(required):len(required)], "content-digest")
+		}
+	}
+	for _, name := range required {
+		if !containsFold(components, name) {
+			return fmt.Errorf("%w: signature does not cover %s", ErrSignature, name)
+		}
+	}
+	if err = checkSignatureTimes(sigParams, opts, now); err != nil {
+		return fmt.Errorf("%w: %s", ErrSignature, err)
+	}
+	base, err := m.signatureBase(components, params)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrSignature, err)
+	}
+	if err = key.Verify(base, sig); err != nil {
+		return fmt.Errorf("%w: %s", ErrSignature, err)
+	}
+	return nil
+}
+
+// checkSignatureTimes checks the created and expires parameters against now
+func checkSignatureTimes(params map[string]string, opts VerifyOptions, now time.Time) error {
+	if v, ok := params["created"]; ok {
+		created, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid created parameter %q", v)
+		}
+		t := time.Unix(created, 0)
+		if t.After(now.Add(opts.Skew)) {
+			return fmt.Errorf("signature created in the future")
+		}
+		if opts.MaxAge > 0 && now.Sub(t) > opts.MaxAge+opts.Skew {
+			return fmt.Errorf("signature is older than %s", opts.MaxAge)
+		}
+	} else if opts.MaxAge > 0 {
+		return fmt.Errorf("signature has no created parameter")
+	}
+	if v, ok := params["expires"]; ok {
+		expires, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid expires parameter %q", v)
+		}
+		if !now.Before(time.Unix(expires, 0).Add(opts.Skew)) {
+			return fmt.Errorf("signature expired")
+		}
+	}
+	return nil
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+type sfMember struct {
+	key, value string
+}
+
+// parseSFDictionary splits a structured field dictionary into its members without interpreting the values
+func parseSFDictionary(s string) []sfMember {
+	var members []sfMember
+	for _, item := range splitTopLevel(s, ',') {
+		k, v, _ := strings.Cut(strings.TrimSpace(item), "=")
+		if k != "" {
+			members = append(members, sfMember{key: k, value: v})
+		}
+	}
+	return members
+}
+
+func lookupSFMember(members []sfMember, key string) (string, bool) {
+	for _, m := range members {
+		if m.key == key {
+			return m.value, true
+		}
+	}
+	return "", false
+}
+
+// parseSignatureParams parses `("@method" "host");created=1;keyid="k"` into component names and parameters
+func parseSignatureParams(s string) ([]string, map[string]string, error) {
+	if !strings.HasPrefix(s, "(") {
+		return nil, nil, fmt.Errorf("invalid signature parameters %q", s)
+	}
+	end := strings.IndexByte(s, ')')
+	if end < 0 {
+		return nil, nil, fmt.Errorf("invalid signature parameters %q", s)
+	}
+	var components []string
+	for _, item := range strings.Fields(s[1:end]) {
+		name, err := strconv.Unquote(item)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unsupported component identifier %s", item)
+		}
+		components = append(components, name)
+	}
+	params := make(map[string]string)
+	for _, p := range splitTopLevel(s[end+1:], ';') {
+		k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+		if k == "" {
+			continue
+		}
+		if unquoted, err := strconv.Unquote(v); err == nil {
+			v = unquoted
+		}
+		params[k] = v
+	}
+	return components, params, nil
+}
+
+// splitTopLevel splits s on sep outside of quoted strings and parentheses
+func splitTopLevel(s string, sep byte) []string {
+	var parts []string
+	depth, quoted, start := 0, false, 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quoted:
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case quoted:
+		case s[i] == '(':
+			depth++
+		case s[i] == ')':
+			depth--
+		case s[i] == sep && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
@@ -0,0 +1,199 @@
+package httpx_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/httpxtest"
+)
+
+func TestSignRequest(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := []struct {
+		signer, verifier httpx.SignatureKey
+	}{
+		{httpx.NewEd25519SignatureKey("ed", priv, nil), httpx.NewEd25519SignatureKey("ed", nil, pub)},
+		{httpx.NewRSAPSSSignatureKey("rsa", rsaKey, nil), httpx.NewRSAPSSSignatureKey("rsa", nil, &rsaKey.PublicKey)},
+		{httpx.NewHMACSignatureKey("mac", []byte("secret")), httpx.NewHMACSignatureKey("mac", []byte("secret"))},
+	}
+	for _, k := range keys {
+		var verifyErr error
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			verifyErr = httpx.VerifyRequestSignature(r, k.verifier, httpx.VerifyOptions{
+				Label:    "sig1",
+				Required: []string{"@method", "@path", "@query", "content-digest"},
+				MaxAge:   time.Minute,
+			})
+		}))
+
+		opts := httpx.SignatureOptions{
+			Components: []string{"@method", "@authority", "@path", "@query", "content-type", "content-digest"},
+			Nonce:      true,
+		}
+		var c httpx.Client = srv.Client()
+		c = httpx.SignRequest(c, k.signer, opts)
+		c = httpx.SetContentDigest(c)
+		c = httpx.SetRequestBodyJSON(c, Thing{Foo: "signed"})
+		c = httpx.SetRequest(c, http.MethodPost, srv.URL+"/things?q=1")
+		if _, err = c.Do(nil); err != nil {
+			t.Fatal(err)
+		}
+		if verifyErr != nil {
+			t.Fatal(k.signer.Algorithm(), verifyErr)
+		}
+	}
+}
+
+func TestVerifyRequestSignatureRejects(t *testing.T) {
+	key := httpx.NewHMACSignatureKey("mac", []byte("secret"))
+	past := httpxtest.NewClock(time.Now().Add(-time.Hour))
+	future := httpxtest.NewClock(time.Now().Add(time.Hour))
+	var verifyErr error
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		verifyErr = httpx.VerifyRequestSignature(r, key, httpx.VerifyOptions{})
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name string
+		c    httpx.Client
+	}{
+		{"unsigned", srv.Client()},
+		{"wrong key", httpx.SignRequest(srv.Client(), httpx.NewHMACSignatureKey("mac", []byte("other")), httpx.SignatureOptions{})},
+		{"wrong key id", httpx.SignRequest(srv.Client(), httpx.NewHMACSignatureKey("other", []byte("secret")), httpx.SignatureOptions{})},
+		{"tampered", httpx.ClientFunc(func(req *http.Request) (*http.Response, error) {
+			req.Method = http.MethodPut
+			return srv.Client().Do(req)
+		})},
+		{"uncovered method", httpx.ClientFunc(func(req *http.Request) (*http.Response, error) {
+			req.Method = http.MethodDelete
+			return srv.Client().Do(req)
+		})},
+		{"expired", httpx.SetClock(httpx.SignRequest(srv.Client(), key, httpx.SignatureOptions{Expires: time.Minute}), past)},
+		{"future", httpx.SetClock(httpx.SignRequest(srv.Client(), key, httpx.SignatureOptions{}), future)},
+	}
+	for _, tt := range tests {
+		c := tt.c
+		switch tt.name {
+		case "tampered":
+			c = httpx.SignRequest(c, key, httpx.SignatureOptions{})
+		case "uncovered method":
+			c = httpx.SignRequest(c, key, httpx.SignatureOptions{Components: []string{"@target-uri"}})
+		}
+		c = httpx.SetRequest(c, http.MethodGet, srv.URL)
+		if _, err := c.Do(nil); err != nil {
+			t.Fatal(err)
+		}
+		if !errors.Is(verifyErr, httpx.ErrSignature) {
+			t.Fatal(tt.name, verifyErr)
+		}
+	}
+}
+
+func TestVerifyResponseSignature(t *testing.T) {
+	key := httpx.NewHMACSignatureKey("mac", []byte("secret"))
+	tamper := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		opts := httpx.SignatureOptions{Components: []string{"@status", "content-type"}}
+		if err := httpx.SignResponse(w, r, http.StatusCreated, key, opts); err != nil {
+			t.Error(err)
+		}
+		if tamper {
+			w.Header().Set("Content-Type", "text/html")
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	var c httpx.Client = srv.Client()
+	c = httpx.VerifyResponseSignature(c, key, httpx.VerifyOptions{Label: "sig1", Required: []string{"@status", "content-type"}})
+	c = httpx.SetRequest(c, http.MethodGet, srv.URL)
+	resp, err := c.Do(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(resp.Header.Get("Signature-Input"), `sig1=("@status" "content-type");created=`) {
+		t.Fatal(resp.Header.Get("Signature-Input"))
+	}
+
+	tamper = true
+	if _, err = c.Do(nil); !errors.Is(err, httpx.ErrSignature) {
+		t.Fatal(err)
+	}
+}
+
+func TestSignRequestDefaults(t *testing.T) {
+	var got http.Header
+	c := httpx.ClientFunc(func(req *http.Request) (*http.Response, error) {
+		got = req.Header
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+	c = httpx.SignRequest(c, httpx.NewHMACSignatureKey("mac", []byte("secret")), httpx.SignatureOptions{Label: "a", Expires: 60e9, Tag: "app"})
+	c = httpx.SetContentDigest(c)
+	c = httpx.SetRequestBodyJSON(c, Thing{Foo: "x"})
+	c = httpx.SetRequest(c, http.MethodPost, "https://example.com/")
+	if _, err := c.Do(nil); err != nil {
+		t.Fatal(err)
+	}
+	input := got.Get("Signature-Input")
+	for _, want := range []string{`a=("@method" "@target-uri" "content-digest");created=`, ";expires=", `;keyid="mac";alg="hmac-sha256";tag="app"`} {
+		if !strings.Contains(input, want) {
+			t.Fatal(input)
+		}
+	}
+	if !strings.HasPrefix(got.Get("Signature"), "a=:") {
+		t.Fatal(got.Get("Signature"))
+	}
+}
+
+func TestSignRequestClock(t *testing.T) {
+	clock := httpxtest.NewClock(time.Unix(1700000000, 0))
+	var got http.Header
+	var c httpx.Client = httpx.ClientFunc(func(req *http.Request) (*http.Response, error) {
+		got = req.Header.Clone()
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+	c = httpx.SignRequest(c, httpx.NewHMACSignatureKey("mac", []byte("secret")), httpx.SignatureOptions{Expires: time.Minute})
+	c = httpx.SetClock(c, clock)
+	c = httpx.SetRequest(c, http.MethodGet, "https://example.com/")
+	if _, err := c.Do(nil); err != nil {
+		t.Fatal(err)
+	}
+	if input := got.Get("Signature-Input"); !strings.Contains(input, ";created=1700000000;expires=1700000060;") {
+		t.Fatal(input)
+	}
+}
+
+func TestSignatureComponentHeaderUnchanged(t *testing.T) {
+	key := httpx.NewHMACSignatureKey("mac", []byte("secret"))
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/", nil)
+	req.Header.Set("X-Padded", "  value  ")
+	c := httpx.SignRequest(httpx.ClientFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	}), key, httpx.SignatureOptions{Components: []string{"@method", "x-padded"}})
+	if _, err := c.Do(req); err != nil {
+		t.Fatal(err)
+	}
+	if v := req.Header.Get("X-Padded"); v != "  value  " {
+		t.Fatalf("signing should not change the header values, got %q", v)
+	}
+	if err := httpx.VerifyRequestSignature(req, key, httpx.VerifyOptions{Required: []string{"@method", "x-padded"}}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,58 @@
+package httpx_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/httpxtest"
+)
+
+func TestSignURL(t *testing.T) {
+	key := []byte("link-key")
+	clock := httpxtest.NewClock(time.Now())
+	httpx.SetDefaultClock(clock)
+	defer httpx.SetDefaultClock(nil)
+
+	signed, err := httpx.SignURL("https://files.example.com/reports/q3.pdf?download=1", key, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verify := func(method, u string, opts ...httpx.SignURLOption) error {
+		return httpx.VerifySignedURL(httptest.NewRequest(method, u, nil), key, opts...)
+	}
+	if err = verify(http.MethodGet, signed); err != nil {
+		t.Fatal(err)
+	}
+	if err = verify(http.MethodHead, signed); err != nil {
+		t.Fatal(err)
+	}
+	for name, u := range map[string]string{
+		"path":    "https://files.example.com/reports/q4.pdf?" + signed[len("https://files.example.com/reports/q3.pdf?"):],
+		"query":   signed + "&download=2",
+		"missing": "https://files.example.com/reports/q3.pdf?download=1",
+	} {
+		if err = verify(http.MethodGet, u); !errors.Is(err, httpx.ErrSignedURL) {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	if err = verify(http.MethodPut, signed); !errors.Is(err, httpx.ErrSignedURL) {
+		t.Fatal(err)
+	}
+
+	clock.Advance(time.Hour)
+	if err = verify(http.MethodGet, signed); !errors.Is(err, httpx.ErrSignedURL) {
+		t.Fatal("expected expired link", err)
+	}
+
+	upload, err := httpx.SignURL("/uploads/a.bin", key, time.Minute, httpx.SignedMethod(http.MethodPut))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = verify(http.MethodPut, upload, httpx.SignedMethod(http.MethodPut)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,28 @@
+package httpx
+
+import (
+	"net/http"
+	"time"
+)
+
+// SetSlowRequestWarning calls fn once for every request that has not received a response within threshold.
+//
+// fn is called from a timer while the request is still in flight, with the time elapsed so far, so that
+// degradations are visible before timeouts trip. It must not modify the request
+func SetSlowRequestWarning(c Client, threshold time.Duration, fn func(*http.Request, time.Duration)) ClientFunc {
+	c = nilClientCheck(c)
+	return func(req *http.Request) (*http.Response, error) {
+		clock := ClockFromContext(req.Context())
+		start := clock.Now()
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			select {
+			case <-clock.After(threshold):
+				fn(req, clock.Now().Sub(start))
+			case <-done:
+			}
+		}()
+		return c.Do(req)
+	}
+}
@@ -0,0 +1,47 @@
+package httpx_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx"
+)
+
+func TestSetSlowRequestWarning(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			<-release
+		}
+	}))
+	defer srv.Close()
+
+	warned := make(chan time.Duration, 2)
+	var c httpx.Client = srv.Client()
+	c = httpx.SetSlowRequestWarning(c, 20*time.Millisecond, func(req *http.Request, elapsed time.Duration) {
+		warned <- elapsed
+	})
+
+	if _, err := httpx.SetRequest(c, http.MethodGet, srv.URL).Do(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := httpx.SetRequest(c, http.MethodGet, srv.URL+"/slow").Do(nil)
+		done <- err
+	}()
+	// the warning fires while the request is still in flight
+	if elapsed := <-warned; elapsed < 20*time.Millisecond {
+		t.Fatal(elapsed)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if len(warned) != 0 {
+		t.Fatal("fast request warned")
+	}
+}
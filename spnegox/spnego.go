@@ -0,0 +1,127 @@
+// Package spnegox performs SPNEGO (Negotiate) HTTP authentication as described in RFC 4559 for httpx clients,
+// as required by many intranet services protected by Active Directory.
+//
+// The Kerberos exchange itself is provided by a SecurityContext, for example backed by gokrb5 or Windows SSPI,
+// so that this package does not depend on a particular GSS-API implementation.
+package spnegox
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/tflyons/httpx"
+)
+
+// ErrMutualAuth is returned when the server's final Negotiate token is rejected by the security context
+var ErrMutualAuth = fmt.Errorf("negotiate mutual authentication failed")
+
+// maxLegs limits the number of round trips of a single negotiation
+const maxLegs = 5
+
+// SecurityContext is a client GSS-API security context
+type SecurityContext interface {
+	// Step processes the token received from the server, nil on the first call, and returns the token to send.
+	// done reports that the context is established and no further token is needed
+	Step(input []byte) (output []byte, done bool, err error)
+}
+
+// NewContext starts a SecurityContext for a service principal name such as HTTP/intranet.example.com
+type NewContext func(spn string) (SecurityContext, error)
+
+// ServicePrincipalName returns the HTTP service principal name for the host of u
+func ServicePrincipalName(u *url.URL) string {
+	return "HTTP/" + u.Hostname()
+}
+
+// SetNegotiate authenticates with SPNEGO when the server responds 401 Unauthorized offering Negotiate.
+//
+// The request is sent again with the token from a new security context for the host, repeating while the server
+// continues the exchange. A token on the final response is passed to the context to verify the server.
+// The request is not sent again if its body cannot be replayed, see httpx.SetRequestBody
+func SetNegotiate(c httpx.Client, newContext NewContext) httpx.ClientFunc {
+	if c == nil {
+		c = httpx.DefaultClient
+	}
+	return func(req *http.Request) (*http.Response, error) {
+		resp, err := c.Do(req)
+		if err != nil || resp.StatusCode != http.StatusUnauthorized {
+			return resp, err
+		}
+		input, offered := negotiateToken(resp.Header)
+		if !offered || (req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+			return resp, nil
+		}
+		sc, err := newContext(ServicePrincipalName(req.URL))
+		if err != nil {
+			return resp, fmt.Errorf("could not start negotiate security context: %w", err)
+		}
+		var done bool
+		for leg := 0; leg < maxLegs; leg++ {
+			var output []byte
+			output, done, err = sc.Step(input)
+			if err != nil {
+				return resp, fmt.Errorf("negotiate failed: %w", err)
+			}
+			discard(resp)
+			if req.GetBody != nil {
+				if req.Body, err = req.GetBody(); err != nil {
+					return nil, fmt.Errorf("could not reset request body for negotiate: %w", err)
+				}
+			}
+			if req.Header == nil {
+				req.Header = make(http.Header)
+			}
+			req.Header.Set("Authorization", "Negotiate "+base64.StdEncoding.EncodeToString(output))
+			if resp, err = c.Do(req); err != nil {
+				return resp, err
+			}
+			input, offered = negotiateToken(resp.Header)
+			if resp.StatusCode != http.StatusUnauthorized {
+				if len(input) > 0 && !done {
+					if _, _, err = sc.Step(input); err != nil {
+						discard(resp)
+						return nil, fmt.Errorf("%w: %v", ErrMutualAuth, err)
+					}
+				}
+				return resp, nil
+			}
+			if len(input) == 0 {
+				// the server rejected the token rather than continuing the exchange
+				return resp, nil
+			}
+		}
+		return resp, nil
+	}
+}
+
+// negotiateToken returns the token of a Negotiate WWW-Authenticate challenge and whether Negotiate was offered
+func negotiateToken(h http.Header) ([]byte, bool) {
+	for _, v := range h.Values("WWW-Authenticate") {
+		scheme, token, _ := strings.Cut(strings.TrimSpace(v), " ")
+		if !strings.EqualFold(scheme, "Negotiate") {
+			continue
+		}
+		token = strings.TrimSpace(token)
+		if token == "" {
+			return nil, true
+		}
+		b, err := base64.StdEncoding.DecodeString(token)
+		if err != nil {
+			return nil, true
+		}
+		return b, true
+	}
+	return nil, false
+}
+
+// discard drains and closes the body so that the connection can be reused for the next leg
+func discard(resp *http.Response) {
+	if resp != nil && resp.Body != nil {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+		resp.Body.Close()
+	}
+}
@@ -0,0 +1,83 @@
+package spnegox_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/spnegox"
+)
+
+// testContext sends a fixed ticket and expects a fixed token from the server
+type testContext struct {
+	spn   string
+	steps int
+}
+
+func (c *testContext) Step(input []byte) ([]byte, bool, error) {
+	c.steps++
+	switch {
+	case input == nil:
+		return []byte("ticket for " + c.spn), false, nil
+	case bytes.Equal(input, []byte("server proof")):
+		return nil, true, nil
+	}
+	return nil, false, errors.New("bad server token")
+}
+
+func negotiateServer(proof string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u, _ := url.Parse("http://" + r.Host)
+		if r.Header.Get("Authorization") != "Negotiate "+b64("ticket for "+spnegox.ServicePrincipalName(u)) {
+			w.Header().Set("WWW-Authenticate", "Negotiate")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("WWW-Authenticate", "Negotiate "+b64(proof))
+		_, _ = w.Write([]byte("hello"))
+	}))
+}
+
+func b64(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+func TestSetNegotiate(t *testing.T) {
+	srv := negotiateServer("server proof")
+	defer srv.Close()
+
+	sc := &testContext{}
+	var c httpx.Client = srv.Client()
+	c = spnegox.SetNegotiate(c, func(spn string) (spnegox.SecurityContext, error) {
+		sc.spn = spn
+		return sc, nil
+	})
+	c = httpx.RequireResponseStatus(c, http.StatusOK)
+	c = httpx.SetRequestBody(c, nil, []byte("replayable"))
+	var body string
+	c = httpx.SetResponseBodyString(c, &body)
+	if _, err := httpx.SetRequest(c, http.MethodPost, srv.URL).Do(nil); err != nil {
+		t.Fatal(err)
+	}
+	if body != "hello" || sc.steps != 2 {
+		t.Fatal(body, sc.steps)
+	}
+}
+
+func TestSetNegotiate_MutualAuthFailure(t *testing.T) {
+	srv := negotiateServer("forged")
+	defer srv.Close()
+
+	var c httpx.Client = srv.Client()
+	c = spnegox.SetNegotiate(c, func(spn string) (spnegox.SecurityContext, error) {
+		return &testContext{spn: spn}, nil
+	})
+	if _, err := httpx.SetRequest(c, http.MethodGet, srv.URL).Do(nil); !errors.Is(err, spnegox.ErrMutualAuth) {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,191 @@
+package httpx
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+)
+
+// ErrSSRF is returned for requests blocked by an SSRFPolicy
+var ErrSSRF = fmt.Errorf("request blocked by ssrf policy")
+
+// defaultSSRFDeny are the address ranges that are not reachable from the internet or that serve cloud metadata
+var defaultSSRFDeny = func() []netip.Prefix {
+	var out []netip.Prefix
+	for _, s := range []string{
+		"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16", "172.16.0.0/12",
+		"192.0.0.0/24", "192.0.2.0/24", "192.168.0.0/16", "198.18.0.0/15", "198.51.100.0/24", "203.0.113.0/24",
+		"224.0.0.0/4", "240.0.0.0/4",
+		"::/128", "::1/128", "64:ff9b::/96", "64:ff9b:1::/48", "100::/64", "2001:db8::/32", "fc00::/7", "fe80::/10",
+		"ff00::/8",
+	} {
+		out = append(out, netip.MustParsePrefix(s))
+	}
+	return out
+}()
+
+// defaultSSRFDenyHosts are metadata service names that resolve to internal addresses
+var defaultSSRFDenyHosts = []string{"metadata", "metadata.google.internal"}
+
+// SSRFPolicy decides which destinations a client may reach, see SetSSRFGuard.
+//
+// Private, loopback, link-local (including cloud metadata services), multicast and reserved addresses are denied.
+// Entries of Allow and Deny are CIDR ranges such as "10.1.0.0/16", IP addresses, host names such as
+// "api.internal" or domain suffixes such as ".example.com". Allow is checked first
+type SSRFPolicy struct {
+	Allow []string
+	Deny  []string
+	// Schemes are the URL schemes allowed, http and https if empty
+	Schemes []string
+	// Resolver looks up host names, net.DefaultResolver if nil
+	Resolver *net.Resolver
+}
+
+// matches reports whether the host or ip matches one of the entries
+func ssrfMatch(entries []string, host string, ip netip.Addr) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, e := range entries {
+		e = strings.ToLower(e)
+		switch {
+		case strings.Contains(e, "/"):
+			if p, err := netip.ParsePrefix(e); err == nil && ip.IsValid() && p.Contains(ip) {
+				return true
+			}
+		case strings.HasPrefix(e, "."):
+			if host != "" && (strings.HasSuffix(host, e) || host == e[1:]) {
+				return true
+			}
+		default:
+			if a, err := netip.ParseAddr(e); err == nil {
+				if ip.IsValid() && a.Unmap() == ip {
+					return true
+				}
+			} else if host == e {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkHost returns an error if the host name is denied
+func (p *SSRFPolicy) checkHost(host string) error {
+	if ssrfMatch(p.Allow, host, netip.Addr{}) {
+		return nil
+	}
+	if ssrfMatch(p.Deny, host, netip.Addr{}) || ssrfMatch(defaultSSRFDenyHosts, host, netip.Addr{}) {
+		return fmt.Errorf("%w: host %s is denied", ErrSSRF, host)
+	}
+	return nil
+}
+
+// checkIP returns an error if the address of host is denied
+func (p *SSRFPolicy) checkIP(host string, ip netip.Addr) error {
+	ip = ip.Unmap()
+	if ssrfMatch(p.Allow, host, ip) {
+		return nil
+	}
+	if ssrfMatch(p.Deny, "", ip) {
+		return fmt.Errorf("%w: address %s of %s is denied", ErrSSRF, ip, host)
+	}
+	for _, prefix := range defaultSSRFDeny {
+		if prefix.Contains(ip) {
+			return fmt.Errorf("%w: address %s of %s is not public", ErrSSRF, ip, host)
+		}
+	}
+	return nil
+}
+
+// check returns an error if the url scheme, host or any address the host resolves to is denied
+func (p *SSRFPolicy) check(ctx context.Context, req *http.Request) error {
+	schemes := p.Schemes
+	if len(schemes) == 0 {
+		schemes = []string{"http", "https"}
+	}
+	allowed := false
+	for _, s := range schemes {
+		allowed = allowed || strings.EqualFold(s, req.URL.Scheme)
+	}
+	if !allowed {
+		return fmt.Errorf("%w: scheme %q is not allowed", ErrSSRF, req.URL.Scheme)
+	}
+	host := req.URL.Hostname()
+	if err := p.checkHost(host); err != nil {
+		return err
+	}
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return p.checkIP(host, ip)
+	}
+	if ssrfMatch(p.Allow, host, netip.Addr{}) {
+		return nil
+	}
+	resolver := p.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	ips, err := resolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("%w: could not resolve %s: %v", ErrSSRF, host, err)
+	}
+	for _, ip := range ips {
+		if err := p.checkIP(host, ip); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Control checks the address a connection is about to be made to. Used as the Control function of a net.Dialer it
+// closes the gap between resolving a host and connecting to it, where DNS answers can change, and also checks the
+// hosts of redirects. Only Allow entries that are addresses apply, since the host name is no longer known
+func (p *SSRFPolicy) Control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSSRF, err)
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSSRF, err)
+	}
+	return p.checkIP(host, ip)
+}
+
+// dialContext wraps dial so that connections to hosts allowed by name are made and all others are checked by
+// Control
+func (p *SSRFPolicy) dialContext(d *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	checked := *d
+	checked.Control = p.Control
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.checkHost(host); err != nil {
+			return nil, err
+		}
+		if _, err := netip.ParseAddr(host); err != nil && ssrfMatch(p.Allow, host, netip.Addr{}) {
+			return d.DialContext(ctx, network, addr)
+		}
+		return checked.DialContext(ctx, network, addr)
+	}
+}
+
+// SetSSRFGuard blocks requests to hosts that the policy denies, checking the scheme, the host name and every
+// address it resolves to before the request is sent, so that services fetching user supplied URLs cannot be used
+// to reach internal systems. A blocked request fails with ErrSSRF.
+//
+// Redirects followed by an http.Client and the address finally dialed are not seen by a decorator, so the client
+// should also use a transport built by NewTransport with TransportConfig.SSRFPolicy set to the same policy
+func SetSSRFGuard(c Client, policy SSRFPolicy) ClientFunc {
+	c = nilClientCheck(c)
+	return func(req *http.Request) (*http.Response, error) {
+		if err := policy.check(req.Context(), req); err != nil {
+			return nil, err
+		}
+		return c.Do(req)
+	}
+}
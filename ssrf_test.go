@@ -0,0 +1,76 @@
+package httpx_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tflyons/httpx"
+)
+
+func TestSetSSRFGuard(t *testing.T) {
+	srv := httptest.NewServer(echoHandler)
+	defer srv.Close()
+	port := srv.URL[strings.LastIndex(srv.URL, ":"):]
+
+	// a resolver that fails so the test does not depend on dns
+	resolver := &net.Resolver{PreferGo: true, Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+		return nil, errors.New("no dns in tests")
+	}}
+	blocked := []string{
+		srv.URL,
+		"http://169.254.169.254/latest/meta-data/",
+		"http://[::1]" + port,
+		"http://[::ffff:10.0.0.1]/",
+		"http://metadata.google.internal/",
+		"file:///etc/passwd",
+		"http://unresolvable.test/",
+	}
+	c := httpx.SetSSRFGuard(srv.Client(), httpx.SSRFPolicy{Resolver: resolver})
+	for _, u := range blocked {
+		if _, err := httpx.SetRequest(c, http.MethodGet, u).Do(nil); !errors.Is(err, httpx.ErrSSRF) {
+			t.Fatal(u, err)
+		}
+	}
+	if _, err := httpx.SetRequest(httpx.SetSSRFGuard(srv.Client(), httpx.SSRFPolicy{Deny: []string{"8.8.8.0/24"}}), http.MethodGet, "http://8.8.8.8/").Do(nil); !errors.Is(err, httpx.ErrSSRF) {
+		t.Fatal(err)
+	}
+
+	// allowed ranges can be reached
+	allowed := httpx.SetSSRFGuard(srv.Client(), httpx.SSRFPolicy{Allow: []string{"127.0.0.0/8"}})
+	resp, err := httpx.SetRequest(allowed, http.MethodGet, srv.URL).Do(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+}
+
+func TestSSRFPolicy_Transport(t *testing.T) {
+	target := httptest.NewServer(echoHandler)
+	defer target.Close()
+	// a public looking url that redirects to an internal address
+	redirect := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	defer redirect.Close()
+
+	policy := &httpx.SSRFPolicy{}
+	tr, err := httpx.NewTransport(httpx.TransportConfig{SSRFPolicy: policy})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: tr}
+	if _, err := httpx.SetRequest(client, http.MethodGet, redirect.URL).Do(nil); !errors.Is(err, httpx.ErrSSRF) {
+		t.Fatal(err)
+	}
+
+	// allowing the redirect host by name does not allow the host it redirects to
+	policy.Allow = []string{"localhost"}
+	redirectURL := strings.Replace(redirect.URL, "127.0.0.1", "localhost", 1)
+	_, err = httpx.SetRequest(client, http.MethodGet, redirectURL).Do(nil)
+	if !errors.Is(err, httpx.ErrSSRF) || !strings.Contains(err.Error(), "127.0.0.1") {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,215 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type attemptKey struct{}
+
+// WithAttempt returns a copy of ctx marking the request as the nth attempt of a retried call.
+// Retrying code sets it so that decorators such as SetStats can tell retries from new calls
+func WithAttempt(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, attemptKey{}, n)
+}
+
+// AttemptFromContext returns the attempt number set by WithAttempt, 1 if none was set
+func AttemptFromContext(ctx context.Context) int {
+	if n, ok := ctx.Value(attemptKey{}).(int); ok && n > 0 {
+		return n
+	}
+	return 1
+}
+
+// Error classes counted by Stats
+const (
+	ErrorClassTimeout  = "timeout"
+	ErrorClassCanceled = "canceled"
+	ErrorClassNetwork  = "network"
+	ErrorClass4xx      = "4xx"
+	ErrorClass5xx      = "5xx"
+	ErrorClassOther    = "other"
+)
+
+// latencyBuckets are the upper bounds of the latency histogram, growing by 25% from 100µs to about 2 minutes
+var latencyBuckets = func() []time.Duration {
+	var b []time.Duration
+	for d := 100 * time.Microsecond; d < 2*time.Minute; d = d * 5 / 4 {
+		b = append(b, d)
+	}
+	return b
+}()
+
+// Stats collects client health counters, see SetStats. The zero value is ready to use
+type Stats struct {
+	requests, retries, inFlight int64
+	bytesSent, bytesReceived    int64
+
+	mu      sync.Mutex
+	errors  map[string]int64
+	latency latencyHistogram
+}
+
+// StatsSnapshot is a point in time copy of the counters in Stats
+type StatsSnapshot struct {
+	// Requests counts every attempt including retries
+	Requests int64
+	Retries  int64
+	InFlight int64
+	// Errors is the total of ErrorsByClass
+	Errors        int64
+	ErrorsByClass map[string]int64
+	BytesSent     int64
+	// BytesReceived counts response body bytes read by the caller
+	BytesReceived int64
+	// Latency percentiles are approximate, reported as the upper bound of a histogram bucket
+	MeanLatency time.Duration
+	P50         time.Duration
+	P90         time.Duration
+	P99         time.Duration
+}
+
+// SetStats records every request made through c in s.
+//
+// Latency is measured until the response headers are received. 4xx and 5xx responses count as errors.
+// Retries are recognized by the attempt number set with WithAttempt
+func SetStats(c Client, s *Stats) ClientFunc {
+	c = nilClientCheck(c)
+	return func(req *http.Request) (*http.Response, error) {
+		atomic.AddInt64(&s.requests, 1)
+		if AttemptFromContext(req.Context()) > 1 {
+			atomic.AddInt64(&s.retries, 1)
+		}
+		atomic.AddInt64(&s.inFlight, 1)
+		defer atomic.AddInt64(&s.inFlight, -1)
+
+		if req.Body != nil && req.Body != http.NoBody {
+			req.Body = &countingBody{ReadCloser: req.Body, n: &s.bytesSent}
+		}
+		clock := ClockFromContext(req.Context())
+		start := clock.Now()
+		resp, err := c.Do(req)
+		s.record(clock.Now().Sub(start), ErrorClass(resp, err))
+		if resp != nil && resp.Body != nil {
+			resp.Body = &countingBody{ReadCloser: resp.Body, n: &s.bytesReceived}
+		}
+		return resp, err
+	}
+}
+
+// ErrorClass returns the error class of a request result as counted by Stats, or an empty string if it succeeded
+func ErrorClass(resp *http.Response, err error) string {
+	var netErr net.Error
+	switch {
+	case err == nil && resp.StatusCode >= 500:
+		return ErrorClass5xx
+	case err == nil && resp.StatusCode >= 400:
+		return ErrorClass4xx
+	case err == nil:
+		return ""
+	case errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()):
+		return ErrorClassTimeout
+	case errors.Is(err, context.Canceled):
+		return ErrorClassCanceled
+	case resp == nil:
+		return ErrorClassNetwork
+	default:
+		return ErrorClassOther
+	}
+}
+
+func (s *Stats) record(d time.Duration, class string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if class != "" {
+		if s.errors == nil {
+			s.errors = make(map[string]int64)
+		}
+		s.errors[class]++
+	}
+	s.latency.add(d)
+}
+
+// Snapshot returns the current counters
+func (s *Stats) Snapshot() StatsSnapshot {
+	snap := StatsSnapshot{
+		Requests:      atomic.LoadInt64(&s.requests),
+		Retries:       atomic.LoadInt64(&s.retries),
+		InFlight:      atomic.LoadInt64(&s.inFlight),
+		BytesSent:     atomic.LoadInt64(&s.bytesSent),
+		BytesReceived: atomic.LoadInt64(&s.bytesReceived),
+		ErrorsByClass: make(map[string]int64),
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for class, n := range s.errors {
+		snap.ErrorsByClass[class] = n
+		snap.Errors += n
+	}
+	if s.latency.count == 0 {
+		return snap
+	}
+	snap.MeanLatency = s.latency.mean()
+	snap.P50 = s.latency.percentile(0.50)
+	snap.P90 = s.latency.percentile(0.90)
+	snap.P99 = s.latency.percentile(0.99)
+	return snap
+}
+
+// latencyHistogram counts latencies in latencyBuckets. The zero value is ready to use
+type latencyHistogram struct {
+	buckets []int64
+	count   int64
+	total   time.Duration
+	max     time.Duration
+}
+
+func (h *latencyHistogram) add(d time.Duration) {
+	if h.buckets == nil {
+		// the last bucket counts latencies above every bound
+		h.buckets = make([]int64, len(latencyBuckets)+1)
+	}
+	h.count++
+	h.total += d
+	if d > h.max {
+		h.max = d
+	}
+	i := sort.Search(len(latencyBuckets), func(i int) bool { return d <= latencyBuckets[i] })
+	h.buckets[i]++
+}
+
+func (h *latencyHistogram) mean() time.Duration {
+	return h.total / time.Duration(h.count)
+}
+
+// percentile returns the bucket bound below which the fraction p of the latencies fall
+func (h *latencyHistogram) percentile(p float64) time.Duration {
+	rank := int64(math.Ceil(p * float64(h.count)))
+	var seen int64
+	for i, n := range h.buckets[:len(latencyBuckets)] {
+		if seen += n; seen >= rank {
+			return latencyBuckets[i]
+		}
+	}
+	return latencyBuckets[len(latencyBuckets)-1]
+}
+
+// countingBody adds the number of bytes read to n
+type countingBody struct {
+	io.ReadCloser
+	n *int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	atomic.AddInt64(b.n, int64(n))
+	return n, err
+}
@@ -0,0 +1,64 @@
+package httpx_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx"
+)
+
+func TestSetStats(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/broken":
+			w.WriteHeader(http.StatusBadGateway)
+		case "/slow":
+			time.Sleep(50 * time.Millisecond)
+		}
+		_, _ = w.Write(b)
+	}))
+	defer srv.Close()
+
+	var s httpx.Stats
+	do := func(ctx context.Context, path string) {
+		var got string
+		var c httpx.Client = srv.Client()
+		c = httpx.SetStats(c, &s)
+		c = httpx.SetResponseBodyString(c, &got)
+		c = httpx.SetRequestBody(c, nil, strings.NewReader("hello"))
+		c = httpx.SetRequestWithContext(ctx, c, http.MethodPost, srv.URL+path)
+		_, _ = c.Do(nil)
+	}
+	do(context.Background(), "/")
+	do(httpx.WithAttempt(context.Background(), 2), "/missing")
+	do(context.Background(), "/broken")
+	for i := 0; i < 7; i++ {
+		do(context.Background(), "/slow")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	do(ctx, "/slow")
+
+	snap := s.Snapshot()
+	if snap.Requests != 11 || snap.Retries != 1 || snap.InFlight != 0 || snap.Errors != 3 {
+		t.Fatalf("%+v", snap)
+	}
+	if snap.ErrorsByClass[httpx.ErrorClass4xx] != 1 || snap.ErrorsByClass[httpx.ErrorClass5xx] != 1 ||
+		snap.ErrorsByClass[httpx.ErrorClassTimeout] != 1 {
+		t.Fatal(snap.ErrorsByClass)
+	}
+	if snap.BytesSent < 50 || snap.BytesReceived != 50 {
+		t.Fatalf("%+v", snap)
+	}
+	if snap.P50 < 50*time.Millisecond || snap.P50 > 100*time.Millisecond || snap.P99 < snap.P50 || snap.MeanLatency == 0 {
+		t.Fatalf("%+v", snap)
+	}
+}
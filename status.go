@@ -0,0 +1,40 @@
+package httpx
+
+import (
+	"net/http"
+)
+
+// OnStatus calls fn when the response status code matches code.
+//
+// If fn returns a non-nil error it is returned from the client along with the response.
+// This can be used for side effects such as invalidating a cached token on 401 or to map a status to a custom error.
+func OnStatus(c Client, code int, fn func(*http.Response) error) ClientFunc {
+	return OnStatusRange(c, code, code, fn)
+}
+
+// OnStatusRange calls fn when the response status code is between min and max inclusive
+func OnStatusRange(c Client, min, max int, fn func(*http.Response) error) ClientFunc {
+	c = nilClientCheck(c)
+	return func(req *http.Request) (*http.Response, error) {
+		resp, err := c.Do(req)
+		if err != nil {
+			return resp, err
+		}
+		if resp.StatusCode >= min && resp.StatusCode <= max {
+			if err = fn(resp); err != nil {
+				return resp, err
+			}
+		}
+		return resp, nil
+	}
+}
+
+// On4xx calls fn for any client error response status code
+func On4xx(c Client, fn func(*http.Response) error) ClientFunc {
+	return OnStatusRange(c, 400, 499, fn)
+}
+
+// On5xx calls fn for any server error response status code
+func On5xx(c Client, fn func(*http.Response) error) ClientFunc {
+	return OnStatusRange(c, 500, 599, fn)
+}
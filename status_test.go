@@ -0,0 +1,50 @@
+package httpx_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/tflyons/httpx"
+)
+
+var statusHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	code, err := strconv.Atoi(r.URL.Query().Get("status"))
+	if err != nil {
+		code = http.StatusOK
+	}
+	w.WriteHeader(code)
+})
+
+func TestOnStatus(t *testing.T) {
+	srv := httptest.NewServer(statusHandler)
+	defer srv.Close()
+
+	errUnauthorized := errors.New("unauthorized")
+	var calls4xx, calls5xx int
+	var c httpx.Client = srv.Client()
+	c = httpx.OnStatus(c, http.StatusUnauthorized, func(*http.Response) error { return errUnauthorized })
+	c = httpx.On4xx(c, func(*http.Response) error { calls4xx++; return nil })
+	c = httpx.On5xx(c, func(*http.Response) error { calls5xx++; return nil })
+
+	for _, tt := range []struct {
+		status int
+		err    error
+	}{
+		{http.StatusOK, nil},
+		{http.StatusNotFound, nil},
+		{http.StatusUnauthorized, errUnauthorized},
+		{http.StatusBadGateway, nil},
+	} {
+		_, err := httpx.SetRequest(c, http.MethodGet, srv.URL+"?status="+strconv.Itoa(tt.status)).Do(nil)
+		if !errors.Is(err, tt.err) {
+			t.Fatal(tt.status, err)
+		}
+	}
+	// the 401 error from the inner decorator is returned before On4xx sees the response
+	if calls4xx != 1 || calls5xx != 1 {
+		t.Fatal(calls4xx, calls5xx)
+	}
+}
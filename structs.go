@@ -0,0 +1,328 @@
+package httpx
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// SetResponseHeaderHandler populates the struct pointed to by ptr from the response headers.
+//
+// Fields are matched using the `header:"Name"` struct tag. Supported field types are strings, bools, ints, uints,
+// floats, time.Time (any format accepted by http.ParseTime, such as RFC1123), time.Duration (Go duration
+// strings or a whole number of seconds as used by Retry-After), pointers to those types and slices of them.
+// Missing headers leave the field untouched.
+func SetResponseHeaderHandler(c Client, ptr any) ClientFunc {
+	c = nilClientCheck(c)
+	return func(req *http.Request) (*http.Response, error) {
+		resp, err := c.Do(req)
+		if err != nil {
+			return resp, err
+		}
+		if err = decodeHeader(resp.Header, ptr); err != nil {
+			return resp, fmt.Errorf("could not decode response headers: %w", err)
+		}
+		return resp, nil
+	}
+}
+
+// decodeHeader sets the tagged fields of the struct pointed to by ptr
+func decodeHeader(h http.Header, ptr any) error {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("expected a non-nil pointer to a struct, got %T", ptr)
+	}
+	v = v.Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _ := tagName(f, "header")
+		if name == "" || !f.IsExported() {
+			continue
+		}
+		values := h.Values(name)
+		if len(values) == 0 {
+			continue
+		}
+		if err := setField(v.Field(i), values); err != nil {
+			return fmt.Errorf("header %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// tagName returns the name and options of the struct tag key, ignoring fields tagged with "-"
+func tagName(f reflect.StructField, key string) (string, []string) {
+	tag, ok := f.Tag.Lookup(key)
+	if !ok || tag == "-" {
+		return "", nil
+	}
+	parts := strings.Split(tag, ",")
+	return parts[0], parts[1:]
+}
+
+// setField converts the string values into the field type
+func setField(v reflect.Value, values []string) error {
+	if v.Kind() == reflect.Pointer {
+		elem := reflect.New(v.Type().Elem())
+		if err := setField(elem.Elem(), values); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		s := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i := range values {
+			if err := setField(s.Index(i), values[i:i+1]); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	}
+	return setScalar(v, values[0])
+}
+
+// setScalar parses s into v
+func setScalar(v reflect.Value, s string) error {
+	switch v.Type() {
+	case timeType:
+		t, err := http.ParseTime(s)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	case durationType:
+		d, err := parseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(strings.TrimSpace(s), 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(strings.TrimSpace(s), 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(strings.TrimSpace(s), v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		// byte slices
+		v.SetBytes([]byte(s))
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+	return nil
+}
+
+// parseDuration accepts either a Go duration string or a whole number of seconds
+func parseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if secs, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Duration(secs) * time.Second, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// SetRequestFromStruct builds the request from the tagged fields of the struct v (or pointer to a struct).
+//
+// Fields tagged `query:"name"` are added to the URL query, `header:"Name"` to the request headers and
+// `path:"name"` replace "{name}" placeholders in the URL path. Fields with a `json:"name"` tag and none of
+// the above tags are encoded together as a JSON object request body.
+// Each tag accepts the "omitempty" option to skip zero values.
+//
+// Times are formatted as RFC3339 in the URL and with http.TimeFormat in headers,
+// durations are formatted with (time.Duration).String.
+//
+// Since path placeholders are replaced on the existing URL this must decorate the client before SetRequest.
+func SetRequestFromStruct(c Client, v any) ClientFunc {
+	c = nilClientCheck(c)
+	return func(req *http.Request) (*http.Response, error) {
+		if err := encodeRequest(req, v); err != nil {
+			return nil, fmt.Errorf("could not encode request from %T: %w", v, err)
+		}
+		return c.Do(req)
+	}
+}
+
+// encodeRequest applies the tagged fields of v to req
+func encodeRequest(req *http.Request, v any) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return fmt.Errorf("nil pointer")
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("expected a struct")
+	}
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+	query := req.URL.Query()
+	path, rawPath := req.URL.Path, req.URL.EscapedPath()
+	var body map[string]any
+
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		fv := rv.Field(i)
+		tagged := false
+		if name, opts := tagName(f, "query"); name != "" {
+			tagged = true
+			if !(hasOption(opts, "omitempty") && fv.IsZero()) {
+				values, err := formatField(fv, time.RFC3339)
+				if err != nil {
+					return fmt.Errorf("query %s: %w", name, err)
+				}
+				query[name] = append(query[name], values...)
+			}
+		}
+		if name, opts := tagName(f, "header"); name != "" {
+			tagged = true
+			if !(hasOption(opts, "omitempty") && fv.IsZero()) {
+				values, err := formatField(fv, http.TimeFormat)
+				if err != nil {
+					return fmt.Errorf("header %s: %w", name, err)
+				}
+				for _, value := range values {
+					req.Header.Add(name, value)
+				}
+			}
+		}
+		if name, opts := tagName(f, "path"); name != "" {
+			tagged = true
+			if !(hasOption(opts, "omitempty") && fv.IsZero()) {
+				values, err := formatField(fv, time.RFC3339)
+				if err != nil {
+					return fmt.Errorf("path %s: %w", name, err)
+				}
+				value := strings.Join(values, ",")
+				path = strings.ReplaceAll(path, "{"+name+"}", value)
+				rawPath = strings.ReplaceAll(rawPath, "{"+name+"}", url.PathEscape(value))
+				rawPath = strings.ReplaceAll(rawPath, "%7B"+name+"%7D", url.PathEscape(value))
+			}
+		}
+		if name, opts := tagName(f, "json"); name != "" && !tagged {
+			if hasOption(opts, "omitempty") && fv.IsZero() {
+				continue
+			}
+			if body == nil {
+				body = make(map[string]any)
+			}
+			body[name] = fv.Interface()
+		}
+	}
+
+	req.URL.Path, req.URL.RawPath = path, rawPath
+	req.URL.RawQuery = query.Encode()
+	if body != nil {
+		b, err := jsonMarshal(body)
+		if err != nil {
+			return fmt.Errorf("could not marshal request body: %w", err)
+		}
+		setBodyBytes(req, b)
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return nil
+}
+
+func hasOption(opts []string, option string) bool {
+	for _, o := range opts {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// formatField converts the field value to one or more strings
+func formatField(v reflect.Value, timeLayout string) ([]string, error) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil, nil
+		}
+		return formatField(v.Elem(), timeLayout)
+	}
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		var out []string
+		for i := 0; i < v.Len(); i++ {
+			s, err := formatField(v.Index(i), timeLayout)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, s...)
+		}
+		return out, nil
+	}
+	s, err := formatScalar(v, timeLayout)
+	if err != nil {
+		return nil, err
+	}
+	return []string{s}, nil
+}
+
+// formatScalar is the inverse of setScalar
+func formatScalar(v reflect.Value, timeLayout string) (string, error) {
+	switch v.Type() {
+	case timeType:
+		t := v.Interface().(time.Time)
+		if timeLayout == http.TimeFormat {
+			t = t.UTC()
+		}
+		return t.Format(timeLayout), nil
+	case durationType:
+		return time.Duration(v.Int()).String(), nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), nil
+	case reflect.Slice:
+		return string(v.Bytes()), nil
+	}
+	return "", fmt.Errorf("unsupported field type %s", v.Type())
+}
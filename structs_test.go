@@ -0,0 +1,129 @@
+package httpx_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx"
+)
+
+func TestSetResponseHeaderHandler(t *testing.T) {
+	reset := time.Date(2022, 10, 7, 12, 0, 0, 0, time.UTC)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "42")
+		w.Header().Set("X-RateLimit-Reset", reset.Format(http.TimeFormat))
+		w.Header().Set("Retry-After", "30")
+		w.Header().Add("X-Tag", "a")
+		w.Header().Add("X-Tag", "b")
+	}))
+	defer srv.Close()
+
+	var meta struct {
+		Remaining  int           `header:"X-RateLimit-Remaining"`
+		Reset      time.Time     `header:"X-RateLimit-Reset"`
+		RetryAfter time.Duration `header:"Retry-After"`
+		Tags       []string      `header:"X-Tag"`
+		Missing    *int          `header:"X-Missing"`
+		Ignored    string
+	}
+	var c httpx.Client = srv.Client()
+	c = httpx.SetResponseHeaderHandler(c, &meta)
+	c = httpx.SetRequest(c, http.MethodGet, srv.URL)
+	if _, err := c.Do(nil); err != nil {
+		t.Fatal(err)
+	}
+	if meta.Remaining != 42 || !meta.Reset.Equal(reset) || meta.RetryAfter != 30*time.Second {
+		t.Fatal(meta)
+	}
+	if len(meta.Tags) != 2 || meta.Tags[1] != "b" || meta.Missing != nil {
+		t.Fatal(meta)
+	}
+}
+
+func TestSetResponseHeaderHandler_InvalidValue(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Count", "lots")
+	}))
+	defer srv.Close()
+
+	var meta struct {
+		Count int `header:"X-Count"`
+	}
+	var c httpx.Client = srv.Client()
+	c = httpx.SetResponseHeaderHandler(c, &meta)
+	c = httpx.SetRequest(c, http.MethodGet, srv.URL)
+	if _, err := c.Do(nil); err == nil {
+		t.Fatal("expected an error decoding the header")
+	}
+}
+
+func TestSetRequestFromStruct(t *testing.T) {
+	var got *http.Request
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+	}))
+	defer srv.Close()
+
+	type UpdateUser struct {
+		ID     string   `path:"id"`
+		Fields []string `query:"fields"`
+		Page   int      `query:"page,omitempty"`
+		APIKey string   `header:"X-Api-Key"`
+		Name   string   `json:"name"`
+		Age    int      `json:"age,omitempty"`
+	}
+	var c httpx.Client = srv.Client()
+	c = httpx.SetRequestFromStruct(c, UpdateUser{
+		ID:     "a/b",
+		Fields: []string{"name", "email"},
+		APIKey: "secret",
+		Name:   "tom",
+	})
+	c = httpx.SetRequest(c, http.MethodPut, srv.URL+"/users/{id}")
+	if _, err := c.Do(nil); err != nil {
+		t.Fatal(err)
+	}
+	if got.URL.EscapedPath() != "/users/a%2Fb" {
+		t.Fatal(got.URL.EscapedPath())
+	}
+	if q := got.URL.Query(); len(q["fields"]) != 2 || q.Has("page") {
+		t.Fatal(q)
+	}
+	if got.Header.Get("X-Api-Key") != "secret" || got.Header.Get("Content-Type") != "application/json" {
+		t.Fatal(got.Header)
+	}
+	if body != `{"name":"tom"}` {
+		t.Fatal(body)
+	}
+}
+
+func TestSetRequestFromStruct_GetBody(t *testing.T) {
+	var c httpx.Client = httpx.ClientFunc(func(req *http.Request) (*http.Response, error) {
+		if req.GetBody == nil {
+			t.Fatal("expected the body to be replayable")
+		}
+		for i := 0; i < 2; i++ {
+			rc, err := req.GetBody()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if b, _ := io.ReadAll(rc); string(b) != `{"name":"tom"}` {
+				t.Fatal(string(b))
+			}
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+	c = httpx.SetRequestFromStruct(c, struct {
+		Name string `json:"name"`
+	}{Name: "tom"})
+	c = httpx.SetRequest(c, http.MethodPost, "https://example.com/users")
+	if _, err := c.Do(nil); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,59 @@
+package httpx
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// TeeResponseBody performs the request and streams the response body through every handler at once,
+// for example to decode it while also hashing it and writing it to disk, without buffering it in memory.
+//
+// Each handler runs in its own goroutine and reads the body at its own pace, the slowest handler limits the rest.
+// Unread data is discarded once a handler returns. The first error from reading the body or from a handler
+// is returned and the returned response has an empty body
+func TeeResponseBody(c Client, handlers ...func(io.Reader) error) ClientFunc {
+	c = RequireResponseBody(c)
+	return func(req *http.Request) (*http.Response, error) {
+		resp, err := c.Do(req)
+		if err != nil {
+			return resp, err
+		}
+		writers := make([]io.Writer, len(handlers))
+		pipes := make([]*io.PipeWriter, len(handlers))
+		errs := make(chan error, len(handlers))
+		for i, handler := range handlers {
+			pr, pw := io.Pipe()
+			writers[i], pipes[i] = pw, pw
+			go func(handler func(io.Reader) error, pr *io.PipeReader) {
+				err := handler(pr)
+				// keep the other handlers streaming
+				_, _ = io.Copy(io.Discard, pr)
+				errs <- err
+			}(handler, pr)
+		}
+
+		_, copyErr := io.Copy(io.MultiWriter(writers...), resp.Body)
+		for _, pw := range pipes {
+			pw.CloseWithError(copyErr)
+		}
+		var handlerErr error
+		for range handlers {
+			if err := <-errs; err != nil && handlerErr == nil {
+				handlerErr = err
+			}
+		}
+		closeErr := resp.Body.Close()
+		resp.Body = http.NoBody
+		if copyErr != nil {
+			return resp, fmt.Errorf("could not read response body: %w", copyErr)
+		}
+		if handlerErr != nil {
+			return resp, handlerErr
+		}
+		if closeErr != nil {
+			return resp, errBodyCloser{next: closeErr}
+		}
+		return resp, nil
+	}
+}
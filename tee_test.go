@@ -0,0 +1,59 @@
+package httpx_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tflyons/httpx"
+)
+
+func TestTeeResponseBody(t *testing.T) {
+	srv := httptest.NewServer(echoHandler)
+	defer srv.Close()
+
+	var got Thing
+	var saved bytes.Buffer
+	hash := sha256.New()
+	var c httpx.Client = srv.Client()
+	c = httpx.TeeResponseBody(c,
+		func(r io.Reader) error { return json.NewDecoder(r).Decode(&got) },
+		func(r io.Reader) error { _, err := io.Copy(hash, r); return err },
+		func(r io.Reader) error { _, err := io.Copy(&saved, r); return err },
+	)
+	c = httpx.SetRequestBodyJSON(c, Thing{Foo: "tee", Bar: 3})
+	if _, err := httpx.SetRequest(c, http.MethodPost, srv.URL).Do(nil); err != nil {
+		t.Fatal(err)
+	}
+	if got.Foo != "tee" || got.Bar != 3 {
+		t.Fatal(got)
+	}
+	if want := sha256.Sum256(saved.Bytes()); !bytes.Equal(hash.Sum(nil), want[:]) || saved.Len() == 0 {
+		t.Fatal(saved.String())
+	}
+}
+
+func TestTeeResponseBodyError(t *testing.T) {
+	srv := httptest.NewServer(echoHandler)
+	defer srv.Close()
+
+	errStop := fmt.Errorf("stop")
+	var n int64
+	var c httpx.Client = srv.Client()
+	c = httpx.TeeResponseBody(c,
+		// returning early does not block the other handler
+		func(r io.Reader) error { return errStop },
+		func(r io.Reader) error { n, _ = io.Copy(io.Discard, r); return nil },
+	)
+	c = httpx.SetRequestBody(c, nil, bytes.Repeat([]byte("x"), 1<<20))
+	_, err := httpx.SetRequest(c, http.MethodPost, srv.URL).Do(nil)
+	if !errors.Is(err, errStop) || n != 1<<20 {
+		t.Fatal(err, n)
+	}
+}
@@ -0,0 +1,24 @@
+package httpx
+
+import "net/http"
+
+// Middleware is a decorator with its arguments already bound, for example
+//
+//	dump := func(c httpx.Client) httpx.ClientFunc { return httpx.SetSlowRequestWarning(c, time.Second, warn) }
+type Middleware func(Client) ClientFunc
+
+// Toggle returns mw switched on and off at runtime by enabled, which is called on every request.
+// The decorated chain is built once, so flipping the flag, for example an atomic.Bool, takes effect for the next
+// request without rebuilding the chain or affecting requests in flight
+func Toggle(mw Middleware, enabled func() bool) Middleware {
+	return func(c Client) ClientFunc {
+		c = nilClientCheck(c)
+		on := mw(c)
+		return func(req *http.Request) (*http.Response, error) {
+			if enabled() {
+				return on.Do(req)
+			}
+			return c.Do(req)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package httpx_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/tflyons/httpx"
+)
+
+func TestToggle(t *testing.T) {
+	srv := httptest.NewServer(echoHandler)
+	defer srv.Close()
+
+	var enabled atomic.Bool
+	debug := httpx.Toggle(func(c httpx.Client) httpx.ClientFunc {
+		return httpx.SetHeader(c, "X-Debug", "1")
+	}, enabled.Load)
+
+	var c httpx.Client = srv.Client()
+	c = debug(c)
+	c = httpx.SetRequest(c, http.MethodGet, srv.URL)
+	for _, on := range []bool{false, true, false} {
+		enabled.Store(on)
+		resp, err := c.Do(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if got := resp.Header.Get("X-Debug") == "1"; got != on {
+			t.Fatal(on, resp.Header)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package httpx
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+// Tracer starts and finishes spans for SetTracer, so that any tracing or APM system can be connected without this
+// module depending on its SDK. The otelx module provides one for OpenTelemetry. Implementations must be safe for
+// concurrent use
+type Tracer interface {
+	// StartSpan starts a span named name, as a child of any span on ctx, and returns a context carrying it
+	StartSpan(ctx context.Context, name string, tags map[string]string) context.Context
+	// FinishSpan finishes the span carried by ctx, adding tags that describe the result.
+	// err is nil if the request succeeded
+	FinishSpan(ctx context.Context, tags map[string]string, err error)
+}
+
+// TracePropagator is implemented by a Tracer that sends the span on ctx to the server in request headers,
+// for example as a W3C traceparent header
+type TracePropagator interface {
+	Inject(ctx context.Context, header http.Header)
+}
+
+// Tags set by SetTracer
+const (
+	TagHTTPMethod     = "http.method"
+	TagHTTPURL        = "http.url"
+	TagHTTPHost       = "http.host"
+	TagHTTPStatusCode = "http.status_code"
+	TagErrorClass     = "error.class"
+	TagAttempt        = "http.attempt"
+)
+
+// SetTracer wraps every request made through c in a span named "HTTP " and the method, tagged with the method, URL
+// and host when it starts and with the status code and the ErrorClass of the result when it finishes. The span is on
+// the context of the request passed to c and, if t is a TracePropagator, it is injected into the request headers.
+// 4xx and 5xx responses finish the span without an error and with an error class.
+// Place it inside SetRetry to trace every attempt as its own span
+func SetTracer(c Client, t Tracer) ClientFunc {
+	c = nilClientCheck(c)
+	return func(req *http.Request) (*http.Response, error) {
+		u := *req.URL
+		u.User = nil
+		ctx := t.StartSpan(req.Context(), "HTTP "+req.Method, map[string]string{
+			TagHTTPMethod: req.Method,
+			TagHTTPURL:    u.String(),
+			TagHTTPHost:   u.Host,
+			TagAttempt:    strconv.Itoa(AttemptFromContext(req.Context())),
+		})
+		req = req.WithContext(ctx)
+		if p, ok := t.(TracePropagator); ok {
+			if req.Header == nil {
+				req.Header = make(http.Header)
+			}
+			p.Inject(ctx, req.Header)
+		}
+		resp, err := c.Do(req)
+		tags := make(map[string]string)
+		if resp != nil {
+			tags[TagHTTPStatusCode] = strconv.Itoa(resp.StatusCode)
+		}
+		if class := ErrorClass(resp, err); class != "" {
+			tags[TagErrorClass] = class
+		}
+		t.FinishSpan(ctx, tags, err)
+		return resp, err
+	}
+}
@@ -0,0 +1,89 @@
+package httpx_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx"
+)
+
+// recordingTracer keeps every finished span
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+type recordedSpan struct {
+	id   int
+	name string
+	tags map[string]string
+	err  error
+}
+
+type spanKey struct{}
+
+func (t *recordingTracer) StartSpan(ctx context.Context, name string, tags map[string]string) context.Context {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	span := &recordedSpan{id: len(t.spans) + 1, name: name, tags: tags}
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+func (t *recordingTracer) FinishSpan(ctx context.Context, tags map[string]string, err error) {
+	span := ctx.Value(spanKey{}).(*recordedSpan)
+	for k, v := range tags {
+		span.tags[k] = v
+	}
+	span.err = err
+}
+
+func (t *recordingTracer) Inject(ctx context.Context, header http.Header) {
+	header.Set("X-Span-Id", fmt.Sprint(ctx.Value(spanKey{}).(*recordedSpan).id))
+}
+
+func TestSetTracer(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls++; calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(r.Header.Get("X-Span-Id")))
+	}))
+	defer srv.Close()
+
+	tracer := &recordingTracer{}
+	var body string
+	var c httpx.Client = srv.Client()
+	c = httpx.SetTracer(c, tracer)
+	c = httpx.SetRetry(c, httpx.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond})
+	c = httpx.SetResponseBodyString(c, &body)
+	if _, err := httpx.SetRequest(c, http.MethodGet, srv.URL+"/things").Do(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tracer.spans) != 2 || body != "2" {
+		t.Fatal(tracer.spans, body)
+	}
+	first, second := tracer.spans[0], tracer.spans[1]
+	if first.name != "HTTP GET" || first.tags[httpx.TagHTTPStatusCode] != "503" || first.tags[httpx.TagErrorClass] != httpx.ErrorClass5xx {
+		t.Fatal(first)
+	}
+	if second.tags[httpx.TagAttempt] != "2" || second.tags[httpx.TagHTTPURL] != srv.URL+"/things" || second.tags[httpx.TagErrorClass] != "" {
+		t.Fatal(second)
+	}
+
+	// transport errors finish the span with the error
+	errDown := fmt.Errorf("down")
+	c = httpx.SetTracer(httpx.ClientFunc(func(*http.Request) (*http.Response, error) { return nil, errDown }), tracer)
+	if _, err := httpx.SetRequest(c, http.MethodGet, srv.URL).Do(nil); !errors.Is(tracer.spans[2].err, errDown) || err == nil {
+		t.Fatal(tracer.spans[2])
+	}
+}
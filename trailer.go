@@ -0,0 +1,48 @@
+package httpx
+
+import (
+	"io"
+	"net/http"
+)
+
+// SetResponseTrailers calls fn with the response trailers, such as a checksum or grpc-status, once the response body
+// has been read to the end. An error from fn is returned by the read that reached the end, so it is returned by
+// the response body handlers.
+//
+// Trailers are only known after the body, so SetResponseTrailers must be inside the decorator reading the body,
+// for example SetResponseBodyHandler(SetResponseTrailers(c, fn), u, &v). fn is not called if the body is closed
+// before it has been read to the end, and is called with an empty header if the server sent no trailers
+func SetResponseTrailers(c Client, fn func(resp *http.Response, trailer http.Header) error) ClientFunc {
+	c = nilClientCheck(c)
+	return func(req *http.Request) (*http.Response, error) {
+		resp, err := c.Do(req)
+		if err != nil || resp.Body == nil {
+			return resp, err
+		}
+		resp.Body = &trailerBody{ReadCloser: resp.Body, resp: resp, fn: fn}
+		return resp, nil
+	}
+}
+
+// trailerBody calls fn when the body reaches io.EOF
+type trailerBody struct {
+	io.ReadCloser
+	resp *http.Response
+	fn   func(*http.Response, http.Header) error
+	done bool
+}
+
+func (b *trailerBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF && !b.done {
+		b.done = true
+		trailer := b.resp.Trailer
+		if trailer == nil {
+			trailer = make(http.Header)
+		}
+		if fnErr := b.fn(b.resp, trailer); fnErr != nil {
+			return n, fnErr
+		}
+	}
+	return n, err
+}
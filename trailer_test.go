@@ -0,0 +1,43 @@
+package httpx_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tflyons/httpx"
+)
+
+func TestSetResponseTrailers(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Trailer", "X-Status")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"Foo":"bar","Bar":1}`))
+		// the status is only known once the body has been streamed
+		w.Header().Set("X-Status", r.URL.Query().Get("status"))
+	}))
+	defer srv.Close()
+
+	errStream := errors.New("stream failed")
+	var got Thing
+	var c httpx.Client = srv.Client()
+	c = httpx.SetResponseTrailers(c, func(resp *http.Response, trailer http.Header) error {
+		if s := trailer.Get("X-Status"); s != "ok" {
+			return fmt.Errorf("%w: %s", errStream, s)
+		}
+		return nil
+	})
+	c = httpx.SetResponseBodyHandlerJSON(c, &got)
+
+	if _, err := httpx.SetRequest(c, http.MethodGet, srv.URL+"?status=ok").Do(nil); err != nil {
+		t.Fatal(err)
+	}
+	if got.Foo != "bar" {
+		t.Fatal(got)
+	}
+	if _, err := httpx.SetRequest(c, http.MethodGet, srv.URL+"?status=aborted").Do(nil); !errors.Is(err, errStream) {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,145 @@
+package httpx
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// TransportConfig describes an http.Transport built by NewTransport. The zero value matches http.DefaultTransport
+type TransportConfig struct {
+	// Proxy is the URL of a proxy for all requests, the standard HTTP_PROXY variables are used if empty
+	Proxy string
+	// CACertFile is a path to a PEM file of certificates trusted in addition to the system pool
+	CACertFile string
+
+	// DialTimeout limits establishing a connection, 30 seconds if zero
+	DialTimeout time.Duration
+	// KeepAlive is the interval of TCP keep-alive probes, 30 seconds if zero and disabled if negative
+	KeepAlive time.Duration
+	// FallbackDelay is how long a dual-stack dial waits for the IPv6 connection before racing an IPv4 one
+	// (RFC 6555 Happy Eyeballs), 300 milliseconds if zero and disabled if negative
+	FallbackDelay time.Duration
+	// SourceAddr is the local IP address connections are made from
+	SourceAddr string
+	// Interface is the name of the network interface connections are made from, using its first IPv4 address or,
+	// if it has none, its first IPv6 address. Only destinations of that address family can be reached. It cannot
+	// be combined with SourceAddr
+	Interface string
+
+	// ExpectContinueTimeout is how long a request with an Expect: 100-continue header waits for the server to
+	// accept the body before sending it anyway, 1 second if zero, see SetExpectContinue
+	ExpectContinueTimeout time.Duration
+
+	// SSRFPolicy, if set, refuses connections to addresses the policy denies, see SetSSRFGuard. A proxy must be
+	// allowed by the policy to be reached
+	SSRFPolicy *SSRFPolicy
+
+	// ConnStats, if set, counts the open and idle connections of the transport per host, see SetConnStats
+	ConnStats *ConnStats
+}
+
+// NewTransport returns a clone of http.DefaultTransport configured by cfg
+func NewTransport(cfg TransportConfig) (*http.Transport, error) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.Proxy != "" {
+		u, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid transport config: proxy: %w", err)
+		}
+		t.Proxy = http.ProxyURL(u)
+	}
+	if cfg.CACertFile != "" {
+		pem, err := os.ReadFile(cfg.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid transport config: ca cert: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("invalid transport config: no certificates found in %s", cfg.CACertFile)
+		}
+		t.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	if cfg.DialTimeout < 0 || cfg.ExpectContinueTimeout < 0 {
+		return nil, fmt.Errorf("invalid transport config: negative timeout")
+	}
+	if cfg.ExpectContinueTimeout > 0 {
+		t.ExpectContinueTimeout = cfg.ExpectContinueTimeout
+	}
+	dialer := &net.Dialer{
+		Timeout:       30 * time.Second,
+		KeepAlive:     cfg.KeepAlive,
+		FallbackDelay: cfg.FallbackDelay,
+	}
+	if cfg.DialTimeout > 0 {
+		dialer.Timeout = cfg.DialTimeout
+	}
+	if dialer.KeepAlive == 0 {
+		dialer.KeepAlive = 30 * time.Second
+	}
+	source, err := sourceIP(cfg.SourceAddr, cfg.Interface)
+	if err != nil {
+		return nil, err
+	}
+	if source != nil {
+		dialer.LocalAddr = &net.TCPAddr{IP: source}
+	}
+	t.DialContext = dialer.DialContext
+	if cfg.SSRFPolicy != nil {
+		t.DialContext = cfg.SSRFPolicy.dialContext(dialer)
+	}
+	if cfg.ConnStats != nil {
+		t.DialContext = cfg.ConnStats.dialer(t.DialContext)
+	}
+	return t, nil
+}
+
+// sourceIP returns the local address of the source address or interface, or nil if neither is set
+func sourceIP(addr, iface string) (net.IP, error) {
+	switch {
+	case addr != "" && iface != "":
+		return nil, fmt.Errorf("invalid transport config: source address and interface are both set")
+	case addr != "":
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid transport config: source address %q is not an IP address", addr)
+		}
+		return ip, nil
+	case iface != "":
+		ifi, err := net.InterfaceByName(iface)
+		if err != nil {
+			return nil, fmt.Errorf("invalid transport config: interface: %w", err)
+		}
+		addrs, err := ifi.Addrs()
+		if err != nil {
+			return nil, fmt.Errorf("invalid transport config: interface %s: %w", iface, err)
+		}
+		var v6 net.IP
+		for _, a := range addrs {
+			ipnet, ok := a.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			if ip4 := ipnet.IP.To4(); ip4 != nil {
+				return ip4, nil
+			}
+			if v6 == nil {
+				v6 = ipnet.IP
+			}
+		}
+		if v6 == nil {
+			return nil, fmt.Errorf("invalid transport config: interface %s has no addresses", iface)
+		}
+		return v6, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,61 @@
+package httpx_test
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx"
+)
+
+func TestNewTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, _ := net.SplitHostPort(r.RemoteAddr)
+		_, _ = w.Write([]byte(host))
+	}))
+	defer srv.Close()
+
+	var loopback string
+	ifaces, _ := net.Interfaces()
+	for _, ifi := range ifaces {
+		if ifi.Flags&net.FlagLoopback != 0 {
+			loopback = ifi.Name
+		}
+	}
+	for name, cfg := range map[string]httpx.TransportConfig{
+		"source":    {SourceAddr: "127.0.0.1", DialTimeout: time.Second, KeepAlive: -1, FallbackDelay: -1},
+		"interface": {Interface: loopback},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if cfg.Interface == "" && name == "interface" {
+				t.Skip("no loopback interface")
+			}
+			tr, err := httpx.NewTransport(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer tr.CloseIdleConnections()
+			var from string
+			c := httpx.SetResponseBodyString(&http.Client{Transport: tr}, &from)
+			if _, err := httpx.SetRequest(c, http.MethodGet, srv.URL).Do(nil); err != nil {
+				t.Fatal(err)
+			}
+			if from != "127.0.0.1" {
+				t.Fatal(from)
+			}
+		})
+	}
+
+	for name, cfg := range map[string]httpx.TransportConfig{
+		"both":      {SourceAddr: "127.0.0.1", Interface: loopback},
+		"not an ip": {SourceAddr: "localhost"},
+		"interface": {Interface: "does-not-exist0"},
+		"timeout":   {DialTimeout: -time.Second},
+	} {
+		if _, err := httpx.NewTransport(cfg); err == nil {
+			t.Fatal(name, "expected error")
+		}
+	}
+}
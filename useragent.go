@@ -0,0 +1,48 @@
+package httpx
+
+import (
+	"net/http"
+	"runtime/debug"
+	"strings"
+)
+
+// SetUserAgent adds a product token such as "myapp/1.2.0 (linux; build 7)" to the User-Agent header.
+//
+// If the request already has a User-Agent the token is appended, so decorating with the application first and
+// a library second produces "myapp/1.2.0 mylib/0.3.0". If version is empty the main module version is read
+// from the build info, and the token is just the product if that is unavailable.
+func SetUserAgent(c Client, product, version string, comment ...string) ClientFunc {
+	c = nilClientCheck(c)
+	if version == "" {
+		version = mainModuleVersion()
+	}
+	token := product
+	if version != "" {
+		token += "/" + version
+	}
+	if len(comment) > 0 {
+		token += " (" + strings.Join(comment, "; ") + ")"
+	}
+	return func(req *http.Request) (*http.Response, error) {
+		if req.Header == nil {
+			req.Header = make(http.Header)
+		}
+		ua := req.Header.Get("User-Agent")
+		switch {
+		case ua == "":
+			req.Header.Set("User-Agent", token)
+		case !strings.Contains(ua, token):
+			req.Header.Set("User-Agent", ua+" "+token)
+		}
+		return c.Do(req)
+	}
+}
+
+// mainModuleVersion returns the version of the main module or an empty string for development builds
+func mainModuleVersion() string {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok || bi.Main.Version == "(devel)" {
+		return ""
+	}
+	return bi.Main.Version
+}
@@ -0,0 +1,48 @@
+package httpx_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/tflyons/httpx"
+)
+
+func TestSetUserAgent(t *testing.T) {
+	var got string
+	var c httpx.Client = httpx.ClientFunc(func(req *http.Request) (*http.Response, error) {
+		got = req.Header.Get("User-Agent")
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+	c = httpx.SetUserAgent(c, "mylib", "0.3.0")
+	c = httpx.SetUserAgent(c, "myapp", "1.2.0", "linux", "build 7")
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	if _, err := c.Do(req); err != nil {
+		t.Fatal(err)
+	}
+	if got != "myapp/1.2.0 (linux; build 7) mylib/0.3.0" {
+		t.Fatal(got)
+	}
+
+	// sending the same request again does not repeat the tokens
+	if _, err := c.Do(req); err != nil {
+		t.Fatal(err)
+	}
+	if got != "myapp/1.2.0 (linux; build 7) mylib/0.3.0" {
+		t.Fatal(got)
+	}
+}
+
+func TestSetUserAgentBuildInfo(t *testing.T) {
+	var got string
+	c := httpx.SetUserAgent(httpx.ClientFunc(func(req *http.Request) (*http.Response, error) {
+		got = req.Header.Get("User-Agent")
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	}), "myapp", "")
+	if _, err := httpx.SetRequest(c, http.MethodGet, "http://example.com").Do(nil); err != nil {
+		t.Fatal(err)
+	}
+	// test binaries are development builds so no version is available
+	if got != "myapp" {
+		t.Fatal(got)
+	}
+}
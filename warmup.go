@@ -0,0 +1,66 @@
+package httpx
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// Warmup sends a HEAD request to each url concurrently so that TCP connections and TLS handshakes
+// are done before the first real request. Responses of any status count as success and their connections
+// are returned to the pool of the underlying transport.
+//
+// The returned error reports how many urls failed and wraps the first failure
+func Warmup(ctx context.Context, c Client, urls ...string) error {
+	c = nilClientCheck(c)
+	return warmupAll(urls, func(u string) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, u, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := c.Do(req)
+		if resp != nil && resp.Body != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		return err
+	})
+}
+
+// WarmupTransport sends a HEAD request to each url through t, for transports that are not wrapped in a Client yet.
+// The connections, set up with the proxy, dialers and TLS configuration of t, are returned to its pool so that
+// later requests reuse them, as with Warmup
+func WarmupTransport(ctx context.Context, t *http.Transport, urls ...string) error {
+	return Warmup(ctx, ClientFunc(t.RoundTrip), urls...)
+}
+
+// warmupAll runs fn for every url concurrently
+func warmupAll(urls []string, fn func(string) error) error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(urls))
+	for i, u := range urls {
+		wg.Add(1)
+		go func(i int, u string) {
+			defer wg.Done()
+			errs[i] = fn(u)
+		}(i, u)
+	}
+	wg.Wait()
+
+	var first error
+	failed := 0
+	for i, err := range errs {
+		if err != nil {
+			failed++
+			if first == nil {
+				first = fmt.Errorf("%s: %w", urls[i], err)
+			}
+		}
+	}
+	if first != nil {
+		return fmt.Errorf("warmup failed for %d of %d urls: %w", failed, len(urls), first)
+	}
+	return nil
+}
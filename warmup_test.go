@@ -0,0 +1,70 @@
+package httpx_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/tflyons/httpx"
+)
+
+func TestWarmup(t *testing.T) {
+	var conns int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+
+	c := srv.Client()
+	if err := httpx.Warmup(context.Background(), c, srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	// the real request reuses the warmed connection
+	if _, err := httpx.SetRequest(c, http.MethodGet, srv.URL).Do(nil); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&conns); n != 1 {
+		t.Fatal(n)
+	}
+
+	err := httpx.Warmup(context.Background(), c, srv.URL, "http://127.0.0.1:1")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestWarmupTransport(t *testing.T) {
+	var conns int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	srv.StartTLS()
+	defer srv.Close()
+
+	transport := srv.Client().Transport.(*http.Transport).Clone()
+	if err := httpx.WarmupTransport(context.Background(), transport, srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	// the real request reuses the pooled connection
+	if _, err := httpx.SetRequest(&http.Client{Transport: transport}, http.MethodGet, srv.URL).Do(nil); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&conns); n != 1 {
+		t.Fatal(n)
+	}
+	if err := httpx.WarmupTransport(context.Background(), &http.Transport{}, srv.URL); err == nil {
+		t.Fatal("expected untrusted certificate error")
+	}
+}
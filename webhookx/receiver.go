@@ -0,0 +1,159 @@
+package webhookx
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tflyons/httpx"
+)
+
+var (
+	// ErrInvalidSignature is returned by a Verifier when the signature is missing or does not match
+	ErrInvalidSignature = fmt.Errorf("invalid webhook signature")
+	// ErrTimestampTolerance is returned when a signed timestamp is too far from the current time
+	ErrTimestampTolerance = fmt.Errorf("webhook timestamp outside of tolerance")
+)
+
+// Verifier checks the signature of an inbound webhook and returns the signed timestamp.
+// The timestamp is zero for schemes that do not sign one.
+type Verifier func(h http.Header, payload []byte) (time.Time, error)
+
+// HMACVerifier verifies webhooks sent by a Sender using the same secret
+func HMACVerifier(secret []byte) Verifier {
+	return func(h http.Header, payload []byte) (time.Time, error) {
+		ts := h.Get(HeaderTimestamp)
+		sent, err := parseUnix(ts)
+		if err != nil {
+			return time.Time{}, err
+		}
+		for _, sig := range strings.Fields(strings.ReplaceAll(h.Get(HeaderSignature), ",", " ")) {
+			if k, v, _ := strings.Cut(sig, "="); k == "v1" && equalHex(v, Sign(secret, ts, payload)) {
+				return sent, nil
+			}
+		}
+		return time.Time{}, ErrInvalidSignature
+	}
+}
+
+// StripeVerifier verifies the Stripe-Signature header ("t=<unix>,v1=<hex>"), signed over "<t>.<payload>"
+func StripeVerifier(secret []byte) Verifier {
+	return func(h http.Header, payload []byte) (time.Time, error) {
+		var ts string
+		var sigs []string
+		for _, part := range strings.Split(h.Get("Stripe-Signature"), ",") {
+			k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+			switch k {
+			case "t":
+				ts = v
+			case "v1":
+				sigs = append(sigs, v)
+			}
+		}
+		sent, err := parseUnix(ts)
+		if err != nil {
+			return time.Time{}, err
+		}
+		expected := Sign(secret, ts, payload)
+		for _, sig := range sigs {
+			if equalHex(sig, expected) {
+				return sent, nil
+			}
+		}
+		return time.Time{}, ErrInvalidSignature
+	}
+}
+
+// GitHubVerifier verifies the X-Hub-Signature-256 header ("sha256=<hex>"), signed over the payload only
+func GitHubVerifier(secret []byte) Verifier {
+	return func(h http.Header, payload []byte) (time.Time, error) {
+		alg, sig, _ := strings.Cut(h.Get("X-Hub-Signature-256"), "=")
+		m := hmac.New(sha256.New, secret)
+		m.Write(payload)
+		if alg != "sha256" || !equalHex(sig, hex.EncodeToString(m.Sum(nil))) {
+			return time.Time{}, ErrInvalidSignature
+		}
+		return time.Time{}, nil
+	}
+}
+
+// ReceiverOptions configures Verify
+type ReceiverOptions struct {
+	// Tolerance is the maximum difference between the signed timestamp and now, 5 minutes if zero.
+	// A negative value disables the check
+	Tolerance time.Duration
+	// MaxBytes limits the size of the payload, 1MB if zero
+	MaxBytes int64
+}
+
+type payloadKey struct{}
+
+// Payload returns the verified payload of a request passed through Verify
+func Payload(r *http.Request) []byte {
+	b, _ := r.Context().Value(payloadKey{}).([]byte)
+	return b
+}
+
+// Verify returns middleware that reads the body, checks its signature and timestamp and passes the request
+// on with the verified payload available from Payload and as a fresh r.Body.
+//
+// Requests are rejected with 413 if the body is too large and 401 if verification fails.
+func Verify(v Verifier, opts ReceiverOptions) func(http.Handler) http.Handler {
+	tolerance := opts.Tolerance
+	if tolerance == 0 {
+		tolerance = 5 * time.Minute
+	}
+	maxBytes := opts.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = 1 << 20
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBytes))
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					http.Error(w, "webhook payload too large", http.StatusRequestEntityTooLarge)
+					return
+				}
+				http.Error(w, "could not read webhook payload", http.StatusBadRequest)
+				return
+			}
+			sent, err := v(r.Header, b)
+			if err == nil && tolerance > 0 && !sent.IsZero() {
+				if d := httpx.ClockFromContext(r.Context()).Now().Sub(sent); d > tolerance || d < -tolerance {
+					err = ErrTimestampTolerance
+				}
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			r = r.WithContext(context.WithValue(r.Context(), payloadKey{}, b))
+			r.Body = io.NopCloser(bytes.NewReader(b))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func parseUnix(ts string) (time.Time, error) {
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: invalid timestamp %q", ErrInvalidSignature, ts)
+	}
+	return time.Unix(sec, 0), nil
+}
+
+// equalHex compares hex signatures in constant time
+func equalHex(a, b string) bool {
+	return hmac.Equal([]byte(strings.ToLower(a)), []byte(b))
+}
@@ -0,0 +1,80 @@
+package webhookx_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx/webhookx"
+)
+
+func TestVerifySenderRoundTrip(t *testing.T) {
+	secret := []byte("secret")
+	var got, body string
+	h := webhookx.Verify(webhookx.HMACVerifier(secret), webhookx.ReceiverOptions{})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = string(webhookx.Payload(r))
+			b, _ := io.ReadAll(r.Body)
+			body = string(b)
+		}))
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	s := &webhookx.Sender{Client: srv.Client(), Secret: secret, MaxAttempts: 1}
+	if _, err := s.SendPayload(context.Background(), srv.URL, []byte(`{"a":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	if got != `{"a":1}` || body != got {
+		t.Fatal(got, body)
+	}
+
+	s.Secret = []byte("wrong")
+	d, err := s.SendPayload(context.Background(), srv.URL, []byte(`{"a":1}`))
+	if err == nil || d.Attempts[0].StatusCode != http.StatusUnauthorized {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifiers(t *testing.T) {
+	secret := []byte("secret")
+	payload := []byte(`{"id":"evt_1"}`)
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	old := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	github := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	tests := []struct {
+		name     string
+		verifier webhookx.Verifier
+		header   http.Header
+		size     int
+		want     int
+	}{
+		{"stripe", webhookx.StripeVerifier(secret), http.Header{"Stripe-Signature": {"t=" + now + ",v1=00,v1=" + webhookx.Sign(secret, now, payload)}}, 0, http.StatusOK},
+		{"stripe stale", webhookx.StripeVerifier(secret), http.Header{"Stripe-Signature": {"t=" + old + ",v1=" + webhookx.Sign(secret, old, payload)}}, 0, http.StatusUnauthorized},
+		{"stripe bad", webhookx.StripeVerifier(secret), http.Header{"Stripe-Signature": {"t=" + now + ",v1=00"}}, 0, http.StatusUnauthorized},
+		{"github", webhookx.GitHubVerifier(secret), http.Header{"X-Hub-Signature-256": {github}}, 0, http.StatusOK},
+		{"github bad", webhookx.GitHubVerifier([]byte("other")), http.Header{"X-Hub-Signature-256": {github}}, 0, http.StatusUnauthorized},
+		{"too large", webhookx.GitHubVerifier(secret), http.Header{"X-Hub-Signature-256": {github}}, 4, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		h := webhookx.Verify(tt.verifier, webhookx.ReceiverOptions{MaxBytes: int64(tt.size)})(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(string(payload)))
+		r.Header = tt.header
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != tt.want {
+			t.Fatal(tt.name, w.Code, w.Body.String())
+		}
+	}
+}
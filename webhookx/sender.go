@@ -0,0 +1,283 @@
+// Package webhookx sends and receives signed webhooks using httpx clients.
+//
+// Payloads are signed with HMAC-SHA256 over "<timestamp>.<payload>" and delivered with the headers
+// Webhook-Id, Webhook-Timestamp and Webhook-Signature ("v1=<hex>").
+package webhookx
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/tflyons/httpx"
+)
+
+// Header names used by the Sender and the default Verifier
+const (
+	HeaderID        = "Webhook-Id"
+	HeaderTimestamp = "Webhook-Timestamp"
+	HeaderSignature = "Webhook-Signature"
+)
+
+// ErrDeliveryFailed is matched by the error returned from Send when every attempt failed
+var ErrDeliveryFailed = fmt.Errorf("webhook delivery failed")
+
+// DeliveryError is the error of a failed delivery. It matches ErrDeliveryFailed and unwraps to the error of the
+// last attempt, or the context error if the wait for the next attempt was cut short
+type DeliveryError struct {
+	Attempts int
+	Err      error
+}
+
+func (e *DeliveryError) Error() string {
+	return fmt.Sprintf("%s after %d attempts: %v", ErrDeliveryFailed, e.Attempts, e.Err)
+}
+
+// Is reports whether target is ErrDeliveryFailed
+func (e *DeliveryError) Is(target error) bool {
+	return target == ErrDeliveryFailed
+}
+
+// Unwrap returns the underlying error
+func (e *DeliveryError) Unwrap() error {
+	return e.Err
+}
+
+// Attempt records a single delivery attempt
+type Attempt struct {
+	ID     string
+	URL    string
+	Number int
+	Start  time.Time
+	// Duration is how long the request took, excluding any time waiting on the rate limit
+	Duration time.Duration
+	// StatusCode is zero if no response was received
+	StatusCode int
+	Err        error
+}
+
+// Delivery is the outcome of sending one payload to one destination
+type Delivery struct {
+	ID       string
+	URL      string
+	Payload  []byte
+	Attempts []Attempt
+	// Err is nil if the payload was accepted with a 2xx status
+	Err error
+}
+
+// Sender signs and delivers webhook payloads.
+//
+// The zero value is not usable, Secret must be set. A Sender is safe for concurrent use.
+type Sender struct {
+	// Client performs the requests, httpx.DefaultClient if nil
+	Client httpx.Client
+	// Secret is the shared HMAC key
+	Secret []byte
+	// MaxAttempts is the number of tries before the delivery is dead-lettered, 5 if zero
+	MaxAttempts int
+	// InitialBackoff is the wait after the first failure, doubling on each retry up to MaxBackoff.
+	// Defaults to 1 second and 1 minute
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Backoff replaces the doubling of InitialBackoff when set, delivery stops when it returns false
+	Backoff httpx.Backoff
+	// RateLimit and RatePeriod limit the number of requests sent to a single host, unlimited if either is zero.
+	// Requests are counted in fixed windows of RatePeriod, see httpx.WindowLimiter
+	RateLimit  int
+	RatePeriod time.Duration
+	// OnAttempt is called after every attempt
+	OnAttempt func(Attempt)
+	// DeadLetter is called with deliveries that failed every attempt or were rejected permanently
+	DeadLetter func(*Delivery)
+
+	once sync.Once
+	// windows counts the requests per host, ended windows are dropped so it does not grow with the hosts seen
+	windows httpx.WindowStore
+}
+
+// retryStatuses are the response codes a delivery is retried on
+var retryStatuses = func() []int {
+	statuses := []int{http.StatusRequestTimeout, http.StatusTooManyRequests}
+	for s := 500; s <= 599; s++ {
+		statuses = append(statuses, s)
+	}
+	return statuses
+}()
+
+// Send marshals event as JSON and delivers it to rawURL, see SendPayload
+func (s *Sender) Send(ctx context.Context, rawURL string, event any) (*Delivery, error) {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal webhook event: %w", err)
+	}
+	return s.SendPayload(ctx, rawURL, b)
+}
+
+// SendPayload delivers the JSON payload to rawURL with httpx.SetRetry, retrying network errors, 5xx, 408 and 429
+// responses with exponential backoff or the Retry-After of the response if it is shorter than MaxBackoff. Any 2xx
+// response is a success. Other 4xx responses are not retried and the httpx.NoRetry override disables retries.
+// Every attempt is signed with a new timestamp. Timestamps and waits use the clock of ctx.
+//
+// The returned Delivery holds every attempt. If delivery failed its Err is also returned, a *DeliveryError, and
+// DeadLetter is called.
+func (s *Sender) SendPayload(ctx context.Context, rawURL string, payload []byte) (*Delivery, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	d := &Delivery{ID: id, URL: rawURL, Payload: payload}
+
+	policy := httpx.RetryPolicy{
+		MaxAttempts:    s.MaxAttempts,
+		InitialBackoff: s.InitialBackoff,
+		MaxBackoff:     s.MaxBackoff,
+		Backoff:        s.Backoff,
+		Statuses:       retryStatuses,
+		Methods:        []string{http.MethodPost},
+	}
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = 5
+	}
+	if policy.InitialBackoff <= 0 {
+		policy.InitialBackoff = time.Second
+	}
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = time.Minute
+	}
+	// the error of the attempt given up on, or of the context when the wait for the next attempt was cut short
+	var gaveUp error
+	policy.OnGiveUp = func(e httpx.RetryEvent) { gaveUp = e.Err }
+
+	c := s.client()
+	c = require2xx(c)
+	c = s.limit(c, u.Host)
+	c = s.record(c, d)
+	c = s.sign(c, d)
+	c = httpx.SetRetry(c, policy)
+	c = httpx.SetHeader(c, HeaderID, d.ID)
+	c = httpx.SetHeader(c, "Content-Type", "application/json")
+	c = httpx.SetRequestBody(c, nil, d.Payload)
+	c = httpx.SetRequestWithContext(ctx, c, http.MethodPost, d.URL)
+	resp, err := c.Do(nil)
+	if resp != nil && resp.Body != nil {
+		resp.Body.Close()
+	}
+	if err == nil {
+		return d, nil
+	}
+	cause := d.Attempts[len(d.Attempts)-1].Err
+	if gaveUp != nil && ctx.Err() != nil && errors.Is(gaveUp, ctx.Err()) {
+		cause = ctx.Err()
+	}
+	d.Err = &DeliveryError{Attempts: len(d.Attempts), Err: cause}
+	if s.DeadLetter != nil {
+		s.DeadLetter(d)
+	}
+	return d, d.Err
+}
+
+// sign sets the timestamp and signature headers for each attempt
+func (s *Sender) sign(c httpx.Client, d *Delivery) httpx.ClientFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		ts := strconv.FormatInt(httpx.ClockFromContext(req.Context()).Now().Unix(), 10)
+		req.Header.Set(HeaderTimestamp, ts)
+		req.Header.Set(HeaderSignature, "v1="+Sign(s.Secret, ts, d.Payload))
+		return c.Do(req)
+	}
+}
+
+// record adds an Attempt to d for each request and reports it to OnAttempt. The start and duration of the request
+// are taken below the rate limit
+func (s *Sender) record(c httpx.Client, d *Delivery) httpx.ClientFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		a := Attempt{ID: d.ID, URL: d.URL, Number: len(d.Attempts) + 1}
+		resp, err := c.Do(req.WithContext(context.WithValue(req.Context(), attemptKey{}, &a)))
+		if resp != nil {
+			a.StatusCode = resp.StatusCode
+		}
+		a.Err = err
+		d.Attempts = append(d.Attempts, a)
+		if s.OnAttempt != nil {
+			s.OnAttempt(a)
+		}
+		return resp, err
+	}
+}
+
+type attemptKey struct{}
+
+// stamp records the start and duration of the request on the Attempt of the context
+func stamp(c httpx.Client) httpx.ClientFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		a, _ := req.Context().Value(attemptKey{}).(*Attempt)
+		if a == nil {
+			return c.Do(req)
+		}
+		clock := httpx.ClockFromContext(req.Context())
+		a.Start = clock.Now()
+		resp, err := c.Do(req)
+		a.Duration = clock.Now().Sub(a.Start)
+		return resp, err
+	}
+}
+
+// require2xx returns an *httpx.StatusError for responses outside of 2xx
+func require2xx(c httpx.Client) httpx.ClientFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		resp, err := c.Do(req)
+		if err == nil && resp.StatusCode/100 != 2 {
+			return resp, &httpx.StatusError{StatusCode: resp.StatusCode, Header: resp.Header}
+		}
+		return resp, err
+	}
+}
+
+// client returns the client requests are sent with
+func (s *Sender) client() httpx.Client {
+	if s.Client == nil {
+		return httpx.DefaultClient
+	}
+	return s.Client
+}
+
+// limit waits for the rate limit of host before each request, then records its timing with stamp
+func (s *Sender) limit(c httpx.Client, host string) httpx.Client {
+	c = stamp(c)
+	if s.RateLimit <= 0 || s.RatePeriod <= 0 {
+		return c
+	}
+	s.once.Do(func() { s.windows = httpx.NewMemoryWindowStore() })
+	return httpx.SetLimiter(c, httpx.NewWindowLimiter(s.windows, host, s.RateLimit, s.RatePeriod))
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of "<timestamp>.<payload>"
+func Sign(secret []byte, timestamp string, payload []byte) string {
+	m := hmac.New(sha256.New, secret)
+	m.Write([]byte(timestamp))
+	m.Write([]byte("."))
+	m.Write(payload)
+	return hex.EncodeToString(m.Sum(nil))
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "msg_" + hex.EncodeToString(b), nil
+}
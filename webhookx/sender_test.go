@@ -0,0 +1,136 @@
+package webhookx_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/httpxtest"
+	"github.com/tflyons/httpx/webhookx"
+)
+
+func TestSenderSignsPayload(t *testing.T) {
+	secret := []byte("secret")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		ts := r.Header.Get(webhookx.HeaderTimestamp)
+		if r.Header.Get(webhookx.HeaderSignature) != "v1="+webhookx.Sign(secret, ts, b) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get(webhookx.HeaderID) == "" || string(b) != `{"event":"created"}` {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	s := &webhookx.Sender{Client: srv.Client(), Secret: secret}
+	d, err := s.Send(context.Background(), srv.URL, map[string]string{"event": "created"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Attempts) != 1 || d.Attempts[0].StatusCode != http.StatusNoContent {
+		t.Fatal(d.Attempts)
+	}
+}
+
+func TestSenderRetriesAndDeadLetters(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	var attempts []webhookx.Attempt
+	var dead *webhookx.Delivery
+	s := &webhookx.Sender{
+		Client:         srv.Client(),
+		Secret:         []byte("secret"),
+		InitialBackoff: time.Millisecond,
+		OnAttempt:      func(a webhookx.Attempt) { attempts = append(attempts, a) },
+		DeadLetter:     func(d *webhookx.Delivery) { dead = d },
+	}
+	if _, err := s.SendPayload(context.Background(), srv.URL, []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	if len(attempts) != 3 || attempts[2].Number != 3 || attempts[0].StatusCode != http.StatusServiceUnavailable {
+		t.Fatal(attempts)
+	}
+	if dead != nil {
+		t.Fatal(dead)
+	}
+
+	atomic.StoreInt32(&calls, 0)
+	s.MaxAttempts = 2
+	d, err := s.SendPayload(context.Background(), srv.URL, []byte(`{}`))
+	if !errors.Is(err, webhookx.ErrDeliveryFailed) || dead != d || len(d.Attempts) != 2 {
+		t.Fatal(err, dead)
+	}
+}
+
+func TestSenderDoesNotRetryClientErrors(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer srv.Close()
+
+	s := &webhookx.Sender{Client: srv.Client(), Secret: []byte("secret"), InitialBackoff: time.Millisecond}
+	_, err := s.SendPayload(context.Background(), srv.URL, []byte(`{}`))
+	var statusErr *httpx.StatusError
+	if !errors.Is(err, webhookx.ErrDeliveryFailed) || !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusGone {
+		t.Fatal("expected the delivery error to wrap the status error", err)
+	}
+	if calls != 1 {
+		t.Fatal(calls)
+	}
+}
+
+func TestSenderAcceptsAny2xxWithClock(t *testing.T) {
+	clock := httpxtest.NewClock(time.Unix(1700000000, 0))
+	httpx.SetDefaultClock(clock)
+	defer httpx.SetDefaultClock(nil)
+	var ts string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ts = r.Header.Get(webhookx.HeaderTimestamp)
+		w.WriteHeader(http.StatusNonAuthoritativeInfo)
+	}))
+	defer srv.Close()
+
+	s := &webhookx.Sender{Client: srv.Client(), Secret: []byte("secret")}
+	d, err := s.SendPayload(context.Background(), srv.URL, []byte(`{}`))
+	if err != nil {
+		t.Fatal("any 2xx status should be a success", err)
+	}
+	if ts != "1700000000" || !d.Attempts[0].Start.Equal(clock.Now()) {
+		t.Fatal("expected the time of the clock", ts, d.Attempts[0].Start)
+	}
+}
+
+func TestSenderRateLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	s := &webhookx.Sender{Client: srv.Client(), Secret: []byte("secret"), RateLimit: 1, RatePeriod: time.Hour}
+	if _, err := s.SendPayload(context.Background(), srv.URL, []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := s.SendPayload(ctx, srv.URL, []byte(`{}`)); !errors.Is(err, webhookx.ErrDeliveryFailed) {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,48 @@
+package yamlx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/tflyons/httpx"
+)
+
+// FromConfig builds a Client from the document of httpx.FromConfig written in YAML, which also accepts JSON:
+//
+//	base_url: https://api.example.com/v1
+//	decorators:
+//	  - headers:
+//	      X-Api-Version: "2"
+//	  - logging:
+//	      prefix: "api "
+//	  - timeout: 30s
+//	  - retry:
+//	      max_attempts: 3
+//	      initial_backoff: 100ms
+//	      max_backoff: 5s
+//	      statuses: [502, 503, 504]
+//	      methods: [GET, PUT]
+//	  - rate_limit:
+//	      max: 100
+//	      per: 1m
+//	  - cache:
+//	      ttl: 5m
+//	      stale_while_revalidate: 1m
+//	      max_entries: 1000
+//
+// The YAML is only converted to JSON, the decorators and their options are those of httpx.FromConfig
+func FromConfig(r io.Reader) (httpx.Client, error) {
+	var doc any
+	if err := yaml.NewDecoder(r).Decode(&doc); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("could not parse client config: %w", err)
+	}
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("could not convert client config to JSON: %w", err)
+	}
+	return httpx.FromConfig(bytes.NewReader(b))
+}
@@ -0,0 +1,105 @@
+package yamlx_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/tflyons/httpx/yamlx"
+)
+
+func TestFromConfig(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.URL.Path != "/v1/things" || r.Header.Get("X-Api-Version") != "2" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := `
+base_url: ` + srv.URL + `/v1/
+decorators:
+  - headers:
+      X-Api-Version: "2"
+  - timeout: 5s
+  - retry:
+      max_attempts: 2
+      initial_backoff: 1ms
+  - rate_limit:
+      max: 10
+      per: 1s
+`
+	c, err := yamlx.FromConfig(strings.NewReader(cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, "things", nil)
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || calls != 2 {
+		t.Fatal(resp.StatusCode, calls)
+	}
+}
+
+func TestFromConfig_Cache(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Write([]byte("cached"))
+	}))
+	defer srv.Close()
+
+	c, err := yamlx.FromConfig(strings.NewReader("decorators: [{cache: {ttl: 1m}}]"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+		resp, err := c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(b) != "cached" {
+			t.Fatal(string(b))
+		}
+	}
+	if calls != 1 {
+		t.Fatal("expected the second request to be served from the cache", calls)
+	}
+}
+
+func TestFromConfig_JSON(t *testing.T) {
+	_, err := yamlx.FromConfig(strings.NewReader(`{"decorators": [{"timeout": "1s"}, {"retry": {"max_attempts": 3}}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFromConfig_Invalid(t *testing.T) {
+	for _, cfg := range []string{
+		`decorators: [{caching: {}}]`,
+		`decorators: [{timeout: 0s}]`,
+		`decorators: [{timeout: -1s}]`,
+		`decorators: [{cache: {ttl: -1m}}]`,
+		`decorators: [{retry: {max_atempts: 3}}]`,
+		`decorators: [{timeout: soon}]`,
+		`decorators: [{timeout: 1s, retry: {}}]`,
+		`base_url: /relative`,
+	} {
+		if _, err := yamlx.FromConfig(strings.NewReader(cfg)); err == nil {
+			t.Error("expected error for", cfg)
+		}
+	}
+}
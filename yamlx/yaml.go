@@ -0,0 +1,26 @@
+// Package yamlx provides YAML body helpers for httpx clients and reads the configuration of httpx.FromConfig from
+// YAML.
+//
+// It is kept separate from httpx so that the YAML dependency is only compiled into programs that use it.
+package yamlx
+
+import (
+	"gopkg.in/yaml.v3"
+
+	"github.com/tflyons/httpx"
+)
+
+// ContentType is the media type sent and accepted by the YAML helpers
+const ContentType = "application/yaml"
+
+// SetRequestBodyYAML is a helper function around SetHeader and SetRequestBody for YAML specific encoding
+func SetRequestBodyYAML(c httpx.Client, v any) httpx.ClientFunc {
+	c = httpx.SetHeader(c, "Content-Type", ContentType)
+	return httpx.SetRequestBody(c, yaml.Marshal, v)
+}
+
+// SetResponseBodyHandlerYAML performs the request and attempts to unmarshal the response body as YAML
+func SetResponseBodyHandlerYAML(c httpx.Client, ptr any) httpx.ClientFunc {
+	c = httpx.SetHeader(c, "Accept", ContentType)
+	return httpx.SetResponseBodyHandler(c, yaml.Unmarshal, ptr)
+}
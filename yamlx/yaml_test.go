@@ -0,0 +1,43 @@
+package yamlx_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tflyons/httpx"
+	"github.com/tflyons/httpx/yamlx"
+)
+
+func TestYAML(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != yamlx.ContentType || r.Header.Get("Accept") != yamlx.ContentType {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", yamlx.ContentType)
+		_, _ = io.Copy(w, r.Body)
+	}))
+	defer srv.Close()
+
+	type deployment struct {
+		Name     string   `yaml:"name"`
+		Replicas int      `yaml:"replicas"`
+		Ports    []string `yaml:"ports"`
+	}
+	in := deployment{Name: "web", Replicas: 3, Ports: []string{"80", "443"}}
+	var out deployment
+
+	var c httpx.Client = srv.Client()
+	c = httpx.RequireResponseStatus(c, http.StatusOK)
+	c = yamlx.SetResponseBodyHandlerYAML(c, &out)
+	c = yamlx.SetRequestBodyYAML(c, in)
+	c = httpx.SetRequest(c, http.MethodPost, srv.URL)
+	if _, err := c.Do(nil); err != nil {
+		t.Fatal(err)
+	}
+	if out.Name != "web" || out.Replicas != 3 || len(out.Ports) != 2 {
+		t.Fatal(out)
+	}
+}